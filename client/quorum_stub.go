@@ -41,6 +41,9 @@ func (qc *StubQuorumClient) RPCCall(result interface{}, method string, args ...i
 		method += reflect.ValueOf(arg).String()
 	}
 	if resp, ok := qc.mockRPC[method]; ok {
+		if err, isErr := resp.(error); isErr {
+			return err
+		}
 		reflect.ValueOf(result).Elem().Set(reflect.ValueOf(resp))
 		return nil
 	}
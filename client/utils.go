@@ -4,6 +4,8 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"math/big"
+	"strings"
 
 	"quorumengineering/quorum-report/log"
 	"quorumengineering/quorum-report/types"
@@ -121,6 +123,28 @@ func CallEIP165(c Client, address types.Address, interfaceId []byte, blockNum ui
 	return asBytes[len(asBytes)-1] == 0x1, nil
 }
 
+// ReplayRevertData replays a call with eth_call at the given block, returning the data it reverted with.
+// No data is returned if the call succeeds, or if the node does not give the revert data.
+func ReplayRevertData(c Client, msg types.ReplayCall, blockNum uint64) (types.HexData, error) {
+	var res types.HexData
+	err := c.RPCCall(&res, ethCall, msg, fmtBlockNum(blockNum))
+	if rpcErr, ok := err.(*msgError); ok {
+		// the node rejected the call, which should include the revert data if it reverted
+		if data, ok := rpcErr.Data.(string); ok {
+			return types.NewHexData(data), nil
+		}
+		return types.NewHexData(""), nil
+	}
+	return types.NewHexData(""), err
+}
+
+// IsCallReverted reports whether an eth_call failed because the call itself reverted,
+// rather than because the node could not make it
+func IsCallReverted(err error) bool {
+	rpcErr, ok := err.(*msgError)
+	return ok && (rpcErr.Code == 3 || strings.Contains(strings.ToLower(rpcErr.Message), "revert"))
+}
+
 func BlockByNumber(c Client, blockNum uint64) (types.RawBlock, error) {
 	var blockOrigin types.RawBlock
 	err := c.RPCCall(&blockOrigin, getBlockByNumber, fmtBlockNum(blockNum), false)
@@ -151,15 +175,56 @@ func TransactionWithReceipt(c Client, transactionHash types.Hash) (Transaction,
 func CallBalanceOfERC20(c Client, contract types.Address, holder types.Address, blockNum uint64) (types.HexData, error) {
 	// 70a08231 is the 4byte function sig for `balanceOf(address)`
 	// "000000000000000000000000" + string(holder) is the token holders address, padded to 32 bytes
+	return CallContract(c, contract, types.NewHexData("0x70a08231"+"000000000000000000000000"+string(holder)), blockNum)
+}
+
+func CallAllowanceOfERC20(c Client, contract types.Address, owner types.Address, spender types.Address, blockNum uint64) (types.HexData, error) {
+	// dd62ed3e is the 4byte function sig for `allowance(address,address)`
+	// the owner and spender addresses are each padded to 32 bytes
+	return CallContract(c, contract, types.NewHexData("0xdd62ed3e"+"000000000000000000000000"+string(owner)+"000000000000000000000000"+string(spender)), blockNum)
+}
+
+func CallTotalSupplyOfERC20(c Client, contract types.Address, blockNum uint64) (types.HexData, error) {
+	// 18160ddd is the 4byte function sig for `totalSupply()`
+	return CallContract(c, contract, types.NewHexData("0x18160ddd"), blockNum)
+}
 
-	blockAsHex := fmtBlockNum(blockNum)
+func CallNameOfERC20(c Client, contract types.Address, blockNum uint64) (types.HexData, error) {
+	// 06fdde03 is the 4byte function sig for `name()`
+	return CallContract(c, contract, types.NewHexData("0x06fdde03"), blockNum)
+}
+
+func CallSymbolOfERC20(c Client, contract types.Address, blockNum uint64) (types.HexData, error) {
+	// 95d89b41 is the 4byte function sig for `symbol()`
+	return CallContract(c, contract, types.NewHexData("0x95d89b41"), blockNum)
+}
+
+func CallDecimalsOfERC20(c Client, contract types.Address, blockNum uint64) (types.HexData, error) {
+	// 313ce567 is the 4byte function sig for `decimals()`
+	return CallContract(c, contract, types.NewHexData("0x313ce567"), blockNum)
+}
+
+func CallBalanceOfERC1155(c Client, contract types.Address, holder types.Address, tokenId *big.Int, blockNum uint64) (types.HexData, error) {
+	// 00fdd58e is the 4byte function sig for `balanceOf(address,uint256)`
+	// the holders address and the token ID are each padded to 32 bytes
+	return CallContract(c, contract, types.NewHexData("0x00fdd58e"+"000000000000000000000000"+string(holder)+fmt.Sprintf("%064x", tokenId)), blockNum)
+}
+
+func CallTokenURIOfERC721(c Client, contract types.Address, tokenId *big.Int, blockNum uint64) (types.HexData, error) {
+	// c87b56dd is the 4byte function sig for `tokenURI(uint256)`
+	// the token ID is padded to 32 bytes
+	return CallContract(c, contract, types.NewHexData("0xc87b56dd"+fmt.Sprintf("%064x", tokenId)), blockNum)
+}
+
+// CallContract makes a read-only call to a contract with the given call data, as of the given block
+func CallContract(c Client, contract types.Address, data types.HexData, blockNum uint64) (types.HexData, error) {
 	msg := types.EIP165Call{
 		To:   contract,
-		Data: types.NewHexData("0x70a08231" + "000000000000000000000000" + string(holder)),
+		Data: data,
 	}
 
 	var res types.HexData
-	err := c.RPCCall(&res, ethCall, msg, blockAsHex)
+	err := c.RPCCall(&res, ethCall, msg, fmtBlockNum(blockNum))
 	return res, err
 }
 
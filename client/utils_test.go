@@ -1,6 +1,8 @@
 package client
 
 import (
+	"errors"
+	"math/big"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -318,6 +320,76 @@ func TestCallBalanceOfERC20(t *testing.T) {
 	assert.Equal(t, types.HexData("12345"), contractCallResult)
 }
 
+func TestCallAllowanceOfERC20(t *testing.T) {
+	mockRPC := map[string]interface{}{
+		"eth_call<types.EIP165Call Value>0x1": types.NewHexData("0x12345"),
+	}
+
+	stubClient := NewStubQuorumClient(nil, mockRPC)
+
+	tokenContract := types.NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17")
+	owner := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	spender := types.NewAddress("0xed9d02e382b34818e88b88a309c7fe71e65f419d")
+
+	contractCallResult, err := CallAllowanceOfERC20(stubClient, tokenContract, owner, spender, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, types.HexData("12345"), contractCallResult)
+}
+
+func TestCallBalanceOfERC1155_WithError(t *testing.T) {
+	stubClient := NewStubQuorumClient(nil, nil)
+
+	tokenContract := types.NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17")
+	holder := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+
+	contractCallResult, err := CallBalanceOfERC1155(stubClient, tokenContract, holder, big.NewInt(5), 1)
+	assert.EqualError(t, err, "not found")
+	assert.Equal(t, types.HexData(""), contractCallResult)
+}
+
+func TestCallBalanceOfERC1155(t *testing.T) {
+	mockRPC := map[string]interface{}{
+		"eth_call<types.EIP165Call Value>0x1": types.NewHexData("0x12345"),
+	}
+
+	stubClient := NewStubQuorumClient(nil, mockRPC)
+
+	tokenContract := types.NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17")
+	holder := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+
+	contractCallResult, err := CallBalanceOfERC1155(stubClient, tokenContract, holder, big.NewInt(5), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, types.HexData("12345"), contractCallResult)
+}
+
+func TestCallTokenURIOfERC721(t *testing.T) {
+	mockRPC := map[string]interface{}{
+		"eth_call<types.EIP165Call Value>0x1": types.NewHexData("0x12345"),
+	}
+
+	stubClient := NewStubQuorumClient(nil, mockRPC)
+
+	tokenContract := types.NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17")
+
+	contractCallResult, err := CallTokenURIOfERC721(stubClient, tokenContract, big.NewInt(5), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, types.HexData("12345"), contractCallResult)
+}
+
+func TestCallTotalSupplyOfERC20(t *testing.T) {
+	mockRPC := map[string]interface{}{
+		"eth_call<types.EIP165Call Value>0x1": types.NewHexData("0x12345"),
+	}
+
+	stubClient := NewStubQuorumClient(nil, mockRPC)
+
+	tokenContract := types.NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17")
+
+	contractCallResult, err := CallTotalSupplyOfERC20(stubClient, tokenContract, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, types.HexData("12345"), contractCallResult)
+}
+
 func TestStorageRoot_WithError(t *testing.T) {
 	stubClient := NewStubQuorumClient(nil, nil)
 
@@ -338,3 +410,54 @@ func TestStorageRoot(t *testing.T) {
 	assert.Nil(t, err)
 	assert.EqualValues(t, "0000000000000000000000000000000000000000000000000000000000000001", result)
 }
+
+func TestReplayRevertData(t *testing.T) {
+	mockRPC := map[string]interface{}{
+		"eth_call<types.ReplayCall Value>0x1": &msgError{Code: 3, Message: "execution reverted", Data: "0x08c379a0"},
+		"eth_call<types.ReplayCall Value>0x2": &msgError{Code: -32000, Message: "execution reverted"},
+		"eth_call<types.ReplayCall Value>0x3": types.NewHexData("0x"),
+	}
+	stubClient := NewStubQuorumClient(nil, mockRPC)
+
+	revertData, err := ReplayRevertData(stubClient, types.ReplayCall{}, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, types.HexData("08c379a0"), revertData)
+
+	// no revert data given by the node
+	revertData, err = ReplayRevertData(stubClient, types.ReplayCall{}, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, types.HexData(""), revertData)
+
+	// the call no longer reverts
+	revertData, err = ReplayRevertData(stubClient, types.ReplayCall{}, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, types.HexData(""), revertData)
+}
+
+func TestIsCallReverted(t *testing.T) {
+	assert.True(t, IsCallReverted(&msgError{Code: 3, Message: "execution reverted", Data: "0x08c379a0"}))
+	assert.True(t, IsCallReverted(&msgError{Code: -32000, Message: "execution reverted"}))
+
+	assert.False(t, IsCallReverted(nil))
+	assert.False(t, IsCallReverted(&msgError{Code: -32000, Message: "missing trie node"}))
+	assert.False(t, IsCallReverted(errors.New("connection refused")))
+}
+
+func TestReplayRevertData_WithError(t *testing.T) {
+	stubClient := NewStubQuorumClient(nil, nil)
+
+	revertData, err := ReplayRevertData(stubClient, types.ReplayCall{}, 1)
+	assert.EqualError(t, err, "not found")
+	assert.Equal(t, types.HexData(""), revertData)
+}
+
+func TestCallContract(t *testing.T) {
+	mockRPC := map[string]interface{}{
+		"eth_call<types.EIP165Call Value>0x5": types.NewHexData("0x00000000000000000000000000000000000000000000000000000000000003e8"),
+	}
+	stubClient := NewStubQuorumClient(nil, mockRPC)
+
+	contractCallResult, err := CallContract(stubClient, types.NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17"), types.NewHexData("0x6d4ce63c"), 5)
+	assert.Nil(t, err)
+	assert.Equal(t, types.NewHexData("0x00000000000000000000000000000000000000000000000000000000000003e8"), contractCallResult)
+}
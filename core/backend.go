@@ -58,8 +58,12 @@ func New(config types.ReportingConfig) (*Backend, error) {
 
 	// store all templates
 	log.Info("Adding templates from configuration file to database")
-	for _, template := range config.Templates {
-		if err := db.AddTemplate(template.TemplateName, template.ABI, template.StorageLayout); err != nil {
+	for _, templateConfig := range config.Templates {
+		template, err := templateConfig.ToTemplate()
+		if err != nil {
+			return nil, err
+		}
+		if err := db.AddTemplate(template); err != nil {
 			return nil, err
 		}
 	}
@@ -90,6 +94,12 @@ func New(config types.ReportingConfig) (*Backend, error) {
 			log.Info("Assign template to initial registered contract", "template", address.TemplateName, "address", address.Address.Hex())
 		}
 	}
+	log.Info("Adding function samplers from configuration file to database")
+	for _, address := range config.Addresses {
+		if err := addSamplers(db, address); err != nil {
+			return nil, err
+		}
+	}
 
 	monitorService, err := monitor.NewMonitorService(db, quorumClient, consensus, config)
 	if err != nil {
@@ -99,8 +109,8 @@ func New(config types.ReportingConfig) (*Backend, error) {
 	backendErrorChan := make(chan error)
 	return &Backend{
 		monitor:          monitorService,
-		filter:           filter.NewFilterService(db, quorumClient),
-		rpc:              rpc.NewRPCService(db, config, backendErrorChan),
+		filter:           filter.NewFilterService(db, quorumClient, config.Tokens),
+		rpc:              rpc.NewRPCService(db, quorumClient, config, backendErrorChan),
 		db:               db,
 		quorumClient:     quorumClient,
 		backendErrorChan: backendErrorChan,
@@ -134,3 +144,29 @@ func (b *Backend) Stop() {
 	// stop quorum client
 	b.quorumClient.Stop()
 }
+
+// addSamplers resolves the samplers of a configured address against the ABI of
+// its template, replacing any existing samplers with the same names
+func addSamplers(db database.Database, address *types.AddressConfig) error {
+	if len(address.Samplers) == 0 {
+		return nil
+	}
+	rawABI, err := db.GetContractABI(address.Address)
+	if err != nil {
+		return err
+	}
+	structure, err := types.NewABIStructureFromJSON(rawABI)
+	if err != nil {
+		return err
+	}
+	for _, sampler := range address.Samplers {
+		if err := sampler.Resolve(structure.ToInternalABI()); err != nil {
+			return fmt.Errorf("invalid sampler %s for address %s: %v", sampler.Function, address.Address.Hex(), err)
+		}
+		if err := db.AddSampler(address.Address, sampler); err != nil {
+			return err
+		}
+		log.Info("Add function sampler to initial registered contract", "sampler", sampler.Name, "address", address.Address.Hex())
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+
+	"quorumengineering/quorum-report/database"
+	"quorumengineering/quorum-report/log"
+	"quorumengineering/quorum-report/types"
+)
+
+var (
+	// UpgradedTopic is the topic hash of the "Upgraded(address)" event, emitted by
+	// EIP-1967 proxies when their implementation changes
+	UpgradedTopic = types.NewHash("0xbc7cd75a20ee27fd9adebab32041f755214dbc6bffa90cc0225b39da2e5c2d3b")
+
+	// implementationSlots are the standard storage slots that proxies keep their implementation address in
+	implementationSlots = []types.Hash{
+		// EIP-1967, bytes32(uint256(keccak256("eip1967.proxy.implementation")) - 1)
+		types.NewHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc"),
+		// EIP-1822, keccak256("PROXIABLE")
+		types.NewHash("0xc5f16f0fcc639fa48a6947836d9850f504798523bf8c9a3a87d5876cf622bcf7"),
+	}
+)
+
+// ProxyFilter detects registered contracts that are proxies, and records which
+// implementation contract they delegate to at each block
+type ProxyFilter struct {
+	db FilterServiceDB
+}
+
+func NewProxyFilter(db FilterServiceDB) *ProxyFilter {
+	return &ProxyFilter{db: db}
+}
+
+func (pFilter *ProxyFilter) ProcessBlocks(indexedAddresses []types.Address, blocks []*types.Block) error {
+	log.Debug("Filtering for proxy implementation changes")
+	defer func() { log.Debug("Finished filtering for proxy implementation changes") }()
+
+	current := make(map[types.Address]types.Address, len(indexedAddresses))
+	for _, address := range indexedAddresses {
+		history, err := pFilter.db.GetImplementationHistory(address)
+		if err != nil && err != database.ErrNotFound {
+			return err
+		}
+		if len(history) > 0 {
+			current[address] = history[len(history)-1].Implementation
+		}
+	}
+
+	for _, block := range blocks {
+		// each transaction is read once for all the addresses, rather than once per address
+		txs := make([]*types.Transaction, 0, len(block.Transactions))
+		for _, txHash := range block.Transactions {
+			tx, err := pFilter.db.ReadTransaction(txHash)
+			if err != nil {
+				return err
+			}
+			txs = append(txs, tx)
+		}
+
+		for _, address := range indexedAddresses {
+			implementation, err := pFilter.implementationAt(address, block, txs)
+			if err != nil {
+				return err
+			}
+			if implementation == "" || implementation == current[address] {
+				continue
+			}
+
+			log.Info("Proxy implementation changed", "proxy", address.Hex(), "implementation", implementation.Hex(), "blocknumber", block.Number)
+			change := &types.ProxyImplementation{Implementation: implementation, FromBlock: block.Number}
+			if err := pFilter.db.RecordImplementation(address, change); err != nil {
+				return err
+			}
+			current[address] = implementation
+		}
+	}
+	return nil
+}
+
+// implementationAt finds the implementation the proxy uses at the end of the given block, either from an
+// "Upgraded" event or from the standard implementation slots. An empty address is returned if the block
+// gives no information about the implementation, i.e. the contract was not called or is not a proxy.
+func (pFilter *ProxyFilter) implementationAt(address types.Address, block *types.Block, txs []*types.Transaction) (types.Address, error) {
+	var implementation types.Address
+	called := false
+	for _, tx := range txs {
+		called = called || isCalled(address, tx)
+
+		for _, event := range tx.Events {
+			if event.Address == address && len(event.Topics) == 2 && event.Topics[0] == UpgradedTopic {
+				if upgradedTo := wordToAddress(string(event.Topics[1])); !upgradedTo.IsEmpty() {
+					implementation = upgradedTo
+				}
+			}
+		}
+	}
+	if implementation != "" || !called {
+		return implementation, nil
+	}
+
+	// storage can only change when the contract is called, so there is no need to look at other blocks
+	storage, err := pFilter.db.GetStorage(address, block.Number)
+	if err != nil {
+		return "", err
+	}
+	for _, slot := range implementationSlots {
+		if value := wordToAddress(storage.Storage[slot]); !value.IsEmpty() {
+			return value, nil
+		}
+	}
+	return "", nil
+}
+
+// isCalled reports whether the contract was created or called by the transaction
+func isCalled(address types.Address, tx *types.Transaction) bool {
+	if tx.To == address || tx.CreatedContract == address {
+		return true
+	}
+	for _, internalCall := range tx.InternalCalls {
+		if internalCall.To == address {
+			return true
+		}
+	}
+	return false
+}
+
+// wordToAddress takes the address from the lowest 20 bytes of a 32 byte word,
+// returning an empty address if the word is not set
+func wordToAddress(word string) types.Address {
+	word = strings.TrimPrefix(word, "0x")
+	if word == "" {
+		return ""
+	}
+	padded := fmt.Sprintf("%064v", word)
+	return types.NewAddress(padded[24:])
+}
@@ -0,0 +1,94 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"quorumengineering/quorum-report/database/memory"
+	"quorumengineering/quorum-report/types"
+)
+
+var (
+	testProxy           = types.NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17")
+	testImplementation1 = types.NewAddress("0x9d13c6d3afe1721beef56b55d303b09e021e27ab")
+	testImplementation2 = types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+)
+
+func setupProxyTest(t *testing.T) *memory.MemoryDB {
+	db := memory.NewMemoryDB()
+	err := db.AddAddresses([]types.Address{testProxy})
+	assert.Nil(t, err)
+
+	// the proxy is created at block 1, setting its implementation in storage
+	err = db.WriteTransactions([]*types.Transaction{
+		{
+			Hash:            types.NewHash("0x01"),
+			BlockNumber:     1,
+			CreatedContract: testProxy,
+		},
+		{
+			Hash:        types.NewHash("0x02"),
+			BlockNumber: 2,
+			To:          testProxy,
+		},
+		{
+			Hash:        types.NewHash("0x03"),
+			BlockNumber: 3,
+			To:          testProxy,
+			Events: []*types.Event{
+				{
+					Address: testProxy,
+					Topics:  []types.Hash{UpgradedTopic, types.NewHash(string(testImplementation2))},
+				},
+			},
+		},
+	})
+	assert.Nil(t, err)
+
+	err = db.IndexStorage(map[types.Address]*types.AccountState{
+		testProxy: {
+			Root:    types.NewHash("0x01"),
+			Storage: map[types.Hash]string{implementationSlots[0]: string(testImplementation1)},
+		},
+	}, 1)
+	assert.Nil(t, err)
+	return db
+}
+
+func TestProxyFilter_ProcessBlocks(t *testing.T) {
+	db := setupProxyTest(t)
+	pFilter := NewProxyFilter(db)
+
+	blocks := []*types.Block{
+		{Number: 1, Transactions: []types.Hash{types.NewHash("0x01")}},
+		{Number: 2, Transactions: []types.Hash{types.NewHash("0x02")}},
+		{Number: 3, Transactions: []types.Hash{types.NewHash("0x03")}},
+	}
+	err := pFilter.ProcessBlocks([]types.Address{testProxy}, blocks)
+	assert.Nil(t, err)
+
+	history, err := db.GetImplementationHistory(testProxy)
+	assert.Nil(t, err)
+	assert.Equal(t, []*types.ProxyImplementation{
+		{Implementation: testImplementation1, FromBlock: 1},
+		{Implementation: testImplementation2, FromBlock: 3},
+	}, history)
+}
+
+func TestProxyFilter_ProcessBlocks_NotAProxy(t *testing.T) {
+	db := setupProxyTest(t)
+	pFilter := NewProxyFilter(db)
+	other := types.NewAddress("0x1234567890123456789012345678901234567890")
+
+	blocks := []*types.Block{
+		{Number: 1, Transactions: []types.Hash{types.NewHash("0x01")}},
+		{Number: 3, Transactions: []types.Hash{types.NewHash("0x03")}},
+	}
+	err := pFilter.ProcessBlocks([]types.Address{other}, blocks)
+	assert.Nil(t, err)
+
+	history, err := db.GetImplementationHistory(other)
+	assert.Nil(t, err)
+	assert.Empty(t, history)
+}
@@ -0,0 +1,84 @@
+package filter
+
+import (
+	"quorumengineering/quorum-report/client"
+	"quorumengineering/quorum-report/log"
+	"quorumengineering/quorum-report/types"
+)
+
+// SamplerFilter calls the view functions sampled for registered contracts at
+// each block they are due, recording their raw output to be decoded when queried
+type SamplerFilter struct {
+	db           FilterServiceDB
+	quorumClient client.Client
+}
+
+func NewSamplerFilter(db FilterServiceDB, quorumClient client.Client) *SamplerFilter {
+	return &SamplerFilter{db: db, quorumClient: quorumClient}
+}
+
+func (sFilter *SamplerFilter) ProcessBlocks(indexedAddresses []types.Address, blocks []*types.Block) error {
+	log.Debug("Sampling view functions")
+	defer func() { log.Debug("Finished sampling view functions") }()
+
+	for _, address := range indexedAddresses {
+		samplers, err := sFilter.db.GetSamplers(address)
+		if err != nil {
+			return err
+		}
+		if len(samplers) == 0 {
+			continue
+		}
+
+		var samples []*types.FunctionSample
+		for _, block := range blocks {
+			called, err := sFilter.isCalledInBlock(address, block)
+			if err != nil {
+				return err
+			}
+			for _, sampler := range samplers {
+				if sampler.IsDue(block.Number, called) {
+					samples = append(samples, sFilter.sample(address, sampler, block))
+				}
+			}
+		}
+		if err := sFilter.db.RecordSamples(samples); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sample calls the sampled function at the given block. A failed call, such as
+// one that reverts, is recorded as such rather than stopping the contract from being indexed.
+func (sFilter *SamplerFilter) sample(address types.Address, sampler *types.FunctionSampler, block *types.Block) *types.FunctionSample {
+	sample := &types.FunctionSample{
+		Address:     address,
+		Name:        sampler.Name,
+		Sig:         sampler.Sig,
+		BlockNumber: block.Number,
+		Timestamp:   block.Timestamp,
+	}
+	output, err := client.CallContract(sFilter.quorumClient, address, sampler.CallData, block.Number)
+	if err != nil {
+		log.Warn("Sampling view function failed", "address", address.Hex(), "sampler", sampler.Name, "blocknumber", block.Number, "err", err)
+		sample.Error = err.Error()
+		return sample
+	}
+	sample.Output = output
+	return sample
+}
+
+// isCalledInBlock reports whether the contract was created or called by any transaction in the block
+func (sFilter *SamplerFilter) isCalledInBlock(address types.Address, block *types.Block) (bool, error) {
+	for _, txHash := range block.Transactions {
+		tx, err := sFilter.db.ReadTransaction(txHash)
+		if err != nil {
+			return false, err
+		}
+		if isCalled(address, tx) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
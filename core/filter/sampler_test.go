@@ -0,0 +1,67 @@
+package filter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"quorumengineering/quorum-report/client"
+	"quorumengineering/quorum-report/database/memory"
+	"quorumengineering/quorum-report/types"
+)
+
+func TestSamplerFilter_ProcessBlocks(t *testing.T) {
+	sampled := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	db := memory.NewMemoryDB()
+	assert.Nil(t, db.AddAddresses([]types.Address{sampled, testProxy}))
+	assert.Nil(t, db.AddSampler(sampled, &types.FunctionSampler{Name: "ratio", Sig: "collateralRatio()", CallData: "b4eae1cb", Interval: 10}))
+	assert.Nil(t, db.AddSampler(sampled, &types.FunctionSampler{Name: "supply", Sig: "totalSupply()", CallData: "18160ddd"}))
+
+	// the contract is only called at block 11
+	assert.Nil(t, db.WriteTransactions([]*types.Transaction{
+		{Hash: types.NewHash("0x01"), BlockNumber: 9, To: testProxy},
+		{Hash: types.NewHash("0x02"), BlockNumber: 11, To: sampled},
+	}))
+	blocks := []*types.Block{
+		{Number: 9, Timestamp: 900, Transactions: []types.Hash{types.NewHash("0x01")}},
+		{Number: 10, Timestamp: 1000},
+		{Number: 11, Timestamp: 1100, Transactions: []types.Hash{types.NewHash("0x02")}},
+	}
+
+	mockRPC := map[string]interface{}{
+		"eth_call<types.EIP165Call Value>0xa": types.NewHexData("0x0000000000000000000000000000000000000000000000000000000000000096"),
+		"eth_call<types.EIP165Call Value>0xb": errors.New("execution reverted"),
+	}
+	samplerFilter := NewSamplerFilter(db, client.NewStubQuorumClient(nil, mockRPC))
+
+	err := samplerFilter.ProcessBlocks([]types.Address{sampled, testProxy}, blocks)
+	assert.Nil(t, err)
+
+	options := &types.QueryOptions{}
+	options.SetDefaults()
+	samples, err := db.GetSamples(sampled, "ratio", options)
+	assert.Nil(t, err)
+	assert.Equal(t, []*types.FunctionSample{
+		{
+			Address:     sampled,
+			Name:        "ratio",
+			Sig:         "collateralRatio()",
+			BlockNumber: 10,
+			Timestamp:   1000,
+			Output:      "0000000000000000000000000000000000000000000000000000000000000096",
+		},
+	}, samples)
+
+	// failed calls are recorded with their error
+	samples, err = db.GetSamples(sampled, "supply", options)
+	assert.Nil(t, err)
+	assert.Len(t, samples, 1)
+	assert.EqualValues(t, 11, samples[0].BlockNumber)
+	assert.Equal(t, "execution reverted", samples[0].Error)
+
+	// contracts without samplers are not sampled
+	samples, err = db.GetSamples(testProxy, "ratio", options)
+	assert.Nil(t, err)
+	assert.Len(t, samples, 0)
+}
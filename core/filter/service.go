@@ -14,7 +14,16 @@ import (
 //TODO: clean this type up, find a better way to pass specific methods to needed pieces
 type FilterServiceDB interface {
 	RecordNewERC20Balance(contract types.Address, holder types.Address, block uint64, amount *big.Int) error
-	RecordERC721Token(contract types.Address, holder types.Address, block uint64, tokenId *big.Int) error
+	GetERC20BalanceAtBlock(contract types.Address, holder types.Address, block uint64) (*big.Int, error)
+	GetAllTokenHolders(contract types.Address, block uint64, options *types.TokenQueryOptions) ([]types.Address, error)
+	RecordERC20Transfers(transfers []*types.ERC20Transfer) error
+	RecordNewERC20Allowance(contract types.Address, owner types.Address, spender types.Address, block uint64, amount *big.Int) error
+	RecordERC20Metadata(metadata *types.ERC20Metadata) error
+	GetERC20Metadata(contract types.Address) (*types.ERC20Metadata, error)
+	RecordNewERC20TotalSupply(contract types.Address, block uint64, amount *big.Int) error
+	RecordERC721Token(token *types.ERC721Token) error
+	RecordERC721Metadata(metadata *types.ERC721Metadata) error
+	RecordNewERC1155Balance(contract types.Address, holder types.Address, tokenId *big.Int, block uint64, amount *big.Int) error
 
 	ReadTransaction(types.Hash) (*types.Transaction, error)
 	ReadBlock(uint64) (*types.Block, error)
@@ -23,6 +32,13 @@ type FilterServiceDB interface {
 
 	GetAddresses() ([]types.Address, error)
 	GetContractABI(types.Address) (string, error)
+	GetStorage(types.Address, uint64) (*types.StorageResult, error)
+
+	RecordImplementation(types.Address, *types.ProxyImplementation) error
+	GetImplementationHistory(types.Address) ([]*types.ProxyImplementation, error)
+
+	GetSamplers(types.Address) ([]*types.FunctionSampler, error)
+	RecordSamples([]*types.FunctionSample) error
 
 	IndexBlocks([]types.Address, []*types.Block) error
 	IndexStorage(map[types.Address]*types.AccountState, uint64) error
@@ -35,22 +51,28 @@ type FilterService struct {
 
 	storageFilter          *StorageFilter
 	contractCreationFilter *ContractCreationFilter
+	proxyFilter            *ProxyFilter
+	samplerFilter          *SamplerFilter
 	erc20processor         *token.ERC20Processor
 	erc721processor        *token.ERC721Processor
+	erc1155processor       *token.ERC1155Processor
 
 	// To check we have actually shut down before returning
 	shutdownChan chan struct{}
 	shutdownWg   sync.WaitGroup
 }
 
-func NewFilterService(db FilterServiceDB, client client.Client) *FilterService {
+func NewFilterService(db FilterServiceDB, client client.Client, tokenConfig types.TokenConfig) *FilterService {
 	return &FilterService{
 		db:                     db,
 		storageFilter:          NewStorageFilter(db, client),
 		contractCreationFilter: NewContractCreationFilter(db, client),
+		proxyFilter:            NewProxyFilter(db),
+		samplerFilter:          NewSamplerFilter(db, client),
 		shutdownChan:           make(chan struct{}),
-		erc20processor:         token.NewERC20Processor(db, client),
-		erc721processor:        token.NewERC721Processor(db),
+		erc20processor:         token.NewERC20Processor(db, client, tokenConfig),
+		erc721processor:        token.NewERC721Processor(db, client),
+		erc1155processor:       token.NewERC1155Processor(db, client),
 	}
 }
 
@@ -205,6 +227,14 @@ func (fs *FilterService) processBatch(batch IndexBatch) error {
 		return err
 	}
 
+	if err := fs.proxyFilter.ProcessBlocks(batch.addresses, batch.blocks); err != nil {
+		return err
+	}
+
+	if err := fs.samplerFilter.ProcessBlocks(batch.addresses, batch.blocks); err != nil {
+		return err
+	}
+
 	addressesWithAbi := make(map[types.Address]string)
 	for _, address := range batch.addresses {
 		abi, err := fs.db.GetContractABI(address)
@@ -220,6 +250,9 @@ func (fs *FilterService) processBatch(batch IndexBatch) error {
 		if err := fs.erc721processor.ProcessBlock(addressesWithAbi, b); err != nil {
 			return err
 		}
+		if err := fs.erc1155processor.ProcessBlock(addressesWithAbi, b); err != nil {
+			return err
+		}
 	}
 
 	log.Info("Processed batch", "start", batch.blocks[0].Number, "end", batch.blocks[len(batch.blocks)-1].Number)
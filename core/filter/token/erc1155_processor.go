@@ -0,0 +1,201 @@
+package token
+
+import (
+	"errors"
+	"math/big"
+
+	"quorumengineering/quorum-report/client"
+	"quorumengineering/quorum-report/log"
+	"quorumengineering/quorum-report/types"
+)
+
+const erc1155AbiString = `[{"anonymous":false,"inputs":[{"indexed":true,"internalType":"address","name":"_owner","type":"address"},{"indexed":true,"internalType":"address","name":"_operator","type":"address"},{"indexed":false,"internalType":"bool","name":"_approved","type":"bool"}],"name":"ApprovalForAll","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"internalType":"address","name":"_operator","type":"address"},{"indexed":true,"internalType":"address","name":"_from","type":"address"},{"indexed":true,"internalType":"address","name":"_to","type":"address"},{"indexed":false,"internalType":"uint256[]","name":"_ids","type":"uint256[]"},{"indexed":false,"internalType":"uint256[]","name":"_values","type":"uint256[]"}],"name":"TransferBatch","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"internalType":"address","name":"_operator","type":"address"},{"indexed":true,"internalType":"address","name":"_from","type":"address"},{"indexed":true,"internalType":"address","name":"_to","type":"address"},{"indexed":false,"internalType":"uint256","name":"_id","type":"uint256"},{"indexed":false,"internalType":"uint256","name":"_value","type":"uint256"}],"name":"TransferSingle","type":"event"},{"anonymous":false,"inputs":[{"indexed":false,"internalType":"string","name":"_value","type":"string"},{"indexed":true,"internalType":"uint256","name":"_id","type":"uint256"}],"name":"URI","type":"event"},{"inputs":[{"internalType":"address","name":"_owner","type":"address"},{"internalType":"uint256","name":"_id","type":"uint256"}],"name":"balanceOf","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address[]","name":"_owners","type":"address[]"},{"internalType":"uint256[]","name":"_ids","type":"uint256[]"}],"name":"balanceOfBatch","outputs":[{"internalType":"uint256[]","name":"","type":"uint256[]"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"_owner","type":"address"},{"internalType":"address","name":"_operator","type":"address"}],"name":"isApprovedForAll","outputs":[{"internalType":"bool","name":"","type":"bool"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"_from","type":"address"},{"internalType":"address","name":"_to","type":"address"},{"internalType":"uint256[]","name":"_ids","type":"uint256[]"},{"internalType":"uint256[]","name":"_values","type":"uint256[]"},{"internalType":"bytes","name":"_data","type":"bytes"}],"name":"safeBatchTransferFrom","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"internalType":"address","name":"_from","type":"address"},{"internalType":"address","name":"_to","type":"address"},{"internalType":"uint256","name":"_id","type":"uint256"},{"internalType":"uint256","name":"_value","type":"uint256"},{"internalType":"bytes","name":"_data","type":"bytes"}],"name":"safeTransferFrom","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"internalType":"address","name":"_operator","type":"address"},{"internalType":"bool","name":"_approved","type":"bool"}],"name":"setApprovalForAll","outputs":[],"stateMutability":"nonpayable","type":"function"}]`
+
+var (
+	// erc1155TransferSingleTopicHash is the topic hash for an ERC1155 TransferSingle event
+	erc1155TransferSingleTopicHash = types.NewHash("0xc3d58168c5ae7397731d063d5bbf3d657854427343f4c083240f7aacaa2d0f62")
+	// erc1155TransferBatchTopicHash is the topic hash for an ERC1155 TransferBatch event
+	erc1155TransferBatchTopicHash = types.NewHash("0x4a39dc06d4c0dbc64b70af90fd698a233a518aa5d07e595d983b8c0526c8f7fb")
+	erc1155Abi, _                 = types.NewABIStructureFromJSON(erc1155AbiString)
+
+	errMalformedTransfer = errors.New("malformed ERC1155 transfer event data")
+)
+
+// ERC1155Holding identifies the balance of a single token ID held by an account
+type ERC1155Holding struct {
+	Holder  types.Address
+	TokenId string
+}
+
+type ERC1155Processor struct {
+	db     TokenFilterDatabase
+	client client.Client
+}
+
+func NewERC1155Processor(database TokenFilterDatabase, client client.Client) *ERC1155Processor {
+	return &ERC1155Processor{db: database, client: client}
+}
+
+func (p *ERC1155Processor) ProcessBlock(lastFilteredWithAbi map[types.Address]string, block *types.Block) error {
+	erc1155Contracts := p.filterForErc1155Contracts(lastFilteredWithAbi)
+
+	events := make([]*types.Event, 0)
+	for _, tx := range block.Transactions {
+		transaction, err := p.db.ReadTransaction(tx)
+		if err != nil {
+			return err
+		}
+		events = append(events, transaction.Events...)
+	}
+
+	erc1155Events := p.filterForErc1155Events(erc1155Contracts, events)
+	return p.UpdateBalances(p.ChangedHoldings(erc1155Events), block.Number)
+}
+
+// UpdateBalances fetches the balance of each changed holding at the block, and records it
+func (p *ERC1155Processor) UpdateBalances(changedHoldings map[types.Address]map[ERC1155Holding]bool, blockNum uint64) error {
+	for contract, holdings := range changedHoldings {
+		for holding := range holdings {
+			tokenId, _ := new(big.Int).SetString(holding.TokenId, 10)
+			bal, err := client.CallBalanceOfERC1155(p.client, contract, holding.Holder, tokenId, blockNum)
+			if err != nil {
+				return err
+			}
+
+			balance := new(big.Int).SetBytes(bal.AsBytes())
+			if err := p.db.RecordNewERC1155Balance(contract, holding.Holder, tokenId, blockNum, balance); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ChangedHoldings finds the senders and recipients of each token ID in the transfer events,
+// as these are the holdings whose balance has changed, leaving out the zero address of mints and burns
+func (p *ERC1155Processor) ChangedHoldings(erc1155TransferEvents []*types.Event) map[types.Address]map[ERC1155Holding]bool {
+	changedHoldings := make(map[types.Address]map[ERC1155Holding]bool)
+
+	for _, event := range erc1155TransferEvents {
+		tokenIds, err := transferredTokenIds(event)
+		if err != nil {
+			log.Warn("Unable to parse ERC1155 transfer", "contract", event.Address, "tx", event.TransactionHash, "err", err)
+			continue
+		}
+
+		fromAddress := types.NewAddress(string(event.Topics[2])[24:64]) //only take the last 40 chars (20 bytes)
+		toAddress := types.NewAddress(string(event.Topics[3])[24:64])
+
+		if changedHoldings[event.Address] == nil {
+			changedHoldings[event.Address] = make(map[ERC1155Holding]bool)
+		}
+		for _, tokenId := range tokenIds {
+			// the zero address is the source of mints and destination of burns, not a holder
+			for _, holder := range []types.Address{fromAddress, toAddress} {
+				if !holder.IsEmpty() {
+					changedHoldings[event.Address][ERC1155Holding{Holder: holder, TokenId: tokenId.String()}] = true
+				}
+			}
+		}
+	}
+	return changedHoldings
+}
+
+// filterForErc1155Events filters out all non-ERC1155 transfer events, returning
+// on the events we are interested in processing further
+func (p *ERC1155Processor) filterForErc1155Events(lastFiltered map[types.Address]bool, events []*types.Event) []*types.Event {
+	erc1155TransferEvents := make([]*types.Event, 0, len(events))
+	for _, event := range events {
+		isTransfer := len(event.Topics) == 4 &&
+			(event.Topics[0] == erc1155TransferSingleTopicHash || event.Topics[0] == erc1155TransferBatchTopicHash)
+		if lastFiltered[event.Address] && isTransfer {
+			erc1155TransferEvents = append(erc1155TransferEvents, event)
+		}
+	}
+	return erc1155TransferEvents
+}
+
+func (p *ERC1155Processor) filterForErc1155Contracts(contractsWithAbi map[types.Address]string) map[types.Address]bool {
+	erc1155Contracts := make(map[types.Address]bool)
+
+	for address, abi := range contractsWithAbi {
+		contractAbi, _ := types.NewABIStructureFromJSON(abi)
+		if isErc1155(contractAbi) {
+			erc1155Contracts[address] = true
+		}
+	}
+
+	return erc1155Contracts
+}
+
+// transferredTokenIds reads the token IDs from the data of a TransferSingle or TransferBatch event.
+// A TransferSingle has the ID as the first word, and a TransferBatch has the offset of the array of IDs.
+func transferredTokenIds(event *types.Event) ([]*big.Int, error) {
+	data := event.Data.AsBytes()
+	word := func(i int) (*big.Int, error) {
+		if i < 0 || (i+1)*32 > len(data) {
+			return nil, errMalformedTransfer
+		}
+		return new(big.Int).SetBytes(data[i*32 : (i+1)*32]), nil
+	}
+
+	if event.Topics[0] == erc1155TransferSingleTopicHash {
+		if len(data) != 64 {
+			return nil, errMalformedTransfer
+		}
+		tokenId, _ := word(0)
+		return []*big.Int{tokenId}, nil
+	}
+
+	offset, err := word(0)
+	if err != nil {
+		return nil, err
+	}
+	if !offset.IsInt64() || offset.Int64()%32 != 0 {
+		return nil, errMalformedTransfer
+	}
+	start := int(offset.Int64() / 32)
+	length, err := word(start)
+	if err != nil {
+		return nil, err
+	}
+	if !length.IsInt64() || length.Int64() > int64(len(data)/32) {
+		return nil, errMalformedTransfer
+	}
+
+	tokenIds := make([]*big.Int, length.Int64())
+	for i := range tokenIds {
+		if tokenIds[i], err = word(start + 1 + i); err != nil {
+			return nil, err
+		}
+	}
+	return tokenIds, nil
+}
+
+func isErc1155(contractAbi types.ABIStructure) bool {
+	for _, erc1155Event := range erc1155Abi.ToInternalABI().Events {
+		found := false
+		for _, contractEvent := range contractAbi.ToInternalABI().Events {
+			if erc1155Event.Signature() == contractEvent.Signature() {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for _, erc1155Method := range erc1155Abi.ToInternalABI().Functions {
+		found := false
+		for _, contractMethod := range contractAbi.ToInternalABI().Functions {
+			if erc1155Method.Signature() == contractMethod.Signature() {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
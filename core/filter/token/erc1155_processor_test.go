@@ -0,0 +1,185 @@
+package token
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"quorumengineering/quorum-report/client"
+	"quorumengineering/quorum-report/types"
+)
+
+var testErc1155TokenBlock = &types.Block{
+	Number:       1,
+	Hash:         types.NewHash("0xe625ba9f14eed0671508966080fb01374d0a3a16b9cee545a324179b75f30aa8"),
+	Transactions: []types.Hash{"f4f803b8d6c6b38e0b15d6cfe80fd1dcea4270ad24e93385fca36512bb9c2c59"},
+}
+
+func TestERC1155Processor_ProcessBlock_TxReadFail(t *testing.T) {
+	db := NewFakeTestTokenDatabase(errors.New("test tx read fail"), []*types.Transaction{})
+	processor := NewERC1155Processor(db, nil)
+
+	err := processor.ProcessBlock(map[types.Address]string{}, testErc1155TokenBlock)
+
+	assert.EqualError(t, err, "test tx read fail")
+}
+
+func TestERC1155Processor_ProcessBlock_EventForNonTrackedAddress(t *testing.T) {
+	tokenAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	tx := &types.Transaction{
+		Hash: types.NewHash("0xf4f803b8d6c6b38e0b15d6cfe80fd1dcea4270ad24e93385fca36512bb9c2c59"),
+		Events: []*types.Event{
+			{
+				Data:    types.NewHexData("0x0000000000000000000000000000000000000000000000000000000000000005000000000000000000000000000000000000000000000000000000000000000a"),
+				Address: types.NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17"),
+				Topics: []types.Hash{
+					"c3d58168c5ae7397731d063d5bbf3d657854427343f4c083240f7aacaa2d0f62",
+					"000000000000000000000000ed9d02e382b34818e88b88a309c7fe71e65f419d",
+					"000000000000000000000000ed9d02e382b34818e88b88a309c7fe71e65f419d",
+					"0000000000000000000000001349f3e1b8d71effb47b840594ff27da7e603d17",
+				},
+			},
+		},
+	}
+
+	db := NewFakeTestTokenDatabase(nil, []*types.Transaction{tx})
+	processor := NewERC1155Processor(db, nil)
+
+	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc1155AbiString}, testErc1155TokenBlock)
+
+	assert.Nil(t, err)
+	assert.Len(t, db.RecordedContract, 0)
+}
+
+func TestERC1155Processor_ProcessBlock_TransferSingle(t *testing.T) {
+	tokenAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	tx := &types.Transaction{
+		Hash:        types.NewHash("0xf4f803b8d6c6b38e0b15d6cfe80fd1dcea4270ad24e93385fca36512bb9c2c59"),
+		BlockNumber: 1,
+		Events: []*types.Event{
+			{
+				Data:    types.NewHexData("0x0000000000000000000000000000000000000000000000000000000000000005000000000000000000000000000000000000000000000000000000000000000a"),
+				Address: tokenAddress,
+				Topics: []types.Hash{
+					"c3d58168c5ae7397731d063d5bbf3d657854427343f4c083240f7aacaa2d0f62",
+					"000000000000000000000000ed9d02e382b34818e88b88a309c7fe71e65f419d",
+					"000000000000000000000000ed9d02e382b34818e88b88a309c7fe71e65f419d",
+					"0000000000000000000000001349f3e1b8d71effb47b840594ff27da7e603d17",
+				},
+			},
+		},
+	}
+
+	db := NewFakeTestTokenDatabase(nil, []*types.Transaction{tx})
+	stubClient := client.NewStubQuorumClient(nil, map[string]interface{}{
+		"eth_call<types.EIP165Call Value>0x1": types.NewHexData("0x03e8"),
+	})
+	processor := NewERC1155Processor(db, stubClient)
+
+	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc1155AbiString}, testErc1155TokenBlock)
+
+	assert.Nil(t, err)
+	assert.Len(t, db.RecordedContract, 2)
+	assert.ElementsMatch(t, []types.Address{"ed9d02e382b34818e88b88a309c7fe71e65f419d", "1349f3e1b8d71effb47b840594ff27da7e603d17"}, db.RecordedHolder)
+	assert.Equal(t, []*big.Int{big.NewInt(5), big.NewInt(5)}, db.RecordedToken)
+	assert.Equal(t, []*big.Int{big.NewInt(1000), big.NewInt(1000)}, db.RecordedAmount)
+	assert.EqualValues(t, 1, db.RecordedBlock)
+}
+
+func TestERC1155Processor_ProcessBlock_TransferBatch(t *testing.T) {
+	tokenAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	tx := &types.Transaction{
+		Hash:        types.NewHash("0xf4f803b8d6c6b38e0b15d6cfe80fd1dcea4270ad24e93385fca36512bb9c2c59"),
+		BlockNumber: 1,
+		Events: []*types.Event{
+			{
+				Data:    types.NewHexData("0x000000000000000000000000000000000000000000000000000000000000004000000000000000000000000000000000000000000000000000000000000000a0000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000050000000000000000000000000000000000000000000000000000000000000007000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002"),
+				Address: tokenAddress,
+				Topics: []types.Hash{
+					"4a39dc06d4c0dbc64b70af90fd698a233a518aa5d07e595d983b8c0526c8f7fb",
+					"000000000000000000000000ed9d02e382b34818e88b88a309c7fe71e65f419d",
+					"0000000000000000000000000000000000000000000000000000000000000000",
+					"0000000000000000000000001349f3e1b8d71effb47b840594ff27da7e603d17",
+				},
+			},
+		},
+	}
+
+	db := NewFakeTestTokenDatabase(nil, []*types.Transaction{tx})
+	stubClient := client.NewStubQuorumClient(nil, map[string]interface{}{
+		"eth_call<types.EIP165Call Value>0x1": types.NewHexData("0x12345"),
+	})
+	processor := NewERC1155Processor(db, stubClient)
+
+	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc1155AbiString}, testErc1155TokenBlock)
+
+	// the batch is minted, so only the recipient's balances are fetched
+	assert.Nil(t, err)
+	assert.Len(t, db.RecordedContract, 2)
+	assert.Equal(t, []types.Address{"1349f3e1b8d71effb47b840594ff27da7e603d17", "1349f3e1b8d71effb47b840594ff27da7e603d17"}, db.RecordedHolder)
+	assert.ElementsMatch(t, []*big.Int{big.NewInt(5), big.NewInt(7)}, db.RecordedToken)
+}
+
+func TestERC1155Processor_ProcessBlock_TransferSingleMint(t *testing.T) {
+	tokenAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	tx := &types.Transaction{
+		Hash:        types.NewHash("0xf4f803b8d6c6b38e0b15d6cfe80fd1dcea4270ad24e93385fca36512bb9c2c59"),
+		BlockNumber: 1,
+		Events: []*types.Event{
+			{
+				Data:    types.NewHexData("0x0000000000000000000000000000000000000000000000000000000000000005000000000000000000000000000000000000000000000000000000000000000a"),
+				Address: tokenAddress,
+				Topics: []types.Hash{
+					"c3d58168c5ae7397731d063d5bbf3d657854427343f4c083240f7aacaa2d0f62",
+					"000000000000000000000000ed9d02e382b34818e88b88a309c7fe71e65f419d",
+					"0000000000000000000000000000000000000000000000000000000000000000",
+					"0000000000000000000000001349f3e1b8d71effb47b840594ff27da7e603d17",
+				},
+			},
+		},
+	}
+
+	db := NewFakeTestTokenDatabase(nil, []*types.Transaction{tx})
+	stubClient := client.NewStubQuorumClient(nil, map[string]interface{}{
+		"eth_call<types.EIP165Call Value>0x1": types.NewHexData("0x0a"),
+	})
+	processor := NewERC1155Processor(db, stubClient)
+
+	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc1155AbiString}, testErc1155TokenBlock)
+
+	// balanceOf is not called for the zero address, which many tokens revert on
+	assert.Nil(t, err)
+	assert.Equal(t, []types.Address{"1349f3e1b8d71effb47b840594ff27da7e603d17"}, db.RecordedHolder)
+	assert.Equal(t, []*big.Int{big.NewInt(5)}, db.RecordedToken)
+	assert.Equal(t, []*big.Int{big.NewInt(10)}, db.RecordedAmount)
+}
+
+func TestERC1155Processor_ProcessBlock_MalformedTransferSkipped(t *testing.T) {
+	tokenAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	tx := &types.Transaction{
+		Hash:        types.NewHash("0xf4f803b8d6c6b38e0b15d6cfe80fd1dcea4270ad24e93385fca36512bb9c2c59"),
+		BlockNumber: 1,
+		Events: []*types.Event{
+			{
+				Data:    types.NewHexData("0x0000000000000000000000000000000000000000000000000000000000000040"),
+				Address: tokenAddress,
+				Topics: []types.Hash{
+					"4a39dc06d4c0dbc64b70af90fd698a233a518aa5d07e595d983b8c0526c8f7fb",
+					"000000000000000000000000ed9d02e382b34818e88b88a309c7fe71e65f419d",
+					"000000000000000000000000ed9d02e382b34818e88b88a309c7fe71e65f419d",
+					"0000000000000000000000001349f3e1b8d71effb47b840594ff27da7e603d17",
+				},
+			},
+		},
+	}
+
+	db := NewFakeTestTokenDatabase(nil, []*types.Transaction{tx})
+	processor := NewERC1155Processor(db, nil)
+
+	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc1155AbiString}, testErc1155TokenBlock)
+
+	assert.Nil(t, err)
+	assert.Len(t, db.RecordedContract, 0)
+}
@@ -1,9 +1,14 @@
 package token
 
 import (
+	"bytes"
+	"encoding/hex"
 	"math/big"
+	"unicode/utf8"
 
 	"quorumengineering/quorum-report/client"
+	"quorumengineering/quorum-report/database"
+	"quorumengineering/quorum-report/log"
 	"quorumengineering/quorum-report/types"
 )
 
@@ -12,21 +17,51 @@ const erc20AbiString = `[{"constant":false,"inputs":[{"name":"_spender","type":"
 var (
 	// erc20TransferTopicHash is the topic hash for an ERC20 Transfer event
 	erc20TransferTopicHash = types.NewHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
-	erc20Abi, _            = types.NewABIStructureFromJSON(erc20AbiString)
+	// erc20ApprovalTopicHash is the topic hash for an ERC20 Approval event
+	erc20ApprovalTopicHash = types.NewHash("0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925")
+	// erc20TransferFromSelector is the 4byte function sig for `transferFrom(address,address,uint256)`
+	erc20TransferFromSelector = []byte{0x23, 0xb8, 0x72, 0xdd}
+	erc20Abi, _               = types.NewABIStructureFromJSON(erc20AbiString)
 )
 
+// reconcilePageSize is how many holders of a token are fetched at a time when reconciling its balances
+const reconcilePageSize = 1000
+
+// ERC20AllowancePair is an owner and a spender they may have given an allowance to
+type ERC20AllowancePair struct {
+	Owner   types.Address
+	Spender types.Address
+}
+
 type ERC20Processor struct {
 	db     TokenFilterDatabase
 	client client.Client
+
+	// config holds how often the total supply is sampled, and whether balances are computed from Transfer events
+	config types.TokenConfig
+	// hasMetadata holds the contracts whose metadata is known to be recorded, to save checking the database again
+	hasMetadata map[types.Address]bool
+	// checkedDeployed holds the contracts that have been checked for whether they were deployed when first seen,
+	// after which they are only seen to be deployed by being created or having transfers
+	checkedDeployed map[types.Address]bool
 }
 
-func NewERC20Processor(database TokenFilterDatabase, client client.Client) *ERC20Processor {
-	return &ERC20Processor{db: database, client: client}
+func NewERC20Processor(database TokenFilterDatabase, client client.Client, config types.TokenConfig) *ERC20Processor {
+	return &ERC20Processor{
+		db:              database,
+		client:          client,
+		config:          config,
+		hasMetadata:     make(map[types.Address]bool),
+		checkedDeployed: make(map[types.Address]bool),
+	}
 }
 
 func (p *ERC20Processor) ProcessBlock(lastFilteredWithAbi map[types.Address]string, block *types.Block) error {
 	addressesWithChangedBalances := make(map[types.Address]map[types.Address]bool)
 	erc20Contracts := p.filterForErc20Contracts(lastFilteredWithAbi)
+	transfers := make([]*types.ERC20Transfer, 0)
+	changedAllowances := make(map[types.Address]map[ERC20AllowancePair]bool)
+	created := make(map[types.Address]bool)
 
 	for _, tx := range block.Transactions {
 		transaction, err := p.db.ReadTransaction(tx)
@@ -34,6 +69,21 @@ func (p *ERC20Processor) ProcessBlock(lastFilteredWithAbi map[types.Address]stri
 			return err
 		}
 
+		for contract := range createdContracts(erc20Contracts, transaction) {
+			created[contract] = true
+		}
+		transfers = append(transfers, p.Transfers(erc20Contracts, transaction)...)
+
+		for contract, pairs := range p.ChangedAllowances(erc20Contracts, transaction) {
+			if changedAllowances[contract] == nil {
+				changedAllowances[contract] = pairs
+				continue
+			}
+			for pair := range pairs {
+				changedAllowances[contract][pair] = true
+			}
+		}
+
 		thisTxTokenChanges := p.ChangedTokenHolders(erc20Contracts, transaction)
 		for contract, holders := range thisTxTokenChanges {
 			if addressesWithChangedBalances[contract] == nil {
@@ -46,7 +96,39 @@ func (p *ERC20Processor) ProcessBlock(lastFilteredWithAbi map[types.Address]stri
 		}
 	}
 
-	return p.UpdateBalances(addressesWithChangedBalances, block.Number)
+	if len(transfers) > 0 {
+		if err := p.db.RecordERC20Transfers(transfers); err != nil {
+			return err
+		}
+	}
+
+	// metadata is recorded first, as it holds whether the balances of a token can be computed from its events
+	deployed, err := p.deployedTokens(erc20Contracts, created, addressesWithChangedBalances, block.Number)
+	if err != nil {
+		return err
+	}
+	if err := p.UpdateMetadata(deployed, block.Number); err != nil {
+		return err
+	}
+	if p.config.EventSourcedBalances {
+		fetched, err := p.ApplyTransfers(transfers, block.Number)
+		if err != nil {
+			return err
+		}
+		toReconcile, err := p.balancesToReconcile(erc20Contracts, addressesWithChangedBalances, block.Number)
+		if err != nil {
+			return err
+		}
+		if err := p.ReconcileBalances(toReconcile, fetched, block.Number); err != nil {
+			return err
+		}
+	} else if err := p.UpdateBalances(addressesWithChangedBalances, block.Number); err != nil {
+		return err
+	}
+	if err := p.UpdateAllowances(changedAllowances, block.Number); err != nil {
+		return err
+	}
+	return p.UpdateTotalSupplies(p.ChangedSupplies(erc20Contracts, transfers, block.Number), block.Number)
 }
 
 func (p *ERC20Processor) filterForErc20Contracts(contractsWithAbi map[types.Address]string) map[types.Address]bool {
@@ -81,6 +163,363 @@ func (p *ERC20Processor) UpdateBalances(addressesWithChangedBalances map[types.A
 	return nil
 }
 
+// ApplyTransfers computes the new balance of each holder in the transfers by applying them to the holder's
+// previous balance, instead of calling balanceOf. The balance is fetched from the token instead if the holder
+// has no previous balance, if the token is flagged as having balances that change without Transfer events,
+// or if the block is due to be reconciled, in which case the token is flagged if the balances don't match.
+// The holders whose balance was fetched from the token are returned.
+func (p *ERC20Processor) ApplyTransfers(transfers []*types.ERC20Transfer, blockNum uint64) (map[types.Address]map[types.Address]bool, error) {
+	deltas := make(map[types.Address]map[types.Address]*big.Int)
+	addDelta := func(contract types.Address, holder types.Address, amount *big.Int) {
+		// the zero address is the source of mints and destination of burns, not a holder
+		if holder.IsEmpty() {
+			return
+		}
+		if deltas[contract] == nil {
+			deltas[contract] = make(map[types.Address]*big.Int)
+		}
+		if deltas[contract][holder] == nil {
+			deltas[contract][holder] = new(big.Int)
+		}
+		deltas[contract][holder].Add(deltas[contract][holder], amount)
+	}
+	for _, transfer := range transfers {
+		amount, _ := new(big.Int).SetString(transfer.Amount, 10)
+		addDelta(transfer.Contract, transfer.From, new(big.Int).Neg(amount))
+		addDelta(transfer.Contract, transfer.To, amount)
+	}
+
+	reconcile := p.config.BalanceReconcileInterval > 0 && blockNum%p.config.BalanceReconcileInterval == 0
+	fetched := make(map[types.Address]map[types.Address]bool)
+	for contract, holders := range deltas {
+		metadata, err := p.db.GetERC20Metadata(contract)
+		if err != nil && err != database.ErrNotFound {
+			return nil, err
+		}
+		drifted := metadata != nil && metadata.BalanceDrift != nil
+
+		for holder, delta := range holders {
+			balance, err := p.db.GetERC20BalanceAtBlock(contract, holder, blockNum-1)
+			if err != nil && err != database.ErrNotFound {
+				return nil, err
+			}
+			if balance != nil {
+				balance.Add(balance, delta)
+			}
+
+			if balance == nil || drifted || reconcile || balance.Sign() < 0 {
+				result, err := client.CallBalanceOfERC20(p.client, contract, holder, blockNum)
+				if err != nil {
+					return nil, err
+				}
+				actual := new(big.Int).SetBytes(result.AsBytes())
+				if fetched[contract] == nil {
+					fetched[contract] = make(map[types.Address]bool)
+				}
+				fetched[contract][holder] = true
+
+				if balance != nil && !drifted && actual.Cmp(balance) != 0 {
+					log.Warn("ERC20 token balance changed without Transfer events", "contract", contract.String(), "holder", holder.String(), "block", blockNum, "computed", balance.String(), "actual", actual.String())
+					if err := p.flagBalanceDrift(contract, blockNum); err != nil {
+						return nil, err
+					}
+					drifted = true
+				}
+				balance = actual
+			}
+
+			if err := p.db.RecordNewERC20Balance(contract, holder, blockNum, balance); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return fetched, nil
+}
+
+// balancesToReconcile finds the tokens whose holders should all have their balances checked at the block. This is
+// every token when the block is due to be reconciled, and otherwise the tokens first found to have balances that
+// change without Transfer events at the block, so that the balances recorded for them before are fetched again.
+func (p *ERC20Processor) balancesToReconcile(erc20Contracts map[types.Address]bool, changed map[types.Address]map[types.Address]bool, blockNum uint64) (map[types.Address]bool, error) {
+	if p.config.BalanceReconcileInterval > 0 && blockNum%p.config.BalanceReconcileInterval == 0 {
+		return erc20Contracts, nil
+	}
+
+	toReconcile := make(map[types.Address]bool)
+	for contract := range changed {
+		metadata, err := p.db.GetERC20Metadata(contract)
+		if err == database.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if metadata.BalanceDrift != nil && *metadata.BalanceDrift == blockNum {
+			toReconcile[contract] = true
+		}
+	}
+	return toReconcile, nil
+}
+
+// ReconcileBalances checks the recorded balance of every current holder of each token against balanceOf at the
+// block, other than the holders whose balance was already fetched at it. A balance that doesn't match is recorded
+// again, and the token is flagged as having balances that change without Transfer events.
+func (p *ERC20Processor) ReconcileBalances(contracts map[types.Address]bool, fetched map[types.Address]map[types.Address]bool, blockNum uint64) error {
+	for contract := range contracts {
+		options := &types.TokenQueryOptions{PageSize: reconcilePageSize}
+		options.SetDefaults()
+		for {
+			holders, err := p.db.GetAllTokenHolders(contract, blockNum, options)
+			if err == database.ErrNotImplemented {
+				break
+			}
+			if err != nil {
+				return err
+			}
+
+			for _, holder := range holders {
+				if fetched[contract][holder] {
+					continue
+				}
+				recorded, err := p.db.GetERC20BalanceAtBlock(contract, holder, blockNum)
+				if err != nil && err != database.ErrNotFound {
+					return err
+				}
+				result, err := client.CallBalanceOfERC20(p.client, contract, holder, blockNum)
+				if err != nil {
+					return err
+				}
+				actual := new(big.Int).SetBytes(result.AsBytes())
+				if recorded != nil && actual.Cmp(recorded) == 0 {
+					continue
+				}
+
+				log.Warn("ERC20 token balance changed without Transfer events", "contract", contract.String(), "holder", holder.String(), "block", blockNum, "actual", actual.String())
+				if err := p.flagBalanceDrift(contract, blockNum); err != nil {
+					return err
+				}
+				if err := p.db.RecordNewERC20Balance(contract, holder, blockNum, actual); err != nil {
+					return err
+				}
+			}
+
+			if len(holders) < options.PageSize {
+				break
+			}
+			options.After = holders[len(holders)-1].String()
+		}
+	}
+	return nil
+}
+
+// flagBalanceDrift records that the balances of the token change without Transfer events, from the given block
+// unless it was already flagged
+func (p *ERC20Processor) flagBalanceDrift(contract types.Address, blockNum uint64) error {
+	metadata, err := p.db.GetERC20Metadata(contract)
+	if err == database.ErrNotFound {
+		metadata = &types.ERC20Metadata{Contract: contract, BlockNumber: blockNum}
+	} else if err != nil {
+		return err
+	}
+	if metadata.BalanceDrift != nil {
+		return nil
+	}
+	metadata.FlagBalanceDrift(blockNum)
+	return p.db.RecordERC20Metadata(metadata)
+}
+
+// UpdateAllowances fetches the allowance of each changed owner and spender pair at the block, and records it
+func (p *ERC20Processor) UpdateAllowances(changedAllowances map[types.Address]map[ERC20AllowancePair]bool, blockNum uint64) error {
+	for contract, pairs := range changedAllowances {
+		for pair := range pairs {
+			result, err := client.CallAllowanceOfERC20(p.client, contract, pair.Owner, pair.Spender, blockNum)
+			if err != nil {
+				return err
+			}
+
+			allowance := new(big.Int).SetBytes(result.AsBytes())
+			if err := p.db.RecordNewERC20Allowance(contract, pair.Owner, pair.Spender, blockNum, allowance); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// deployedTokens finds the tokens seen to be deployed at the block, whose metadata can be read if it is not
+// recorded yet. A token is seen to be deployed when it is created, when it is first seen after being registered
+// if it has code by then, and otherwise when it has transfers.
+func (p *ERC20Processor) deployedTokens(erc20Contracts map[types.Address]bool, created map[types.Address]bool, changed map[types.Address]map[types.Address]bool, blockNum uint64) (map[types.Address]bool, error) {
+	deployed := make(map[types.Address]bool)
+	for contract := range created {
+		deployed[contract] = true
+	}
+	for contract := range changed {
+		deployed[contract] = true
+	}
+
+	for contract := range erc20Contracts {
+		if deployed[contract] || p.hasMetadata[contract] || p.checkedDeployed[contract] {
+			continue
+		}
+		p.checkedDeployed[contract] = true
+		if _, err := p.db.GetERC20Metadata(contract); err == nil {
+			p.hasMetadata[contract] = true
+			continue
+		} else if err != database.ErrNotFound {
+			return nil, err
+		}
+
+		code, err := client.GetCode(p.client, contract, blockNum)
+		if err != nil {
+			return nil, err
+		}
+		// a token registered before it is deployed is seen when it is created instead
+		if len(code.AsBytes()) > 0 {
+			deployed[contract] = true
+		}
+	}
+	return deployed, nil
+}
+
+// createdContracts finds the tokens created by the transaction, either directly or by another contract
+func createdContracts(erc20Contracts map[types.Address]bool, tx *types.Transaction) map[types.Address]bool {
+	created := make(map[types.Address]bool)
+	if erc20Contracts[tx.CreatedContract] {
+		created[tx.CreatedContract] = true
+	}
+	for _, internalCall := range tx.InternalCalls {
+		if (internalCall.Type == "CREATE" || internalCall.Type == "CREATE2") && erc20Contracts[internalCall.To] {
+			created[internalCall.To] = true
+		}
+	}
+	return created
+}
+
+// UpdateMetadata records the name, symbol and decimals of each token the first time it is seen to be deployed
+func (p *ERC20Processor) UpdateMetadata(contracts map[types.Address]bool, blockNum uint64) error {
+	for contract := range contracts {
+		if p.hasMetadata[contract] {
+			continue
+		}
+		if _, err := p.db.GetERC20Metadata(contract); err == nil {
+			p.hasMetadata[contract] = true
+			continue
+		} else if err != database.ErrNotFound {
+			return err
+		}
+
+		// each of these is optional in the standard, so a token may not have them
+		metadata := &types.ERC20Metadata{Contract: contract, BlockNumber: blockNum}
+		name, err := p.callOptional(client.CallNameOfERC20, contract, blockNum)
+		if err != nil {
+			return err
+		}
+		symbol, err := p.callOptional(client.CallSymbolOfERC20, contract, blockNum)
+		if err != nil {
+			return err
+		}
+		decimals, err := p.callOptional(client.CallDecimalsOfERC20, contract, blockNum)
+		if err != nil {
+			return err
+		}
+		metadata.Name = decodeTokenString(name.AsBytes())
+		metadata.Symbol = decodeTokenString(symbol.AsBytes())
+		metadata.Decimals = decodeTokenDecimals(decimals.AsBytes())
+
+		if err := p.db.RecordERC20Metadata(metadata); err != nil {
+			return err
+		}
+		p.hasMetadata[contract] = true
+	}
+	return nil
+}
+
+// callOptional calls a function the token may not implement, giving no data if the call reverts. Any other
+// failure is returned, so that the metadata is fetched again later instead of being recorded without it.
+func (p *ERC20Processor) callOptional(call func(client.Client, types.Address, uint64) (types.HexData, error), contract types.Address, blockNum uint64) (types.HexData, error) {
+	result, err := call(p.client, contract, blockNum)
+	if client.IsCallReverted(err) {
+		log.Debug("ERC20 token does not implement optional function", "contract", contract.String(), "err", err)
+		return types.NewHexData(""), nil
+	}
+	return result, err
+}
+
+// ChangedSupplies finds the tokens whose total supply should be recorded at the block, which are those
+// that were minted or burned, or all of them if the block is due to be sampled
+func (p *ERC20Processor) ChangedSupplies(lastFilteredWithAbi map[types.Address]bool, transfers []*types.ERC20Transfer, blockNum uint64) map[types.Address]bool {
+	if p.config.TotalSupplySampleInterval > 0 && blockNum%p.config.TotalSupplySampleInterval == 0 {
+		return lastFilteredWithAbi
+	}
+
+	changedSupplies := make(map[types.Address]bool)
+	for _, transfer := range transfers {
+		if transfer.Kind == types.TransferKindMint || transfer.Kind == types.TransferKindBurn {
+			changedSupplies[transfer.Contract] = true
+		}
+	}
+	return changedSupplies
+}
+
+// UpdateTotalSupplies fetches the total supply of each token at the block, and records it
+func (p *ERC20Processor) UpdateTotalSupplies(contracts map[types.Address]bool, blockNum uint64) error {
+	for contract := range contracts {
+		result, err := client.CallTotalSupplyOfERC20(p.client, contract, blockNum)
+		if err != nil {
+			return err
+		}
+		// a sampled contract may not have been deployed yet
+		if len(result.AsBytes()) == 0 {
+			continue
+		}
+
+		supply := new(big.Int).SetBytes(result.AsBytes())
+		if err := p.db.RecordNewERC20TotalSupply(contract, blockNum, supply); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ChangedAllowances finds the owner and spender pairs whose allowance may have changed in the transaction,
+// which are those given in Approval events, and those used by a successful call to transferFrom
+func (p *ERC20Processor) ChangedAllowances(lastFilteredWithAbi map[types.Address]bool, tx *types.Transaction) map[types.Address]map[ERC20AllowancePair]bool {
+	changedAllowances := make(map[types.Address]map[ERC20AllowancePair]bool)
+	add := func(contract types.Address, owner types.Address, spender types.Address) {
+		if changedAllowances[contract] == nil {
+			changedAllowances[contract] = make(map[ERC20AllowancePair]bool)
+		}
+		changedAllowances[contract][ERC20AllowancePair{Owner: owner, Spender: spender}] = true
+	}
+
+	for _, event := range tx.Events {
+		isApproval := (len(event.Topics) == 3) && (event.Topics[0] == erc20ApprovalTopicHash)
+		if lastFilteredWithAbi[event.Address] && isApproval {
+			add(event.Address, types.NewAddress(string(event.Topics[1])[24:64]), types.NewAddress(string(event.Topics[2])[24:64]))
+		}
+	}
+
+	if !tx.Status {
+		return changedAllowances
+	}
+	data := tx.Data
+	if len(tx.PrivateData) > 0 {
+		data = tx.PrivateData
+	}
+	if owner, ok := transferFromOwner(data); ok && lastFilteredWithAbi[tx.To] {
+		add(tx.To, owner, tx.From)
+	}
+	for _, call := range tx.InternalCalls {
+		if call.Type != "CALL" || call.Error != "" || !lastFilteredWithAbi[call.To] {
+			continue
+		}
+		if owner, ok := transferFromOwner(call.Input); ok {
+			add(call.To, owner, call.From)
+		}
+	}
+	return changedAllowances
+}
+
 // ChangedTokenHolders filters through all events in the transaction and
 // returns a list of all the token holders who have had a balance change
 func (p *ERC20Processor) ChangedTokenHolders(lastFilteredWithAbi map[types.Address]bool, tx *types.Transaction) map[types.Address]map[types.Address]bool {
@@ -91,6 +530,21 @@ func (p *ERC20Processor) ChangedTokenHolders(lastFilteredWithAbi map[types.Addre
 	return p.filterErc20EventsForAddresses(erc20TransferEvents)
 }
 
+// Transfers converts all the ERC20 transfer events in the transaction to transfer records
+func (p *ERC20Processor) Transfers(lastFilteredWithAbi map[types.Address]bool, tx *types.Transaction) []*types.ERC20Transfer {
+	erc20TransferEvents := p.filterForErc20Events(lastFilteredWithAbi, tx.Events)
+
+	transfers := make([]*types.ERC20Transfer, 0, len(erc20TransferEvents))
+	for _, event := range erc20TransferEvents {
+		from := types.NewAddress(string(event.Topics[1])[24:64]) //only take the last 40 chars (20 bytes)
+		to := types.NewAddress(string(event.Topics[2])[24:64])
+		amount := new(big.Int).SetBytes(event.Data.AsBytes())
+
+		transfers = append(transfers, types.NewERC20Transfer(event, from, to, amount))
+	}
+	return transfers
+}
+
 func (p *ERC20Processor) filterErc20EventsForAddresses(erc20TransferEvents []*types.Event) map[types.Address]map[types.Address]bool {
 	//find all senders and recipients for each token
 	addressesWithChangedBalances := make(map[types.Address]map[types.Address]bool)
@@ -123,6 +577,62 @@ func (p *ERC20Processor) filterForErc20Events(lastFiltered map[types.Address]boo
 	return erc20TransferEvents
 }
 
+// transferFromOwner returns the owner of the tokens moved by a call to transferFrom,
+// or false if the call data is not for transferFrom
+func transferFromOwner(data types.HexData) (types.Address, bool) {
+	input := data.AsBytes()
+	if len(input) < 4+32 || !bytes.Equal(input[:4], erc20TransferFromSelector) {
+		return "", false
+	}
+	return types.NewAddress(hex.EncodeToString(input[4+12 : 4+32])), true
+}
+
+// decodeTokenString reads the string returned by name or symbol. Some older
+// tokens return a bytes32 instead, which is padded with zero bytes.
+func decodeTokenString(data []byte) string {
+	if len(data) == 32 {
+		trimmed := bytes.TrimRight(data, "\x00")
+		if bytes.IndexByte(trimmed, 0) >= 0 {
+			return ""
+		}
+		return validString(trimmed)
+	}
+	if len(data) < 64 {
+		return ""
+	}
+
+	offset := new(big.Int).SetBytes(data[:32])
+	if !offset.IsInt64() || offset.Int64() > int64(len(data)-32) {
+		return ""
+	}
+	start := int(offset.Int64()) + 32
+	length := new(big.Int).SetBytes(data[start-32 : start])
+	if !length.IsInt64() || length.Int64() > int64(len(data)-start) {
+		return ""
+	}
+	return validString(data[start : start+int(length.Int64())])
+}
+
+// decodeTokenDecimals reads the uint8 returned by decimals
+func decodeTokenDecimals(data []byte) *uint8 {
+	if len(data) != 32 {
+		return nil
+	}
+	value := new(big.Int).SetBytes(data)
+	if !value.IsUint64() || value.Uint64() > 255 {
+		return nil
+	}
+	decimals := uint8(value.Uint64())
+	return &decimals
+}
+
+func validString(data []byte) string {
+	if !utf8.Valid(data) {
+		return ""
+	}
+	return string(data)
+}
+
 func isErc20(contractAbi types.ABIStructure) bool {
 	for _, erc20Event := range erc20Abi.ToInternalABI().Events {
 		found := false
@@ -11,6 +11,10 @@ import (
 	"quorumengineering/quorum-report/types"
 )
 
+// testErc20NotDeployed is the call giving no code for the test token at the test block, so that it is not
+// seen to be deployed until it has transfers
+const testErc20NotDeployed = "eth_getCode0x1932c48b2bf8102ba33b4a6b545c32236e342f340x1"
+
 var testErc20TokenBlock = &types.Block{
 	Number:       1,
 	Hash:         types.NewHash("0xe625ba9f14eed0671508966080fb01374d0a3a16b9cee545a324179b75f30aa8"),
@@ -19,7 +23,7 @@ var testErc20TokenBlock = &types.Block{
 
 func TestERC20Processor_ProcessBlock_TxReadFail(t *testing.T) {
 	db := NewFakeTestTokenDatabase(errors.New("test tx read fail"), []*types.Transaction{})
-	processor := NewERC20Processor(db, nil)
+	processor := NewERC20Processor(db, nil, types.TokenConfig{})
 
 	err := processor.ProcessBlock(map[types.Address]string{}, testErc20TokenBlock)
 
@@ -34,7 +38,8 @@ func TestERC20Processor_ProcessBlock_NoEventsDoesNothing(t *testing.T) {
 	}
 
 	db := NewFakeTestTokenDatabase(nil, []*types.Transaction{tx})
-	processor := NewERC20Processor(db, nil)
+	stubClient := client.NewStubQuorumClient(nil, map[string]interface{}{testErc20NotDeployed: types.NewHexData("")})
+	processor := NewERC20Processor(db, stubClient, types.TokenConfig{})
 
 	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc20AbiString}, testErc20TokenBlock)
 
@@ -56,7 +61,8 @@ func TestERC20Processor_ProcessBlock_NoErc20Events(t *testing.T) {
 	}
 
 	db := NewFakeTestTokenDatabase(nil, []*types.Transaction{tx})
-	processor := NewERC20Processor(db, nil)
+	stubClient := client.NewStubQuorumClient(nil, map[string]interface{}{testErc20NotDeployed: types.NewHexData("")})
+	processor := NewERC20Processor(db, stubClient, types.TokenConfig{})
 
 	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc20AbiString}, testErc20TokenBlock)
 
@@ -82,7 +88,8 @@ func TestERC20Processor_ProcessBlock_Erc20EventForNonTrackedAddress(t *testing.T
 	}
 
 	db := NewFakeTestTokenDatabase(nil, []*types.Transaction{tx})
-	processor := NewERC20Processor(db, nil)
+	stubClient := client.NewStubQuorumClient(nil, map[string]interface{}{testErc20NotDeployed: types.NewHexData("")})
+	processor := NewERC20Processor(db, stubClient, types.TokenConfig{})
 
 	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc20AbiString}, testErc20TokenBlock)
 
@@ -112,7 +119,7 @@ func TestERC20Processor_ProcessBlock_SingleErc20Event(t *testing.T) {
 	stubClient := client.NewStubQuorumClient(nil, map[string]interface{}{
 		"eth_call<types.EIP165Call Value>0x1": types.NewHexData("0x12345"),
 	})
-	processor := NewERC20Processor(db, stubClient)
+	processor := NewERC20Processor(db, stubClient, types.TokenConfig{})
 
 	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc20AbiString}, testErc20TokenBlock)
 
@@ -127,6 +134,142 @@ func TestERC20Processor_ProcessBlock_SingleErc20Event(t *testing.T) {
 	assert.EqualValues(t, db.RecordedToken[1], big.NewInt(4660)) //TODO: improve stub client to return different value for second account
 }
 
+func TestERC20Processor_ProcessBlock_RecordsTransfers(t *testing.T) {
+	tokenAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	tx := &types.Transaction{
+		Hash:        types.NewHash("0xf4f803b8d6c6b38e0b15d6cfe80fd1dcea4270ad24e93385fca36512bb9c2c59"),
+		BlockNumber: 1,
+		Events: []*types.Event{
+			{
+				Index:           4,
+				Data:            types.NewHexData("0x00000000000000000000000000000000000000000000000000000000000003e8"),
+				Address:         tokenAddress,
+				BlockNumber:     1,
+				TransactionHash: types.NewHash("0xf4f803b8d6c6b38e0b15d6cfe80fd1dcea4270ad24e93385fca36512bb9c2c59"),
+				Topics: []types.Hash{
+					"ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+					"0000000000000000000000000000000000000000000000000000000000000000",
+					"0000000000000000000000001349f3e1b8d71effb47b840594ff27da7e603d17",
+				},
+			},
+		},
+	}
+
+	db := NewFakeTestTokenDatabase(nil, []*types.Transaction{tx})
+	stubClient := client.NewStubQuorumClient(nil, map[string]interface{}{
+		"eth_call<types.EIP165Call Value>0x1": types.NewHexData("0x12345"),
+	})
+	processor := NewERC20Processor(db, stubClient, types.TokenConfig{})
+
+	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc20AbiString}, testErc20TokenBlock)
+
+	expected := &types.ERC20Transfer{
+		Contract:        tokenAddress,
+		From:            types.NewAddress("0x0"),
+		To:              types.NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17"),
+		Amount:          "1000",
+		Kind:            types.TransferKindMint,
+		TransactionHash: types.NewHash("0xf4f803b8d6c6b38e0b15d6cfe80fd1dcea4270ad24e93385fca36512bb9c2c59"),
+		Index:           4,
+		BlockNumber:     1,
+	}
+	assert.Nil(t, err)
+	assert.Equal(t, []*types.ERC20Transfer{expected}, db.RecordedTransfers)
+}
+
+func TestERC20Processor_ProcessBlock_ApprovalEventUpdatesAllowance(t *testing.T) {
+	tokenAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	tx := &types.Transaction{
+		Hash:        types.NewHash("0xf4f803b8d6c6b38e0b15d6cfe80fd1dcea4270ad24e93385fca36512bb9c2c59"),
+		BlockNumber: 1,
+		Events: []*types.Event{
+			{
+				Data:    types.NewHexData("0x00000000000000000000000000000000000000000000000000000000000003e8"),
+				Address: tokenAddress,
+				Topics: []types.Hash{
+					"8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925",
+					"000000000000000000000000ed9d02e382b34818e88b88a309c7fe71e65f419d",
+					"0000000000000000000000001349f3e1b8d71effb47b840594ff27da7e603d17",
+				},
+			},
+		},
+	}
+
+	db := NewFakeTestTokenDatabase(nil, []*types.Transaction{tx})
+	stubClient := client.NewStubQuorumClient(nil, map[string]interface{}{
+		testErc20NotDeployed:                  types.NewHexData(""),
+		"eth_call<types.EIP165Call Value>0x1": types.NewHexData("0x03e8"),
+	})
+	processor := NewERC20Processor(db, stubClient, types.TokenConfig{})
+
+	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc20AbiString}, testErc20TokenBlock)
+
+	expected := types.ERC20Allowance{
+		Contract:    tokenAddress,
+		Owner:       types.NewAddress("0xed9d02e382b34818e88b88a309c7fe71e65f419d"),
+		Spender:     types.NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17"),
+		Amount:      "1000",
+		BlockNumber: 1,
+	}
+	assert.Nil(t, err)
+	assert.Equal(t, []types.ERC20Allowance{expected}, db.RecordedAllowances)
+	assert.Len(t, db.RecordedHolder, 0)
+}
+
+func TestERC20Processor_ProcessBlock_TransferFromCallUpdatesAllowance(t *testing.T) {
+	tokenAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	transferFromData := "0x23b872dd" +
+		"000000000000000000000000ed9d02e382b34818e88b88a309c7fe71e65f419d" +
+		"0000000000000000000000008a5e2a6343108babed07899510fb42297938d41f" +
+		"00000000000000000000000000000000000000000000000000000000000003e8"
+	tx := &types.Transaction{
+		Hash:        types.NewHash("0xf4f803b8d6c6b38e0b15d6cfe80fd1dcea4270ad24e93385fca36512bb9c2c59"),
+		Status:      true,
+		BlockNumber: 1,
+		From:        types.NewAddress("0xabcdef0000000000000000000000000000000000"),
+		To:          types.NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17"),
+		InternalCalls: []*types.InternalCall{
+			{
+				From:  types.NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17"),
+				To:    tokenAddress,
+				Type:  "CALL",
+				Input: types.NewHexData(transferFromData),
+			},
+		},
+	}
+	failedTx := &types.Transaction{
+		Hash:        types.NewHash("0x0dcd9ca18a6f9e0e8dc5e3fa0bc6b04a1a2bc8fe1b6b85d0f4ef6c5f2ef68e4b"),
+		Status:      false,
+		BlockNumber: 1,
+		From:        types.NewAddress("0xabcdef0000000000000000000000000000000000"),
+		To:          tokenAddress,
+		Data:        types.NewHexData(transferFromData),
+	}
+	block := &types.Block{
+		Number:       1,
+		Transactions: []types.Hash{tx.Hash, failedTx.Hash},
+	}
+
+	db := NewFakeTestTokenDatabase(nil, []*types.Transaction{tx, failedTx})
+	stubClient := client.NewStubQuorumClient(nil, map[string]interface{}{
+		testErc20NotDeployed:                  types.NewHexData(""),
+		"eth_call<types.EIP165Call Value>0x1": types.NewHexData("0x00"),
+	})
+	processor := NewERC20Processor(db, stubClient, types.TokenConfig{})
+
+	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc20AbiString}, block)
+
+	expected := types.ERC20Allowance{
+		Contract:    tokenAddress,
+		Owner:       types.NewAddress("0xed9d02e382b34818e88b88a309c7fe71e65f419d"),
+		Spender:     types.NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17"),
+		Amount:      "0",
+		BlockNumber: 1,
+	}
+	assert.Nil(t, err)
+	assert.Equal(t, []types.ERC20Allowance{expected}, db.RecordedAllowances)
+}
+
 func TestERC20Processor_ProcessBlock_SingleErc20EventOnNonErc20Contract(t *testing.T) {
 	tokenAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
 	tx := &types.Transaction{
@@ -149,7 +292,7 @@ func TestERC20Processor_ProcessBlock_SingleErc20EventOnNonErc20Contract(t *testi
 	stubClient := client.NewStubQuorumClient(nil, map[string]interface{}{
 		"eth_call<types.EIP165Call Value>0x1": types.NewHexData("0x12345"),
 	})
-	processor := NewERC20Processor(db, stubClient)
+	processor := NewERC20Processor(db, stubClient, types.TokenConfig{})
 
 	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: `{}`}, testErc20TokenBlock)
 
@@ -180,7 +323,7 @@ func TestERC20Processor_ProcessBlock_SingleErc20Event_WithClientError(t *testing
 
 	db := NewFakeTestTokenDatabase(nil, []*types.Transaction{tx})
 	stubClient := client.NewStubQuorumClient(nil, nil)
-	processor := NewERC20Processor(db, stubClient)
+	processor := NewERC20Processor(db, stubClient, types.TokenConfig{})
 
 	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc20AbiString}, testErc20TokenBlock)
 
@@ -210,7 +353,7 @@ func TestERC20Processor_ProcessBlock_SingleErc20Event_WithDatabaseError(t *testi
 	stubClient := client.NewStubQuorumClient(nil, map[string]interface{}{
 		"eth_call<types.EIP165Call Value>0x1": types.NewHexData("0x12345"),
 	})
-	processor := NewERC20Processor(db, stubClient)
+	processor := NewERC20Processor(db, stubClient, types.TokenConfig{})
 
 	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc20AbiString}, testErc20TokenBlock)
 
@@ -248,7 +391,7 @@ func TestERC20Processor_ProcessBlock_MultipleErc20Events(t *testing.T) {
 	stubClient := client.NewStubQuorumClient(nil, map[string]interface{}{
 		"eth_call<types.EIP165Call Value>0x1": types.NewHexData("0x12345"),
 	})
-	processor := NewERC20Processor(db, stubClient)
+	processor := NewERC20Processor(db, stubClient, types.TokenConfig{})
 
 	err := processor.ProcessBlock(map[types.Address]string{
 		types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34"): erc20AbiString,
@@ -270,3 +413,344 @@ func TestERC20Processor_ProcessBlock_MultipleErc20Events(t *testing.T) {
 	assert.EqualValues(t, db.RecordedToken[2], big.NewInt(4660)) //TODO: improve stub client to return different value for second account
 	assert.EqualValues(t, db.RecordedToken[3], big.NewInt(4660)) //TODO: improve stub client to return different value for second account
 }
+
+func TestERC20Processor_ProcessBlock_MintRecordsMetadataAndTotalSupply(t *testing.T) {
+	tokenAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	tx := &types.Transaction{
+		Hash:        types.NewHash("0xf4f803b8d6c6b38e0b15d6cfe80fd1dcea4270ad24e93385fca36512bb9c2c59"),
+		BlockNumber: 1,
+		Events: []*types.Event{
+			{
+				Data:    types.NewHexData("0x00000000000000000000000000000000000000000000000000000000000003e8"),
+				Address: tokenAddress,
+				Topics: []types.Hash{
+					"ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+					"0000000000000000000000000000000000000000000000000000000000000000",
+					"0000000000000000000000001349f3e1b8d71effb47b840594ff27da7e603d17",
+				},
+			},
+		},
+	}
+
+	db := NewFakeTestTokenDatabase(nil, []*types.Transaction{tx})
+	stubClient := client.NewStubQuorumClient(nil, map[string]interface{}{
+		"eth_call<types.EIP165Call Value>0x1": types.NewHexData("0x0000000000000000000000000000000000000000000000000000000000000012"),
+	})
+	processor := NewERC20Processor(db, stubClient, types.TokenConfig{})
+
+	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc20AbiString}, testErc20TokenBlock)
+	assert.Nil(t, err)
+	err = processor.ProcessBlock(map[types.Address]string{tokenAddress: erc20AbiString}, testErc20TokenBlock)
+	assert.Nil(t, err)
+
+	assert.Len(t, db.RecordedMetadata, 1)
+	assert.Equal(t, tokenAddress, db.RecordedMetadata[0].Contract)
+	assert.Equal(t, "", db.RecordedMetadata[0].Name)
+	assert.EqualValues(t, 18, *db.RecordedMetadata[0].Decimals)
+	assert.Len(t, db.RecordedSupplies, 2)
+	assert.Equal(t, "18", db.RecordedSupplies[0].Amount)
+	assert.EqualValues(t, 1, db.RecordedSupplies[0].BlockNumber)
+}
+
+func TestERC20Processor_UpdateMetadata_CallFails(t *testing.T) {
+	tokenAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	contracts := map[types.Address]bool{tokenAddress: true}
+	db := NewFakeTestTokenDatabase(nil, nil)
+
+	// the node failing to make the call is not the token lacking metadata, so nothing is recorded
+	processor := NewERC20Processor(db, client.NewStubQuorumClient(nil, nil), types.TokenConfig{})
+	err := processor.UpdateMetadata(contracts, 1)
+	assert.EqualError(t, err, "not found")
+	assert.Len(t, db.RecordedMetadata, 0)
+
+	// and it is fetched again at the next block
+	processor.client = client.NewStubQuorumClient(nil, map[string]interface{}{
+		"eth_call<types.EIP165Call Value>0x2": types.NewHexData("0x0000000000000000000000000000000000000000000000000000000000000012"),
+	})
+	err = processor.UpdateMetadata(contracts, 2)
+	assert.Nil(t, err)
+	assert.Len(t, db.RecordedMetadata, 1)
+	assert.EqualValues(t, 18, *db.RecordedMetadata[0].Decimals)
+}
+
+func TestERC20Processor_ProcessBlock_RecordsMetadataWhenCreated(t *testing.T) {
+	tokenAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	tx := &types.Transaction{
+		Hash:            types.NewHash("0xf4f803b8d6c6b38e0b15d6cfe80fd1dcea4270ad24e93385fca36512bb9c2c59"),
+		BlockNumber:     1,
+		CreatedContract: tokenAddress,
+		Events:          []*types.Event{},
+	}
+
+	db := NewFakeTestTokenDatabase(nil, []*types.Transaction{tx})
+	stubClient := client.NewStubQuorumClient(nil, map[string]interface{}{
+		"eth_call<types.EIP165Call Value>0x1": types.NewHexData("0x0000000000000000000000000000000000000000000000000000000000000012"),
+	})
+	processor := NewERC20Processor(db, stubClient, types.TokenConfig{})
+
+	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc20AbiString}, testErc20TokenBlock)
+
+	// the metadata is read without the token needing any transfers
+	assert.Nil(t, err)
+	assert.Len(t, db.RecordedMetadata, 1)
+	assert.EqualValues(t, 1, db.RecordedMetadata[0].BlockNumber)
+	assert.EqualValues(t, 18, *db.RecordedMetadata[0].Decimals)
+}
+
+func TestERC20Processor_ProcessBlock_RecordsMetadataWhenFirstSeenDeployed(t *testing.T) {
+	tokenAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	tx := &types.Transaction{
+		Hash:   types.NewHash("0xf4f803b8d6c6b38e0b15d6cfe80fd1dcea4270ad24e93385fca36512bb9c2c59"),
+		Events: []*types.Event{},
+	}
+
+	// a token registered before it is deployed has no metadata read, and is not checked again
+	db := NewFakeTestTokenDatabase(nil, []*types.Transaction{tx})
+	stubClient := client.NewStubQuorumClient(nil, map[string]interface{}{testErc20NotDeployed: types.NewHexData("")})
+	processor := NewERC20Processor(db, stubClient, types.TokenConfig{})
+	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc20AbiString}, testErc20TokenBlock)
+	assert.Nil(t, err)
+	assert.Len(t, db.RecordedMetadata, 0)
+	processor.client = client.NewStubQuorumClient(nil, nil)
+	err = processor.ProcessBlock(map[types.Address]string{tokenAddress: erc20AbiString}, testErc20TokenBlock)
+	assert.Nil(t, err)
+
+	// a token registered after it is deployed has its metadata read when it is first seen
+	db = NewFakeTestTokenDatabase(nil, []*types.Transaction{tx})
+	stubClient = client.NewStubQuorumClient(nil, map[string]interface{}{
+		testErc20NotDeployed:                  types.NewHexData("0x6080604052"),
+		"eth_call<types.EIP165Call Value>0x1": types.NewHexData("0x0000000000000000000000000000000000000000000000000000000000000012"),
+	})
+	processor = NewERC20Processor(db, stubClient, types.TokenConfig{})
+	err = processor.ProcessBlock(map[types.Address]string{tokenAddress: erc20AbiString}, testErc20TokenBlock)
+	assert.Nil(t, err)
+	assert.Len(t, db.RecordedMetadata, 1)
+	assert.EqualValues(t, 18, *db.RecordedMetadata[0].Decimals)
+}
+
+func TestERC20Processor_ProcessBlock_SamplesTotalSupplyAtInterval(t *testing.T) {
+	tokenAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	tx := &types.Transaction{
+		Hash:   types.NewHash("0xf4f803b8d6c6b38e0b15d6cfe80fd1dcea4270ad24e93385fca36512bb9c2c59"),
+		Events: []*types.Event{},
+	}
+
+	db := NewFakeTestTokenDatabase(nil, []*types.Transaction{tx})
+	stubClient := client.NewStubQuorumClient(nil, map[string]interface{}{
+		testErc20NotDeployed:                  types.NewHexData(""),
+		"eth_call<types.EIP165Call Value>0x1": types.NewHexData("0x03e8"),
+	})
+
+	err := NewERC20Processor(db, stubClient, types.TokenConfig{TotalSupplySampleInterval: 2}).ProcessBlock(map[types.Address]string{tokenAddress: erc20AbiString}, testErc20TokenBlock)
+	assert.Nil(t, err)
+	assert.Len(t, db.RecordedSupplies, 0)
+
+	err = NewERC20Processor(db, stubClient, types.TokenConfig{TotalSupplySampleInterval: 1}).ProcessBlock(map[types.Address]string{tokenAddress: erc20AbiString}, testErc20TokenBlock)
+	assert.Nil(t, err)
+	assert.Len(t, db.RecordedSupplies, 1)
+	assert.Equal(t, tokenAddress, db.RecordedSupplies[0].Contract)
+	assert.Equal(t, "1000", db.RecordedSupplies[0].Amount)
+	assert.Len(t, db.RecordedMetadata, 0)
+}
+
+func TestERC20Processor_ProcessBlock_EventSourcedBalances(t *testing.T) {
+	tokenAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	sender := types.NewAddress("ed9d02e382b34818e88b88a309c7fe71e65f419d")
+	recipient := types.NewAddress("1349f3e1b8d71effb47b840594ff27da7e603d17")
+	newHolder := types.NewAddress("9d13c6d3afe1721beef56b55d303b09e021e27ab")
+	tx := &types.Transaction{
+		Hash: types.NewHash("0xf4f803b8d6c6b38e0b15d6cfe80fd1dcea4270ad24e93385fca36512bb9c2c59"),
+		Events: []*types.Event{
+			{
+				Data:    types.NewHexData("0x00000000000000000000000000000000000000000000000000000000000003e8"),
+				Address: tokenAddress,
+				Topics: []types.Hash{
+					"ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+					"000000000000000000000000ed9d02e382b34818e88b88a309c7fe71e65f419d",
+					"0000000000000000000000001349f3e1b8d71effb47b840594ff27da7e603d17",
+				},
+			},
+			{
+				Index:   1,
+				Data:    types.NewHexData("0x0000000000000000000000000000000000000000000000000000000000000064"),
+				Address: tokenAddress,
+				Topics: []types.Hash{
+					"ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+					"0000000000000000000000000000000000000000000000000000000000000000",
+					"0000000000000000000000009d13c6d3afe1721beef56b55d303b09e021e27ab",
+				},
+			},
+		},
+	}
+
+	db := NewFakeTestTokenDatabase(nil, []*types.Transaction{tx})
+	db.PreviousBalances = map[types.Address]*big.Int{sender: big.NewInt(5000), recipient: big.NewInt(10)}
+	stubClient := client.NewStubQuorumClient(nil, map[string]interface{}{
+		"eth_call<types.EIP165Call Value>0x1": types.NewHexData("0x1234"),
+	})
+	processor := NewERC20Processor(db, stubClient, types.TokenConfig{EventSourcedBalances: true})
+
+	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc20AbiString}, testErc20TokenBlock)
+
+	assert.Nil(t, err)
+	balances := make(map[types.Address]*big.Int)
+	for i, holder := range db.RecordedHolder {
+		balances[holder] = db.RecordedToken[i]
+	}
+	assert.Len(t, balances, 3)
+	assert.EqualValues(t, big.NewInt(4000), balances[sender])
+	assert.EqualValues(t, big.NewInt(1010), balances[recipient])
+	// a holder with no previous balance has it fetched from the token
+	assert.EqualValues(t, big.NewInt(4660), balances[newHolder])
+	assert.Nil(t, db.RecordedMetadata[0].BalanceDrift)
+}
+
+func TestERC20Processor_ProcessBlock_EventSourcedBalancesFlagsDrift(t *testing.T) {
+	tokenAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	sender := types.NewAddress("ed9d02e382b34818e88b88a309c7fe71e65f419d")
+	recipient := types.NewAddress("1349f3e1b8d71effb47b840594ff27da7e603d17")
+	tx := &types.Transaction{
+		Hash: types.NewHash("0xf4f803b8d6c6b38e0b15d6cfe80fd1dcea4270ad24e93385fca36512bb9c2c59"),
+		Events: []*types.Event{
+			{
+				Data:    types.NewHexData("0x00000000000000000000000000000000000000000000000000000000000003e8"),
+				Address: tokenAddress,
+				Topics: []types.Hash{
+					"ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+					"000000000000000000000000ed9d02e382b34818e88b88a309c7fe71e65f419d",
+					"0000000000000000000000001349f3e1b8d71effb47b840594ff27da7e603d17",
+				},
+			},
+		},
+	}
+
+	db := NewFakeTestTokenDatabase(nil, []*types.Transaction{tx})
+	db.PreviousBalances = map[types.Address]*big.Int{sender: big.NewInt(5000), recipient: big.NewInt(10)}
+	stubClient := client.NewStubQuorumClient(nil, map[string]interface{}{
+		"eth_call<types.EIP165Call Value>0x1": types.NewHexData("0x1234"),
+	})
+	processor := NewERC20Processor(db, stubClient, types.TokenConfig{EventSourcedBalances: true, BalanceReconcileInterval: 1})
+
+	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc20AbiString}, testErc20TokenBlock)
+
+	assert.Nil(t, err)
+	// the balances of the token don't match the events, so the actual balances are recorded
+	assert.Len(t, db.RecordedToken, 2)
+	assert.EqualValues(t, big.NewInt(4660), db.RecordedToken[0])
+	assert.EqualValues(t, big.NewInt(4660), db.RecordedToken[1])
+	metadata, err := db.GetERC20Metadata(tokenAddress)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, *metadata.BalanceDrift)
+}
+
+func TestERC20Processor_ProcessBlock_ReconcilesAllHolders(t *testing.T) {
+	tokenAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	matching := types.NewAddress("1349f3e1b8d71effb47b840594ff27da7e603d17")
+	drifted := types.NewAddress("ed9d02e382b34818e88b88a309c7fe71e65f419d")
+	tx := &types.Transaction{
+		Hash:   types.NewHash("0xf4f803b8d6c6b38e0b15d6cfe80fd1dcea4270ad24e93385fca36512bb9c2c59"),
+		Events: []*types.Event{},
+	}
+
+	db := NewFakeTestTokenDatabase(nil, []*types.Transaction{tx})
+	db.PreviousBalances = map[types.Address]*big.Int{matching: big.NewInt(4660), drifted: big.NewInt(10)}
+	db.Holders = []types.Address{matching, drifted}
+	stubClient := client.NewStubQuorumClient(nil, map[string]interface{}{
+		testErc20NotDeployed:                  types.NewHexData(""),
+		"eth_call<types.EIP165Call Value>0x1": types.NewHexData("0x1234"),
+	})
+	processor := NewERC20Processor(db, stubClient, types.TokenConfig{EventSourcedBalances: true, BalanceReconcileInterval: 1})
+
+	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc20AbiString}, testErc20TokenBlock)
+
+	// holders are checked even without transfers in the block, and only those that don't match are recorded
+	assert.Nil(t, err)
+	assert.Equal(t, []types.Address{drifted}, db.RecordedHolder)
+	assert.Equal(t, []*big.Int{big.NewInt(4660)}, db.RecordedToken)
+	metadata, err := db.GetERC20Metadata(tokenAddress)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, *metadata.BalanceDrift)
+}
+
+func TestERC20Processor_ProcessBlock_RefetchesBalancesOnceDrifted(t *testing.T) {
+	tokenAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	sender := types.NewAddress("ed9d02e382b34818e88b88a309c7fe71e65f419d")
+	recipient := types.NewAddress("1349f3e1b8d71effb47b840594ff27da7e603d17")
+	other := types.NewAddress("9d13c6d3afe1721beef56b55d303b09e021e27ab")
+	tx := &types.Transaction{
+		Hash: types.NewHash("0xf4f803b8d6c6b38e0b15d6cfe80fd1dcea4270ad24e93385fca36512bb9c2c59"),
+		Events: []*types.Event{
+			{
+				Data:    types.NewHexData("0x00000000000000000000000000000000000000000000000000000000000003e8"),
+				Address: tokenAddress,
+				Topics: []types.Hash{
+					"ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+					"000000000000000000000000ed9d02e382b34818e88b88a309c7fe71e65f419d",
+					"0000000000000000000000001349f3e1b8d71effb47b840594ff27da7e603d17",
+				},
+			},
+		},
+	}
+
+	// the sender sends more than the balance computed from events, so the token is found to have drifted
+	db := NewFakeTestTokenDatabase(nil, []*types.Transaction{tx})
+	db.PreviousBalances = map[types.Address]*big.Int{sender: big.NewInt(10), recipient: big.NewInt(10), other: big.NewInt(10)}
+	db.Holders = []types.Address{recipient, other, sender}
+	stubClient := client.NewStubQuorumClient(nil, map[string]interface{}{
+		"eth_call<types.EIP165Call Value>0x1": types.NewHexData("0x1234"),
+	})
+	processor := NewERC20Processor(db, stubClient, types.TokenConfig{EventSourcedBalances: true})
+
+	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc20AbiString}, testErc20TokenBlock)
+
+	// the holder without a transfer has its stale balance fetched again, as does the recipient if its balance
+	// was computed from events before the drift was found
+	assert.Nil(t, err)
+	latest := make(map[types.Address]*big.Int)
+	for i, holder := range db.RecordedHolder {
+		latest[holder] = db.RecordedToken[i]
+	}
+	assert.Equal(t, map[types.Address]*big.Int{sender: big.NewInt(4660), recipient: big.NewInt(4660), other: big.NewInt(4660)}, latest)
+	metadata, err := db.GetERC20Metadata(tokenAddress)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, *metadata.BalanceDrift)
+}
+
+func TestERC20Processor_ApplyTransfers_ReturnsFetchedHolders(t *testing.T) {
+	tokenAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	otherToken := types.NewAddress("0x9d13c6d3afe1721beef56b55d303b09e021e27ab")
+	sender := types.NewAddress("ed9d02e382b34818e88b88a309c7fe71e65f419d")
+	recipient := types.NewAddress("1349f3e1b8d71effb47b840594ff27da7e603d17")
+
+	db := NewFakeTestTokenDatabase(nil, nil)
+	db.PreviousBalances = map[types.Address]*big.Int{sender: big.NewInt(10), recipient: big.NewInt(10)}
+	stubClient := client.NewStubQuorumClient(nil, map[string]interface{}{
+		"eth_call<types.EIP165Call Value>0x1": types.NewHexData("0x1234"),
+	})
+	processor := NewERC20Processor(db, stubClient, types.TokenConfig{EventSourcedBalances: true})
+
+	// the sender of the other token goes negative, so is fetched, while the recipient is computed from events
+	fetched, err := processor.ApplyTransfers([]*types.ERC20Transfer{
+		{Contract: tokenAddress, From: sender, To: recipient, Amount: "5"},
+		{Contract: otherToken, From: sender, To: types.NewAddress("0x0000000000000000000000000000000000000000"), Amount: "20"},
+	}, 1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[types.Address]map[types.Address]bool{otherToken: {sender: true}}, fetched)
+}
+
+func TestDecodeTokenString(t *testing.T) {
+	abiEncoded := types.NewHexData("0x" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"000000000000000000000000000000000000000000000000000000000000000a" +
+		"5465737420546f6b656e00000000000000000000000000000000000000000000")
+	bytes32 := types.NewHexData("0x4d4b520000000000000000000000000000000000000000000000000000000000")
+	badLength := types.NewHexData("0x" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"00000000000000000000000000000000000000000000000000000000000000ff")
+
+	assert.Equal(t, "Test Token", decodeTokenString(abiEncoded.AsBytes()))
+	assert.Equal(t, "MKR", decodeTokenString(bytes32.AsBytes()))
+	assert.Equal(t, "", decodeTokenString(badLength.AsBytes()))
+	assert.Equal(t, "", decodeTokenString(nil))
+}
@@ -1,9 +1,16 @@
 package token
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
 	"math/big"
+	"net/url"
 	"sort"
+	"strings"
 
+	"quorumengineering/quorum-report/client"
+	"quorumengineering/quorum-report/log"
 	"quorumengineering/quorum-report/types"
 )
 
@@ -12,15 +19,37 @@ const erc721AbiString = `[{"anonymous":false,"inputs":[{"indexed":true,"internal
 var (
 	// erc721TransferTopicHash is the topic hash for an ERC721 Transfer event
 	erc721TransferTopicHash = types.NewHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
-	erc721Abi, _            = types.NewABIStructureFromJSON(erc721AbiString)
+	// erc721MetadataUpdateTopicHash is the topic hash for an EIP-4906 MetadataUpdate event
+	erc721MetadataUpdateTopicHash = types.NewHash("0xf8e1a15aba9398e019f0b49df1a4fde98ee17ae345cb5f6b5e2c27f5033e8ce7")
+	// erc721BatchMetadataUpdateTopicHash is the topic hash for an EIP-4906 BatchMetadataUpdate event
+	erc721BatchMetadataUpdateTopicHash = types.NewHash("0x6bd5c950a8d8df17f772f5af37cb3655737899cbf903264b9795592da439661c")
+	erc721Abi, _                       = types.NewABIStructureFromJSON(erc721AbiString)
 )
 
+// maxBatchMetadataUpdate is the most tokens a BatchMetadataUpdate event is followed for, as the
+// range may cover every possible token ID
+const maxBatchMetadataUpdate = 1000
+
+// erc721Names is the name and symbol of an ERC721 contract
+type erc721Names struct {
+	name   string
+	symbol string
+}
+
 type ERC721Processor struct {
-	db TokenFilterDatabase
+	db     TokenFilterDatabase
+	client client.Client
+
+	// names holds the name and symbol of each contract, so they are only fetched once
+	names map[types.Address]erc721Names
 }
 
-func NewERC721Processor(database TokenFilterDatabase) *ERC721Processor {
-	return &ERC721Processor{db: database}
+func NewERC721Processor(database TokenFilterDatabase, client client.Client) *ERC721Processor {
+	return &ERC721Processor{
+		db:     database,
+		client: client,
+		names:  make(map[types.Address]erc721Names),
+	}
 }
 
 func (p *ERC721Processor) ProcessBlock(lastFilteredWithAbi map[types.Address]string, block *types.Block) error {
@@ -35,17 +64,84 @@ func (p *ERC721Processor) ProcessBlock(lastFilteredWithAbi map[types.Address]str
 		events = append(events, transaction.Events...)
 	}
 	erc721Events := p.filterForErc721Events(erc721Contracts, events)
-	mappedTokens := p.MapEventsToHolders(erc721Events)
-	return p.SaveTokenTransfers(mappedTokens, block.Number)
+	mappedTokens := p.MapEventsToHolders(erc721Events, block.Number)
+	if err := p.SaveTokenTransfers(mappedTokens); err != nil {
+		return err
+	}
+
+	changedMetadata := p.ChangedMetadata(erc721Contracts, mappedTokens, events)
+	return p.UpdateMetadata(changedMetadata, block.Number)
 }
 
-func (p *ERC721Processor) SaveTokenTransfers(tokenTransfers map[types.Address]map[string]types.Address, blockNum uint64) error {
-	for contract, holderMap := range tokenTransfers {
-		for token, holder := range holderMap {
-			convertedToken := types.NewHexData(token)
-			tokenId := new(big.Int).SetBytes(convertedToken.AsBytes())
+// ChangedMetadata finds the tokens whose metadata should be recorded at the block, which are those that
+// were minted, and those the contract signalled a change for with a MetadataUpdate or BatchMetadataUpdate event
+func (p *ERC721Processor) ChangedMetadata(erc721Contracts map[types.Address]bool, tokenTransfers map[types.Address]map[string]*types.ERC721Token, events []*types.Event) map[types.Address]map[string]bool {
+	changed := make(map[types.Address]map[string]bool)
+	add := func(contract types.Address, tokenId string) {
+		if changed[contract] == nil {
+			changed[contract] = make(map[string]bool)
+		}
+		changed[contract][tokenId] = true
+	}
 
-			if err := p.db.RecordERC721Token(contract, holder, blockNum, tokenId); err != nil {
+	for contract, tokenMap := range tokenTransfers {
+		for _, token := range tokenMap {
+			if token.Kind == types.TransferKindMint {
+				add(contract, token.Token)
+			}
+		}
+	}
+
+	for _, event := range events {
+		if !erc721Contracts[event.Address] || len(event.Topics) == 0 {
+			continue
+		}
+		data := event.Data.AsBytes()
+		switch {
+		case event.Topics[0] == erc721MetadataUpdateTopicHash && len(data) == 32:
+			add(event.Address, new(big.Int).SetBytes(data).String())
+		case event.Topics[0] == erc721BatchMetadataUpdateTopicHash && len(data) == 64:
+			from := new(big.Int).SetBytes(data[:32])
+			to := new(big.Int).SetBytes(data[32:])
+			count := new(big.Int).Sub(to, from)
+			if count.Sign() < 0 || count.Cmp(big.NewInt(maxBatchMetadataUpdate)) >= 0 {
+				log.Info("Skipping ERC721 metadata update of too many tokens", "contract", event.Address.String(), "from", from.String(), "to", to.String())
+				continue
+			}
+			for tokenId := from; tokenId.Cmp(to) <= 0; tokenId = new(big.Int).Add(tokenId, big.NewInt(1)) {
+				add(event.Address, tokenId.String())
+			}
+		}
+	}
+	return changed
+}
+
+// UpdateMetadata records the name and symbol of the contract and the URI of each of the given tokens,
+// decoding the content of the URI if it is held on-chain as a data URI
+func (p *ERC721Processor) UpdateMetadata(tokens map[types.Address]map[string]bool, blockNum uint64) error {
+	for contract, tokenIds := range tokens {
+		names := p.contractNames(contract, blockNum)
+		for tokenId := range tokenIds {
+			metadata := &types.ERC721Metadata{
+				Contract:    contract,
+				Token:       tokenId,
+				Name:        names.name,
+				Symbol:      names.symbol,
+				BlockNumber: blockNum,
+			}
+
+			// tokenURI is optional in the standard, so a contract may not have it
+			id, _ := new(big.Int).SetString(tokenId, 10)
+			if uri, err := client.CallTokenURIOfERC721(p.client, contract, id, blockNum); err == nil {
+				metadata.TokenURI = decodeTokenString(uri.AsBytes())
+				if content, ok := decodeDataURI(metadata.TokenURI); ok && isJSONObject(content) {
+					metadata.Metadata = content
+				}
+			} else {
+				log.Debug("Unable to fetch ERC721 token URI", "contract", contract.String(), "token", tokenId, "err", err)
+			}
+
+			if err := p.db.RecordERC721Metadata(metadata); err != nil {
 				return err
 			}
 		}
@@ -53,26 +149,80 @@ func (p *ERC721Processor) SaveTokenTransfers(tokenTransfers map[types.Address]ma
 	return nil
 }
 
-func (p *ERC721Processor) MapEventsToHolders(erc721TransferEvents []*types.Event) map[types.Address]map[string]types.Address {
+// contractNames fetches the name and symbol of a contract the first time they are needed
+func (p *ERC721Processor) contractNames(contract types.Address, blockNum uint64) erc721Names {
+	if names, ok := p.names[contract]; ok {
+		return names
+	}
+
+	// these share their function signatures with those of ERC20, and are optional in both standards
+	var names erc721Names
+	if name, err := client.CallNameOfERC20(p.client, contract, blockNum); err == nil {
+		names.name = decodeTokenString(name.AsBytes())
+	} else {
+		log.Debug("Unable to fetch ERC721 token name", "contract", contract.String(), "err", err)
+	}
+	if symbol, err := client.CallSymbolOfERC20(p.client, contract, blockNum); err == nil {
+		names.symbol = decodeTokenString(symbol.AsBytes())
+	} else {
+		log.Debug("Unable to fetch ERC721 token symbol", "contract", contract.String(), "err", err)
+	}
+	p.names[contract] = names
+	return names
+}
+
+func (p *ERC721Processor) SaveTokenTransfers(tokenTransfers map[types.Address]map[string]*types.ERC721Token) error {
+	for _, tokenMap := range tokenTransfers {
+		for _, token := range tokenMap {
+			if err := p.db.RecordERC721Token(token); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *ERC721Processor) MapEventsToHolders(erc721TransferEvents []*types.Event, blockNum uint64) map[types.Address]map[string]*types.ERC721Token {
 	sortFunc := func(i, j int) bool { return erc721TransferEvents[i].Index < erc721TransferEvents[j].Index }
 	sort.Slice(erc721TransferEvents, sortFunc)
 
-	mappedTransfers := make(map[types.Address]map[string]types.Address)
+	mappedTransfers := make(map[types.Address]map[string]*types.ERC721Token)
 
 	for _, erc721Event := range erc721TransferEvents {
 		if mappedTransfers[erc721Event.Address] == nil {
-			mappedTransfers[erc721Event.Address] = make(map[string]types.Address)
+			mappedTransfers[erc721Event.Address] = make(map[string]*types.ERC721Token)
 		}
 
-		recipientAddressHex := string(erc721Event.Topics[2])[24:64] //only take the last 40 chars (20 bytes)
+		senderAddress := types.NewAddress(string(erc721Event.Topics[1])[24:64]) //only take the last 40 chars (20 bytes)
+		recipientAddressHex := string(erc721Event.Topics[2])[24:64]             //only take the last 40 chars (20 bytes)
 		recipientAddress := types.NewAddress(recipientAddressHex)
 
 		tokenId := erc721Event.Topics[3].String()
 
 		//this will overwrite the previous token holder, if there was another receiver
 		//of this token in this block
-		//this means the resolution of owning tokens is at the block level
-		mappedTransfers[erc721Event.Address][tokenId] = recipientAddress
+		//this means the resolution of owning tokens is at the block level,
+		//so the token was sent by its holder before the first transfer in the block
+		token := mappedTransfers[erc721Event.Address][tokenId]
+		if token == nil {
+			convertedToken := types.NewHexData(tokenId)
+			token = &types.ERC721Token{
+				Contract: erc721Event.Address,
+				Token:    new(big.Int).SetBytes(convertedToken.AsBytes()).String(),
+				HeldFrom: blockNum,
+				From:     senderAddress,
+			}
+			mappedTransfers[erc721Event.Address][tokenId] = token
+		}
+		token.Holder = recipientAddress
+		token.TransactionHash = erc721Event.TransactionHash
+
+		token.Kind = types.TransferKindTransfer
+		if recipientAddress.IsEmpty() {
+			token.Kind = types.TransferKindBurn
+		} else if token.From.IsEmpty() {
+			token.Kind = types.TransferKindMint
+		}
 	}
 	return mappedTransfers
 }
@@ -132,3 +282,34 @@ func isErc721(contractAbi types.ABIStructure) bool {
 
 	return true
 }
+
+// decodeDataURI reads the content of a data URI, which is either base64 or percent encoded,
+// or returns false if the URI is not a data URI, such as one held off-chain
+func decodeDataURI(uri string) ([]byte, bool) {
+	if !strings.HasPrefix(uri, "data:") {
+		return nil, false
+	}
+	comma := strings.IndexByte(uri, ',')
+	if comma < 0 {
+		return nil, false
+	}
+	mediaType, data := uri[len("data:"):comma], uri[comma+1:]
+
+	if strings.HasSuffix(mediaType, ";base64") {
+		decoded, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return nil, false
+		}
+		return decoded, true
+	}
+	decoded, err := url.PathUnescape(data)
+	if err != nil {
+		return nil, false
+	}
+	return []byte(decoded), true
+}
+
+// isJSONObject checks the content of a token URI is a JSON metadata document, rather than e.g. an image
+func isJSONObject(content []byte) bool {
+	return json.Valid(content) && bytes.HasPrefix(bytes.TrimSpace(content), []byte("{"))
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"quorumengineering/quorum-report/client"
 	"quorumengineering/quorum-report/types"
 )
 
@@ -18,7 +19,7 @@ var testErc721TokenBlock = &types.Block{
 
 func TestERC721Processor_ProcessBlock_TxReadFail(t *testing.T) {
 	db := NewFakeTestTokenDatabase(errors.New("test tx read fail"), []*types.Transaction{})
-	processor := NewERC721Processor(db)
+	processor := NewERC721Processor(db, nil)
 
 	err := processor.ProcessBlock(map[types.Address]string{}, testErc721TokenBlock)
 
@@ -33,7 +34,7 @@ func TestERC721Processor_ProcessTransaction_NoEventsDoesNothing(t *testing.T) {
 	}
 
 	db := NewFakeTestTokenDatabase(nil, []*types.Transaction{tx})
-	processor := NewERC721Processor(db)
+	processor := NewERC721Processor(db, nil)
 
 	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc721AbiString}, testErc721TokenBlock)
 
@@ -55,7 +56,7 @@ func TestERC721Processor_ProcessTransaction_NoErc721Events(t *testing.T) {
 	}
 
 	db := NewFakeTestTokenDatabase(nil, []*types.Transaction{tx})
-	processor := NewERC721Processor(db)
+	processor := NewERC721Processor(db, nil)
 
 	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc721AbiString}, testErc721TokenBlock)
 
@@ -82,7 +83,7 @@ func TestERC721Processor_ProcessTransaction_Erc721EventForNonTrackedAddress(t *t
 	}
 
 	db := NewFakeTestTokenDatabase(nil, []*types.Transaction{tx})
-	processor := NewERC721Processor(db)
+	processor := NewERC721Processor(db, nil)
 
 	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc721AbiString}, testErc721TokenBlock)
 
@@ -110,7 +111,7 @@ func TestERC721Processor_ProcessTransaction_SingleErc721Event(t *testing.T) {
 	}
 
 	db := NewFakeTestTokenDatabase(nil, []*types.Transaction{tx})
-	processor := NewERC721Processor(db)
+	processor := NewERC721Processor(db, nil)
 
 	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc721AbiString}, testErc721TokenBlock)
 
@@ -123,6 +124,138 @@ func TestERC721Processor_ProcessTransaction_SingleErc721Event(t *testing.T) {
 	assert.EqualValues(t, db.RecordedToken[0], big.NewInt(1))
 }
 
+func TestERC721Processor_ProcessTransaction_RecordsTransferOfMintedToken(t *testing.T) {
+	tokenAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	txHash := types.NewHash("0xf4f803b8d6c6b38e0b15d6cfe80fd1dcea4270ad24e93385fca36512bb9c2c59")
+	tx := &types.Transaction{
+		Hash:        txHash,
+		BlockNumber: 1,
+		Events: []*types.Event{
+			{
+				Index:           0,
+				Address:         tokenAddress,
+				TransactionHash: txHash,
+				Topics: []types.Hash{
+					"ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+					"0000000000000000000000000000000000000000000000000000000000000000",
+					"000000000000000000000000ed9d02e382b34818e88b88a309c7fe71e65f419d",
+					"0000000000000000000000000000000000000000000000000000000000000001",
+				},
+			},
+			{
+				Index:           1,
+				Address:         tokenAddress,
+				TransactionHash: txHash,
+				Topics: []types.Hash{
+					"ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+					"000000000000000000000000ed9d02e382b34818e88b88a309c7fe71e65f419d",
+					"0000000000000000000000001349f3e1b8d71effb47b840594ff27da7e603d17",
+					"0000000000000000000000000000000000000000000000000000000000000001",
+				},
+			},
+		},
+	}
+
+	db := NewFakeTestTokenDatabase(nil, []*types.Transaction{tx})
+	processor := NewERC721Processor(db, client.NewStubQuorumClient(nil, nil))
+
+	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc721AbiString}, testErc721TokenBlock)
+
+	// the token was minted and transferred in the same block, so only its holder at the end of the block is recorded
+	assert.Nil(t, err)
+	assert.Len(t, db.RecordedERC721Tokens, 1)
+	expected := &types.ERC721Token{
+		Contract:        tokenAddress,
+		Holder:          types.NewAddress("1349f3e1b8d71effb47b840594ff27da7e603d17"),
+		Token:           "1",
+		HeldFrom:        1,
+		From:            types.NewAddress("0000000000000000000000000000000000000000"),
+		TransactionHash: txHash,
+		Kind:            types.TransferKindMint,
+	}
+	assert.Equal(t, expected, db.RecordedERC721Tokens[0])
+
+	// the contract does not provide any metadata, so it is recorded empty
+	assert.Equal(t, []*types.ERC721Metadata{{Contract: tokenAddress, Token: "1", BlockNumber: 1}}, db.RecordedERC721Metadata)
+}
+
+func TestERC721Processor_ProcessTransaction_MetadataUpdateRefreshesTokenURI(t *testing.T) {
+	tokenAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	tx := &types.Transaction{
+		Hash:        types.NewHash("0xf4f803b8d6c6b38e0b15d6cfe80fd1dcea4270ad24e93385fca36512bb9c2c59"),
+		BlockNumber: 1,
+		Events: []*types.Event{
+			{
+				Data:    types.NewHexData("0x0000000000000000000000000000000000000000000000000000000000000007"),
+				Address: tokenAddress,
+				Topics:  []types.Hash{"f8e1a15aba9398e019f0b49df1a4fde98ee17ae345cb5f6b5e2c27f5033e8ce7"},
+			},
+		},
+	}
+
+	// every call returns the same data URI, so it is also taken as the name and symbol
+	dataURI := "data:application/json;base64,eyJuYW1lIjoiVG9rZW4gMSJ9"
+	stubClient := client.NewStubQuorumClient(nil, map[string]interface{}{
+		"eth_call<types.EIP165Call Value>0x1": types.NewHexData("0x00000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000035646174613a6170706c69636174696f6e2f6a736f6e3b6261736536342c65794a755957316c496a6f69564739725a5734674d534a390000000000000000000000"),
+	})
+	db := NewFakeTestTokenDatabase(nil, []*types.Transaction{tx})
+	processor := NewERC721Processor(db, stubClient)
+
+	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc721AbiString}, testErc721TokenBlock)
+
+	assert.Nil(t, err)
+	assert.Len(t, db.RecordedERC721Tokens, 0)
+	expected := &types.ERC721Metadata{
+		Contract:    tokenAddress,
+		Token:       "7",
+		Name:        dataURI,
+		Symbol:      dataURI,
+		TokenURI:    dataURI,
+		Metadata:    []byte(`{"name":"Token 1"}`),
+		BlockNumber: 1,
+	}
+	assert.Equal(t, []*types.ERC721Metadata{expected}, db.RecordedERC721Metadata)
+}
+
+func TestERC721Processor_ChangedMetadata_BatchMetadataUpdate(t *testing.T) {
+	tokenAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	batchUpdate := func(from, to string) *types.Event {
+		return &types.Event{
+			Data:    types.NewHexData("0x" + from + to),
+			Address: tokenAddress,
+			Topics:  []types.Hash{"6bd5c950a8d8df17f772f5af37cb3655737899cbf903264b9795592da439661c"},
+		}
+	}
+	processor := NewERC721Processor(nil, nil)
+
+	changed := processor.ChangedMetadata(map[types.Address]bool{tokenAddress: true}, nil, []*types.Event{
+		batchUpdate("0000000000000000000000000000000000000000000000000000000000000002", "0000000000000000000000000000000000000000000000000000000000000004"),
+	})
+	assert.Equal(t, map[types.Address]map[string]bool{tokenAddress: {"2": true, "3": true, "4": true}}, changed)
+
+	// a range covering every token is not followed
+	changed = processor.ChangedMetadata(map[types.Address]bool{tokenAddress: true}, nil, []*types.Event{
+		batchUpdate("0000000000000000000000000000000000000000000000000000000000000000", "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"),
+	})
+	assert.Len(t, changed, 0)
+}
+
+func TestDecodeDataURI(t *testing.T) {
+	content, ok := decodeDataURI("data:application/json;base64,eyJuYW1lIjoiVG9rZW4gMSJ9")
+	assert.True(t, ok)
+	assert.Equal(t, `{"name":"Token 1"}`, string(content))
+
+	content, ok = decodeDataURI(`data:application/json,{"name":"Token%201"}`)
+	assert.True(t, ok)
+	assert.Equal(t, `{"name":"Token 1"}`, string(content))
+
+	_, ok = decodeDataURI("ipfs://QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG/1")
+	assert.False(t, ok)
+
+	_, ok = decodeDataURI("data:application/json;base64,not base64")
+	assert.False(t, ok)
+}
+
 func TestERC721Processor_ProcessTransaction_SingleErc721EventForNonErc721Contract(t *testing.T) {
 	tokenAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
 	tx := &types.Transaction{
@@ -143,7 +276,7 @@ func TestERC721Processor_ProcessTransaction_SingleErc721EventForNonErc721Contrac
 	}
 
 	db := NewFakeTestTokenDatabase(nil, []*types.Transaction{tx})
-	processor := NewERC721Processor(db)
+	processor := NewERC721Processor(db, nil)
 
 	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc20AbiString}, testErc721TokenBlock)
 
@@ -174,7 +307,7 @@ func TestERC721Processor_ProcessTransaction_SingleErc721Event_WithDatabaseError(
 	}
 
 	db := NewFakeTestTokenDatabase(errors.New("test error - database"), []*types.Transaction{tx})
-	processor := NewERC721Processor(db)
+	processor := NewERC721Processor(db, nil)
 
 	err := processor.ProcessBlock(map[types.Address]string{tokenAddress: erc721AbiString}, testErc721TokenBlock)
 
@@ -210,7 +343,7 @@ func TestERC721Processor_ProcessTransaction_MultipleErc721Events(t *testing.T) {
 	}
 
 	db := NewFakeTestTokenDatabase(nil, []*types.Transaction{tx})
-	processor := NewERC721Processor(db)
+	processor := NewERC721Processor(db, nil)
 
 	err := processor.ProcessBlock(map[types.Address]string{
 		types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34"): erc721AbiString,
@@ -8,7 +8,16 @@ import (
 
 type TokenFilterDatabase interface {
 	RecordNewERC20Balance(contract types.Address, holder types.Address, block uint64, amount *big.Int) error
-	RecordERC721Token(contract types.Address, holder types.Address, block uint64, tokenId *big.Int) error
+	GetERC20BalanceAtBlock(contract types.Address, holder types.Address, block uint64) (*big.Int, error)
+	GetAllTokenHolders(contract types.Address, block uint64, options *types.TokenQueryOptions) ([]types.Address, error)
+	RecordERC20Transfers(transfers []*types.ERC20Transfer) error
+	RecordNewERC20Allowance(contract types.Address, owner types.Address, spender types.Address, block uint64, amount *big.Int) error
+	RecordERC20Metadata(metadata *types.ERC20Metadata) error
+	GetERC20Metadata(contract types.Address) (*types.ERC20Metadata, error)
+	RecordNewERC20TotalSupply(contract types.Address, block uint64, amount *big.Int) error
+	RecordERC721Token(token *types.ERC721Token) error
+	RecordERC721Metadata(metadata *types.ERC721Metadata) error
+	RecordNewERC1155Balance(contract types.Address, holder types.Address, tokenId *big.Int, block uint64, amount *big.Int) error
 
 	ReadTransaction(types.Hash) (*types.Transaction, error)
 }
@@ -3,6 +3,7 @@ package token
 import (
 	"errors"
 	"math/big"
+	"quorumengineering/quorum-report/database"
 	"quorumengineering/quorum-report/types"
 )
 
@@ -26,6 +27,20 @@ type FakeTestTokenDatabase struct {
 	RecordedHolder   []types.Address
 	RecordedBlock    uint64
 	RecordedToken    []*big.Int
+	RecordedAmount   []*big.Int
+
+	RecordedTransfers  []*types.ERC20Transfer
+	RecordedAllowances []types.ERC20Allowance
+	RecordedMetadata   []*types.ERC20Metadata
+	RecordedSupplies   []types.ERC20TotalSupply
+
+	RecordedERC721Tokens   []*types.ERC721Token
+	RecordedERC721Metadata []*types.ERC721Metadata
+
+	// PreviousBalances holds the balance of each holder before the block being processed
+	PreviousBalances map[types.Address]*big.Int
+	// Holders are the current holders of every token, in order
+	Holders []types.Address
 }
 
 func (db *FakeTestTokenDatabase) RecordNewERC20Balance(contract types.Address, holder types.Address, block uint64, amount *big.Int) error {
@@ -39,7 +54,105 @@ func (db *FakeTestTokenDatabase) RecordNewERC20Balance(contract types.Address, h
 	return nil
 }
 
-func (db *FakeTestTokenDatabase) RecordERC721Token(contract types.Address, holder types.Address, block uint64, tokenId *big.Int) error {
+func (db *FakeTestTokenDatabase) GetERC20BalanceAtBlock(contract types.Address, holder types.Address, block uint64) (*big.Int, error) {
+	if db.testErr != nil {
+		return nil, db.testErr
+	}
+	if balance, ok := db.PreviousBalances[holder]; ok {
+		return new(big.Int).Set(balance), nil
+	}
+	return nil, database.ErrNotFound
+}
+
+func (db *FakeTestTokenDatabase) GetAllTokenHolders(contract types.Address, block uint64, options *types.TokenQueryOptions) ([]types.Address, error) {
+	if db.testErr != nil {
+		return nil, db.testErr
+	}
+	holders := make([]types.Address, 0)
+	for _, holder := range db.Holders {
+		if holder.String() > options.After && len(holders) < options.PageSize {
+			holders = append(holders, holder)
+		}
+	}
+	return holders, nil
+}
+
+func (db *FakeTestTokenDatabase) RecordERC20Transfers(transfers []*types.ERC20Transfer) error {
+	if db.testErr != nil {
+		return db.testErr
+	}
+	db.RecordedTransfers = append(db.RecordedTransfers, transfers...)
+	return nil
+}
+
+func (db *FakeTestTokenDatabase) RecordNewERC20Allowance(contract types.Address, owner types.Address, spender types.Address, block uint64, amount *big.Int) error {
+	if db.testErr != nil {
+		return db.testErr
+	}
+	db.RecordedAllowances = append(db.RecordedAllowances, types.ERC20Allowance{
+		Contract:    contract,
+		Owner:       owner,
+		Spender:     spender,
+		Amount:      amount.String(),
+		BlockNumber: block,
+	})
+	return nil
+}
+
+func (db *FakeTestTokenDatabase) RecordERC20Metadata(metadata *types.ERC20Metadata) error {
+	if db.testErr != nil {
+		return db.testErr
+	}
+	db.RecordedMetadata = append(db.RecordedMetadata, metadata)
+	return nil
+}
+
+func (db *FakeTestTokenDatabase) GetERC20Metadata(contract types.Address) (*types.ERC20Metadata, error) {
+	if db.testErr != nil {
+		return nil, db.testErr
+	}
+	for _, metadata := range db.RecordedMetadata {
+		if metadata.Contract == contract {
+			return metadata, nil
+		}
+	}
+	return nil, database.ErrNotFound
+}
+
+func (db *FakeTestTokenDatabase) RecordNewERC20TotalSupply(contract types.Address, block uint64, amount *big.Int) error {
+	if db.testErr != nil {
+		return db.testErr
+	}
+	db.RecordedSupplies = append(db.RecordedSupplies, types.ERC20TotalSupply{
+		Contract:    contract,
+		Amount:      amount.String(),
+		BlockNumber: block,
+	})
+	return nil
+}
+
+func (db *FakeTestTokenDatabase) RecordERC721Token(token *types.ERC721Token) error {
+	if db.testErr != nil {
+		return db.testErr
+	}
+	tokenId, _ := new(big.Int).SetString(token.Token, 10)
+	db.RecordedContract = append(db.RecordedContract, token.Contract)
+	db.RecordedHolder = append(db.RecordedHolder, token.Holder)
+	db.RecordedBlock = token.HeldFrom
+	db.RecordedToken = append(db.RecordedToken, tokenId)
+	db.RecordedERC721Tokens = append(db.RecordedERC721Tokens, token)
+	return nil
+}
+
+func (db *FakeTestTokenDatabase) RecordERC721Metadata(metadata *types.ERC721Metadata) error {
+	if db.testErr != nil {
+		return db.testErr
+	}
+	db.RecordedERC721Metadata = append(db.RecordedERC721Metadata, metadata)
+	return nil
+}
+
+func (db *FakeTestTokenDatabase) RecordNewERC1155Balance(contract types.Address, holder types.Address, tokenId *big.Int, block uint64, amount *big.Int) error {
 	if db.testErr != nil {
 		return db.testErr
 	}
@@ -47,6 +160,7 @@ func (db *FakeTestTokenDatabase) RecordERC721Token(contract types.Address, holde
 	db.RecordedHolder = append(db.RecordedHolder, holder)
 	db.RecordedBlock = block
 	db.RecordedToken = append(db.RecordedToken, tokenId)
+	db.RecordedAmount = append(db.RecordedAmount, amount)
 	return nil
 }
 
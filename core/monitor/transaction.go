@@ -84,34 +84,76 @@ func (tm *DefaultTransactionMonitor) fetchTransaction(block *types.Block, hash t
 		return nil, err
 	}
 
-	calls := flattenCalls(traceResp.Calls)
-	tx.InternalCalls = make([]*types.InternalCall, len(calls))
-	for i, respCall := range calls {
-		tx.InternalCalls[i] = &types.InternalCall{
-			From:    respCall.From,
-			To:      respCall.To,
-			Gas:     respCall.Gas.ToUint64(),
-			GasUsed: respCall.GasUsed.ToUint64(),
-			Value:   respCall.Value.ToUint64(),
-			Input:   respCall.Input,
-			Output:  respCall.Output,
-			Type:    respCall.Type,
+	tx.InternalCalls = flattenCalls(traceResp.Calls, -1, nil)
+
+	if !tx.Status {
+		if err := tm.captureRevert(tx, traceResp); err != nil {
+			return nil, err
 		}
 	}
 	return tx, nil
 }
 
+// captureRevert records why a transaction failed, taking the revert data from the trace of the
+// transaction if the node gives it, or otherwise by replaying the transaction at the parent block
+func (tm *DefaultTransactionMonitor) captureRevert(tx *types.Transaction, trace types.RawOuterCall) error {
+	tx.Error = trace.Error
+	tx.RevertData = trace.Output
+	if !tx.RevertData.IsEmpty() || tx.BlockNumber == 0 {
+		return nil
+	}
+
+	data := tx.Data
+	if !tx.PrivateData.IsEmpty() {
+		data = tx.PrivateData
+	}
+	msg := types.ReplayCall{
+		From:  tx.From,
+		Gas:   types.HexNumber(tx.Gas),
+		Value: types.HexNumber(tx.Value),
+		Data:  data,
+	}
+	if !tx.To.IsEmpty() {
+		msg.To = &tx.To
+	}
+
+	revertData, err := client.ReplayRevertData(tm.quorumClient, msg, tx.BlockNumber-1)
+	if err != nil {
+		return err
+	}
+	tx.RevertData = revertData
+	return nil
+}
+
 //flattens the list of internal calls to a single list
 //e.g [1 [2 3 [4 5] 6 [7]]] -> [1 2 3 4 5 6 7]
-func flattenCalls(calls []types.RawInnerCall) []types.RawInnerCall {
-	if len(calls) == 0 {
-		return []types.RawInnerCall{}
-	}
+//each call records its depth, path and the index of its parent in the list (-1 if made directly
+//by the transaction), so that the tree can be rebuilt from the flat list
+func flattenCalls(calls []types.RawInnerCall, parent int, path []uint64) []*types.InternalCall {
+	results := make([]*types.InternalCall, 0, len(calls))
+	for i, c := range calls {
+		callPath := make([]uint64, len(path)+1)
+		copy(callPath, path)
+		callPath[len(path)] = uint64(i)
+
+		results = append(results, &types.InternalCall{
+			From:    c.From,
+			To:      c.To,
+			Gas:     c.Gas.ToUint64(),
+			GasUsed: c.GasUsed.ToUint64(),
+			Value:   c.Value.ToUint64(),
+			Input:   c.Input,
+			Output:  c.Output,
+			Type:    c.Type,
+			Error:   c.Error,
+			Depth:   uint64(len(callPath)),
+			Parent:  parent,
+			Path:    callPath,
+		})
 
-	var results []types.RawInnerCall
-	for _, c := range calls {
-		results = append(results, c)
-		results = append(results, flattenCalls(c.Calls)...)
+		// the calls of each frame are listed straight after it, so its index is offset from its parent
+		children := flattenCalls(c.Calls, parent+len(results), callPath)
+		results = append(results, children...)
 	}
 	return results
 }
@@ -132,3 +132,90 @@ func TestTransactionMonitor_PullTransactions(t *testing.T) {
 	assert.EqualValues(t, types.NewHash("0xefe5cb8d23d632b5d2cdd9f0a151c4b1a84ccb7afa1c57331009aa922d5e4f36"), tx.Events[0].Topics[0])
 	assert.Len(t, tx.InternalCalls, 1)
 }
+
+func TestCreateTransaction_Failed(t *testing.T) {
+	testBlock := &types.Block{
+		Number:    2,
+		Timestamp: uint64(0x1000),
+	}
+	failedResp := make(map[string]interface{})
+	for k, v := range graphqlResp {
+		failedResp[k] = v
+	}
+	failedResp["status"] = "0x0"
+	failedResp["logs"] = []map[string]interface{}{}
+	mockGraphQL := map[string]map[string]interface{}{
+		client.TransactionDetailQuery(types.NewHash("0xe625ba9f14eed0671508966080fb01374d0a3a16b9cee545a324179b75f30aa8")): {
+			"transaction": interface{}(failedResp),
+		},
+	}
+
+	// revert data is taken from the trace when it is given
+	mockRPC := map[string]interface{}{
+		"debug_traceTransaction0xe625ba9f14eed0671508966080fb01374d0a3a16b9cee545a324179b75f30aa8<*client.TraceConfig Value>": types.RawOuterCall{
+			Error:  "execution reverted",
+			Output: types.NewHexData("0x4e487b710000000000000000000000000000000000000000000000000000000000000001"),
+		},
+	}
+	tm := NewDefaultTransactionMonitor(client.NewStubQuorumClient(mockGraphQL, mockRPC))
+	tx, err := tm.fetchTransaction(testBlock, types.NewHash("0xe625ba9f14eed0671508966080fb01374d0a3a16b9cee545a324179b75f30aa8"))
+	assert.Nil(t, err)
+	assert.False(t, tx.Status)
+	assert.Equal(t, "execution reverted", tx.Error)
+	assert.Equal(t, types.NewHexData("0x4e487b710000000000000000000000000000000000000000000000000000000000000001"), tx.RevertData)
+
+	// otherwise the transaction is replayed at the parent block
+	mockRPC = map[string]interface{}{
+		"debug_traceTransaction0xe625ba9f14eed0671508966080fb01374d0a3a16b9cee545a324179b75f30aa8<*client.TraceConfig Value>": types.RawOuterCall{
+			Error: "execution reverted",
+		},
+		"eth_call<types.ReplayCall Value>0x1": types.NewHexData("0x"),
+	}
+	tm = NewDefaultTransactionMonitor(client.NewStubQuorumClient(mockGraphQL, mockRPC))
+	tx, err = tm.fetchTransaction(testBlock, types.NewHash("0xe625ba9f14eed0671508966080fb01374d0a3a16b9cee545a324179b75f30aa8"))
+	assert.Nil(t, err)
+	assert.Equal(t, "execution reverted", tx.Error)
+	assert.True(t, tx.RevertData.IsEmpty())
+}
+
+func TestFlattenCalls(t *testing.T) {
+	// [A [B [C]] D]
+	calls := []types.RawInnerCall{
+		{
+			To: "000000000000000000000000000000000000000a",
+			Calls: []types.RawInnerCall{
+				{
+					To:    "000000000000000000000000000000000000000b",
+					Error: "execution reverted",
+					Calls: []types.RawInnerCall{
+						{To: "000000000000000000000000000000000000000c"},
+					},
+				},
+			},
+		},
+		{To: "000000000000000000000000000000000000000d"},
+	}
+
+	flattened := flattenCalls(calls, -1, nil)
+
+	assert.Len(t, flattened, 4)
+	expected := []struct {
+		to     types.Address
+		depth  uint64
+		parent int
+		path   []uint64
+	}{
+		{"000000000000000000000000000000000000000a", 1, -1, []uint64{0}},
+		{"000000000000000000000000000000000000000b", 2, 0, []uint64{0, 0}},
+		{"000000000000000000000000000000000000000c", 3, 1, []uint64{0, 0, 0}},
+		{"000000000000000000000000000000000000000d", 1, -1, []uint64{1}},
+	}
+	for i, e := range expected {
+		assert.EqualValues(t, e.to, flattened[i].To)
+		assert.EqualValues(t, e.depth, flattened[i].Depth)
+		assert.EqualValues(t, e.parent, flattened[i].Parent)
+		assert.EqualValues(t, e.path, flattened[i].Path)
+	}
+	assert.Equal(t, "execution reverted", flattened[1].Error)
+	assert.Empty(t, flattened[0].Error)
+}
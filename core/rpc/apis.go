@@ -1,10 +1,14 @@
 package rpc
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"math/big"
 	"net/http"
+	"reflect"
 
+	"quorumengineering/quorum-report/client"
 	"quorumengineering/quorum-report/core/storageparsing"
 	"quorumengineering/quorum-report/database"
 	"quorumengineering/quorum-report/types"
@@ -12,11 +16,26 @@ import (
 
 type RPCAPIs struct {
 	db                      database.Database
+	quorumClient            client.Client
 	contractTemplateManager ContractTemplateManager
+	signatures              *signatureRegistry
+	mappingKeys             *mappingKeyCache
 }
 
-func NewRPCAPIs(db database.Database, contractTemplateManager ContractTemplateManager) *RPCAPIs {
-	return &RPCAPIs{db, contractTemplateManager}
+func NewRPCAPIs(db database.Database, quorumClient client.Client, contractTemplateManager ContractTemplateManager) *RPCAPIs {
+	return &RPCAPIs{
+		db:                      db,
+		quorumClient:            quorumClient,
+		contractTemplateManager: contractTemplateManager,
+		signatures:              newSignatureRegistry(db),
+		mappingKeys:             newMappingKeyCache(),
+	}
+}
+
+// SeedSignatures adds the functions and events of the ABI in the given file to the
+// signature registry, to decode contracts that have no template
+func (r *RPCAPIs) SeedSignatures(path string) error {
+	return r.signatures.seedFromFile(path)
 }
 
 func (r *RPCAPIs) GetLastPersistedBlockNumber(req *http.Request, args *NullArgs, reply *uint64) error {
@@ -58,7 +77,11 @@ func (r *RPCAPIs) GetTransaction(req *http.Request, hash *types.Hash, reply *typ
 	if address.IsEmpty() {
 		address = tx.CreatedContract
 	}
-	contractABI, err := r.db.GetContractABI(address)
+	templates, err := r.getContractTemplates(address)
+	if err != nil {
+		return err
+	}
+	contractABI, err := templates.abiAt(tx.BlockNumber)
 	if err != nil {
 		return err
 	}
@@ -69,26 +92,79 @@ func (r *RPCAPIs) GetTransaction(req *http.Request, hash *types.Hash, reply *typ
 		if err = parsedTx.ParseTransaction(contractABI); err != nil {
 			return err
 		}
+	} else {
+		registry, err := r.signatures.get()
+		if err != nil {
+			return err
+		}
+		if err := parsedTx.ParseTransactionFromRegistry(registry); err != nil {
+			return err
+		}
+	}
+	internalABI, err := templates.internalABIAt(tx.BlockNumber)
+	if err != nil {
+		return err
+	}
+	if err := parsedTx.ParseRevert(internalABI); err != nil {
+		return err
+	}
+	if parsedTx.ParsedInternalCalls, err = r.parseInternalCalls(tx.InternalCalls, tx.BlockNumber); err != nil {
+		return err
 	}
 	parsedTx.ParsedEvents = make([]*types.ParsedEvent, len(parsedTx.RawTransaction.Events))
 	for i, e := range parsedTx.RawTransaction.Events {
-		parsedTx.ParsedEvents[i] = &types.ParsedEvent{
-			RawEvent: e,
+		eventTemplates, err := r.getContractTemplates(e.Address)
+		if err != nil {
+			return err
 		}
-		contractABI, err := r.db.GetContractABI(e.Address)
+		contractABI, err := eventTemplates.abiAt(tx.BlockNumber)
 		if err != nil {
 			return err
 		}
-		if contractABI != "" {
-			if err := parsedTx.ParsedEvents[i].ParseEvent(contractABI); err != nil {
-				return err
-			}
+		if parsedTx.ParsedEvents[i], err = r.parseEvent(e, contractABI); err != nil {
+			return err
 		}
 	}
 	*reply = *parsedTx
 	return nil
 }
 
+// GetCallTree rebuilds the tree of internal calls made by a transaction, decoding
+// each call with the template of the contract that was called
+func (r *RPCAPIs) GetCallTree(req *http.Request, hash *types.Hash, reply *CallTreeResp) error {
+	if hash.IsEmpty() {
+		return errors.New("no transaction hash given")
+	}
+	tx, err := r.db.ReadTransaction(*hash)
+	if err != nil {
+		return err
+	}
+
+	parsedCalls, err := r.parseInternalCalls(tx.InternalCalls, tx.BlockNumber)
+	if err != nil {
+		return err
+	}
+
+	frames := make([]*CallTreeFrame, len(parsedCalls))
+	topLevel := make([]*CallTreeFrame, 0)
+	for i, parsedCall := range parsedCalls {
+		frames[i] = &CallTreeFrame{ParsedInternalCall: parsedCall, Calls: make([]*CallTreeFrame, 0)}
+		// calls indexed before their depth was recorded have no parent information, so are left flat
+		internalCall := parsedCall.RawCall
+		if internalCall.Depth > 1 && internalCall.Parent >= 0 && internalCall.Parent < i {
+			frames[internalCall.Parent].Calls = append(frames[internalCall.Parent].Calls, frames[i])
+		} else {
+			topLevel = append(topLevel, frames[i])
+		}
+	}
+
+	*reply = CallTreeResp{
+		Hash:  tx.Hash,
+		Calls: topLevel,
+	}
+	return nil
+}
+
 func (r *RPCAPIs) GetContractCreationTransaction(req *http.Request, address *types.Address, reply *types.Hash) error {
 	txHash, err := r.db.GetContractCreationTransaction(*address)
 	if err != nil {
@@ -101,6 +177,15 @@ func (r *RPCAPIs) GetContractCreationTransaction(req *http.Request, address *typ
 	return nil
 }
 
+func (r *RPCAPIs) GetImplementationHistory(req *http.Request, address *types.Address, reply *[]*types.ProxyImplementation) error {
+	implementations, err := r.db.GetImplementationHistory(*address)
+	if err != nil {
+		return err
+	}
+	*reply = implementations
+	return nil
+}
+
 func (r *RPCAPIs) GetAllTransactionsToAddress(req *http.Request, args *AddressWithOptions, reply *TransactionsResp) error {
 	if args.Address == nil {
 		return ErrNoAddress
@@ -109,6 +194,12 @@ func (r *RPCAPIs) GetAllTransactionsToAddress(req *http.Request, args *AddressWi
 		args.Options = &types.QueryOptions{}
 	}
 	args.Options.SetDefaults()
+	if err := args.Options.ValidatePagination(); err != nil {
+		return err
+	}
+	if err := args.Options.ValidateCallFilter(); err != nil {
+		return err
+	}
 
 	total, err := r.db.GetTransactionsToAddressTotal(*args.Address, args.Options)
 	if err != nil {
@@ -119,15 +210,23 @@ func (r *RPCAPIs) GetAllTransactionsToAddress(req *http.Request, args *AddressWi
 		return err
 	}
 
+	next, err := r.nextTransactionsCursor(txs, args.Options)
+	if err != nil {
+		return err
+	}
+
 	*reply = TransactionsResp{
 		Transactions: txs,
 		Total:        total,
 		Options:      args.Options,
+		Next:         next,
 	}
 	return nil
 }
 
-func (r *RPCAPIs) GetAllTransactionsInternalToAddress(req *http.Request, args *AddressWithOptions, reply *TransactionsResp) error {
+// GetAllTransactionsFromAddress finds the transactions sent from an address, or in which it made
+// an internal call. The address does not need to be registered.
+func (r *RPCAPIs) GetAllTransactionsFromAddress(req *http.Request, args *AddressWithOptions, reply *TransactionsResp) error {
 	if args.Address == nil {
 		return ErrNoAddress
 	}
@@ -135,12 +234,20 @@ func (r *RPCAPIs) GetAllTransactionsInternalToAddress(req *http.Request, args *A
 		args.Options = &types.QueryOptions{}
 	}
 	args.Options.SetDefaults()
+	if err := args.Options.ValidatePagination(); err != nil {
+		return err
+	}
 
-	total, err := r.db.GetTransactionsInternalToAddressTotal(*args.Address, args.Options)
+	total, err := r.db.GetTransactionsFromAddressTotal(*args.Address, args.Options)
 	if err != nil {
 		return err
 	}
-	txs, err := r.db.GetAllTransactionsInternalToAddress(*args.Address, args.Options)
+	txs, err := r.db.GetAllTransactionsFromAddress(*args.Address, args.Options)
+	if err != nil {
+		return err
+	}
+
+	next, err := r.nextTransactionsCursor(txs, args.Options)
 	if err != nil {
 		return err
 	}
@@ -149,11 +256,12 @@ func (r *RPCAPIs) GetAllTransactionsInternalToAddress(req *http.Request, args *A
 		Transactions: txs,
 		Total:        total,
 		Options:      args.Options,
+		Next:         next,
 	}
 	return nil
 }
 
-func (r *RPCAPIs) GetAllEventsFromAddress(req *http.Request, args *AddressWithOptions, reply *EventsResp) error {
+func (r *RPCAPIs) GetFailedTransactionsToAddress(req *http.Request, args *AddressWithOptions, reply *FailedTransactionsResp) error {
 	if args.Address == nil {
 		return ErrNoAddress
 	}
@@ -161,35 +269,196 @@ func (r *RPCAPIs) GetAllEventsFromAddress(req *http.Request, args *AddressWithOp
 		args.Options = &types.QueryOptions{}
 	}
 	args.Options.SetDefaults()
+	if err := args.Options.ValidatePagination(); err != nil {
+		return err
+	}
 
-	total, err := r.db.GetEventsFromAddressTotal(*args.Address, args.Options)
+	total, err := r.db.GetFailedTransactionsToAddressTotal(*args.Address, args.Options)
 	if err != nil {
 		return err
 	}
-	events, err := r.db.GetAllEventsFromAddress(*args.Address, args.Options)
+	txs, err := r.db.GetFailedTransactionsToAddress(*args.Address, args.Options)
 	if err != nil {
 		return err
 	}
-	contractABI, err := r.db.GetContractABI(*args.Address)
+	templates, err := r.getContractTemplates(*args.Address)
 	if err != nil {
 		return err
 	}
-	parsedEvents := make([]*types.ParsedEvent, len(events))
-	for i, e := range events {
-		parsedEvents[i] = &types.ParsedEvent{
-			RawEvent: e,
+
+	failures := make([]*TransactionFailure, len(txs))
+	for i, hash := range txs {
+		tx, err := r.db.ReadTransaction(hash)
+		if err != nil {
+			return err
+		}
+		contractABI, err := templates.internalABIAt(tx.BlockNumber)
+		if err != nil {
+			return err
 		}
-		if contractABI != "" {
-			if err = parsedEvents[i].ParseEvent(contractABI); err != nil {
+		failures[i] = &TransactionFailure{
+			Hash:        tx.Hash,
+			BlockNumber: tx.BlockNumber,
+			Error:       tx.Error,
+			RevertData:  tx.RevertData,
+			Revert:      types.ParseRevertData(tx.RevertData.AsBytes(), contractABI),
+		}
+	}
+
+	next, err := r.nextTransactionsCursor(txs, args.Options)
+	if err != nil {
+		return err
+	}
+
+	*reply = FailedTransactionsResp{
+		Transactions: failures,
+		Total:        total,
+		Options:      args.Options,
+		Next:         next,
+	}
+	return nil
+}
+
+func (r *RPCAPIs) GetAllTransactionsInternalToAddress(req *http.Request, args *AddressWithOptions, reply *InternalTransactionsResp) error {
+	if args.Address == nil {
+		return ErrNoAddress
+	}
+	if args.Options == nil {
+		args.Options = &types.QueryOptions{}
+	}
+	args.Options.SetDefaults()
+	if err := args.Options.ValidatePagination(); err != nil {
+		return err
+	}
+
+	total, err := r.db.GetTransactionsInternalToAddressTotal(*args.Address, args.Options)
+	if err != nil {
+		return err
+	}
+	txs, err := r.db.GetAllTransactionsInternalToAddress(*args.Address, args.Options)
+	if err != nil {
+		return err
+	}
+	templates, err := r.getContractTemplates(*args.Address)
+	if err != nil {
+		return err
+	}
+
+	internalCalls := make([]*InternalCallToAddress, 0, len(txs))
+	for _, hash := range txs {
+		tx, err := r.db.ReadTransaction(hash)
+		if err != nil {
+			return err
+		}
+		contractABI, err := templates.internalABIAt(tx.BlockNumber)
+		if err != nil {
+			return err
+		}
+		for i, internalCall := range tx.InternalCalls {
+			if internalCall.To != *args.Address {
+				continue
+			}
+			parsedCall, err := r.parseInternalCall(internalCall, contractABI)
+			if err != nil {
 				return err
 			}
+			internalCalls = append(internalCalls, &InternalCallToAddress{
+				TransactionHash:    hash,
+				Index:              i,
+				ParsedInternalCall: parsedCall,
+			})
 		}
 	}
 
+	next, err := r.nextTransactionsCursor(txs, args.Options)
+	if err != nil {
+		return err
+	}
+
+	*reply = InternalTransactionsResp{
+		Transactions:  txs,
+		InternalCalls: internalCalls,
+		Total:         total,
+		Options:       args.Options,
+		Next:          next,
+	}
+	return nil
+}
+
+func (r *RPCAPIs) GetAllEventsFromAddress(req *http.Request, args *AddressWithOptions, reply *EventsResp) error {
+	if args.Address == nil {
+		return ErrNoAddress
+	}
+	if args.Options == nil {
+		args.Options = &types.QueryOptions{}
+	}
+	args.Options.SetDefaults()
+	if err := args.Options.ValidatePagination(); err != nil {
+		return err
+	}
+
+	total, err := r.db.GetEventsFromAddressTotal(*args.Address, args.Options)
+	if err != nil {
+		return err
+	}
+	events, err := r.db.GetAllEventsFromAddress(*args.Address, args.Options)
+	if err != nil {
+		return err
+	}
+	parsedEvents, err := r.parseEvents(*args.Address, events)
+	if err != nil {
+		return err
+	}
+
+	next := nextEventsCursor(events, args.Options)
+
 	*reply = EventsResp{
 		Events:  parsedEvents,
 		Total:   total,
 		Options: args.Options,
+		Next:    next,
+	}
+	return nil
+}
+
+func (r *RPCAPIs) GetFilteredEventsFromAddress(req *http.Request, args *EventFilterArgs, reply *EventsResp) error {
+	if args.Address == nil {
+		return ErrNoAddress
+	}
+	if args.Filter == nil {
+		args.Filter = &types.EventFilter{}
+	}
+	if err := args.Filter.Validate(); err != nil {
+		return err
+	}
+	if args.Options == nil {
+		args.Options = &types.QueryOptions{}
+	}
+	args.Options.SetDefaults()
+	if err := args.Options.ValidatePagination(); err != nil {
+		return err
+	}
+
+	total, err := r.db.GetFilteredEventsFromAddressTotal(*args.Address, args.Filter, args.Options)
+	if err != nil {
+		return err
+	}
+	events, err := r.db.GetFilteredEventsFromAddress(*args.Address, args.Filter, args.Options)
+	if err != nil {
+		return err
+	}
+	parsedEvents, err := r.parseEvents(*args.Address, events)
+	if err != nil {
+		return err
+	}
+
+	next := nextEventsCursor(events, args.Options)
+
+	*reply = EventsResp{
+		Events:  parsedEvents,
+		Total:   total,
+		Options: args.Options,
+		Next:    next,
 	}
 	return nil
 }
@@ -216,6 +485,157 @@ func (r *RPCAPIs) GetStorage(req *http.Request, args *AddressWithOptionalBlock,
 	return nil
 }
 
+// CallFunction calls a function of a contract as of a given block, or the last persisted block if not
+// given, encoding the call and decoding the output with the template of the contract at that block
+func (r *RPCAPIs) CallFunction(req *http.Request, args *FunctionCallArgs, reply *FunctionCallResp) error {
+	if args.Address == nil {
+		return ErrNoAddress
+	}
+	if args.Function == "" {
+		return errors.New("no function given")
+	}
+	if args.BlockNumber == nil {
+		lastPersisted, err := r.db.GetLastPersistedBlockNumber()
+		if err != nil {
+			return err
+		}
+		args.BlockNumber = &lastPersisted
+	}
+
+	templates, err := r.getContractTemplates(*args.Address)
+	if err != nil {
+		return err
+	}
+	contractABI, err := templates.internalABIAt(*args.BlockNumber)
+	if err != nil {
+		return err
+	}
+	if contractABI == nil {
+		return errors.New("no ABI found for contract at block")
+	}
+	function, err := contractABI.FindFunction(args.Function, len(args.Args))
+	if err != nil {
+		return err
+	}
+
+	callData, err := function.EncodeCall(args.Args)
+	if err != nil {
+		return err
+	}
+	output, err := client.CallContract(r.quorumClient, *args.Address, types.HexData(hex.EncodeToString(callData)), *args.BlockNumber)
+	if err != nil {
+		return err
+	}
+	if output.IsEmpty() && len(function.Outputs) > 0 {
+		return errors.New("call returned no data, the contract may not have existed at the block")
+	}
+	parsedOutput, err := function.ParseOutput(output.AsBytes())
+	if err != nil {
+		return err
+	}
+
+	*reply = FunctionCallResp{
+		Sig:          function.String(),
+		BlockNumber:  *args.BlockNumber,
+		Output:       output,
+		ParsedOutput: parsedOutput,
+	}
+	return nil
+}
+
+// AddSampler adds a view function of a contract to be sampled as blocks are indexed, replacing any
+// sampler with the same name. The function is found in the ABI in use at the last persisted block.
+func (r *RPCAPIs) AddSampler(req *http.Request, args *SamplerArgs, reply *NullArgs) error {
+	if args.Address == nil {
+		return ErrNoAddress
+	}
+	if args.Sampler == nil {
+		return errors.New("no sampler given")
+	}
+
+	lastPersisted, err := r.db.GetLastPersistedBlockNumber()
+	if err != nil {
+		return err
+	}
+	templates, err := r.getContractTemplates(*args.Address)
+	if err != nil {
+		return err
+	}
+	contractABI, err := templates.internalABIAt(lastPersisted)
+	if err != nil {
+		return err
+	}
+	if err := args.Sampler.Resolve(contractABI); err != nil {
+		return err
+	}
+	return r.db.AddSampler(*args.Address, args.Sampler)
+}
+
+func (r *RPCAPIs) DeleteSampler(req *http.Request, args *SamplerNameArgs, reply *NullArgs) error {
+	if args.Address == nil {
+		return ErrNoAddress
+	}
+	return r.db.DeleteSampler(*args.Address, args.Name)
+}
+
+func (r *RPCAPIs) GetSamplers(req *http.Request, address *types.Address, reply *[]*types.FunctionSampler) error {
+	samplers, err := r.db.GetSamplers(*address)
+	if err != nil {
+		return err
+	}
+	*reply = samplers
+	return nil
+}
+
+// GetSamples fetches a page of the samples taken by a sampler, oldest first unless sorted desc
+func (r *RPCAPIs) GetSamples(req *http.Request, args *SamplesArgs, reply *SamplesResp) error {
+	if args.Address == nil {
+		return ErrNoAddress
+	}
+	if args.Name == "" {
+		return errors.New("no sampler name given")
+	}
+	if args.Options == nil {
+		args.Options = &types.QueryOptions{}
+	}
+	args.Options.SetDefaults()
+	args.Options = types.SamplesOptions(args.Options)
+	if err := args.Options.ValidatePagination(); err != nil {
+		return err
+	}
+
+	total, err := r.db.GetSamplesTotal(*args.Address, args.Name, args.Options)
+	if err != nil {
+		return err
+	}
+	samples, err := r.db.GetSamples(*args.Address, args.Name, args.Options)
+	if err != nil {
+		return err
+	}
+	templates, err := r.getContractTemplates(*args.Address)
+	if err != nil {
+		return err
+	}
+	parsedSamples := make([]*ParsedSample, len(samples))
+	for i, sample := range samples {
+		contractABI, err := templates.internalABIAt(sample.BlockNumber)
+		if err != nil {
+			return err
+		}
+		parsedSamples[i] = parseSample(sample, contractABI)
+	}
+
+	*reply = SamplesResp{
+		Address: *args.Address,
+		Name:    args.Name,
+		Samples: parsedSamples,
+		Total:   total,
+		Options: args.Options,
+		Next:    nextSamplesCursor(samples, args.Options),
+	}
+	return nil
+}
+
 func (r *RPCAPIs) GetStorageHistoryCount(req *http.Request, args *AddressWithBlockRange, reply *RangeQueryResult) error {
 	if args.Address == nil {
 		return ErrNoAddress
@@ -244,18 +664,14 @@ func (r *RPCAPIs) GetStorageHistory(req *http.Request, args *AddressWithBlockRan
 		args.Options = &types.PageOptions{}
 	}
 	args.Options.SetDefaults()
+	if err := args.Options.ValidatePagination(); err != nil {
+		return err
+	}
 
-	rawAbi, err := r.db.GetStorageLayout(*args.Address)
+	templates, err := r.getContractTemplates(*args.Address)
 	if err != nil {
 		return err
 	}
-	if rawAbi == "" {
-		return errors.New("no Storage Layout present to parse with")
-	}
-	var parsedAbi types.SolidityStorageDocument
-	if err = json.Unmarshal([]byte(rawAbi), &parsedAbi); err != nil {
-		return errors.New("unable to decode Storage Layout: " + err.Error())
-	}
 
 	total, err := r.db.GetStorageTotal(*args.Address, args.Options)
 
@@ -268,13 +684,22 @@ func (r *RPCAPIs) GetStorageHistory(req *http.Request, args *AddressWithBlockRan
 	if err != nil {
 		return err
 	}
+	var mappingKeys *storageparsing.MappingKeys
 	for _, rawStorage := range results {
 
 		if rawStorage == nil {
 			continue
 		}
 
-		historicStorage, err := storageparsing.ParseRawStorage(rawStorage.Storage, parsedAbi)
+		parsedAbi, err := templates.storageLayoutAt(rawStorage.BlockNumber)
+		if err != nil {
+			return err
+		}
+		if mappingKeys, err = r.getMappingKeys(*args.Address, parsedAbi, mappingKeys); err != nil {
+			return err
+		}
+
+		historicStorage, err := storageparsing.ParseRawStorage(rawStorage.Storage, parsedAbi, mappingKeys)
 		if err != nil {
 			return err
 		}
@@ -288,10 +713,150 @@ func (r *RPCAPIs) GetStorageHistory(req *http.Request, args *AddressWithBlockRan
 		HistoricState: historicStates,
 		Total:         total,
 		Options:       args.Options,
+		Next:          nextStorageCursor(results, args.Options),
 	}
 	return nil
 }
 
+func (r *RPCAPIs) GetVariableHistory(req *http.Request, args *AddressWithVariablePath, reply *types.VariableHistoryResponse) error {
+	if args.Address == nil {
+		return ErrNoAddress
+	}
+	if args.Path == "" {
+		return errors.New("no variable path provided")
+	}
+
+	if args.Options == nil {
+		args.Options = &types.PageOptions{}
+	}
+	args.Options.SetDefaults()
+	if err := args.Options.ValidatePagination(); err != nil {
+		return err
+	}
+
+	templates, err := r.getContractTemplates(*args.Address)
+	if err != nil {
+		return err
+	}
+
+	total, err := r.db.GetStorageTotal(*args.Address, args.Options)
+	if err != nil {
+		return err
+	}
+
+	results, err := r.db.GetStorageWithOptions(*args.Address, args.Options)
+	if err != nil {
+		return err
+	}
+
+	var mappingKeys *storageparsing.MappingKeys
+	parseVariable := func(rawStorage *types.StorageResult) (*types.StorageItem, error) {
+		parsedAbi, err := templates.storageLayoutAt(rawStorage.BlockNumber)
+		if err != nil {
+			return nil, err
+		}
+		if storageparsing.NeedsMappingKeys(parsedAbi, args.Path) {
+			if mappingKeys, err = r.getMappingKeys(*args.Address, parsedAbi, mappingKeys); err != nil {
+				return nil, err
+			}
+		}
+		return storageparsing.ParseVariable(rawStorage.Storage, parsedAbi, args.Path, mappingKeys)
+	}
+
+	var variableType string
+	states := []*types.VariableState{}
+	for _, rawStorage := range results {
+		if rawStorage == nil {
+			continue
+		}
+
+		variable, err := parseVariable(rawStorage)
+		if err != nil {
+			return err
+		}
+		variableType = variable.VarType
+		states = append(states, &types.VariableState{
+			BlockNumber: rawStorage.BlockNumber,
+			Value:       variable.Value,
+		})
+	}
+
+	// the oldest state on this page is compared against the latest state before it, if there is one in range
+	var oldest *types.VariableState
+	if len(states) > 0 {
+		oldest = states[len(states)-1]
+		if args.Options.Ascending() {
+			oldest = states[0]
+		}
+	}
+	var precedingValue interface{}
+	hasPreceding := false
+	if oldest != nil && oldest.BlockNumber > args.Options.BeginBlockNumber.Uint64() {
+		preceding, err := r.db.GetStorageWithOptions(*args.Address, &types.PageOptions{
+			BeginBlockNumber: args.Options.BeginBlockNumber,
+			EndBlockNumber:   new(big.Int).SetUint64(oldest.BlockNumber - 1),
+			PageSize:         1,
+		})
+		if err != nil {
+			return err
+		}
+		if len(preceding) > 0 && preceding[0] != nil {
+			precedingVariable, err := parseVariable(preceding[0])
+			if err != nil {
+				return err
+			}
+			precedingValue = precedingVariable.Value
+			hasPreceding = true
+		}
+	}
+
+	history := changedStates(states, args.Options.Ascending(), precedingValue, hasPreceding)
+
+	blockNumbers := make([]uint64, len(history))
+	for i, state := range history {
+		blockNumbers[i] = state.BlockNumber
+	}
+	blocks, err := r.db.ReadBlocks(blockNumbers)
+	if err != nil {
+		return err
+	}
+	for i, block := range blocks {
+		history[i].Timestamp = block.Timestamp
+	}
+
+	*reply = types.VariableHistoryResponse{
+		Address:     *args.Address,
+		Path:        args.Path,
+		Type:        variableType,
+		History:     history,
+		TotalStates: total,
+		Options:     args.Options,
+		Next:        nextStorageCursor(results, args.Options),
+	}
+	return nil
+}
+
+// changedStates keeps only the states where the value changed from the one before it, walking the states in
+// the order they are sorted in. The oldest state is compared against the preceding value, if there is one.
+func changedStates(states []*types.VariableState, ascending bool, precedingValue interface{}, hasPreceding bool) []*types.VariableState {
+	history := []*types.VariableState{}
+	for i, state := range states {
+		previous := i + 1
+		if ascending {
+			previous = i - 1
+		}
+		if previous >= 0 && previous < len(states) {
+			if reflect.DeepEqual(state.Value, states[previous].Value) {
+				continue
+			}
+		} else if hasPreceding && reflect.DeepEqual(state.Value, precedingValue) {
+			continue
+		}
+		history = append(history, state)
+	}
+	return history
+}
+
 func (r *RPCAPIs) AddAddress(req *http.Request, args *AddressWithOptionalBlock, reply *NullArgs) error {
 	if args.Address == nil {
 		return ErrNoAddress
@@ -305,7 +870,11 @@ func (r *RPCAPIs) AddAddress(req *http.Request, args *AddressWithOptionalBlock,
 }
 
 func (r *RPCAPIs) DeleteAddress(req *http.Request, address *types.Address, reply *NullArgs) error {
-	return r.db.DeleteAddress(*address)
+	if err := r.db.DeleteAddress(*address); err != nil {
+		return err
+	}
+	r.mappingKeys.invalidate()
+	return nil
 }
 
 func (r *RPCAPIs) GetAddresses(req *http.Request, args *NullArgs, reply *[]types.Address) error {
@@ -335,7 +904,12 @@ func (r *RPCAPIs) AddABI(req *http.Request, args *AddressWithData, reply *NullAr
 	if _, err := types.NewABIStructureFromJSON(args.Data); err != nil {
 		return err
 	}
-	return r.contractTemplateManager.AddContractABI(*args.Address, args.Data)
+	if err := r.contractTemplateManager.AddContractABI(*args.Address, args.Data); err != nil {
+		return err
+	}
+	r.signatures.invalidate()
+	r.mappingKeys.invalidate()
+	return nil
 }
 
 func (r *RPCAPIs) GetABI(req *http.Request, address *types.Address, reply *string) error {
@@ -347,16 +921,29 @@ func (r *RPCAPIs) GetABI(req *http.Request, address *types.Address, reply *strin
 	return nil
 }
 
-func (r *RPCAPIs) AddStorageABI(req *http.Request, args *AddressWithData, reply *NullArgs) error {
+func (r *RPCAPIs) AddStorageABI(req *http.Request, args *AddressWithCompiledData, reply *NullArgs) error {
 	if args.Address == nil {
 		return ErrNoAddress
 	}
 
+	if types.IsCompilerOutput(args.Data) {
+		if err := r.contractTemplateManager.AddCompilerOutput(*args.Address, args.Data, args.ContractName); err != nil {
+			return err
+		}
+		r.signatures.invalidate()
+		r.mappingKeys.invalidate()
+		return nil
+	}
+
 	var storageAbi types.SolidityStorageDocument
 	if err := json.Unmarshal([]byte(args.Data), &storageAbi); err != nil {
 		return errors.New("invalid JSON: " + err.Error())
 	}
-	return r.contractTemplateManager.AddStorageLayout(*args.Address, args.Data)
+	if err := r.contractTemplateManager.AddStorageLayout(*args.Address, args.Data); err != nil {
+		return err
+	}
+	r.mappingKeys.invalidate()
+	return nil
 }
 
 func (r *RPCAPIs) GetStorageABI(req *http.Request, address *types.Address, reply *string) error {
@@ -369,6 +956,22 @@ func (r *RPCAPIs) GetStorageABI(req *http.Request, address *types.Address, reply
 }
 
 func (r *RPCAPIs) AddTemplate(req *http.Request, args *TemplateArgs, reply *NullArgs) error {
+	if args.CompilerOutput != "" {
+		if args.Abi != "" || args.StorageLayout != "" {
+			return errors.New("compiler output cannot be given with an ABI or storage layout")
+		}
+		template, err := types.NewTemplateFromCompilerOutput(args.Name, args.CompilerOutput, args.ContractName)
+		if err != nil {
+			return err
+		}
+		if err := r.db.AddTemplate(template); err != nil {
+			return err
+		}
+		r.signatures.invalidate()
+		r.mappingKeys.invalidate()
+		return nil
+	}
+
 	// check ABI is valid
 	if _, err := types.NewABIStructureFromJSON(args.Abi); err != nil {
 		return err
@@ -378,14 +981,76 @@ func (r *RPCAPIs) AddTemplate(req *http.Request, args *TemplateArgs, reply *Null
 	if err := json.Unmarshal([]byte(args.StorageLayout), &storageAbi); err != nil {
 		return errors.New("invalid JSON: " + err.Error())
 	}
-	return r.db.AddTemplate(args.Name, args.Abi, args.StorageLayout)
+	err := r.db.AddTemplate(&types.Template{
+		TemplateName:  args.Name,
+		ABI:           args.Abi,
+		StorageLayout: args.StorageLayout,
+	})
+	if err != nil {
+		return err
+	}
+	r.signatures.invalidate()
+	r.mappingKeys.invalidate()
+	return nil
 }
 
 func (r *RPCAPIs) AssignTemplate(req *http.Request, args *AddressWithData, reply *NullArgs) error {
 	if args.Address == nil {
 		return ErrNoAddress
 	}
-	return r.db.AssignTemplate(*args.Address, args.Data)
+	if err := r.db.AssignTemplate(*args.Address, args.Data); err != nil {
+		return err
+	}
+	r.mappingKeys.invalidate()
+	return nil
+}
+
+// AssignTemplateFromBlock assigns a version of a template to a contract from the given block onwards,
+// until the next assignment. If no version is given, the current latest version is used, so that
+// later updates to the template do not change how past data is decoded.
+func (r *RPCAPIs) AssignTemplateFromBlock(req *http.Request, args *TemplateAssignmentArgs, reply *NullArgs) error {
+	if args.Address == nil {
+		return ErrNoAddress
+	}
+	if args.Name == "" {
+		return errors.New("no template name provided")
+	}
+
+	template, err := r.db.GetTemplateVersion(args.Name, args.Version)
+	if err == database.ErrNotFound {
+		return errors.New("template version not found")
+	}
+	if err != nil {
+		return err
+	}
+	err = r.db.AssignTemplateFromBlock(*args.Address, &types.TemplateAssignment{
+		TemplateName: args.Name,
+		Version:      template.Version,
+		FromBlock:    args.FromBlock,
+	})
+	if err != nil {
+		return err
+	}
+	r.mappingKeys.invalidate()
+	return nil
+}
+
+// RedecodeContract decodes the indexed transactions and events of a contract again with the templates assigned to it
+// now, which is needed for data indexed before its templates were added or changed to be filtered by decoded values
+func (r *RPCAPIs) RedecodeContract(req *http.Request, address *types.Address, reply *NullArgs) error {
+	if address == nil {
+		return ErrNoAddress
+	}
+	return r.db.RedecodeContract(*address)
+}
+
+func (r *RPCAPIs) GetTemplateAssignments(req *http.Request, address *types.Address, reply *[]*types.TemplateAssignment) error {
+	assignments, err := r.db.GetTemplateAssignments(*address)
+	if err != nil {
+		return err
+	}
+	*reply = assignments
+	return nil
 }
 
 func (r *RPCAPIs) GetTemplates(req *http.Request, args *NullArgs, result *[]string) error {
@@ -405,3 +1070,151 @@ func (r *RPCAPIs) GetTemplateDetails(req *http.Request, templateName *string, re
 	*reply = *template
 	return nil
 }
+
+func (r *RPCAPIs) GetTemplateVersion(req *http.Request, args *TemplateVersionArgs, reply *types.Template) error {
+	template, err := r.db.GetTemplateVersion(args.Name, args.Version)
+	if err != nil {
+		return err
+	}
+	*reply = *template
+	return nil
+}
+
+// internal functions
+
+// nextTransactionsCursor returns the cursor to continue from after a page of transactions, or an
+// empty string if the page is not full, as then there are no more transactions
+func (r *RPCAPIs) nextTransactionsCursor(txs []types.Hash, options *types.QueryOptions) (string, error) {
+	if len(txs) == 0 || len(txs) < options.PageSize {
+		return "", nil
+	}
+	last, err := r.db.ReadTransaction(txs[len(txs)-1])
+	if err != nil {
+		return "", err
+	}
+	return types.NewCursor(last.BlockNumber, last.Index).Encode(), nil
+}
+
+// nextEventsCursor returns the cursor to continue from after a page of events, or an empty string
+// if the page is not full
+func nextEventsCursor(events []*types.Event, options *types.QueryOptions) string {
+	if len(events) == 0 || len(events) < options.PageSize {
+		return ""
+	}
+	last := events[len(events)-1]
+	return types.NewCursor(last.BlockNumber, last.Index).Encode()
+}
+
+// nextStorageCursor returns the cursor to continue from after a page of storage results, or an empty
+// string if the page is not full. Each block has a single result, so the index is always zero.
+func nextStorageCursor(results []*types.StorageResult, options *types.PageOptions) string {
+	if len(results) == 0 || len(results) < options.PageSize {
+		return ""
+	}
+	for i := len(results) - 1; i >= 0; i-- {
+		if results[i] != nil {
+			return types.NewCursor(results[i].BlockNumber, 0).Encode()
+		}
+	}
+	return ""
+}
+
+// nextSamplesCursor returns the cursor to continue from after a page of samples, or an empty string if
+// the page is not full. A sampler has a single sample at each block, so the index is always zero.
+func nextSamplesCursor(samples []*types.FunctionSample, options *types.QueryOptions) string {
+	if len(samples) == 0 || len(samples) < options.PageSize {
+		return ""
+	}
+	return types.NewCursor(samples[len(samples)-1].BlockNumber, 0).Encode()
+}
+
+// parseInternalCalls decodes each internal call of a transaction with the
+// template that the called contract had at the block of the transaction
+func (r *RPCAPIs) parseInternalCalls(internalCalls []*types.InternalCall, blockNumber uint64) ([]*types.ParsedInternalCall, error) {
+	calleeTemplates := make(map[types.Address]*contractTemplates)
+	parsedCalls := make([]*types.ParsedInternalCall, len(internalCalls))
+	for i, internalCall := range internalCalls {
+		templates, ok := calleeTemplates[internalCall.To]
+		if !ok {
+			var err error
+			if templates, err = r.getContractTemplates(internalCall.To); err != nil {
+				return nil, err
+			}
+			calleeTemplates[internalCall.To] = templates
+		}
+		contractABI, err := templates.internalABIAt(blockNumber)
+		if err != nil {
+			return nil, err
+		}
+		if parsedCalls[i], err = r.parseInternalCall(internalCall, contractABI); err != nil {
+			return nil, err
+		}
+	}
+	return parsedCalls, nil
+}
+
+// parseInternalCall decodes an internal call with the given ABI of the callee,
+// or from the signature registry if the callee has no ABI
+func (r *RPCAPIs) parseInternalCall(internalCall *types.InternalCall, contractABI *types.ContractABI) (*types.ParsedInternalCall, error) {
+	parsedCall := &types.ParsedInternalCall{RawCall: internalCall}
+	if contractABI != nil {
+		return parsedCall, parsedCall.ParseCall(contractABI)
+	}
+	registry, err := r.signatures.get()
+	if err != nil {
+		return nil, err
+	}
+	return parsedCall, parsedCall.ParseCallFromRegistry(registry)
+}
+
+// parseEvents decodes the events of a contract, each with the ABI valid at the block it was emitted in
+func (r *RPCAPIs) parseEvents(address types.Address, events []*types.Event) ([]*types.ParsedEvent, error) {
+	templates, err := r.getContractTemplates(address)
+	if err != nil {
+		return nil, err
+	}
+	parsedEvents := make([]*types.ParsedEvent, len(events))
+	for i, e := range events {
+		contractABI, err := templates.abiAt(e.BlockNumber)
+		if err != nil {
+			return nil, err
+		}
+		if parsedEvents[i], err = r.parseEvent(e, contractABI); err != nil {
+			return nil, err
+		}
+	}
+	return parsedEvents, nil
+}
+
+// parseEvent decodes an event with the given ABI of the emitting contract,
+// or from the signature registry if the contract has no ABI
+func (r *RPCAPIs) parseEvent(event *types.Event, contractABI string) (*types.ParsedEvent, error) {
+	parsedEvent := &types.ParsedEvent{RawEvent: event}
+	if contractABI != "" {
+		return parsedEvent, parsedEvent.ParseEvent(contractABI)
+	}
+	registry, err := r.signatures.get()
+	if err != nil {
+		return nil, err
+	}
+	return parsedEvent, parsedEvent.ParseEventFromRegistry(registry)
+}
+
+// parseSample decodes the output of a sample with the function of the same signature in the
+// contract ABI. The output is left undecoded if the function is no longer in the ABI.
+func parseSample(sample *types.FunctionSample, contractABI *types.ContractABI) *ParsedSample {
+	parsed := &ParsedSample{FunctionSample: sample}
+	if contractABI == nil || sample.Error != "" || sample.Output.IsEmpty() {
+		return parsed
+	}
+	for _, function := range contractABI.Functions {
+		if function.String() != sample.Sig {
+			continue
+		}
+		if output, err := function.ParseOutput(sample.Output.AsBytes()); err == nil {
+			parsed.ParsedOutput = output
+		}
+		break
+	}
+	return parsed
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"quorumengineering/quorum-report/client"
 	"quorumengineering/quorum-report/database/memory"
 	"quorumengineering/quorum-report/types"
 )
@@ -76,15 +77,24 @@ var (
 
 func TestAPIValidation(t *testing.T) {
 	db := memory.NewMemoryDB()
-	apis := NewRPCAPIs(db, NewDefaultContractManager(db))
+	apis := NewRPCAPIs(db, nil, NewDefaultContractManager(db))
 
 	err := apis.AddAddress(dummyReq, &AddressWithOptionalBlock{}, nil)
 	assert.EqualError(t, err, "address not provided")
+
+	err = apis.GetVariableHistory(dummyReq, &AddressWithVariablePath{}, nil)
+	assert.EqualError(t, err, "address not provided")
+
+	err = apis.GetVariableHistory(dummyReq, &AddressWithVariablePath{Address: &addr}, nil)
+	assert.EqualError(t, err, "no variable path provided")
+
+	err = apis.AddTemplate(dummyReq, &TemplateArgs{Name: "test", Abi: validABI, CompilerOutput: `{"contracts": {}}`}, nil)
+	assert.EqualError(t, err, "compiler output cannot be given with an ABI or storage layout")
 }
 
 func TestAPIParsing(t *testing.T) {
 	db := memory.NewMemoryDB()
-	apis := NewRPCAPIs(db, NewDefaultContractManager(db))
+	apis := NewRPCAPIs(db, nil, NewDefaultContractManager(db))
 	err := apis.AddAddress(dummyReq, &AddressWithOptionalBlock{Address: &addr}, nil)
 	assert.Nil(t, err)
 
@@ -132,9 +142,46 @@ func TestAPIParsing(t *testing.T) {
 	assert.Equal(t, big.NewInt(1000), eventsResp.Events[0].ParsedData["_value"])
 }
 
+func TestGetFilteredEventsFromAddress(t *testing.T) {
+	db := memory.NewMemoryDB()
+	apis := NewRPCAPIs(db, nil, NewDefaultContractManager(db))
+	assert.Nil(t, apis.AddAddress(dummyReq, &AddressWithOptionalBlock{Address: &addr}, nil))
+	assert.Nil(t, apis.AddABI(dummyReq, &AddressWithData{&addr, validABI}, nil))
+	assert.Nil(t, db.WriteTransactions([]*types.Transaction{tx1, tx2, tx3}))
+	assert.Nil(t, db.WriteBlocks([]*types.Block{block}))
+	assert.Nil(t, db.IndexBlocks([]types.Address{addr}, []*types.Block{block}))
+
+	eventsResp := &EventsResp{}
+	err := apis.GetFilteredEventsFromAddress(dummyReq, &EventFilterArgs{
+		Address: &addr,
+		Filter:  &types.EventFilter{Event: "valueSet", Args: []*types.ArgFilter{{Name: "_value", Op: "gte", Value: float64(1000)}}},
+	}, eventsResp)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, eventsResp.Total)
+	assert.Equal(t, "event valueSet(uint256 _value)", eventsResp.Events[0].Sig)
+	assert.Equal(t, big.NewInt(1000), eventsResp.Events[0].ParsedData["_value"])
+
+	err = apis.GetFilteredEventsFromAddress(dummyReq, &EventFilterArgs{
+		Address: &addr,
+		Filter:  &types.EventFilter{Event: "valueSet(uint256)", Args: []*types.ArgFilter{{Name: "_value", Op: "gt", Value: "1000"}}},
+	}, eventsResp)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, eventsResp.Total)
+	assert.Len(t, eventsResp.Events, 0)
+
+	err = apis.GetFilteredEventsFromAddress(dummyReq, &EventFilterArgs{
+		Address: &addr,
+		Filter:  &types.EventFilter{Args: []*types.ArgFilter{{Name: "_value", Op: "between", Value: "1000"}}},
+	}, eventsResp)
+	assert.EqualError(t, err, "unknown operator between for argument _value")
+
+	err = apis.GetFilteredEventsFromAddress(dummyReq, &EventFilterArgs{}, eventsResp)
+	assert.Equal(t, ErrNoAddress, err)
+}
+
 func TestAddAddressWithFrom(t *testing.T) {
 	db := memory.NewMemoryDB()
-	apis := NewRPCAPIs(db, NewDefaultContractManager(db))
+	apis := NewRPCAPIs(db, nil, NewDefaultContractManager(db))
 	from := uint64(100)
 
 	params := &AddressWithOptionalBlock{
@@ -149,3 +196,448 @@ func TestAddAddressWithFrom(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, from-1, lastFiltered)
 }
+
+func TestGetFailedTransactionsToAddress(t *testing.T) {
+	db := memory.NewMemoryDB()
+	apis := NewRPCAPIs(db, nil, NewDefaultContractManager(db))
+	err := apis.AddAddress(dummyReq, &AddressWithOptionalBlock{Address: &addr}, nil)
+	assert.Nil(t, err)
+	abiWithError := validABI[:len(validABI)-1] + `,{"inputs":[{"name":"value","type":"uint256"}],"name":"ValueTooHigh","type":"error"}]`
+	err = apis.AddABI(dummyReq, &AddressWithData{&addr, abiWithError}, nil)
+	assert.Nil(t, err)
+
+	succeeded := &types.Transaction{
+		Hash:        types.NewHash("0x01"),
+		BlockNumber: 2,
+		Status:      true,
+		To:          addr,
+	}
+	reverted := &types.Transaction{
+		Hash:        types.NewHash("0x02"),
+		BlockNumber: 2,
+		To:          addr,
+		Data:        tx2.Data,
+		Error:       "execution reverted",
+		RevertData:  types.NewHexData("0xa253bc7000000000000000000000000000000000000000000000000000000000000003e8"),
+	}
+	outOfGas := &types.Transaction{
+		Hash:        types.NewHash("0x03"),
+		BlockNumber: 2,
+		To:          addr,
+		Error:       "out of gas",
+	}
+	err = db.WriteTransactions([]*types.Transaction{succeeded, reverted, outOfGas})
+	assert.Nil(t, err)
+	failedBlock := &types.Block{
+		Number:       2,
+		Transactions: []types.Hash{succeeded.Hash, reverted.Hash, outOfGas.Hash},
+	}
+	err = db.WriteBlocks([]*types.Block{failedBlock})
+	assert.Nil(t, err)
+	err = db.IndexBlocks([]types.Address{addr}, []*types.Block{failedBlock})
+	assert.Nil(t, err)
+
+	var failures FailedTransactionsResp
+	err = apis.GetFailedTransactionsToAddress(dummyReq, &AddressWithOptions{Address: &addr}, &failures)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, failures.Total)
+	assert.Len(t, failures.Transactions, 2)
+	assert.Equal(t, reverted.Hash, failures.Transactions[0].Hash)
+	assert.Equal(t, "ValueTooHigh(uint256 value)", failures.Transactions[0].Revert.Sig)
+	assert.Equal(t, big.NewInt(1000), failures.Transactions[0].Revert.ParsedData["value"])
+	assert.Equal(t, "out of gas", failures.Transactions[1].Error)
+	assert.Nil(t, failures.Transactions[1].Revert)
+
+	parsedTx := &types.ParsedTransaction{}
+	err = apis.GetTransaction(dummyReq, &reverted.Hash, parsedTx)
+	assert.Nil(t, err)
+	assert.Equal(t, "ValueTooHigh(uint256 value)", parsedTx.Revert.Sig)
+
+	err = apis.GetFailedTransactionsToAddress(dummyReq, &AddressWithOptions{}, &failures)
+	assert.Equal(t, ErrNoAddress, err)
+}
+
+func TestGetCallTree(t *testing.T) {
+	db := memory.NewMemoryDB()
+	apis := NewRPCAPIs(db, nil, NewDefaultContractManager(db))
+	err := apis.AddAddress(dummyReq, &AddressWithOptionalBlock{Address: &addr}, nil)
+	assert.Nil(t, err)
+	err = apis.AddABI(dummyReq, &AddressWithData{&addr, validABI}, nil)
+	assert.Nil(t, err)
+
+	// an unregistered contract which calls set(1000) and then get() on the registered contract
+	caller := types.NewAddress("0x0000000000000000000000000000000000000002")
+	tx := &types.Transaction{
+		Hash:        types.NewHash("0x01"),
+		BlockNumber: 2,
+		Status:      true,
+		To:          caller,
+		Data:        types.NewHexData("0x12345678"),
+		InternalCalls: []*types.InternalCall{
+			{Type: "CALL", To: caller, Input: types.NewHexData("0x12345678"), Depth: 1, Parent: -1, Path: []uint64{0}},
+			{Type: "CALL", To: addr, Input: tx2.Data, Depth: 2, Parent: 0, Path: []uint64{0, 0}},
+			{
+				Type:   "STATICCALL",
+				To:     addr,
+				Input:  types.NewHexData("0x6d4ce63c"),
+				Output: types.NewHexData("0x00000000000000000000000000000000000000000000000000000000000003e8"),
+				Depth:  2,
+				Parent: 0,
+				Path:   []uint64{0, 1},
+			},
+		},
+	}
+	err = db.WriteTransactions([]*types.Transaction{tx})
+	assert.Nil(t, err)
+
+	var tree CallTreeResp
+	err = apis.GetCallTree(dummyReq, &tx.Hash, &tree)
+	assert.Nil(t, err)
+	assert.Equal(t, tx.Hash, tree.Hash)
+	assert.Len(t, tree.Calls, 1)
+	assert.Empty(t, tree.Calls[0].Sig)
+
+	calls := tree.Calls[0].Calls
+	assert.Len(t, calls, 2)
+	assert.Equal(t, "set(uint256 _x)", calls[0].Sig)
+	assert.Equal(t, big.NewInt(999), calls[0].ParsedInput["_x"])
+	assert.Empty(t, calls[0].Calls)
+	assert.Equal(t, "get()", calls[1].Sig)
+	assert.Equal(t, big.NewInt(1000), calls[1].ParsedOutput["output0"])
+
+	emptyHash := types.NewHash("")
+	err = apis.GetCallTree(dummyReq, &emptyHash, &tree)
+	assert.EqualError(t, err, "no transaction hash given")
+}
+
+func TestGetAllTransactionsToAddress_WithCallFilter(t *testing.T) {
+	db := memory.NewMemoryDB()
+	apis := NewRPCAPIs(db, nil, NewDefaultContractManager(db))
+	assert.Nil(t, apis.AddAddress(dummyReq, &AddressWithOptionalBlock{Address: &addr}, nil))
+	assert.Nil(t, apis.AddABI(dummyReq, &AddressWithData{&addr, validABI}, nil))
+	assert.Nil(t, db.WriteTransactions([]*types.Transaction{tx1, tx2, tx3}))
+	assert.Nil(t, db.WriteBlocks([]*types.Block{block}))
+	assert.Nil(t, db.IndexBlocks([]types.Address{addr}, []*types.Block{block}))
+
+	var txs TransactionsResp
+	err := apis.GetAllTransactionsToAddress(dummyReq, &AddressWithOptions{
+		Address: &addr,
+		Options: &types.QueryOptions{Function: "set", Inputs: []*types.ArgFilter{{Name: "_x", Op: "gte", Value: float64(1000)}}},
+	}, &txs)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, txs.Total)
+	assert.Equal(t, []types.Hash{tx3.Hash}, txs.Transactions)
+
+	err = apis.GetAllTransactionsToAddress(dummyReq, &AddressWithOptions{
+		Address: &addr,
+		Options: &types.QueryOptions{Function: "0x60FE47B1", Status: types.StatusFailure},
+	}, &txs)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, txs.Total)
+
+	err = apis.GetAllTransactionsToAddress(dummyReq, &AddressWithOptions{
+		Address: &addr,
+		Options: &types.QueryOptions{Function: "set(uint256)", Status: types.StatusSuccess},
+	}, &txs)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, txs.Total)
+
+	err = apis.GetAllTransactionsToAddress(dummyReq, &AddressWithOptions{
+		Address: &addr,
+		Options: &types.QueryOptions{Status: "reverted"},
+	}, &txs)
+	assert.EqualError(t, err, "status must be success or failure")
+}
+
+func TestGetAllTransactionsFromAddress(t *testing.T) {
+	db := memory.NewMemoryDB()
+	apis := NewRPCAPIs(db, nil, NewDefaultContractManager(db))
+
+	sender := types.NewAddress("0x0000000000000000000000000000000000000002")
+	err := db.WriteTransactions([]*types.Transaction{
+		{Hash: types.NewHash("0x01"), BlockNumber: 1, From: sender, To: addr},
+		{Hash: types.NewHash("0x02"), BlockNumber: 2, From: addr, InternalCalls: []*types.InternalCall{{From: sender, To: addr}}},
+		{Hash: types.NewHash("0x03"), BlockNumber: 3, From: addr},
+	})
+	assert.Nil(t, err)
+
+	var txs TransactionsResp
+	err = apis.GetAllTransactionsFromAddress(dummyReq, &AddressWithOptions{Address: &sender}, &txs)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, txs.Total)
+	assert.Equal(t, []types.Hash{types.NewHash("0x02"), types.NewHash("0x01")}, txs.Transactions)
+
+	err = apis.GetAllTransactionsFromAddress(dummyReq, &AddressWithOptions{}, &txs)
+	assert.Equal(t, ErrNoAddress, err)
+}
+
+func TestGetAllTransactionsFromAddress_WithCursor(t *testing.T) {
+	db := memory.NewMemoryDB()
+	apis := NewRPCAPIs(db, nil, NewDefaultContractManager(db))
+
+	sender := types.NewAddress("0x0000000000000000000000000000000000000002")
+	err := db.WriteTransactions([]*types.Transaction{
+		{Hash: types.NewHash("0x01"), BlockNumber: 1, From: sender},
+		{Hash: types.NewHash("0x02"), BlockNumber: 2, From: sender},
+		{Hash: types.NewHash("0x03"), BlockNumber: 3, From: sender},
+	})
+	assert.Nil(t, err)
+
+	var txs TransactionsResp
+	var pages [][]types.Hash
+	options := &types.QueryOptions{PageSize: 2}
+	for {
+		err = apis.GetAllTransactionsFromAddress(dummyReq, &AddressWithOptions{Address: &sender, Options: options}, &txs)
+		assert.Nil(t, err)
+		pages = append(pages, txs.Transactions)
+		if txs.Next == "" {
+			break
+		}
+		options = &types.QueryOptions{PageSize: 2, After: txs.Next}
+	}
+	assert.Equal(t, [][]types.Hash{
+		{types.NewHash("0x03"), types.NewHash("0x02")},
+		{types.NewHash("0x01")},
+	}, pages)
+
+	err = apis.GetAllTransactionsFromAddress(dummyReq, &AddressWithOptions{Address: &sender, Options: &types.QueryOptions{Sort: "newest"}}, &txs)
+	assert.EqualError(t, err, "sort must be asc or desc")
+}
+
+func TestGetAllTransactionsInternalToAddress_DecodesCalls(t *testing.T) {
+	db := memory.NewMemoryDB()
+	apis := NewRPCAPIs(db, nil, NewDefaultContractManager(db))
+	err := apis.AddAddress(dummyReq, &AddressWithOptionalBlock{Address: &addr}, nil)
+	assert.Nil(t, err)
+	err = apis.AddABI(dummyReq, &AddressWithData{&addr, validABI}, nil)
+	assert.Nil(t, err)
+
+	caller := types.NewAddress("0x0000000000000000000000000000000000000002")
+	tx := &types.Transaction{
+		Hash:        types.NewHash("0x01"),
+		BlockNumber: 2,
+		Status:      true,
+		To:          caller,
+		Data:        types.NewHexData("0x12345678"),
+		InternalCalls: []*types.InternalCall{
+			{Type: "CALL", To: caller, Input: types.NewHexData("0x12345678"), Depth: 1, Parent: -1, Path: []uint64{0}},
+			{
+				Type:   "STATICCALL",
+				To:     addr,
+				Input:  types.NewHexData("0x6d4ce63c"),
+				Output: types.NewHexData("0x00000000000000000000000000000000000000000000000000000000000003e8"),
+				Depth:  2,
+				Parent: 0,
+				Path:   []uint64{0, 0},
+			},
+		},
+	}
+	err = db.WriteTransactions([]*types.Transaction{tx})
+	assert.Nil(t, err)
+	txBlock := &types.Block{Number: 2, Transactions: []types.Hash{tx.Hash}}
+	err = db.WriteBlocks([]*types.Block{txBlock})
+	assert.Nil(t, err)
+	err = db.IndexBlocks([]types.Address{addr}, []*types.Block{txBlock})
+	assert.Nil(t, err)
+
+	var internalTxs InternalTransactionsResp
+	err = apis.GetAllTransactionsInternalToAddress(dummyReq, &AddressWithOptions{Address: &addr}, &internalTxs)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, internalTxs.Total)
+	assert.Equal(t, []types.Hash{tx.Hash}, internalTxs.Transactions)
+	assert.Len(t, internalTxs.InternalCalls, 1)
+	assert.Equal(t, tx.Hash, internalTxs.InternalCalls[0].TransactionHash)
+	assert.Equal(t, 1, internalTxs.InternalCalls[0].Index)
+	assert.Equal(t, "get()", internalTxs.InternalCalls[0].Sig)
+	assert.Equal(t, big.NewInt(1000), internalTxs.InternalCalls[0].ParsedOutput["output0"])
+
+	parsedTx := &types.ParsedTransaction{}
+	err = apis.GetTransaction(dummyReq, &tx.Hash, parsedTx)
+	assert.Nil(t, err)
+	assert.Len(t, parsedTx.ParsedInternalCalls, 2)
+	assert.Empty(t, parsedTx.ParsedInternalCalls[0].Sig)
+	assert.Equal(t, "get()", parsedTx.ParsedInternalCalls[1].Sig)
+	assert.Equal(t, big.NewInt(1000), parsedTx.ParsedInternalCalls[1].ParsedOutput["output0"])
+}
+
+func TestGetTransaction_DecodesFromSignatureRegistry(t *testing.T) {
+	db := memory.NewMemoryDB()
+	apis := NewRPCAPIs(db, nil, NewDefaultContractManager(db))
+
+	// a call to set(999) and a valueSet event on a contract that has no template
+	unregistered := types.NewAddress("0x0000000000000000000000000000000000000002")
+	tx := &types.Transaction{
+		Hash:        types.NewHash("0x01"),
+		BlockNumber: 2,
+		Status:      true,
+		To:          unregistered,
+		Data:        tx2.Data,
+		Events: []*types.Event{
+			{
+				Address: unregistered,
+				Topics:  []types.Hash{types.NewHash("0xefe5cb8d23d632b5d2cdd9f0a151c4b1a84ccb7afa1c57331009aa922d5e4f36")},
+				Data:    types.NewHexData("0x00000000000000000000000000000000000000000000000000000000000003e7"),
+			},
+		},
+	}
+	err := db.WriteTransactions([]*types.Transaction{tx})
+	assert.Nil(t, err)
+
+	parsedTx := &types.ParsedTransaction{}
+	err = apis.GetTransaction(dummyReq, &tx.Hash, parsedTx)
+	assert.Nil(t, err)
+	assert.Empty(t, parsedTx.Sig)
+	assert.False(t, parsedTx.RegistryDecoded)
+
+	// the registry is rebuilt once a template is uploaded
+	err = apis.AddTemplate(dummyReq, &TemplateArgs{Name: "SimpleStorage", Abi: validABI, StorageLayout: "{}"}, nil)
+	assert.Nil(t, err)
+
+	parsedTx = &types.ParsedTransaction{}
+	err = apis.GetTransaction(dummyReq, &tx.Hash, parsedTx)
+	assert.Nil(t, err)
+	assert.Equal(t, "set(uint256 _x)", parsedTx.Sig)
+	assert.Equal(t, big.NewInt(999), parsedTx.ParsedData["_x"])
+	assert.True(t, parsedTx.RegistryDecoded)
+	assert.Equal(t, "event valueSet(uint256 _value)", parsedTx.ParsedEvents[0].Sig)
+	assert.True(t, parsedTx.ParsedEvents[0].RegistryDecoded)
+}
+
+func TestSeedSignatures(t *testing.T) {
+	db := memory.NewMemoryDB()
+	apis := NewRPCAPIs(db, nil, NewDefaultContractManager(db))
+
+	err := apis.SeedSignatures("../../signatures.json")
+	assert.Nil(t, err)
+
+	registry, err := apis.signatures.get()
+	assert.Nil(t, err)
+	transfers := registry.Functions("a9059cbb")
+	assert.Len(t, transfers, 1)
+	assert.Equal(t, "transfer(address to,uint256 value)", transfers[0].String())
+
+	err = apis.SeedSignatures("does-not-exist.json")
+	assert.NotNil(t, err)
+}
+
+func TestCallFunction(t *testing.T) {
+	mockRPC := map[string]interface{}{
+		"eth_call<types.EIP165Call Value>0x5": types.NewHexData("0x00000000000000000000000000000000000000000000000000000000000003e8"),
+	}
+	db := memory.NewMemoryDB()
+	apis := NewRPCAPIs(db, client.NewStubQuorumClient(nil, mockRPC), NewDefaultContractManager(db))
+	err := apis.AddAddress(dummyReq, &AddressWithOptionalBlock{Address: &addr}, nil)
+	assert.Nil(t, err)
+	err = apis.AddABI(dummyReq, &AddressWithData{&addr, validABI}, nil)
+	assert.Nil(t, err)
+
+	blockNumber := uint64(5)
+	var result FunctionCallResp
+	err = apis.CallFunction(dummyReq, &FunctionCallArgs{Address: &addr, Function: "get", BlockNumber: &blockNumber}, &result)
+	assert.Nil(t, err)
+	assert.Equal(t, "get()", result.Sig)
+	assert.EqualValues(t, 5, result.BlockNumber)
+	assert.Equal(t, big.NewInt(1000), result.ParsedOutput["output0"])
+
+	err = apis.CallFunction(dummyReq, &FunctionCallArgs{Address: &addr, Function: "set", Args: []interface{}{"not a number"}, BlockNumber: &blockNumber}, &result)
+	assert.EqualError(t, err, "_x: invalid integer not a number")
+
+	err = apis.CallFunction(dummyReq, &FunctionCallArgs{Address: &addr, Function: "unknown", BlockNumber: &blockNumber}, &result)
+	assert.EqualError(t, err, "function not found: unknown")
+
+	err = apis.CallFunction(dummyReq, &FunctionCallArgs{Function: "get"}, &result)
+	assert.Equal(t, ErrNoAddress, err)
+}
+
+func TestSamplers(t *testing.T) {
+	db := memory.NewMemoryDB()
+	apis := NewRPCAPIs(db, nil, NewDefaultContractManager(db))
+	err := apis.AddAddress(dummyReq, &AddressWithOptionalBlock{Address: &addr}, nil)
+	assert.Nil(t, err)
+
+	// samplers can only be added for functions in the contract's ABI
+	err = apis.AddSampler(dummyReq, &SamplerArgs{Address: &addr, Sampler: &types.FunctionSampler{Function: "get"}}, nil)
+	assert.EqualError(t, err, "no ABI found for contract")
+	err = apis.AddABI(dummyReq, &AddressWithData{&addr, validABI}, nil)
+	assert.Nil(t, err)
+	err = apis.AddSampler(dummyReq, &SamplerArgs{Address: &addr, Sampler: &types.FunctionSampler{Function: "unknown"}}, nil)
+	assert.EqualError(t, err, "function not found: unknown")
+
+	err = apis.AddSampler(dummyReq, &SamplerArgs{Address: &addr, Sampler: &types.FunctionSampler{Name: "value", Function: "get", Interval: 10}}, nil)
+	assert.Nil(t, err)
+
+	var samplers []*types.FunctionSampler
+	err = apis.GetSamplers(dummyReq, &addr, &samplers)
+	assert.Nil(t, err)
+	assert.Equal(t, []*types.FunctionSampler{
+		{Name: "value", Function: "get", Interval: 10, Sig: "get()", CallData: "6d4ce63c"},
+	}, samplers)
+
+	err = db.RecordSamples([]*types.FunctionSample{
+		{Address: addr, Name: "value", Sig: "get()", BlockNumber: 10, Timestamp: 1000, Output: "00000000000000000000000000000000000000000000000000000000000003e8"},
+		{Address: addr, Name: "value", Sig: "get()", BlockNumber: 20, Timestamp: 2000, Error: "execution reverted"},
+	})
+	assert.Nil(t, err)
+
+	var result SamplesResp
+	err = apis.GetSamples(dummyReq, &SamplesArgs{Address: &addr, Name: "value"}, &result)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, result.Total)
+	assert.Len(t, result.Samples, 2)
+	assert.Equal(t, big.NewInt(1000), result.Samples[0].ParsedOutput["output0"])
+	assert.Equal(t, "execution reverted", result.Samples[1].Error)
+	assert.Nil(t, result.Samples[1].ParsedOutput)
+	assert.Equal(t, "", result.Next)
+
+	// a full page gives the cursor to continue from
+	err = apis.GetSamples(dummyReq, &SamplesArgs{Address: &addr, Name: "value", Options: &types.QueryOptions{PageSize: 1}}, &result)
+	assert.Nil(t, err)
+	assert.Len(t, result.Samples, 1)
+	assert.NotEqual(t, "", result.Next)
+	err = apis.GetSamples(dummyReq, &SamplesArgs{Address: &addr, Name: "value", Options: &types.QueryOptions{PageSize: 1, After: result.Next}}, &result)
+	assert.Nil(t, err)
+	assert.Len(t, result.Samples, 1)
+	assert.EqualValues(t, 20, result.Samples[0].BlockNumber)
+
+	err = apis.GetSamples(dummyReq, &SamplesArgs{Address: &addr, Name: "value", Options: &types.QueryOptions{Sort: "newest"}}, &result)
+	assert.EqualError(t, err, "sort must be asc or desc")
+	err = apis.GetSamples(dummyReq, &SamplesArgs{Address: &addr, Name: "value", Options: &types.QueryOptions{After: "not a cursor"}}, &result)
+	assert.Equal(t, types.ErrInvalidCursor, err)
+
+	err = apis.DeleteSampler(dummyReq, &SamplerNameArgs{Address: &addr, Name: "value"}, nil)
+	assert.Nil(t, err)
+	err = apis.GetSamplers(dummyReq, &addr, &samplers)
+	assert.Nil(t, err)
+	assert.Len(t, samplers, 0)
+
+	err = apis.GetSamples(dummyReq, &SamplesArgs{Address: &addr}, &result)
+	assert.EqualError(t, err, "no sampler name given")
+	err = apis.AddSampler(dummyReq, &SamplerArgs{Sampler: &types.FunctionSampler{Function: "get"}}, nil)
+	assert.Equal(t, ErrNoAddress, err)
+}
+
+func TestChangedStates(t *testing.T) {
+	newest := []*types.VariableState{
+		{BlockNumber: 9, Value: "2"},
+		{BlockNumber: 7, Value: "2"},
+		{BlockNumber: 5, Value: "1"},
+		{BlockNumber: 3, Value: "1"},
+	}
+	history := changedStates(newest, false, "1", true)
+	assert.Equal(t, []*types.VariableState{{BlockNumber: 7, Value: "2"}}, history)
+
+	history = changedStates(newest, false, nil, false)
+	assert.Equal(t, []*types.VariableState{{BlockNumber: 7, Value: "2"}, {BlockNumber: 3, Value: "1"}}, history)
+
+	oldest := []*types.VariableState{
+		{BlockNumber: 3, Value: "1"},
+		{BlockNumber: 5, Value: "1"},
+		{BlockNumber: 7, Value: "2"},
+		{BlockNumber: 9, Value: "2"},
+	}
+	history = changedStates(oldest, true, "0", true)
+	assert.Equal(t, []*types.VariableState{{BlockNumber: 3, Value: "1"}, {BlockNumber: 7, Value: "2"}}, history)
+
+	history = changedStates(oldest, true, "1", true)
+	assert.Equal(t, []*types.VariableState{{BlockNumber: 7, Value: "2"}}, history)
+}
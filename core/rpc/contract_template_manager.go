@@ -9,6 +9,7 @@ import (
 type ContractTemplateManager interface {
 	AddStorageLayout(address types.Address, layout string) error
 	AddContractABI(address types.Address, abi string) error
+	AddCompilerOutput(address types.Address, output string, contractName string) error
 }
 
 type DefaultContractTemplateManager struct {
@@ -34,17 +35,11 @@ func (cm *DefaultContractTemplateManager) AddStorageLayout(address types.Address
 		return err
 	}
 
+	newTemplate := &types.Template{TemplateName: address.String(), StorageLayout: layout}
 	if err == nil {
-		if err := cm.db.AddTemplate(address.String(), template.ABI, layout); err != nil {
-			return err
-		}
-	} else {
-		if err := cm.db.AddTemplate(address.String(), "", layout); err != nil {
-			return err
-		}
+		newTemplate.ABI = template.ABI
 	}
-
-	return cm.db.AssignTemplate(address, address.String())
+	return cm.addOwnTemplate(address, newTemplate)
 }
 
 func (cm *DefaultContractTemplateManager) AddContractABI(address types.Address, abi string) error {
@@ -60,15 +55,77 @@ func (cm *DefaultContractTemplateManager) AddContractABI(address types.Address,
 		return err
 	}
 
+	newTemplate := &types.Template{TemplateName: address.String(), ABI: abi}
 	if err == nil {
-		if err := cm.db.AddTemplate(address.String(), abi, template.StorageLayout); err != nil {
+		newTemplate.StorageLayout = template.StorageLayout
+	}
+	return cm.addOwnTemplate(address, newTemplate)
+}
+
+// AddCompilerOutput extracts the ABI, storage layout and compiler details of the chosen
+// contract from the compiler output, replacing the whole template of the contract
+func (cm *DefaultContractTemplateManager) AddCompilerOutput(address types.Address, output string, contractName string) error {
+	// check contract existence before updating
+	if _, err := cm.db.GetContractTemplate(address); err != nil {
+		return err
+	}
+
+	// create new template named contract.Address.String()
+	template, err := types.NewTemplateFromCompilerOutput(address.String(), output, contractName)
+	if err != nil {
+		return err
+	}
+	return cm.addOwnTemplate(address, template)
+}
+
+// addOwnTemplate adds a new version of the template named after the contract, and assigns it to the contract.
+// A contract with a single assignment is assigned the new version for all blocks, replacing it. A contract that
+// has been assigned templates from later blocks keeps that history, and uses the new version from the block after
+// the last one filtered; its assignments of the latest version of a template are pinned to the version they used,
+// so adding the new version does not change how earlier blocks are decoded.
+func (cm *DefaultContractTemplateManager) addOwnTemplate(address types.Address, template *types.Template) error {
+	assignments, err := cm.db.GetTemplateAssignments(address)
+	if err != nil && err != database.ErrNotFound {
+		return err
+	}
+	if len(assignments) < 2 && (len(assignments) == 0 || assignments[0].FromBlock == 0) {
+		if err := cm.db.AddTemplate(template); err != nil {
+			return err
+		}
+		return cm.db.AssignTemplate(address, template.TemplateName)
+	}
+
+	for _, assignment := range assignments {
+		if assignment.Version != 0 {
+			continue
+		}
+		latest, err := cm.db.GetTemplateDetails(assignment.TemplateName)
+		if err == database.ErrNotFound {
+			continue
+		}
+		if err != nil {
 			return err
 		}
-	} else {
-		if err := cm.db.AddTemplate(address.String(), abi, ""); err != nil {
+		pinned := &types.TemplateAssignment{TemplateName: assignment.TemplateName, Version: latest.Version, FromBlock: assignment.FromBlock}
+		if err := cm.db.AssignTemplateFromBlock(address, pinned); err != nil {
 			return err
 		}
 	}
 
-	return cm.db.AssignTemplate(address, address.String())
+	if err := cm.db.AddTemplate(template); err != nil {
+		return err
+	}
+	added, err := cm.db.GetTemplateDetails(template.TemplateName)
+	if err != nil {
+		return err
+	}
+	lastFiltered, err := cm.db.GetLastFiltered(address)
+	if err != nil {
+		return err
+	}
+	return cm.db.AssignTemplateFromBlock(address, &types.TemplateAssignment{
+		TemplateName: template.TemplateName,
+		Version:      added.Version,
+		FromBlock:    lastFiltered + 1,
+	})
 }
@@ -13,7 +13,7 @@ func TestDefaultContractManager_AddContractABI_ExistingTemplate(t *testing.T) {
 	address := types.NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17")
 
 	db := memory.NewMemoryDB()
-	_ = db.AddTemplate("sample template", "sample abi", "sample layout")
+	_ = db.AddTemplate(&types.Template{TemplateName: "sample template", ABI: "sample abi", StorageLayout: "sample layout"})
 	_ = db.AssignTemplate(address, "sample template")
 
 	contractManager := NewDefaultContractManager(db)
@@ -59,7 +59,7 @@ func TestDefaultContractManager_AddStorageLayout_ExistingTemplate(t *testing.T)
 	address := types.NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17")
 
 	db := memory.NewMemoryDB()
-	_ = db.AddTemplate("sample template", "sample abi", "sample layout")
+	_ = db.AddTemplate(&types.Template{TemplateName: "sample template", ABI: "sample abi", StorageLayout: "sample layout"})
 	_ = db.AssignTemplate(address, "sample template")
 
 	contractManager := NewDefaultContractManager(db)
@@ -100,3 +100,57 @@ func TestDefaultContractManager_AddStorageLayout_NoExistingTemplate(t *testing.T
 	templateName, _ := db.GetContractTemplate(address)
 	assert.Equal(t, address.Hex(), templateName)
 }
+
+func TestDefaultContractManager_AddCompilerOutput(t *testing.T) {
+	address := types.NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17")
+
+	db := memory.NewMemoryDB()
+	_ = db.AddTemplate(&types.Template{TemplateName: "sample template", ABI: "sample abi", StorageLayout: "sample layout"})
+	_ = db.AssignTemplate(address, "sample template")
+
+	contractManager := NewDefaultContractManager(db)
+
+	output := `{"contracts": {"SimpleStorage.sol": {"SimpleStorage": {"abi": [], "storageLayout": {"storage": [], "types": {}}}}}}`
+	err := contractManager.AddCompilerOutput(address, output, "SimpleStorage")
+	assert.Nil(t, err)
+
+	template, _ := db.GetTemplateDetails(address.Hex())
+	assert.Equal(t, address.Hex(), template.TemplateName)
+	assert.Equal(t, "[]", template.ABI)
+	assert.Equal(t, `{"storage": [], "types": {}}`, template.StorageLayout)
+	assert.Equal(t, "SimpleStorage", template.ContractName)
+
+	templateName, _ := db.GetContractTemplate(address)
+	assert.Equal(t, address.Hex(), templateName)
+
+	err = contractManager.AddCompilerOutput(address, output, "Missing")
+	assert.EqualError(t, err, "contract Missing not found in compiler output")
+}
+
+func TestDefaultContractManager_AddContractABI_KeepsVersionedAssignments(t *testing.T) {
+	address := types.NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17")
+
+	db := memory.NewMemoryDB()
+	_ = db.AddAddresses([]types.Address{address})
+	_ = db.AddTemplate(&types.Template{TemplateName: "v1 template", ABI: "v1 abi", StorageLayout: "v1 layout"})
+	_ = db.AddTemplate(&types.Template{TemplateName: "v2 template", ABI: "v2 abi", StorageLayout: "v2 layout"})
+	_ = db.AssignTemplate(address, "v1 template")
+	_ = db.AssignTemplateFromBlock(address, &types.TemplateAssignment{TemplateName: "v2 template", Version: 1, FromBlock: 10})
+	_ = db.IndexBlocks([]types.Address{address}, []*types.Block{{Number: 20}})
+
+	contractManager := NewDefaultContractManager(db)
+
+	err := contractManager.AddContractABI(address, "new sample abi")
+	assert.Nil(t, err)
+
+	assignments, _ := db.GetTemplateAssignments(address)
+	assert.Equal(t, []*types.TemplateAssignment{
+		{TemplateName: "v1 template", Version: 1, FromBlock: 0},
+		{TemplateName: "v2 template", Version: 1, FromBlock: 10},
+		{TemplateName: address.Hex(), Version: 1, FromBlock: 21},
+	}, assignments)
+
+	template, _ := db.GetTemplateDetails(address.Hex())
+	assert.Equal(t, "new sample abi", template.ABI)
+	assert.Equal(t, "v2 layout", template.StorageLayout)
+}
@@ -67,7 +67,7 @@ func SetupRpcServer(db database.Database) *RPCService {
 	}
 	config := types.ReportingConfig{Server: serverConfig}
 
-	return NewRPCService(db, config, errorChan)
+	return NewRPCService(db, nil, config, errorChan)
 }
 
 //TODO: error case
@@ -212,7 +212,7 @@ func TestRPCAPIs_GetAllTransactionsInternalToAddress(t *testing.T) {
 	rpcResponse, err := doRequest(msg)
 	assert.Nil(t, err)
 
-	var result TransactionsResp
+	var result InternalTransactionsResp
 	_ = json.Unmarshal(rpcResponse.Result, &result)
 
 	expectedOptions := &types.QueryOptions{}
@@ -221,6 +221,8 @@ func TestRPCAPIs_GetAllTransactionsInternalToAddress(t *testing.T) {
 	assert.Equal(t, "null", string(rpcResponse.Error))
 	assert.EqualValues(t, 1, result.Total)
 	assert.Contains(t, result.Transactions, tx3.Hash)
+	assert.Len(t, result.InternalCalls, 1)
+	assert.Equal(t, tx3.Hash, result.InternalCalls[0].TransactionHash)
 	assert.Equal(t, result.Options, expectedOptions)
 }
 
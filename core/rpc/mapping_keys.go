@@ -0,0 +1,399 @@
+package rpc
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+
+	"quorumengineering/quorum-report/core/storageparsing"
+	"quorumengineering/quorum-report/database"
+	"quorumengineering/quorum-report/types"
+)
+
+// mappingKeySourcePageSize is the page size used when walking through indexed
+// data for mapping keys, which is the largest page that can be fetched
+const mappingKeySourcePageSize = 1000
+
+// mappingKeyCache keeps the candidate mapping keys found for each contract, along with the next block to look for
+// keys from, so that later requests only look through the data indexed since. Cached keys are never changed, as
+// other requests may be using them; new keys are merged into a copy instead.
+type mappingKeyCache struct {
+	mu      sync.Mutex
+	entries map[types.Address]cachedMappingKeys
+}
+
+type cachedMappingKeys struct {
+	keys      *storageparsing.MappingKeys
+	nextBlock uint64
+}
+
+func newMappingKeyCache() *mappingKeyCache {
+	return &mappingKeyCache{entries: make(map[types.Address]cachedMappingKeys)}
+}
+
+func (mc *mappingKeyCache) get(address types.Address) (*storageparsing.MappingKeys, uint64) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	cached := mc.entries[address]
+	return cached.keys, cached.nextBlock
+}
+
+func (mc *mappingKeyCache) set(address types.Address, keys *storageparsing.MappingKeys, nextBlock uint64) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if cached, ok := mc.entries[address]; ok && cached.nextBlock > nextBlock {
+		return
+	}
+	mc.entries[address] = cachedMappingKeys{keys: keys, nextBlock: nextBlock}
+}
+
+// invalidate removes all cached keys, e.g. after templates change, since keys are decoded using the contract ABIs
+func (mc *mappingKeyCache) invalidate() {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.entries = make(map[types.Address]cachedMappingKeys)
+}
+
+// getMappingKeys finds candidate mapping keys for the contract, if the storage layout contains any mappings and they
+// have not already been found. Keys are found up to the last block filtered for the contract, which is also fine for
+// earlier blocks, since only the keys that have a value at the block are in the parsed mapping.
+func (r *RPCAPIs) getMappingKeys(address types.Address, layout types.SolidityStorageDocument, existing *storageparsing.MappingKeys) (*storageparsing.MappingKeys, error) {
+	if existing != nil || !storageparsing.HasMappings(layout) {
+		return existing, nil
+	}
+
+	lastFiltered, err := r.db.GetLastFiltered(address)
+	if err != nil {
+		return nil, err
+	}
+	cached, nextBlock := r.mappingKeys.get(address)
+	if cached != nil && nextBlock > lastFiltered {
+		return cached, nil
+	}
+
+	found, err := r.discoverMappingKeys(address, nextBlock, lastFiltered)
+	if err != nil {
+		return nil, err
+	}
+	if cached != nil {
+		found.Merge(cached)
+	}
+	r.mappingKeys.set(address, found, lastFiltered+1)
+	return found, nil
+}
+
+// discoverMappingKeys finds candidate keys for the mappings of a contract from the data that has been indexed for it
+// between the given blocks. Keys are taken from the senders and decoded address/integer arguments of transactions and
+// internal calls to the contract, the decoded address/integer arguments of its events, and any token holders and
+// token IDs it has at the end block.
+func (r *RPCAPIs) discoverMappingKeys(address types.Address, beginBlock uint64, endBlock uint64) (*storageparsing.MappingKeys, error) {
+	templates, err := r.getContractTemplates(address)
+	if err != nil {
+		return nil, err
+	}
+	collector := &mappingKeyCollector{
+		address:       address,
+		contractABIAt: templates.internalABIAt,
+		keys:          storageparsing.NewMappingKeys(),
+	}
+	blocks := blockRange{begin: beginBlock, end: endBlock}
+
+	fetchTxsTo := func(options *types.QueryOptions) ([]types.Hash, error) {
+		return r.db.GetAllTransactionsToAddress(address, options)
+	}
+	if err := r.walkTransactions(blocks, fetchTxsTo, collector.addTransaction); err != nil {
+		return nil, err
+	}
+	fetchInternalTxsTo := func(options *types.QueryOptions) ([]types.Hash, error) {
+		return r.db.GetAllTransactionsInternalToAddress(address, options)
+	}
+	if err := r.walkTransactions(blocks, fetchInternalTxsTo, collector.addTransaction); err != nil {
+		return nil, err
+	}
+	if err := r.walkEvents(address, blocks, collector.addEvent); err != nil {
+		return nil, err
+	}
+	if err := r.collectTokenKeys(address, endBlock, collector.keys); err != nil {
+		return nil, err
+	}
+
+	return collector.keys, nil
+}
+
+// blockRange is an inclusive range of blocks to walk through
+type blockRange struct {
+	begin uint64
+	end   uint64
+}
+
+func (r *RPCAPIs) walkTransactions(blocks blockRange, fetch func(*types.QueryOptions) ([]types.Hash, error), handle func(*types.Transaction) error) error {
+	seen := make(map[types.Hash]uint64)
+
+	return walkBackwards(blocks, func(options *types.QueryOptions) (int, int, uint64, error) {
+		hashes, err := fetch(options)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+
+		unseen := 0
+		lowestBlock := uint64(math.MaxUint64)
+		for _, hash := range hashes {
+			blockNumber, ok := seen[hash]
+			if !ok {
+				tx, err := r.db.ReadTransaction(hash)
+				if err != nil {
+					return 0, 0, 0, err
+				}
+				if err := handle(tx); err != nil {
+					return 0, 0, 0, err
+				}
+				blockNumber = tx.BlockNumber
+				seen[hash] = blockNumber
+				unseen++
+			}
+			if blockNumber < lowestBlock {
+				lowestBlock = blockNumber
+			}
+		}
+		return len(hashes), unseen, lowestBlock, nil
+	})
+}
+
+func (r *RPCAPIs) walkEvents(address types.Address, blocks blockRange, handle func(*types.Event) error) error {
+	seen := make(map[string]bool)
+
+	return walkBackwards(blocks, func(options *types.QueryOptions) (int, int, uint64, error) {
+		events, err := r.db.GetAllEventsFromAddress(address, options)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+
+		unseen := 0
+		lowestBlock := uint64(math.MaxUint64)
+		for _, event := range events {
+			id := fmt.Sprintf("%s-%d", event.TransactionHash.String(), event.Index)
+			if !seen[id] {
+				if err := handle(event); err != nil {
+					return 0, 0, 0, err
+				}
+				seen[id] = true
+				unseen++
+			}
+			if event.BlockNumber < lowestBlock {
+				lowestBlock = event.BlockNumber
+			}
+		}
+		return len(events), unseen, lowestBlock, nil
+	})
+}
+
+// walkBackwards pages backwards through indexed data in the given range of blocks. Results can only be paged through so
+// deep, so each new page ends at the lowest block seen on the previous page instead. The page function returns how
+// many results it found, how many of those had not been seen before and the lowest block of all the results.
+func walkBackwards(blocks blockRange, page func(*types.QueryOptions) (int, int, uint64, error)) error {
+	options := &types.QueryOptions{
+		BeginBlockNumber: new(big.Int).SetUint64(blocks.begin),
+		EndBlockNumber:   new(big.Int).SetUint64(blocks.end),
+		PageSize:         mappingKeySourcePageSize,
+	}
+	options.SetDefaults()
+
+	for {
+		results, unseen, lowestBlock, err := page(options)
+		if err != nil {
+			return err
+		}
+		if results < options.PageSize {
+			return nil
+		}
+
+		currentEnd := options.EndBlockNumber
+		if lowestBlock > currentEnd.Uint64() {
+			// the results were not limited to the block range, so there is nothing more to fetch
+			return nil
+		}
+
+		// if nothing new was found, the lowest block is full of results we have already seen, so move past it
+		nextEnd := new(big.Int).SetUint64(lowestBlock)
+		if unseen == 0 {
+			nextEnd.Sub(nextEnd, big.NewInt(1))
+		}
+		if nextEnd.Cmp(currentEnd) >= 0 {
+			nextEnd.Sub(currentEnd, big.NewInt(1))
+		}
+		if nextEnd.Cmp(options.BeginBlockNumber) < 0 {
+			return nil
+		}
+		options.EndBlockNumber = nextEnd
+	}
+}
+
+// collectTokenKeys adds any ERC20/ERC721 holders, and ERC721 token IDs, that the contract has at the given block
+func (r *RPCAPIs) collectTokenKeys(address types.Address, block uint64, keys *storageparsing.MappingKeys) error {
+	fetchHolders := []func(types.Address, uint64, *types.TokenQueryOptions) ([]types.Address, error){
+		r.db.GetAllTokenHolders,
+		r.db.AllHoldersAtBlock,
+	}
+	for _, fetch := range fetchHolders {
+		options := &types.TokenQueryOptions{PageSize: mappingKeySourcePageSize}
+		options.SetDefaults()
+		for {
+			holders, err := fetch(address, block, options)
+			if err == database.ErrNotImplemented {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			for _, holder := range holders {
+				keys.AddAddress(holder)
+			}
+			if len(holders) < options.PageSize {
+				break
+			}
+			options.After = holders[len(holders)-1].String()
+		}
+	}
+
+	options := &types.TokenQueryOptions{PageSize: mappingKeySourcePageSize}
+	options.SetDefaults()
+	for {
+		tokens, err := r.db.AllERC721TokensAtBlock(address, block, options)
+		if err == database.ErrNotImplemented {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		for _, token := range tokens {
+			if tokenID, ok := new(big.Int).SetString(token.Token, 10); ok {
+				keys.AddInteger(tokenID)
+			}
+		}
+		if len(tokens) < options.PageSize {
+			break
+		}
+		options.After = tokens[len(tokens)-1].Token
+	}
+	return nil
+}
+
+// mappingKeyCollector adds candidate mapping keys from the transactions and events of a single contract,
+// decoding each with the contract ABI that was valid at its block
+type mappingKeyCollector struct {
+	address       types.Address
+	contractABIAt func(blockNumber uint64) (*types.ContractABI, error)
+	keys          *storageparsing.MappingKeys
+}
+
+func (c *mappingKeyCollector) addTransaction(tx *types.Transaction) error {
+	contractABI, err := c.contractABIAt(tx.BlockNumber)
+	if err != nil {
+		return err
+	}
+
+	if tx.To == c.address || tx.CreatedContract == c.address {
+		c.addAddress(tx.From)
+	}
+	if tx.To == c.address {
+		data := tx.Data
+		if len(tx.PrivateData) > 0 {
+			data = tx.PrivateData
+		}
+		c.addCallData(contractABI, data.AsBytes())
+	}
+	for _, call := range tx.InternalCalls {
+		if call.To == c.address {
+			c.addAddress(call.From)
+			c.addCallData(contractABI, call.Input.AsBytes())
+		}
+	}
+	return nil
+}
+
+// addAddress adds an address, if it has been set. The zero address is kept, as
+// it is a valid key, e.g. for a token balance that has been burned
+func (c *mappingKeyCollector) addAddress(address types.Address) {
+	if address != "" {
+		c.keys.AddAddress(address)
+	}
+}
+
+func (c *mappingKeyCollector) addCallData(contractABI *types.ContractABI, data []byte) {
+	if contractABI == nil || len(data) < 4 {
+		return
+	}
+	selector := hex.EncodeToString(data[:4])
+	for _, function := range contractABI.Functions {
+		if function.Signature() == selector {
+			if parsed, err := function.Parse(data[4:]); err == nil {
+				c.addValue(parsed)
+			}
+			return
+		}
+	}
+}
+
+func (c *mappingKeyCollector) addEvent(event *types.Event) error {
+	contractABI, err := c.contractABIAt(event.BlockNumber)
+	if err != nil {
+		return err
+	}
+	if contractABI == nil || len(event.Topics) == 0 {
+		return nil
+	}
+	for _, abiEvent := range contractABI.Events {
+		if "0x"+abiEvent.Signature() != event.Topics[0].String() {
+			continue
+		}
+		if parsed, err := abiEvent.Parse(event.Data.AsBytes()); err == nil {
+			c.addValue(parsed)
+		}
+
+		// indexed arguments are in the topics, but only static types can be
+		// recovered, since dynamic types are stored as their hash
+		nextTopic := 1
+		for _, arg := range abiEvent.Inputs {
+			if !arg.Indexed {
+				continue
+			}
+			if nextTopic >= len(event.Topics) {
+				break
+			}
+			topic, _ := hex.DecodeString(string(event.Topics[nextTopic]))
+			nextTopic++
+			if arg.IsDynamic() {
+				continue
+			}
+			if parsed, _, err := types.ParseStaticType(arg.ContractABIArgument, topic, 0); err == nil {
+				c.addValue(parsed)
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// addValue adds any addresses or integers within a decoded value, including
+// those nested in arrays and tuples
+func (c *mappingKeyCollector) addValue(value interface{}) {
+	switch v := value.(type) {
+	case *big.Int:
+		c.keys.AddInteger(v)
+	case string:
+		if decoded, err := hex.DecodeString(strings.TrimPrefix(v, "0x")); err == nil && strings.HasPrefix(v, "0x") && len(decoded) == 20 {
+			c.addAddress(types.NewAddress(v))
+		}
+	case []interface{}:
+		for _, element := range v {
+			c.addValue(element)
+		}
+	case map[string]interface{}:
+		for _, element := range v {
+			c.addValue(element)
+		}
+	}
+}
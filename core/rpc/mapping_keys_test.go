@@ -0,0 +1,176 @@
+package rpc
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"quorumengineering/quorum-report/core/storageparsing"
+	"quorumengineering/quorum-report/database/memory"
+	"quorumengineering/quorum-report/types"
+)
+
+const transferABI = `
+[
+	{"anonymous":false,"inputs":[{"indexed":true,"name":"from","type":"address"},{"indexed":true,"name":"to","type":"address"},{"indexed":false,"name":"value","type":"uint256"}],"name":"Transfer","type":"event"}
+]`
+
+func TestDiscoverMappingKeys(t *testing.T) {
+	db := memory.NewMemoryDB()
+	apis := NewRPCAPIs(db, nil, NewDefaultContractManager(db))
+	err := apis.AddAddress(dummyReq, &AddressWithOptionalBlock{Address: &addr}, nil)
+	assert.Nil(t, err)
+	err = apis.AddABI(dummyReq, &AddressWithData{&addr, validABI}, nil)
+	assert.Nil(t, err)
+
+	err = db.WriteTransactions([]*types.Transaction{tx1, tx2, tx3})
+	assert.Nil(t, err)
+	err = db.WriteBlocks([]*types.Block{block})
+	assert.Nil(t, err)
+	err = db.IndexBlocks([]types.Address{addr}, []*types.Block{block})
+	assert.Nil(t, err)
+
+	keys, err := apis.discoverMappingKeys(addr, 0, 1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []types.Address{tx1.From}, keys.Addresses())
+	assert.Equal(t, []*big.Int{big.NewInt(999), big.NewInt(1000)}, keys.Integers())
+}
+
+func TestGetMappingKeys_Cached(t *testing.T) {
+	db := memory.NewMemoryDB()
+	apis := NewRPCAPIs(db, nil, NewDefaultContractManager(db))
+	err := apis.AddAddress(dummyReq, &AddressWithOptionalBlock{Address: &addr}, nil)
+	assert.Nil(t, err)
+	err = apis.AddABI(dummyReq, &AddressWithData{&addr, validABI}, nil)
+	assert.Nil(t, err)
+
+	err = db.WriteTransactions([]*types.Transaction{tx1, tx2, tx3})
+	assert.Nil(t, err)
+	err = db.IndexBlocks([]types.Address{addr}, []*types.Block{block})
+	assert.Nil(t, err)
+
+	layout := types.SolidityStorageDocument{
+		Types: map[string]types.SolidityTypeEntry{"t_mapping(t_uint256,t_uint256)": {}},
+	}
+	keys, err := apis.getMappingKeys(addr, layout, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []*big.Int{big.NewInt(999), big.NewInt(1000)}, keys.Integers())
+
+	// nothing has been indexed since, so the same keys are used
+	cached, err := apis.getMappingKeys(addr, layout, nil)
+	assert.Nil(t, err)
+	assert.True(t, keys == cached)
+
+	// keys from newly indexed blocks are added to those already found
+	laterTx := &types.Transaction{
+		Hash:        types.NewHash("0x0000000000000000000000000000000000000000000000000000000000000a02"),
+		BlockNumber: 2,
+		From:        types.NewAddress("0x0000000000000000000000000000000000000009"),
+		To:          addr,
+		Data:        types.NewHexData("0x60fe47b10000000000000000000000000000000000000000000000000000000000000007"),
+	}
+	err = db.WriteTransactions([]*types.Transaction{laterTx})
+	assert.Nil(t, err)
+	err = db.IndexBlocks([]types.Address{addr}, []*types.Block{{Number: 2, Transactions: []types.Hash{laterTx.Hash}}})
+	assert.Nil(t, err)
+
+	keys, err = apis.getMappingKeys(addr, layout, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []*big.Int{big.NewInt(7), big.NewInt(999), big.NewInt(1000)}, keys.Integers())
+	assert.Equal(t, []*big.Int{big.NewInt(999), big.NewInt(1000)}, cached.Integers())
+}
+
+func TestMappingKeyCollector_IndexedEventArguments(t *testing.T) {
+	structure, err := types.NewABIStructureFromJSON(transferABI)
+	assert.Nil(t, err)
+
+	collector := &mappingKeyCollector{
+		address:       addr,
+		contractABIAt: func(uint64) (*types.ContractABI, error) { return structure.ToInternalABI(), nil },
+		keys:          storageparsing.NewMappingKeys(),
+	}
+	collector.addEvent(&types.Event{
+		Address: addr,
+		Topics: []types.Hash{
+			types.NewHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"),
+			types.NewHash("0x0000000000000000000000000000000000000000000000000000000000000011"),
+			types.NewHash("0x0000000000000000000000000000000000000000000000000000000000000022"),
+		},
+		Data: types.NewHexData("0x0000000000000000000000000000000000000000000000000000000000000064"),
+	})
+
+	assert.Equal(t, []types.Address{
+		types.NewAddress("0x0000000000000000000000000000000000000011"),
+		types.NewAddress("0x0000000000000000000000000000000000000022"),
+	}, collector.keys.Addresses())
+	assert.Equal(t, []*big.Int{big.NewInt(100)}, collector.keys.Integers())
+}
+
+func TestMappingKeyCollector_InternalCalls(t *testing.T) {
+	structure, err := types.NewABIStructureFromJSON(validABI)
+	assert.Nil(t, err)
+
+	collector := &mappingKeyCollector{
+		address:       addr,
+		contractABIAt: func(uint64) (*types.ContractABI, error) { return structure.ToInternalABI(), nil },
+		keys:          storageparsing.NewMappingKeys(),
+	}
+	collector.addTransaction(&types.Transaction{
+		From: types.NewAddress("0x0000000000000000000000000000000000000009"),
+		To:   types.NewAddress("0x0000000000000000000000000000000000000005"),
+		InternalCalls: []*types.InternalCall{
+			{
+				From:  types.NewAddress("0x0000000000000000000000000000000000000005"),
+				To:    addr,
+				Input: types.NewHexData("0x60fe47b100000000000000000000000000000000000000000000000000000000000003e7"),
+			},
+		},
+	})
+
+	assert.Equal(t, []types.Address{types.NewAddress("0x0000000000000000000000000000000000000005")}, collector.keys.Addresses())
+	assert.Equal(t, []*big.Int{big.NewInt(999)}, collector.keys.Integers())
+}
+
+func TestWalkBackwards(t *testing.T) {
+	// 2500 results, spread over blocks 100 down to 1, 25 results per block
+	page := func(requestedEnds *[]int64) func(*types.QueryOptions) (int, int, uint64, error) {
+		return func(options *types.QueryOptions) (int, int, uint64, error) {
+			*requestedEnds = append(*requestedEnds, options.EndBlockNumber.Int64())
+
+			end := options.EndBlockNumber.Int64()
+			remaining := int(end * 25)
+			if remaining > mappingKeySourcePageSize {
+				return mappingKeySourcePageSize, mappingKeySourcePageSize, uint64(end - 39), nil
+			}
+			return remaining, remaining, 1, nil
+		}
+	}
+
+	var requestedEnds []int64
+	err := walkBackwards(blockRange{begin: 0, end: 100}, page(&requestedEnds))
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{100, 61, 22}, requestedEnds)
+
+	// blocks before the start of the range are not fetched
+	requestedEnds = nil
+	err = walkBackwards(blockRange{begin: 50, end: 100}, page(&requestedEnds))
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{100, 61}, requestedEnds)
+}
+
+func TestWalkBackwards_UnboundedResults(t *testing.T) {
+	// results that ignore the block range must not be fetched forever
+	calls := 0
+	err := walkBackwards(blockRange{begin: 0, end: 20}, func(options *types.QueryOptions) (int, int, uint64, error) {
+		calls++
+		if calls == 1 {
+			return mappingKeySourcePageSize, mappingKeySourcePageSize, 10, nil
+		}
+		return mappingKeySourcePageSize, 0, 10, nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, calls)
+}
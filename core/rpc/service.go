@@ -11,6 +11,7 @@ import (
 	"github.com/gorilla/rpc/v2/json"
 	"github.com/rs/cors"
 
+	"quorumengineering/quorum-report/client"
 	"quorumengineering/quorum-report/database"
 	"quorumengineering/quorum-report/log"
 	"quorumengineering/quorum-report/types"
@@ -23,9 +24,11 @@ const (
 )
 
 type RPCService struct {
-	cors        []string
-	httpAddress string
-	db          database.Database
+	cors          []string
+	httpAddress   string
+	signatureFile string
+	db            database.Database
+	quorumClient  client.Client
 
 	httpServer *http.Server
 
@@ -33,11 +36,13 @@ type RPCService struct {
 	shutdownWg             sync.WaitGroup
 }
 
-func NewRPCService(db database.Database, config types.ReportingConfig, backendErrorChan chan error) *RPCService {
+func NewRPCService(db database.Database, quorumClient client.Client, config types.ReportingConfig, backendErrorChan chan error) *RPCService {
 	return &RPCService{
-		cors:        config.Server.RPCCorsList,
-		httpAddress: config.Server.RPCAddr,
-		db:          db,
+		cors:          config.Server.RPCCorsList,
+		httpAddress:   config.Server.RPCAddr,
+		signatureFile: config.SignatureFile,
+		db:            db,
+		quorumClient:  quorumClient,
 
 		httpServerErrorChannel: backendErrorChan,
 	}
@@ -48,10 +53,17 @@ func (r *RPCService) Start() error {
 
 	jsonrpcServer := rpc.NewServer()
 	jsonrpcServer.RegisterCodec(json.NewCodec(), "application/json")
-	if err := jsonrpcServer.RegisterService(NewRPCAPIs(r.db, NewDefaultContractManager(r.db)), "reporting"); err != nil {
+	reportingAPIs := NewRPCAPIs(r.db, r.quorumClient, NewDefaultContractManager(r.db))
+	if r.signatureFile != "" {
+		log.Info("Loading signature file", "path", r.signatureFile)
+		if err := reportingAPIs.SeedSignatures(r.signatureFile); err != nil {
+			return err
+		}
+	}
+	if err := jsonrpcServer.RegisterService(reportingAPIs, "reporting"); err != nil {
 		return err
 	}
-	if err := jsonrpcServer.RegisterService(NewTokenRPCAPIs(r.db), "token"); err != nil {
+	if err := jsonrpcServer.RegisterService(NewTokenRPCAPIs(r.db, r.quorumClient), "token"); err != nil {
 		return err
 	}
 
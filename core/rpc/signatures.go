@@ -0,0 +1,89 @@
+package rpc
+
+import (
+	"io/ioutil"
+	"sync"
+
+	"quorumengineering/quorum-report/database"
+	"quorumengineering/quorum-report/log"
+	"quorumengineering/quorum-report/types"
+)
+
+// signatureRegistry builds the registry of known function and event signatures from
+// every version of every template, along with any ABIs it was seeded with. The
+// registry is built when first needed, and rebuilt after templates are added.
+type signatureRegistry struct {
+	db   database.Database
+	seed []*types.ContractABI
+
+	mu       sync.Mutex
+	registry *types.SignatureRegistry
+}
+
+func newSignatureRegistry(db database.Database) *signatureRegistry {
+	return &signatureRegistry{db: db}
+}
+
+// seedFromFile adds the ABI entries in the given JSON file to the registry, in
+// the same format as a contract ABI
+func (sr *signatureRegistry) seedFromFile(path string) error {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	structure, err := types.NewABIStructureFromJSON(string(contents))
+	if err != nil {
+		return err
+	}
+
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	sr.seed = append(sr.seed, structure.ToInternalABI())
+	sr.registry = nil
+	return nil
+}
+
+// invalidate causes the registry to be rebuilt the next time it is used, so
+// that it includes templates added since
+func (sr *signatureRegistry) invalidate() {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	sr.registry = nil
+}
+
+func (sr *signatureRegistry) get() (*types.SignatureRegistry, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	if sr.registry != nil {
+		return sr.registry, nil
+	}
+
+	registry := types.NewSignatureRegistry()
+	for _, contractABI := range sr.seed {
+		registry.AddABI(contractABI)
+	}
+	templateNames, err := sr.db.GetTemplates()
+	if err != nil && err != database.ErrNotFound {
+		return nil, err
+	}
+	for _, templateName := range templateNames {
+		latest, err := sr.db.GetTemplateDetails(templateName)
+		if err != nil {
+			return nil, err
+		}
+		for version := uint64(1); version <= latest.Version; version++ {
+			template := latest
+			if version < latest.Version {
+				if template, err = sr.db.GetTemplateVersion(templateName, version); err != nil {
+					return nil, err
+				}
+			}
+			if err := registry.AddRawABI(template.ABI); err != nil {
+				// a bad ABI in one template should not stop the rest from being used
+				log.Warn("Unable to add template ABI to signature registry", "template", templateName, "version", version, "err", err)
+			}
+		}
+	}
+	sr.registry = registry
+	return registry, nil
+}
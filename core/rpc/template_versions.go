@@ -0,0 +1,166 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+
+	"quorumengineering/quorum-report/database"
+	"quorumengineering/quorum-report/types"
+)
+
+type templateVersionKey struct {
+	name    string
+	version uint64
+}
+
+// contractTemplates finds the template version that was assigned to a contract
+// at a given block, so that each record is decoded with the ABI and storage
+// layout that were valid when it was created. For proxies, the template of the
+// implementation that was live at the block is used, if it has one. Template
+// versions are cached, as most records will share the same one.
+type contractTemplates struct {
+	db          database.Database
+	assignments []*types.TemplateAssignment
+
+	implementations           []*types.ProxyImplementation
+	implementationAssignments map[types.Address][]*types.TemplateAssignment
+
+	templates map[templateVersionKey]*types.Template
+	abis      map[templateVersionKey]*types.ContractABI
+	layouts   map[templateVersionKey]types.SolidityStorageDocument
+}
+
+func (r *RPCAPIs) getContractTemplates(address types.Address) (*contractTemplates, error) {
+	assignments, err := r.db.GetTemplateAssignments(address)
+	if err != nil && err != database.ErrNotFound {
+		return nil, err
+	}
+	implementations, err := r.db.GetImplementationHistory(address)
+	if err != nil && err != database.ErrNotFound {
+		return nil, err
+	}
+	return &contractTemplates{
+		db:                        r.db,
+		assignments:               assignments,
+		implementations:           implementations,
+		implementationAssignments: make(map[types.Address][]*types.TemplateAssignment),
+		templates:                 make(map[templateVersionKey]*types.Template),
+		abis:                      make(map[templateVersionKey]*types.ContractABI),
+		layouts:                   make(map[templateVersionKey]types.SolidityStorageDocument),
+	}, nil
+}
+
+// templateAt returns the template version assigned at the given block, or
+// nil if there was no template assigned
+func (ct *contractTemplates) templateAt(blockNumber uint64) (*types.Template, error) {
+	assignment, err := ct.implementationAssignmentAt(blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if assignment == nil {
+		assignment = assignmentAt(ct.assignments, blockNumber)
+	}
+	if assignment == nil {
+		return nil, nil
+	}
+
+	key := templateVersionKey{assignment.TemplateName, assignment.Version}
+	if template, ok := ct.templates[key]; ok {
+		return template, nil
+	}
+	template, err := ct.db.GetTemplateVersion(assignment.TemplateName, assignment.Version)
+	if err != nil && err != database.ErrNotFound {
+		return nil, err
+	}
+	ct.templates[key] = template
+	return template, nil
+}
+
+// implementationAssignmentAt returns the template assignment of the proxy implementation
+// that was live at the given block, or nil if there is none
+func (ct *contractTemplates) implementationAssignmentAt(blockNumber uint64) (*types.TemplateAssignment, error) {
+	var implementation *types.ProxyImplementation
+	for _, candidate := range ct.implementations {
+		if candidate.FromBlock > blockNumber {
+			break
+		}
+		implementation = candidate
+	}
+	if implementation == nil {
+		return nil, nil
+	}
+
+	assignments, ok := ct.implementationAssignments[implementation.Implementation]
+	if !ok {
+		var err error
+		assignments, err = ct.db.GetTemplateAssignments(implementation.Implementation)
+		if err != nil && err != database.ErrNotFound {
+			return nil, err
+		}
+		ct.implementationAssignments[implementation.Implementation] = assignments
+	}
+	return assignmentAt(assignments, blockNumber), nil
+}
+
+// assignmentAt returns the assignment in use at the given block, from a list ordered by block
+func assignmentAt(assignments []*types.TemplateAssignment, blockNumber uint64) *types.TemplateAssignment {
+	var assignment *types.TemplateAssignment
+	for _, candidate := range assignments {
+		if candidate.FromBlock > blockNumber {
+			break
+		}
+		assignment = candidate
+	}
+	return assignment
+}
+
+// abiAt returns the contract ABI valid at the given block, or an empty string if there is none
+func (ct *contractTemplates) abiAt(blockNumber uint64) (string, error) {
+	template, err := ct.templateAt(blockNumber)
+	if err != nil || template == nil {
+		return "", err
+	}
+	return template.ABI, nil
+}
+
+// internalABIAt returns the parsed contract ABI valid at the given block, or nil if there is none
+func (ct *contractTemplates) internalABIAt(blockNumber uint64) (*types.ContractABI, error) {
+	template, err := ct.templateAt(blockNumber)
+	if err != nil || template == nil || template.ABI == "" {
+		return nil, err
+	}
+
+	key := templateVersionKey{template.TemplateName, template.Version}
+	if contractABI, ok := ct.abis[key]; ok {
+		return contractABI, nil
+	}
+	structure, err := types.NewABIStructureFromJSON(template.ABI)
+	if err != nil {
+		return nil, err
+	}
+	ct.abis[key] = structure.ToInternalABI()
+	return ct.abis[key], nil
+}
+
+// storageLayoutAt returns the decoded storage layout valid at the given block
+func (ct *contractTemplates) storageLayoutAt(blockNumber uint64) (types.SolidityStorageDocument, error) {
+	var parsedAbi types.SolidityStorageDocument
+
+	template, err := ct.templateAt(blockNumber)
+	if err != nil {
+		return parsedAbi, err
+	}
+	if template == nil || template.StorageLayout == "" {
+		return parsedAbi, errors.New("no Storage Layout present to parse with")
+	}
+
+	key := templateVersionKey{template.TemplateName, template.Version}
+	if layout, ok := ct.layouts[key]; ok {
+		return layout, nil
+	}
+	if err = json.Unmarshal([]byte(template.StorageLayout), &parsedAbi); err != nil {
+		return parsedAbi, errors.New("unable to decode Storage Layout: " + err.Error())
+	}
+	ct.layouts[key] = parsedAbi
+	return parsedAbi, nil
+}
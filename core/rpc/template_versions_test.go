@@ -0,0 +1,163 @@
+package rpc
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"quorumengineering/quorum-report/database/memory"
+	"quorumengineering/quorum-report/types"
+)
+
+// the same as validABI, but with the argument of set and the event renamed
+const upgradedABI = `
+[
+	{"constant":false,"inputs":[{"name":"newValue","type":"uint256"}],"name":"set","outputs":[],"payable":false,"stateMutability":"nonpayable","type":"function"},
+	{"anonymous":false,"inputs":[{"indexed":false,"name":"newValue","type":"uint256"}],"name":"valueSet","type":"event"}
+]`
+
+func setupTemplateVersionsTest(t *testing.T) (*RPCAPIs, *types.Transaction) {
+	db := memory.NewMemoryDB()
+	apis := NewRPCAPIs(db, nil, NewDefaultContractManager(db))
+
+	err := apis.AddAddress(dummyReq, &AddressWithOptionalBlock{Address: &addr}, nil)
+	assert.Nil(t, err)
+	err = apis.AddTemplate(dummyReq, &TemplateArgs{Name: "storage", Abi: validABI, StorageLayout: "{}"}, nil)
+	assert.Nil(t, err)
+	err = apis.AssignTemplate(dummyReq, &AddressWithData{&addr, "storage"}, nil)
+	assert.Nil(t, err)
+	err = apis.AssignTemplateFromBlock(dummyReq, &TemplateAssignmentArgs{Address: &addr, Name: "storage", FromBlock: 0}, nil)
+	assert.Nil(t, err)
+
+	// upgrade the template, and use the new version from block 5
+	err = apis.AddTemplate(dummyReq, &TemplateArgs{Name: "storage", Abi: upgradedABI, StorageLayout: "{}"}, nil)
+	assert.Nil(t, err)
+	err = apis.AssignTemplateFromBlock(dummyReq, &TemplateAssignmentArgs{Address: &addr, Name: "storage", FromBlock: 5}, nil)
+	assert.Nil(t, err)
+
+	upgradedTx := &types.Transaction{
+		Hash:        types.NewHash("0x7ddb1a0bdfcee1f0e5e1cc6af94ea5e1ee3e1c3a1bb7e2d8a0e8c1f7c6e7e9b1"),
+		BlockNumber: 5,
+		From:        types.NewAddress("0x0000000000000000000000000000000000000009"),
+		To:          addr,
+		Data:        types.NewHexData("0x60fe47b10000000000000000000000000000000000000000000000000000000000000007"),
+	}
+	err = apis.db.WriteTransactions([]*types.Transaction{tx1, tx2, tx3, upgradedTx})
+	assert.Nil(t, err)
+
+	return apis, upgradedTx
+}
+
+func TestGetTransaction_UsesTemplateVersionAtBlock(t *testing.T) {
+	apis, upgradedTx := setupTemplateVersionsTest(t)
+
+	parsedTx := &types.ParsedTransaction{}
+	err := apis.GetTransaction(dummyReq, &tx2.Hash, parsedTx)
+	assert.Nil(t, err)
+	assert.Equal(t, "set(uint256 _x)", parsedTx.Sig)
+	assert.Equal(t, big.NewInt(999), parsedTx.ParsedData["_x"])
+
+	parsedTx = &types.ParsedTransaction{}
+	err = apis.GetTransaction(dummyReq, &upgradedTx.Hash, parsedTx)
+	assert.Nil(t, err)
+	assert.Equal(t, "set(uint256 newValue)", parsedTx.Sig)
+	assert.Equal(t, big.NewInt(7), parsedTx.ParsedData["newValue"])
+}
+
+func TestGetAllEventsFromAddress_UsesTemplateVersionAtBlock(t *testing.T) {
+	apis, _ := setupTemplateVersionsTest(t)
+
+	upgradedEvent := &types.Event{
+		Index:           1,
+		Address:         addr,
+		BlockNumber:     6,
+		Data:            types.NewHexData("0x0000000000000000000000000000000000000000000000000000000000000007"),
+		Topics:          []types.Hash{types.NewHash("0xefe5cb8d23d632b5d2cdd9f0a151c4b1a84ccb7afa1c57331009aa922d5e4f36")},
+		TransactionHash: types.NewHash("0x7ddb1a0bdfcee1f0e5e1cc6af94ea5e1ee3e1c3a1bb7e2d8a0e8c1f7c6e7e9b1"),
+	}
+	upgradedBlock := &types.Block{
+		Number:       6,
+		Transactions: []types.Hash{upgradedEvent.TransactionHash},
+	}
+	err := apis.db.IndexBlocks([]types.Address{addr}, []*types.Block{block})
+	assert.Nil(t, err)
+	err = apis.db.WriteTransactions([]*types.Transaction{{
+		Hash:        upgradedEvent.TransactionHash,
+		BlockNumber: 6,
+		To:          addr,
+		Events:      []*types.Event{upgradedEvent},
+	}})
+	assert.Nil(t, err)
+	err = apis.db.IndexBlocks([]types.Address{addr}, []*types.Block{upgradedBlock})
+	assert.Nil(t, err)
+
+	eventsResp := &EventsResp{}
+	err = apis.GetAllEventsFromAddress(dummyReq, &AddressWithOptions{Address: &addr}, eventsResp)
+	assert.Nil(t, err)
+	assert.Len(t, eventsResp.Events, 2)
+	for _, event := range eventsResp.Events {
+		if event.RawEvent.BlockNumber == 6 {
+			assert.Equal(t, big.NewInt(7), event.ParsedData["newValue"])
+		} else {
+			assert.Equal(t, big.NewInt(1000), event.ParsedData["_value"])
+		}
+	}
+}
+
+func TestAssignTemplateFromBlock(t *testing.T) {
+	apis, _ := setupTemplateVersionsTest(t)
+
+	var assignments []*types.TemplateAssignment
+	err := apis.GetTemplateAssignments(dummyReq, &addr, &assignments)
+	assert.Nil(t, err)
+	assert.Equal(t, []*types.TemplateAssignment{
+		{TemplateName: "storage", Version: 1, FromBlock: 0},
+		{TemplateName: "storage", Version: 2, FromBlock: 5},
+	}, assignments)
+
+	var template types.Template
+	err = apis.GetTemplateVersion(dummyReq, &TemplateVersionArgs{Name: "storage", Version: 1}, &template)
+	assert.Nil(t, err)
+	assert.Equal(t, validABI, template.ABI)
+
+	err = apis.AssignTemplateFromBlock(dummyReq, &TemplateAssignmentArgs{Address: &addr, Name: "storage", Version: 3}, nil)
+	assert.EqualError(t, err, "template version not found")
+
+	err = apis.AssignTemplateFromBlock(dummyReq, &TemplateAssignmentArgs{Address: &addr}, nil)
+	assert.EqualError(t, err, "no template name provided")
+}
+
+func TestGetTransaction_UsesProxyImplementationTemplate(t *testing.T) {
+	apis, upgradedTx := setupTemplateVersionsTest(t)
+
+	// addr is a proxy to an implementation with the upgraded template from block 1,
+	// and to an implementation without a template from block 5
+	implementation := types.NewAddress("0x0000000000000000000000000000000000000011")
+	err := apis.AddTemplate(dummyReq, &TemplateArgs{Name: "implementation", Abi: upgradedABI, StorageLayout: "{}"}, nil)
+	assert.Nil(t, err)
+	err = apis.AssignTemplate(dummyReq, &AddressWithData{&implementation, "implementation"}, nil)
+	assert.Nil(t, err)
+	err = apis.db.RecordImplementation(addr, &types.ProxyImplementation{Implementation: implementation, FromBlock: 1})
+	assert.Nil(t, err)
+	err = apis.db.RecordImplementation(addr, &types.ProxyImplementation{Implementation: types.NewAddress("0x12"), FromBlock: 5})
+	assert.Nil(t, err)
+
+	var history []*types.ProxyImplementation
+	err = apis.GetImplementationHistory(dummyReq, &addr, &history)
+	assert.Nil(t, err)
+	assert.Len(t, history, 2)
+	assert.Equal(t, implementation, history[0].Implementation)
+
+	parsedTx := &types.ParsedTransaction{}
+	err = apis.GetTransaction(dummyReq, &tx2.Hash, parsedTx)
+	assert.Nil(t, err)
+	assert.Equal(t, "set(uint256 newValue)", parsedTx.Sig)
+	assert.Equal(t, big.NewInt(999), parsedTx.ParsedData["newValue"])
+
+	// the implementation has no template, so the proxy's own template is used
+	parsedTx = &types.ParsedTransaction{}
+	err = apis.GetTransaction(dummyReq, &upgradedTx.Hash, parsedTx)
+	assert.Nil(t, err)
+	assert.Equal(t, "set(uint256 newValue)", parsedTx.Sig)
+}
@@ -0,0 +1,345 @@
+package rpc
+
+import (
+	"errors"
+	"math/big"
+	"net/http"
+	"sort"
+
+	"quorumengineering/quorum-report/types"
+)
+
+// maxHolderBuckets is the most buckets the holders of a token are counted over in one request
+const maxHolderBuckets = 1000
+
+// GetERC20HolderDistribution measures how concentrated the holders of a token are at a block, giving
+// the largest holders up to the page size of the options
+func (r *TokenRPCAPIs) GetERC20HolderDistribution(req *http.Request, query *ERC20TokenQuery, reply *ERC20HolderDistribution) error {
+	if query.Contract == nil {
+		return errors.New("no token contract provided")
+	}
+	if query.Block == 0 {
+		return errors.New("no block given")
+	}
+	if query.Options == nil {
+		query.Options = &types.TokenQueryOptions{}
+	}
+	query.Options.SetDefaults()
+	decimals, err := r.scaleBy(*query.Contract, query.Scaled)
+	if err != nil {
+		return err
+	}
+
+	block := new(big.Int).SetUint64(query.Block)
+	balances, err := r.db.GetERC20BalancesOfToken(*query.Contract, &types.TokenQueryOptions{BeginBlockNumber: block, EndBlockNumber: block})
+	if err != nil {
+		return err
+	}
+
+	amounts := make([]*big.Int, 0, len(balances))
+	holders := make([]types.ERC20HolderBalance, 0, len(balances))
+	for _, balance := range balances {
+		amount, ok := new(big.Int).SetString(balance.Amount, 10)
+		if !ok || amount.Sign() <= 0 || balance.Holder.IsEmpty() {
+			continue
+		}
+		amounts = append(amounts, amount)
+		holders = append(holders, types.ERC20HolderBalance{Holder: balance.Holder, Amount: balance.Amount, HeldFrom: balance.HeldFrom})
+	}
+	// order by amount, largest first, then by holder, as with token.getERC20BalancesAtBlock
+	order := make([]int, len(holders))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		if cmp := amounts[order[i]].Cmp(amounts[order[j]]); cmp != 0 {
+			return cmp > 0
+		}
+		return holders[order[i]].Holder < holders[order[j]].Holder
+	})
+
+	total := new(big.Int)
+	for _, amount := range amounts {
+		total.Add(total, amount)
+	}
+
+	distribution := ERC20HolderDistribution{
+		Contract:   *query.Contract,
+		Block:      query.Block,
+		Holders:    len(holders),
+		Total:      total.String(),
+		TopHolders: make([]*ERC20HolderShare, 0, query.Options.PageSize),
+	}
+	top10 := new(big.Int)
+	for rank, i := range order {
+		if rank < 10 {
+			top10.Add(top10, amounts[i])
+		}
+		if rank < query.Options.PageSize {
+			share := &ERC20HolderShare{ERC20HolderBalance: holders[i], Percentage: 100 * ratio(amounts[i], total)}
+			if decimals != nil {
+				share.ScaledAmount = scaleAmount(share.Amount, *decimals)
+			}
+			distribution.TopHolders = append(distribution.TopHolders, share)
+		}
+	}
+	if decimals != nil {
+		distribution.ScaledTotal = scaleAmount(distribution.Total, *decimals)
+	}
+	distribution.Top10Percentage = 100 * ratio(top10, total)
+	distribution.Gini = gini(amounts)
+	distribution.Herfindahl = herfindahl(amounts)
+
+	*reply = distribution
+	return nil
+}
+
+// GetERC20HolderHistory counts the holders of a token at the end of each bucket of blocks or time within
+// the block range of the options, along with how many holders started and stopped holding it
+func (r *TokenRPCAPIs) GetERC20HolderHistory(req *http.Request, query *ERC20HolderHistoryQuery, reply *[]*ERC20HolderBucket) error {
+	if query.Contract == nil {
+		return errors.New("no token contract provided")
+	}
+	if query.Interval == 0 && query.TimeInterval == 0 {
+		return errors.New("no interval given")
+	}
+	if query.Interval != 0 && query.TimeInterval != 0 {
+		return errors.New("only one of interval and time interval can be given")
+	}
+	if query.Options == nil {
+		query.Options = &types.TokenQueryOptions{}
+	}
+	query.Options.SetDefaults()
+	if query.Options.EndBlockNumber.Sign() < 0 {
+		return errors.New("no end block given")
+	}
+	if query.Options.BeginBlockNumber.Sign() < 0 || query.Options.BeginBlockNumber.Cmp(query.Options.EndBlockNumber) > 0 {
+		return errors.New("begin block must be between 0 and the end block")
+	}
+	begin, end := query.Options.BeginBlockNumber.Uint64(), query.Options.EndBlockNumber.Uint64()
+
+	var buckets []*ERC20HolderBucket
+	if query.TimeInterval != 0 {
+		timeBuckets, err := r.timeBuckets(begin, end, query.TimeInterval)
+		if err != nil {
+			return err
+		}
+		buckets = timeBuckets
+	} else {
+		if (end-begin)/query.Interval >= maxHolderBuckets {
+			return errors.New("too many buckets, the interval must be larger")
+		}
+		buckets = blockBuckets(begin, end, query.Interval)
+	}
+
+	// the holders before the first bucket are needed to know who is new in it
+	checkpoints := make([]uint64, 0, len(buckets)+1)
+	if begin > 0 {
+		checkpoints = append(checkpoints, begin-1)
+	}
+	for _, bucket := range buckets {
+		checkpoints = append(checkpoints, bucket.EndBlock)
+	}
+
+	options := &types.TokenQueryOptions{BeginBlockNumber: new(big.Int).SetUint64(checkpoints[0]), EndBlockNumber: query.Options.EndBlockNumber}
+	balances, err := r.db.GetERC20BalancesOfToken(*query.Contract, options)
+	if err != nil {
+		return err
+	}
+
+	counts := countHolders(balances, checkpoints)
+	offset := len(checkpoints) - len(buckets)
+	for i, bucket := range buckets {
+		bucket.Holders = counts.holders[i+offset]
+		bucket.NewHolders = counts.entered[i+offset]
+		bucket.ExitingHolders = counts.exited[i+offset]
+	}
+
+	*reply = buckets
+	return nil
+}
+
+// blockBuckets splits the block range into buckets of the interval, the last of which ends at the end block
+func blockBuckets(begin uint64, end uint64, interval uint64) []*ERC20HolderBucket {
+	buckets := make([]*ERC20HolderBucket, 0)
+	for start := begin; start <= end; start += interval {
+		bucket := &ERC20HolderBucket{StartBlock: start, EndBlock: start + interval - 1}
+		if bucket.EndBlock > end || bucket.EndBlock < start {
+			bucket.EndBlock = end
+		}
+		buckets = append(buckets, bucket)
+		if bucket.EndBlock == end {
+			break
+		}
+	}
+	return buckets
+}
+
+// timeBuckets splits the block range into buckets of the interval in seconds, from the time of the begin block to
+// the time of the end block. Each bucket ends at the last block within its time, and a bucket of time without any
+// blocks in it is left out.
+func (r *TokenRPCAPIs) timeBuckets(begin uint64, end uint64, interval uint64) ([]*ERC20HolderBucket, error) {
+	blocks, err := r.db.ReadBlocks([]uint64{begin, end})
+	if err != nil {
+		return nil, err
+	}
+	beginTime, endTime := blocks[0].Timestamp, blocks[1].Timestamp
+	if (endTime-beginTime)/interval >= maxHolderBuckets {
+		return nil, errors.New("too many buckets, the time interval must be larger")
+	}
+
+	buckets := make([]*ERC20HolderBucket, 0)
+	start := begin
+	for startTime := beginTime; start <= end; startTime += interval {
+		bucket := &ERC20HolderBucket{StartBlock: start, StartTime: startTime, EndTime: startTime + interval - 1}
+		if bucket.EndTime > endTime || bucket.EndTime < startTime {
+			bucket.EndTime = endTime
+		}
+		// the end of the previous bucket is before this one, and the begin block is in the first
+		low := start
+		if start > begin {
+			low = start - 1
+		}
+		last, err := r.lastBlockBy(bucket.EndTime, low, end)
+		if err != nil {
+			return nil, err
+		}
+		if last < start {
+			continue
+		}
+		bucket.EndBlock = last
+		buckets = append(buckets, bucket)
+		start = last + 1
+	}
+	return buckets, nil
+}
+
+// lastBlockBy finds the last block from low to high with a timestamp at or before the given time, where the block
+// at low is known to be at or before it, so is the result if none of the others are
+func (r *TokenRPCAPIs) lastBlockBy(timestamp uint64, low uint64, high uint64) (uint64, error) {
+	for low < high {
+		mid := low + (high-low+1)/2
+		block, err := r.db.ReadBlock(mid)
+		if err != nil {
+			return 0, err
+		}
+		if block.Timestamp <= timestamp {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+	return low, nil
+}
+
+// holderCounts are the number of holders at each of a list of blocks, and how many holders started
+// and stopped holding since the previous block in the list
+type holderCounts struct {
+	holders []int
+	entered []int
+	exited  []int
+}
+
+// countHolders counts the holders at each of the blocks, which are in order, from the non-zero balances
+// held across them. A holder's balances are consecutive, so they only hold once at each block.
+func countHolders(balances []types.ERC20Balance, blocks []uint64) holderCounts {
+	// spans are the indexes of the blocks each holder held at, from inclusive to until exclusive
+	type span struct{ from, until int }
+	spans := make(map[types.Address][]span)
+	for _, balance := range balances {
+		// a holder that sent out everything has a zero balance recorded, so no longer holds the token
+		amount, ok := new(big.Int).SetString(balance.Amount, 10)
+		if !ok || amount.Sign() <= 0 || balance.Holder.IsEmpty() {
+			continue
+		}
+		from := sort.Search(len(blocks), func(i int) bool { return blocks[i] >= balance.HeldFrom })
+		until := len(blocks)
+		if balance.HeldUntil != nil {
+			until = sort.Search(len(blocks), func(i int) bool { return blocks[i] > *balance.HeldUntil })
+		}
+		if from < until {
+			spans[balance.Holder] = append(spans[balance.Holder], span{from, until})
+		}
+	}
+
+	counts := holderCounts{
+		holders: make([]int, len(blocks)),
+		entered: make([]int, len(blocks)),
+		exited:  make([]int, len(blocks)),
+	}
+	change := make([]int, len(blocks)+1)
+	for _, holderSpans := range spans {
+		sort.Slice(holderSpans, func(i, j int) bool { return holderSpans[i].from < holderSpans[j].from })
+
+		// balances that change between two blocks are one span of holding, so are merged
+		merged := []span{holderSpans[0]}
+		for _, next := range holderSpans[1:] {
+			last := &merged[len(merged)-1]
+			if next.from <= last.until {
+				if next.until > last.until {
+					last.until = next.until
+				}
+				continue
+			}
+			merged = append(merged, next)
+		}
+
+		for _, s := range merged {
+			change[s.from]++
+			change[s.until]--
+			counts.entered[s.from]++
+			if s.until < len(blocks) {
+				counts.exited[s.until]++
+			}
+		}
+	}
+
+	holders := 0
+	for i := range blocks {
+		holders += change[i]
+		counts.holders[i] = holders
+	}
+	return counts
+}
+
+// gini is the Gini coefficient of the amounts, from 0 if they are all equal, towards 1 as all is held by one
+func gini(amounts []*big.Int) float64 {
+	if len(amounts) == 0 {
+		return 0
+	}
+	sorted := make([]*big.Int, len(amounts))
+	copy(sorted, amounts)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Cmp(sorted[j]) < 0 })
+
+	// G = 2 * sum(i * x_i) / (n * sum(x_i)) - (n + 1) / n, with the amounts in ascending order from i = 1
+	n := big.NewInt(int64(len(sorted)))
+	weighted, total := new(big.Int), new(big.Int)
+	for i, amount := range sorted {
+		weighted.Add(weighted, new(big.Int).Mul(big.NewInt(int64(i+1)), amount))
+		total.Add(total, amount)
+	}
+	if total.Sign() == 0 {
+		return 0
+	}
+	numerator := new(big.Int).Sub(new(big.Int).Mul(weighted, big.NewInt(2)), new(big.Int).Mul(new(big.Int).Add(n, big.NewInt(1)), total))
+	return ratio(numerator, new(big.Int).Mul(n, total))
+}
+
+// herfindahl is the Herfindahl-Hirschman index of the amounts, the sum of the squares of each of their shares
+func herfindahl(amounts []*big.Int) float64 {
+	squares, total := new(big.Int), new(big.Int)
+	for _, amount := range amounts {
+		squares.Add(squares, new(big.Int).Mul(amount, amount))
+		total.Add(total, amount)
+	}
+	return ratio(squares, new(big.Int).Mul(total, total))
+}
+
+// ratio divides two amounts, which may be too large to convert to floats before dividing
+func ratio(numerator *big.Int, denominator *big.Int) float64 {
+	if denominator.Sign() == 0 {
+		return 0
+	}
+	result, _ := new(big.Rat).SetFrac(numerator, denominator).Float64()
+	return result
+}
@@ -0,0 +1,222 @@
+package rpc
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"quorumengineering/quorum-report/database"
+	"quorumengineering/quorum-report/database/memory"
+	"quorumengineering/quorum-report/types"
+)
+
+var (
+	analyticsToken   = types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	analyticsHolderA = types.NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17")
+	analyticsHolderB = types.NewAddress("0x8a5e2a6343108babed07899510fb42297938d41f")
+)
+
+// fakeTokenDB gives the balances and metadata of a token, which the memory database does not record
+type fakeTokenDB struct {
+	*memory.MemoryDB
+	balances []types.ERC20Balance
+	metadata *types.ERC20Metadata
+}
+
+func (db *fakeTokenDB) GetERC20BalancesOfToken(contract types.Address, options *types.TokenQueryOptions) ([]types.ERC20Balance, error) {
+	matching := make([]types.ERC20Balance, 0)
+	for _, balance := range db.balances {
+		if balance.HeldFrom <= options.EndBlockNumber.Uint64() && (balance.HeldUntil == nil || *balance.HeldUntil >= options.BeginBlockNumber.Uint64()) {
+			matching = append(matching, balance)
+		}
+	}
+	return matching, nil
+}
+
+func (db *fakeTokenDB) GetERC20Metadata(contract types.Address) (*types.ERC20Metadata, error) {
+	if db.metadata == nil {
+		return nil, database.ErrNotFound
+	}
+	return db.metadata, nil
+}
+
+// setupAnalyticsTest has A hold the token throughout, and B hold it from block 5 until sending out everything
+// at block 15. Blocks 0 to 9 are 10 seconds apart from 1000, and blocks 10 to 19 the same from 1200.
+func setupAnalyticsTest(t *testing.T) *fakeTokenDB {
+	until := func(block uint64) *uint64 { return &block }
+	db := &fakeTokenDB{
+		MemoryDB: memory.NewMemoryDB(),
+		balances: []types.ERC20Balance{
+			{Contract: analyticsToken, Holder: analyticsHolderA, Amount: "100", HeldFrom: 1, HeldUntil: until(9)},
+			{Contract: analyticsToken, Holder: analyticsHolderA, Amount: "350", HeldFrom: 10},
+			{Contract: analyticsToken, Holder: analyticsHolderB, Amount: "50", HeldFrom: 5, HeldUntil: until(14)},
+			{Contract: analyticsToken, Holder: analyticsHolderB, Amount: "0", HeldFrom: 15},
+			{Contract: analyticsToken, Holder: types.NewAddress("0x0000000000000000000000000000000000000000"), Amount: "50", HeldFrom: 1},
+		},
+	}
+
+	blocks := make([]*types.Block, 20)
+	for i := range blocks {
+		blocks[i] = &types.Block{Number: uint64(i), Timestamp: 1000 + uint64(i)*10}
+		if i >= 10 {
+			blocks[i].Timestamp += 100
+		}
+	}
+	assert.Nil(t, db.WriteBlocks(blocks))
+	return db
+}
+
+func TestGetERC20HolderDistribution(t *testing.T) {
+	db := setupAnalyticsTest(t)
+	apis := NewTokenRPCAPIs(db, nil)
+
+	var distribution ERC20HolderDistribution
+	err := apis.GetERC20HolderDistribution(dummyReq, &ERC20TokenQuery{Block: 12}, &distribution)
+	assert.EqualError(t, err, "no token contract provided")
+	err = apis.GetERC20HolderDistribution(dummyReq, &ERC20TokenQuery{Contract: &analyticsToken}, &distribution)
+	assert.EqualError(t, err, "no block given")
+	err = apis.GetERC20HolderDistribution(dummyReq, &ERC20TokenQuery{Contract: &analyticsToken, Block: 12, Scaled: true}, &distribution)
+	assert.EqualError(t, err, "decimals of token are not known")
+
+	// only the largest holder is listed, but all the holders are measured
+	err = apis.GetERC20HolderDistribution(dummyReq, &ERC20TokenQuery{Contract: &analyticsToken, Block: 12, Options: &types.TokenQueryOptions{PageSize: 1}}, &distribution)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, distribution.Holders)
+	assert.Equal(t, "400", distribution.Total)
+	assert.Equal(t, "", distribution.ScaledTotal)
+	assert.Equal(t, []*ERC20HolderShare{
+		{ERC20HolderBalance: types.ERC20HolderBalance{Holder: analyticsHolderA, Amount: "350", HeldFrom: 10}, Percentage: 87.5},
+	}, distribution.TopHolders)
+	assert.Equal(t, 0.375, distribution.Gini)
+	assert.Equal(t, 0.78125, distribution.Herfindahl)
+	assert.Equal(t, float64(100), distribution.Top10Percentage)
+
+	decimals := uint8(2)
+	db.metadata = &types.ERC20Metadata{Contract: analyticsToken, Decimals: &decimals}
+	err = apis.GetERC20HolderDistribution(dummyReq, &ERC20TokenQuery{Contract: &analyticsToken, Block: 12, Scaled: true}, &distribution)
+	assert.Nil(t, err)
+	assert.Equal(t, "4", distribution.ScaledTotal)
+	assert.Len(t, distribution.TopHolders, 2)
+	assert.Equal(t, "3.5", distribution.TopHolders[0].ScaledAmount)
+	assert.Equal(t, "0.5", distribution.TopHolders[1].ScaledAmount)
+
+	// after B sends out everything, it is no longer a holder
+	err = apis.GetERC20HolderDistribution(dummyReq, &ERC20TokenQuery{Contract: &analyticsToken, Block: 15}, &distribution)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, distribution.Holders)
+}
+
+func TestGetERC20HolderHistory(t *testing.T) {
+	db := setupAnalyticsTest(t)
+	apis := NewTokenRPCAPIs(db, nil)
+	blockRange := func(begin int64, end int64) *types.TokenQueryOptions {
+		return &types.TokenQueryOptions{BeginBlockNumber: big.NewInt(begin), EndBlockNumber: big.NewInt(end)}
+	}
+
+	var buckets []*ERC20HolderBucket
+	err := apis.GetERC20HolderHistory(dummyReq, &ERC20HolderHistoryQuery{Interval: 5, Options: blockRange(0, 19)}, &buckets)
+	assert.EqualError(t, err, "no token contract provided")
+	err = apis.GetERC20HolderHistory(dummyReq, &ERC20HolderHistoryQuery{Contract: &analyticsToken, Options: blockRange(0, 19)}, &buckets)
+	assert.EqualError(t, err, "no interval given")
+	err = apis.GetERC20HolderHistory(dummyReq, &ERC20HolderHistoryQuery{Contract: &analyticsToken, Interval: 5, TimeInterval: 100, Options: blockRange(0, 19)}, &buckets)
+	assert.EqualError(t, err, "only one of interval and time interval can be given")
+	err = apis.GetERC20HolderHistory(dummyReq, &ERC20HolderHistoryQuery{Contract: &analyticsToken, Interval: 5}, &buckets)
+	assert.EqualError(t, err, "no end block given")
+	err = apis.GetERC20HolderHistory(dummyReq, &ERC20HolderHistoryQuery{Contract: &analyticsToken, Interval: 5, Options: blockRange(10, 9)}, &buckets)
+	assert.EqualError(t, err, "begin block must be between 0 and the end block")
+	err = apis.GetERC20HolderHistory(dummyReq, &ERC20HolderHistoryQuery{Contract: &analyticsToken, Interval: 1, Options: blockRange(0, 5000)}, &buckets)
+	assert.EqualError(t, err, "too many buckets, the interval must be larger")
+
+	// starting from block 0, there are no holders before the first bucket
+	err = apis.GetERC20HolderHistory(dummyReq, &ERC20HolderHistoryQuery{Contract: &analyticsToken, Interval: 5, Options: blockRange(0, 19)}, &buckets)
+	assert.Nil(t, err)
+	assert.Equal(t, []*ERC20HolderBucket{
+		{StartBlock: 0, EndBlock: 4, Holders: 1, NewHolders: 1},
+		{StartBlock: 5, EndBlock: 9, Holders: 2, NewHolders: 1},
+		{StartBlock: 10, EndBlock: 14, Holders: 2},
+		{StartBlock: 15, EndBlock: 19, Holders: 1, ExitingHolders: 1},
+	}, buckets)
+
+	// otherwise the holders before the first bucket are not new in it
+	err = apis.GetERC20HolderHistory(dummyReq, &ERC20HolderHistoryQuery{Contract: &analyticsToken, Interval: 5, Options: blockRange(5, 14)}, &buckets)
+	assert.Nil(t, err)
+	assert.Equal(t, []*ERC20HolderBucket{
+		{StartBlock: 5, EndBlock: 9, Holders: 2, NewHolders: 1},
+		{StartBlock: 10, EndBlock: 14, Holders: 2},
+	}, buckets)
+}
+
+func TestGetERC20HolderHistory_TimeBuckets(t *testing.T) {
+	db := setupAnalyticsTest(t)
+	apis := NewTokenRPCAPIs(db, nil)
+
+	// there are no blocks between 1100 and 1199, so that bucket is left out
+	var buckets []*ERC20HolderBucket
+	options := &types.TokenQueryOptions{BeginBlockNumber: big.NewInt(0), EndBlockNumber: big.NewInt(19)}
+	err := apis.GetERC20HolderHistory(dummyReq, &ERC20HolderHistoryQuery{Contract: &analyticsToken, TimeInterval: 100, Options: options}, &buckets)
+	assert.Nil(t, err)
+	assert.Equal(t, []*ERC20HolderBucket{
+		{StartBlock: 0, EndBlock: 9, StartTime: 1000, EndTime: 1099, Holders: 2, NewHolders: 2},
+		{StartBlock: 10, EndBlock: 19, StartTime: 1200, EndTime: 1290, Holders: 1, ExitingHolders: 1},
+	}, buckets)
+
+	options = &types.TokenQueryOptions{BeginBlockNumber: big.NewInt(3), EndBlockNumber: big.NewInt(12)}
+	err = apis.GetERC20HolderHistory(dummyReq, &ERC20HolderHistoryQuery{Contract: &analyticsToken, TimeInterval: 50, Options: options}, &buckets)
+	assert.Nil(t, err)
+	assert.Equal(t, []*ERC20HolderBucket{
+		{StartBlock: 3, EndBlock: 7, StartTime: 1030, EndTime: 1079, Holders: 2, NewHolders: 1},
+		{StartBlock: 8, EndBlock: 9, StartTime: 1080, EndTime: 1129, Holders: 2},
+		{StartBlock: 10, EndBlock: 12, StartTime: 1180, EndTime: 1220, Holders: 2},
+	}, buckets)
+
+	options = &types.TokenQueryOptions{BeginBlockNumber: big.NewInt(0), EndBlockNumber: big.NewInt(20)}
+	err = apis.GetERC20HolderHistory(dummyReq, &ERC20HolderHistoryQuery{Contract: &analyticsToken, TimeInterval: 100, Options: options}, &buckets)
+	assert.EqualError(t, err, "block does not exist")
+}
+
+func TestGini(t *testing.T) {
+	assert.Equal(t, float64(0), gini(nil))
+	assert.Equal(t, float64(0), gini([]*big.Int{big.NewInt(5), big.NewInt(5), big.NewInt(5)}))
+	// one of four holders holds everything, which is as concentrated as four holders can be
+	assert.Equal(t, 0.75, gini([]*big.Int{big.NewInt(0), big.NewInt(0), big.NewInt(100), big.NewInt(0)}))
+	assert.InDelta(t, 0.25, gini([]*big.Int{big.NewInt(3), big.NewInt(1)}), 1e-9)
+
+	// amounts too large for a float64 to hold exactly
+	large, _ := new(big.Int).SetString("100000000000000000000000000000000000000000", 10)
+	assert.InDelta(t, 0.25, gini([]*big.Int{new(big.Int).Mul(large, big.NewInt(3)), large}), 1e-9)
+}
+
+func TestHerfindahl(t *testing.T) {
+	assert.Equal(t, float64(0), herfindahl(nil))
+	assert.Equal(t, float64(1), herfindahl([]*big.Int{big.NewInt(42)}))
+	assert.Equal(t, 0.25, herfindahl([]*big.Int{big.NewInt(5), big.NewInt(5), big.NewInt(5), big.NewInt(5)}))
+	assert.Equal(t, 0.625, herfindahl([]*big.Int{big.NewInt(3), big.NewInt(1)}))
+}
+
+func TestCountHolders(t *testing.T) {
+	holderA := types.NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17")
+	holderB := types.NewAddress("0x8a5e2a6343108babed07899510fb42297938d41f")
+	holderC := types.NewAddress("0x9d13c6d3afe1721beef56b55d303b09e021e27ab")
+	until := func(block uint64) *uint64 { return &block }
+
+	balances := []types.ERC20Balance{
+		// A holds throughout, with a balance that changes in the middle
+		{Holder: holderA, Amount: "10", HeldFrom: 1, HeldUntil: until(14)},
+		{Holder: holderA, Amount: "20", HeldFrom: 15},
+		// B starts holding in the second bucket, and stops in the third
+		{Holder: holderB, Amount: "5", HeldFrom: 12, HeldUntil: until(24)},
+		// C only holds between the checkpoints, so is never counted
+		{Holder: holderC, Amount: "5", HeldFrom: 3, HeldUntil: until(6)},
+		// C then sends out everything, which records a zero balance that is not holding
+		{Holder: holderC, Amount: "0", HeldFrom: 7},
+		// the zero address is not a holder
+		{Holder: types.NewAddress("0x0000000000000000000000000000000000000000"), Amount: "5", HeldFrom: 1},
+	}
+
+	counts := countHolders(balances, []uint64{9, 19, 29})
+
+	assert.Equal(t, []int{1, 2, 1}, counts.holders)
+	assert.Equal(t, []int{1, 1, 0}, counts.entered)
+	assert.Equal(t, []int{0, 0, 1}, counts.exited)
+}
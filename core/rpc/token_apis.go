@@ -2,22 +2,36 @@ package rpc
 
 import (
 	"errors"
+	"fmt"
 	"math/big"
 	"net/http"
+	"sort"
+	"strconv"
 
+	"quorumengineering/quorum-report/client"
 	"quorumengineering/quorum-report/database"
 	"quorumengineering/quorum-report/types"
 )
 
+// maxPortfolios is the most portfolios returned by one request for the portfolio history of a holder
+const maxPortfolios = 1000
+
+// TokenAPIsDB is the database the token APIs read from, which includes blocks so that times can be found
+type TokenAPIsDB interface {
+	database.TokenDB
+	database.BlockDB
+}
+
 type TokenRPCAPIs struct {
-	db database.TokenDB
+	db           TokenAPIsDB
+	quorumClient client.Client
 }
 
-func NewTokenRPCAPIs(db database.TokenDB) *TokenRPCAPIs {
-	return &TokenRPCAPIs{db}
+func NewTokenRPCAPIs(db TokenAPIsDB, quorumClient client.Client) *TokenRPCAPIs {
+	return &TokenRPCAPIs{db, quorumClient}
 }
 
-func (r *TokenRPCAPIs) GetERC20TokenBalance(req *http.Request, query *ERC20TokenQuery, reply *map[uint64]*big.Int) error {
+func (r *TokenRPCAPIs) GetERC20TokenBalance(req *http.Request, query *ERC20TokenQuery, reply *map[uint64]*types.TokenAmount) error {
 	if query.Contract == nil {
 		return errors.New("no token contract provided")
 	}
@@ -28,13 +42,20 @@ func (r *TokenRPCAPIs) GetERC20TokenBalance(req *http.Request, query *ERC20Token
 		query.Options = &types.TokenQueryOptions{}
 	}
 	query.Options.SetDefaults()
+	if err := query.Options.ValidatePagination(); err != nil {
+		return err
+	}
+	decimals, err := r.scaleBy(*query.Contract, query.Scaled)
+	if err != nil {
+		return err
+	}
 
 	bal, err := r.db.GetERC20Balance(*query.Contract, *query.Holder, query.Options)
 	if err != nil {
 		return err
 	}
 
-	*reply = bal
+	*reply = toTokenAmounts(bal, decimals)
 	return nil
 }
 
@@ -49,6 +70,9 @@ func (r *TokenRPCAPIs) GetERC20TokenHoldersAtBlock(req *http.Request, query *ERC
 		query.Options = &types.TokenQueryOptions{}
 	}
 	query.Options.SetDefaults()
+	if err := query.Options.ValidatePagination(); err != nil {
+		return err
+	}
 
 	bal, err := r.db.GetAllTokenHolders(*query.Contract, query.Block, query.Options)
 	if err != nil {
@@ -59,6 +83,483 @@ func (r *TokenRPCAPIs) GetERC20TokenHoldersAtBlock(req *http.Request, query *ERC
 	return nil
 }
 
+// GetERC20BalancesAtBlock fetches the balance of each holder of a token at a block, ordered by amount
+func (r *TokenRPCAPIs) GetERC20BalancesAtBlock(req *http.Request, query *ERC20TokenQuery, reply *[]types.ERC20HolderBalance) error {
+	if query.Contract == nil {
+		return errors.New("no token contract provided")
+	}
+	if query.Block == 0 {
+		return errors.New("no block given")
+	}
+	if query.MinBalance != nil && query.MinBalance.Sign() < 0 {
+		return errors.New("minimum balance must not be negative")
+	}
+	if query.Options == nil {
+		query.Options = &types.TokenQueryOptions{}
+	}
+	query.Options.SetDefaults()
+	if err := query.Options.ValidatePagination(); err != nil {
+		return err
+	}
+	decimals, err := r.scaleBy(*query.Contract, query.Scaled)
+	if err != nil {
+		return err
+	}
+
+	balances, err := r.db.GetERC20BalancesAtBlock(*query.Contract, query.Block, query.MinBalance, query.Options)
+	if err != nil {
+		return err
+	}
+
+	if decimals != nil {
+		for i := range balances {
+			balances[i].ScaledAmount = scaleAmount(balances[i].Amount, *decimals)
+		}
+	}
+	*reply = balances
+	return nil
+}
+
+// ReconcileERC20Balances checks the recorded balance of each holder of a token at a block against the
+// balance the token reports, flagging the token if any differ, as its balances change without Transfer events
+func (r *TokenRPCAPIs) ReconcileERC20Balances(req *http.Request, query *ERC20TokenQuery, reply *ERC20ReconciliationResp) error {
+	if query.Contract == nil {
+		return errors.New("no token contract provided")
+	}
+	if query.Block == 0 {
+		return errors.New("no block given")
+	}
+	if query.Options == nil {
+		query.Options = &types.TokenQueryOptions{}
+	}
+	query.Options.SetDefaults()
+	if err := query.Options.ValidatePagination(); err != nil {
+		return err
+	}
+
+	holders, err := r.db.GetAllTokenHolders(*query.Contract, query.Block, query.Options)
+	if err != nil {
+		return err
+	}
+
+	mismatches := make([]*ERC20BalanceMismatch, 0)
+	for _, holder := range holders {
+		recorded, err := r.db.GetERC20BalanceAtBlock(*query.Contract, holder, query.Block)
+		if err != nil {
+			return err
+		}
+		result, err := client.CallBalanceOfERC20(r.quorumClient, *query.Contract, holder, query.Block)
+		if err != nil {
+			return err
+		}
+		actual := new(big.Int).SetBytes(result.AsBytes())
+		if actual.Cmp(recorded) != 0 {
+			mismatches = append(mismatches, &ERC20BalanceMismatch{
+				Holder:   holder,
+				Recorded: recorded.String(),
+				Actual:   actual.String(),
+			})
+		}
+	}
+
+	if len(mismatches) > 0 {
+		metadata, err := r.db.GetERC20Metadata(*query.Contract)
+		if err == database.ErrNotFound {
+			metadata = &types.ERC20Metadata{Contract: *query.Contract, BlockNumber: query.Block}
+		} else if err != nil {
+			return err
+		}
+		metadata.FlagBalanceDrift(query.Block)
+		if err := r.db.RecordERC20Metadata(metadata); err != nil {
+			return err
+		}
+	}
+
+	var last types.Address
+	var next string
+	if len(holders) > 0 {
+		last = holders[len(holders)-1]
+	}
+	if len(holders) > 0 && len(holders) == query.Options.PageSize {
+		next = types.NewTokenCursor(last.String()).Encode()
+	}
+	*reply = ERC20ReconciliationResp{
+		Checked:    len(holders),
+		Mismatches: mismatches,
+		Last:       last,
+		Next:       next,
+	}
+	return nil
+}
+
+// GetERC20Allowance fetches the history of the allowance an owner has given a spender
+func (r *TokenRPCAPIs) GetERC20Allowance(req *http.Request, query *ERC20AllowanceQuery, reply *map[uint64]*types.TokenAmount) error {
+	if query.Contract == nil {
+		return errors.New("no token contract provided")
+	}
+	if query.Owner == nil {
+		return errors.New("no token owner provided")
+	}
+	if query.Spender == nil {
+		return errors.New("no spender provided")
+	}
+	if query.Options == nil {
+		query.Options = &types.TokenQueryOptions{}
+	}
+	query.Options.SetDefaults()
+	if err := query.Options.ValidatePagination(); err != nil {
+		return err
+	}
+	decimals, err := r.scaleBy(*query.Contract, query.Scaled)
+	if err != nil {
+		return err
+	}
+
+	allowances, err := r.db.GetERC20Allowance(*query.Contract, *query.Owner, *query.Spender, query.Options)
+	if err != nil {
+		return err
+	}
+
+	*reply = toTokenAmounts(allowances, decimals)
+	return nil
+}
+
+// GetERC20ApprovalsForOwner fetches the allowances an owner has given to any spender at a block
+func (r *TokenRPCAPIs) GetERC20ApprovalsForOwner(req *http.Request, query *ERC20AllowanceQuery, reply *[]types.ERC20Allowance) error {
+	if query.Contract == nil {
+		return errors.New("no token contract provided")
+	}
+	if query.Owner == nil {
+		return errors.New("no token owner provided")
+	}
+	if query.Block == 0 {
+		return errors.New("no block given")
+	}
+	if query.Options == nil {
+		query.Options = &types.TokenQueryOptions{}
+	}
+	query.Options.SetDefaults()
+	if err := query.Options.ValidatePagination(); err != nil {
+		return err
+	}
+	decimals, err := r.scaleBy(*query.Contract, query.Scaled)
+	if err != nil {
+		return err
+	}
+
+	results, err := r.db.GetERC20ApprovalsForOwner(*query.Contract, *query.Owner, query.Block, query.Options)
+	if err != nil {
+		return err
+	}
+
+	if decimals != nil {
+		for i := range results {
+			results[i].ScaledAmount = scaleAmount(results[i].Amount, *decimals)
+		}
+	}
+	*reply = results
+	return nil
+}
+
+// GetERC20Metadata fetches the name, symbol and decimals of a token
+func (r *TokenRPCAPIs) GetERC20Metadata(req *http.Request, query *ERC20TokenQuery, reply *types.ERC20Metadata) error {
+	if query.Contract == nil {
+		return errors.New("no token contract provided")
+	}
+
+	metadata, err := r.db.GetERC20Metadata(*query.Contract)
+	if err != nil {
+		return err
+	}
+
+	*reply = *metadata
+	return nil
+}
+
+// GetERC20TotalSupply fetches the history of the total supply of a token
+func (r *TokenRPCAPIs) GetERC20TotalSupply(req *http.Request, query *ERC20TokenQuery, reply *map[uint64]*types.TokenAmount) error {
+	if query.Contract == nil {
+		return errors.New("no token contract provided")
+	}
+	if query.Options == nil {
+		query.Options = &types.TokenQueryOptions{}
+	}
+	query.Options.SetDefaults()
+	if err := query.Options.ValidatePagination(); err != nil {
+		return err
+	}
+	decimals, err := r.scaleBy(*query.Contract, query.Scaled)
+	if err != nil {
+		return err
+	}
+
+	supplies, err := r.db.GetERC20TotalSupply(*query.Contract, query.Options)
+	if err != nil {
+		return err
+	}
+
+	*reply = toTokenAmounts(supplies, decimals)
+	return nil
+}
+
+// GetERC20Transfers fetches the transfers of a token, optionally only those sent or received by a holder
+func (r *TokenRPCAPIs) GetERC20Transfers(req *http.Request, query *ERC20TransferQuery, reply *ERC20TransfersResp) error {
+	if query.Contract == nil {
+		return errors.New("no token contract provided")
+	}
+	if query.Filter == nil {
+		query.Filter = &types.ERC20TransferFilter{}
+	}
+	if err := query.Filter.Validate(); err != nil {
+		return err
+	}
+	if query.Options == nil {
+		query.Options = &types.QueryOptions{}
+	}
+	query.Options.SetDefaults()
+	if err := query.Options.ValidatePagination(); err != nil {
+		return err
+	}
+	decimals, err := r.scaleBy(*query.Contract, query.Scaled)
+	if err != nil {
+		return err
+	}
+
+	total, err := r.db.GetERC20TransfersTotal(*query.Contract, query.Filter, query.Options)
+	if err != nil {
+		return err
+	}
+	transfers, err := r.db.GetERC20Transfers(*query.Contract, query.Filter, query.Options)
+	if err != nil {
+		return err
+	}
+
+	if decimals != nil {
+		for _, transfer := range transfers {
+			transfer.ScaledAmount = scaleAmount(transfer.Amount, *decimals)
+		}
+	}
+
+	var next string
+	if len(transfers) > 0 && len(transfers) == query.Options.PageSize {
+		last := transfers[len(transfers)-1]
+		next = types.NewCursor(last.BlockNumber, last.Index).Encode()
+	}
+
+	*reply = ERC20TransfersResp{
+		Transfers: transfers,
+		Total:     total,
+		Options:   query.Options,
+		Next:      next,
+	}
+	return nil
+}
+
+// GetPortfolio fetches every ERC20 balance, ERC721 token and ERC1155 balance held by an address at a block
+func (r *TokenRPCAPIs) GetPortfolio(req *http.Request, query *PortfolioQuery, reply *Portfolio) error {
+	if query.Holder == nil {
+		return errors.New("no token holder provided")
+	}
+	if query.Block == 0 {
+		return errors.New("no block given")
+	}
+	block := new(big.Int).SetUint64(query.Block)
+	options := &types.TokenQueryOptions{BeginBlockNumber: block, EndBlockNumber: block}
+
+	held, err := r.holdingsOf(*query.Holder, options)
+	if err != nil {
+		return err
+	}
+	portfolios, err := r.portfolios(held, []uint64{query.Block}, query.Scaled)
+	if err != nil {
+		return err
+	}
+
+	*reply = *portfolios[0]
+	return nil
+}
+
+// GetPortfolioHistory fetches the portfolio of an address at the start of the block range,
+// and at each block within it that any of its holdings changed, a page of blocks at a time
+func (r *TokenRPCAPIs) GetPortfolioHistory(req *http.Request, query *PortfolioQuery, reply *[]*Portfolio) error {
+	if query.Holder == nil {
+		return errors.New("no token holder provided")
+	}
+	if query.Options == nil {
+		query.Options = &types.TokenQueryOptions{}
+	}
+	query.Options.SetDefaults()
+	if err := query.Options.ValidatePagination(); err != nil {
+		return err
+	}
+	if query.Options.PageSize > maxPortfolios {
+		return fmt.Errorf("page size must be at most %d", maxPortfolios)
+	}
+
+	begin := query.Options.BeginBlockNumber.Uint64()
+	unbounded := query.Options.EndBlockNumber.Cmp(big.NewInt(-1)) == 0
+	end := query.Options.EndBlockNumber.Uint64()
+	if !unbounded && end < begin {
+		return errors.New("end block must not be before begin block")
+	}
+
+	held, err := r.holdingsOf(*query.Holder, query.Options)
+	if err != nil {
+		return err
+	}
+	blocks := []uint64{begin}
+	for _, change := range held.changes() {
+		if change > begin && (unbounded || change <= end) {
+			blocks = append(blocks, change)
+		}
+	}
+	blocks, err = pageOfBlocks(blocks, query.Options)
+	if err != nil {
+		return err
+	}
+
+	portfolios, err := r.portfolios(held, blocks, query.Scaled)
+	if err != nil {
+		return err
+	}
+
+	*reply = portfolios
+	return nil
+}
+
+// pageOfBlocks takes the page of the blocks, which are in order, given by the options: those after the
+// block number in After if it is given, otherwise by page number
+func pageOfBlocks(blocks []uint64, options *types.TokenQueryOptions) ([]uint64, error) {
+	start := options.PageNumber * options.PageSize
+	if options.After != "" {
+		after, err := strconv.ParseUint(options.After, 10, 64)
+		if err != nil {
+			return nil, errors.New("after must be a block number")
+		}
+		start = sort.Search(len(blocks), func(i int) bool { return blocks[i] > after })
+	}
+	if start >= len(blocks) {
+		return []uint64{}, nil
+	}
+	end := start + options.PageSize
+	if end > len(blocks) {
+		end = len(blocks)
+	}
+	return blocks[start:end], nil
+}
+
+// holdings are all the tokens a holder held at any point within a block range
+type holdings struct {
+	holder  types.Address
+	erc20   []types.ERC20Balance
+	erc721  []types.ERC721Token
+	erc1155 []types.ERC1155Token
+}
+
+func (r *TokenRPCAPIs) holdingsOf(holder types.Address, options *types.TokenQueryOptions) (*holdings, error) {
+	erc20, err := r.db.GetERC20BalancesOfHolder(holder, options)
+	if err != nil {
+		return nil, err
+	}
+	erc721, err := r.db.GetERC721TokensOfHolder(holder, options)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.withERC721Metadata(erc721); err != nil {
+		return nil, err
+	}
+	erc1155, err := r.db.GetERC1155TokensOfHolder(holder, options)
+	if err != nil {
+		return nil, err
+	}
+	return &holdings{holder: holder, erc20: erc20, erc721: erc721, erc1155: erc1155}, nil
+}
+
+// changes finds the blocks, in order, that any of the holdings changed at
+func (h *holdings) changes() []uint64 {
+	changed := make(map[uint64]bool)
+	addChange := func(heldFrom uint64, heldUntil *uint64) {
+		changed[heldFrom] = true
+		if heldUntil != nil {
+			changed[*heldUntil+1] = true
+		}
+	}
+	for _, balance := range h.erc20 {
+		addChange(balance.HeldFrom, balance.HeldUntil)
+	}
+	for _, token := range h.erc721 {
+		addChange(token.HeldFrom, token.HeldUntil)
+	}
+	for _, token := range h.erc1155 {
+		addChange(token.HeldFrom, token.HeldUntil)
+	}
+
+	blocks := make([]uint64, 0, len(changed))
+	for block := range changed {
+		blocks = append(blocks, block)
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i] < blocks[j] })
+	return blocks
+}
+
+// portfolios builds the portfolio of the holder at each of the blocks, which are all within the range of the holdings
+func (r *TokenRPCAPIs) portfolios(h *holdings, blocks []uint64, scaled bool) ([]*Portfolio, error) {
+	metadata := make(map[types.Address]*types.ERC20Metadata)
+	for _, balance := range h.erc20 {
+		if _, ok := metadata[balance.Contract]; ok {
+			continue
+		}
+		tokenMetadata, err := r.db.GetERC20Metadata(balance.Contract)
+		if err != nil && err != database.ErrNotFound {
+			return nil, err
+		}
+		metadata[balance.Contract] = tokenMetadata
+	}
+
+	portfolios := make([]*Portfolio, 0, len(blocks))
+	for _, block := range blocks {
+		portfolio := &Portfolio{
+			Holder:  h.holder,
+			Block:   block,
+			ERC20:   make([]*PortfolioERC20, 0),
+			ERC721:  make([]types.ERC721Token, 0),
+			ERC1155: make([]types.ERC1155Token, 0),
+		}
+		for _, balance := range h.erc20 {
+			if !heldAt(block, balance.HeldFrom, balance.HeldUntil) {
+				continue
+			}
+			holding := &PortfolioERC20{
+				Contract: balance.Contract,
+				Amount:   balance.Amount,
+				HeldFrom: balance.HeldFrom,
+				Metadata: metadata[balance.Contract],
+			}
+			if scaled && holding.Metadata != nil && holding.Metadata.Decimals != nil {
+				holding.ScaledAmount = scaleAmount(balance.Amount, *holding.Metadata.Decimals)
+			}
+			portfolio.ERC20 = append(portfolio.ERC20, holding)
+		}
+		for _, token := range h.erc721 {
+			if heldAt(block, token.HeldFrom, token.HeldUntil) {
+				portfolio.ERC721 = append(portfolio.ERC721, token)
+			}
+		}
+		for _, token := range h.erc1155 {
+			if heldAt(block, token.HeldFrom, token.HeldUntil) {
+				portfolio.ERC1155 = append(portfolio.ERC1155, token)
+			}
+		}
+		portfolios = append(portfolios, portfolio)
+	}
+	return portfolios, nil
+}
+
+// heldAt returns whether a holding held from and until the given blocks was held at the block
+func heldAt(block uint64, heldFrom uint64, heldUntil *uint64) bool {
+	return heldFrom <= block && (heldUntil == nil || *heldUntil >= block)
+}
+
 func (r *TokenRPCAPIs) GetHolderForERC721TokenAtBlock(req *http.Request, query *ERC721TokenQuery, reply *types.Address) error {
 	if query.Contract == nil {
 		return errors.New("no token contract provided")
@@ -79,6 +580,124 @@ func (r *TokenRPCAPIs) GetHolderForERC721TokenAtBlock(req *http.Request, query *
 	return nil
 }
 
+// GetERC721TokenHistory fetches every holder of a token, with the transfer that gave it to them
+func (r *TokenRPCAPIs) GetERC721TokenHistory(req *http.Request, query *ERC721TokenQuery, reply *[]types.ERC721Token) error {
+	if query.Contract == nil {
+		return errors.New("no token contract provided")
+	}
+	if query.TokenId == nil {
+		return errors.New("no token ID provided")
+	}
+	if query.Options == nil {
+		query.Options = &types.TokenQueryOptions{}
+	}
+	query.Options.SetDefaults()
+	if err := query.Options.ValidatePagination(); err != nil {
+		return err
+	}
+
+	history, err := r.db.GetERC721TokenHistory(*query.Contract, query.TokenId, query.Options)
+	if err != nil {
+		return err
+	}
+	if err := r.withERC721Metadata(history); err != nil {
+		return err
+	}
+
+	*reply = history
+	return nil
+}
+
+// GetERC721HolderActivity fetches the ERC721 tokens a holder acquired and disposed of within a block range,
+// optionally only those of one contract
+func (r *TokenRPCAPIs) GetERC721HolderActivity(req *http.Request, query *ERC721TokenQuery, reply *ERC721HolderActivityResp) error {
+	if query.Holder == nil {
+		return errors.New("no token holder provided")
+	}
+	if query.Options == nil {
+		query.Options = &types.TokenQueryOptions{}
+	}
+	query.Options.SetDefaults()
+
+	acquired, err := r.db.GetERC721TokensAcquiredByHolder(*query.Holder, query.Options)
+	if err != nil {
+		return err
+	}
+	disposed, err := r.db.GetERC721TokensDisposedByHolder(*query.Holder, query.Options)
+	if err != nil {
+		return err
+	}
+
+	acquired = filterERC721Contract(acquired, query.Contract)
+	disposed = filterERC721Contract(disposed, query.Contract)
+	if err := r.withERC721Metadata(acquired); err != nil {
+		return err
+	}
+	if err := r.withERC721Metadata(disposed); err != nil {
+		return err
+	}
+
+	*reply = ERC721HolderActivityResp{Acquired: acquired, Disposed: disposed}
+	return nil
+}
+
+// filterERC721Contract keeps only the tokens of the contract, if one is given
+func filterERC721Contract(tokens []types.ERC721Token, contract *types.Address) []types.ERC721Token {
+	filtered := make([]types.ERC721Token, 0, len(tokens))
+	for _, token := range tokens {
+		if contract == nil || token.Contract == *contract {
+			filtered = append(filtered, token)
+		}
+	}
+	return filtered
+}
+
+// withERC721Metadata fills in the stored metadata of each of the tokens, leaving it empty for any not recorded
+func (r *TokenRPCAPIs) withERC721Metadata(tokens []types.ERC721Token) error {
+	tokenIds := make(map[types.Address][]string)
+	for _, token := range tokens {
+		tokenIds[token.Contract] = append(tokenIds[token.Contract], token.Token)
+	}
+
+	for contract, ids := range tokenIds {
+		metadata, err := r.db.GetERC721Metadata(contract, ids)
+		if err != nil {
+			return err
+		}
+		byToken := make(map[string]*types.ERC721Metadata, len(metadata))
+		for _, tokenMetadata := range metadata {
+			byToken[tokenMetadata.Token] = tokenMetadata
+		}
+		for i := range tokens {
+			if tokens[i].Contract == contract {
+				tokens[i].Metadata = byToken[tokens[i].Token]
+			}
+		}
+	}
+	return nil
+}
+
+// GetERC721Metadata fetches the name and symbol of a contract and the URI of one of its tokens
+func (r *TokenRPCAPIs) GetERC721Metadata(req *http.Request, query *ERC721TokenQuery, reply *types.ERC721Metadata) error {
+	if query.Contract == nil {
+		return errors.New("no token contract provided")
+	}
+	if query.TokenId == nil {
+		return errors.New("no token ID provided")
+	}
+
+	metadata, err := r.db.GetERC721Metadata(*query.Contract, []string{query.TokenId.String()})
+	if err != nil {
+		return err
+	}
+	if len(metadata) == 0 {
+		return database.ErrNotFound
+	}
+
+	*reply = *metadata[0]
+	return nil
+}
+
 func (r *TokenRPCAPIs) ERC721TokensForAccountAtBlock(req *http.Request, query *ERC721TokenQuery, reply *[]types.ERC721Token) error {
 	if query.Contract == nil {
 		return errors.New("no token contract provided")
@@ -93,11 +712,17 @@ func (r *TokenRPCAPIs) ERC721TokensForAccountAtBlock(req *http.Request, query *E
 		query.Options = &types.TokenQueryOptions{}
 	}
 	query.Options.SetDefaults()
+	if err := query.Options.ValidatePagination(); err != nil {
+		return err
+	}
 
 	results, err := r.db.ERC721TokensForAccountAtBlock(*query.Contract, *query.Holder, query.Block, query.Options)
 	if err != nil {
 		return err
 	}
+	if err := r.withERC721Metadata(results); err != nil {
+		return err
+	}
 
 	*reply = results
 	return nil
@@ -114,11 +739,17 @@ func (r *TokenRPCAPIs) AllERC721TokensAtBlock(req *http.Request, query *ERC721To
 		query.Options = &types.TokenQueryOptions{}
 	}
 	query.Options.SetDefaults()
+	if err := query.Options.ValidatePagination(); err != nil {
+		return err
+	}
 
 	results, err := r.db.AllERC721TokensAtBlock(*query.Contract, query.Block, query.Options)
 	if err != nil {
 		return err
 	}
+	if err := r.withERC721Metadata(results); err != nil {
+		return err
+	}
 
 	*reply = results
 	return nil
@@ -135,6 +766,9 @@ func (r *TokenRPCAPIs) AllERC721HoldersAtBlock(req *http.Request, query *ERC721T
 		query.Options = &types.TokenQueryOptions{}
 	}
 	query.Options.SetDefaults()
+	if err := query.Options.ValidatePagination(); err != nil {
+		return err
+	}
 
 	results, err := r.db.AllHoldersAtBlock(*query.Contract, query.Block, query.Options)
 	if err != nil {
@@ -144,3 +778,112 @@ func (r *TokenRPCAPIs) AllERC721HoldersAtBlock(req *http.Request, query *ERC721T
 	*reply = results
 	return nil
 }
+
+func (r *TokenRPCAPIs) GetERC1155TokenBalance(req *http.Request, query *ERC1155TokenQuery, reply *map[uint64]*big.Int) error {
+	if query.Contract == nil {
+		return errors.New("no token contract provided")
+	}
+	if query.Holder == nil {
+		return errors.New("no token holder provided")
+	}
+	if query.TokenId == nil {
+		return errors.New("no token ID provided")
+	}
+	if query.Options == nil {
+		query.Options = &types.TokenQueryOptions{}
+	}
+	query.Options.SetDefaults()
+	if err := query.Options.ValidatePagination(); err != nil {
+		return err
+	}
+
+	bal, err := r.db.GetERC1155Balance(*query.Contract, *query.Holder, query.TokenId, query.Options)
+	if err != nil {
+		return err
+	}
+
+	*reply = bal
+	return nil
+}
+
+func (r *TokenRPCAPIs) GetERC1155TokenHoldersAtBlock(req *http.Request, query *ERC1155TokenQuery, reply *[]types.Address) error {
+	if query.Contract == nil {
+		return errors.New("no token contract provided")
+	}
+	if query.TokenId == nil {
+		return errors.New("no token ID provided")
+	}
+	if query.Block == 0 {
+		return errors.New("no block given")
+	}
+	if query.Options == nil {
+		query.Options = &types.TokenQueryOptions{}
+	}
+	query.Options.SetDefaults()
+	if err := query.Options.ValidatePagination(); err != nil {
+		return err
+	}
+
+	results, err := r.db.GetERC1155HoldersAtBlock(*query.Contract, query.TokenId, query.Block, query.Options)
+	if err != nil {
+		return err
+	}
+
+	*reply = results
+	return nil
+}
+
+func (r *TokenRPCAPIs) GetERC1155TokensForAccountAtBlock(req *http.Request, query *ERC1155TokenQuery, reply *[]types.ERC1155Token) error {
+	if query.Contract == nil {
+		return errors.New("no token contract provided")
+	}
+	if query.Holder == nil {
+		return errors.New("no token holder provided")
+	}
+	if query.Block == 0 {
+		return errors.New("no block given")
+	}
+	if query.Options == nil {
+		query.Options = &types.TokenQueryOptions{}
+	}
+	query.Options.SetDefaults()
+	if err := query.Options.ValidatePagination(); err != nil {
+		return err
+	}
+
+	results, err := r.db.GetERC1155TokensForHolderAtBlock(*query.Contract, *query.Holder, query.Block, query.Options)
+	if err != nil {
+		return err
+	}
+
+	*reply = results
+	return nil
+}
+
+// scaleBy fetches the decimals to scale the amounts of a token by, if scaled amounts were asked for
+func (r *TokenRPCAPIs) scaleBy(contract types.Address, scaled bool) (*uint8, error) {
+	if !scaled {
+		return nil, nil
+	}
+	metadata, err := r.db.GetERC20Metadata(contract)
+	if err != nil && err != database.ErrNotFound {
+		return nil, err
+	}
+	if metadata == nil || metadata.Decimals == nil {
+		return nil, errors.New("decimals of token are not known")
+	}
+	return metadata.Decimals, nil
+}
+
+func toTokenAmounts(amounts map[uint64]*big.Int, decimals *uint8) map[uint64]*types.TokenAmount {
+	tokenAmounts := make(map[uint64]*types.TokenAmount, len(amounts))
+	for block, amount := range amounts {
+		tokenAmounts[block] = types.NewTokenAmount(amount, decimals)
+	}
+	return tokenAmounts
+}
+
+func scaleAmount(amount string, decimals uint8) string {
+	raw, _ := new(big.Int).SetString(amount, 10)
+	return types.ScaleAmount(raw, decimals)
+}
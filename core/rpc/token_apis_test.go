@@ -0,0 +1,33 @@
+package rpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"quorumengineering/quorum-report/types"
+)
+
+func TestPageOfBlocks(t *testing.T) {
+	blocks := []uint64{1, 4, 5, 9, 12}
+
+	page, err := pageOfBlocks(blocks, &types.TokenQueryOptions{PageSize: 2, PageNumber: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{5, 9}, page)
+
+	page, err = pageOfBlocks(blocks, &types.TokenQueryOptions{PageSize: 2, PageNumber: 3})
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{}, page)
+
+	// after takes precedence over the page number, and need not be one of the blocks
+	page, err = pageOfBlocks(blocks, &types.TokenQueryOptions{PageSize: 2, PageNumber: 1, After: "6"})
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{9, 12}, page)
+
+	page, err = pageOfBlocks(blocks, &types.TokenQueryOptions{PageSize: 2, After: "12"})
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{}, page)
+
+	_, err = pageOfBlocks(blocks, &types.TokenQueryOptions{PageSize: 2, After: "0x01"})
+	assert.EqualError(t, err, "after must be a block number")
+}
@@ -23,10 +23,34 @@ type AddressWithData struct {
 	Data    string
 }
 
+// AddressWithCompiledData holds either a raw storage layout, or compiler
+// output along with the name of the contract to take from it
+type AddressWithCompiledData struct {
+	Address      *types.Address
+	Data         string
+	ContractName string
+}
+
+// TemplateArgs holds either a raw ABI and storage layout, or compiler output
+// along with the name of the contract to take from it
 type TemplateArgs struct {
-	Name          string
-	Abi           string
-	StorageLayout string
+	Name           string
+	Abi            string
+	StorageLayout  string
+	CompilerOutput string
+	ContractName   string
+}
+
+type TemplateAssignmentArgs struct {
+	Address   *types.Address
+	Name      string
+	Version   uint64
+	FromBlock uint64
+}
+
+type TemplateVersionArgs struct {
+	Name    string
+	Version uint64
 }
 
 type AddressWithOptionalBlock struct {
@@ -39,13 +63,88 @@ type AddressWithBlockRange struct {
 	Options *types.PageOptions
 }
 
+type AddressWithVariablePath struct {
+	Address *types.Address
+	Path    string
+	Options *types.PageOptions
+}
+
+// FunctionCallArgs is a call to a function of a contract's template, by either its name
+// or its signature, e.g. "balanceOf(address)", if the function is overloaded
+type FunctionCallArgs struct {
+	Address     *types.Address
+	Function    string
+	Args        []interface{}
+	BlockNumber *uint64
+}
+
+type SamplerArgs struct {
+	Address *types.Address
+	Sampler *types.FunctionSampler
+}
+
+type SamplerNameArgs struct {
+	Address *types.Address
+	Name    string
+}
+
+type SamplesArgs struct {
+	Address *types.Address
+	Name    string
+	Options *types.QueryOptions
+}
+
+type EventFilterArgs struct {
+	Address *types.Address
+	Filter  *types.EventFilter
+	Options *types.QueryOptions
+}
+
+// ERC20TokenQuery, ERC20AllowanceQuery and ERC20TransferQuery can ask for amounts to also
+// be given scaled by the decimals of the token, with Scaled
 type ERC20TokenQuery struct {
+	Contract   *types.Address
+	Holder     *types.Address
+	Block      uint64
+	MinBalance *big.Int
+	Scaled     bool
+	Options    *types.TokenQueryOptions
+}
+
+// PortfolioQuery asks for the holdings of a holder across all tokens, either at a block,
+// or at each block they changed within the block range of the options
+type PortfolioQuery struct {
+	Holder  *types.Address
+	Block   uint64
+	Scaled  bool
+	Options *types.TokenQueryOptions
+}
+
+// ERC20HolderHistoryQuery asks for the holders of a token over the block range of the options,
+// split into buckets of either Interval blocks or TimeInterval seconds
+type ERC20HolderHistoryQuery struct {
+	Contract     *types.Address
+	Interval     uint64
+	TimeInterval uint64
+	Options      *types.TokenQueryOptions
+}
+
+type ERC20AllowanceQuery struct {
 	Contract *types.Address
-	Holder   *types.Address
+	Owner    *types.Address
+	Spender  *types.Address
 	Block    uint64
+	Scaled   bool
 	Options  *types.TokenQueryOptions
 }
 
+type ERC20TransferQuery struct {
+	Contract *types.Address
+	Filter   *types.ERC20TransferFilter
+	Scaled   bool
+	Options  *types.QueryOptions
+}
+
 type ERC721TokenQuery struct {
 	Contract *types.Address
 	Holder   *types.Address
@@ -54,18 +153,174 @@ type ERC721TokenQuery struct {
 	Options  *types.TokenQueryOptions
 }
 
+type ERC1155TokenQuery struct {
+	Contract *types.Address
+	Holder   *types.Address
+	TokenId  *big.Int
+	Block    uint64
+	Options  *types.TokenQueryOptions
+}
+
 //Outputs
 
 type TransactionsResp struct {
 	Transactions []types.Hash        `json:"transactions"`
 	Total        uint64              `json:"total"`
 	Options      *types.QueryOptions `json:"options"`
+	Next         string              `json:"next,omitempty"`
+}
+
+type InternalTransactionsResp struct {
+	Transactions  []types.Hash             `json:"transactions"`
+	InternalCalls []*InternalCallToAddress `json:"internalCalls"`
+	Total         uint64                   `json:"total"`
+	Options       *types.QueryOptions      `json:"options"`
+	Next          string                   `json:"next,omitempty"`
+}
+
+// InternalCallToAddress is a decoded internal call made to a contract, along with the
+// transaction it was made in and its index in the internal calls of that transaction
+type InternalCallToAddress struct {
+	TransactionHash types.Hash `json:"transactionHash"`
+	Index           int        `json:"index"`
+	*types.ParsedInternalCall
+}
+
+type FailedTransactionsResp struct {
+	Transactions []*TransactionFailure `json:"transactions"`
+	Total        uint64                `json:"total"`
+	Options      *types.QueryOptions   `json:"options"`
+	Next         string                `json:"next,omitempty"`
+}
+
+// TransactionFailure is the reason a transaction failed, with the revert data decoded where possible
+type TransactionFailure struct {
+	Hash        types.Hash          `json:"hash"`
+	BlockNumber uint64              `json:"blockNumber"`
+	Error       string              `json:"error,omitempty"`
+	RevertData  types.HexData       `json:"revertData,omitempty"`
+	Revert      *types.ParsedRevert `json:"revert,omitempty"`
+}
+
+// CallTreeFrame is an internal call of a transaction, along with the calls that it made in turn
+type CallTreeFrame struct {
+	*types.ParsedInternalCall
+	Calls []*CallTreeFrame `json:"calls"`
+}
+
+type CallTreeResp struct {
+	Hash  types.Hash       `json:"hash"`
+	Calls []*CallTreeFrame `json:"calls"`
+}
+
+type FunctionCallResp struct {
+	Sig          string                 `json:"sig"`
+	BlockNumber  uint64                 `json:"blockNumber"`
+	Output       types.HexData          `json:"output"`
+	ParsedOutput map[string]interface{} `json:"parsedOutput"`
+}
+
+type SamplesResp struct {
+	Address types.Address       `json:"address"`
+	Name    string              `json:"name"`
+	Samples []*ParsedSample     `json:"samples"`
+	Total   uint64              `json:"total"`
+	Options *types.QueryOptions `json:"options"`
+	Next    string              `json:"next,omitempty"`
+}
+
+// ParsedSample is a sample of a view function, with its output decoded using the
+// template of the contract at the block it was taken at
+type ParsedSample struct {
+	*types.FunctionSample
+	ParsedOutput map[string]interface{} `json:"parsedOutput,omitempty"`
 }
 
 type EventsResp struct {
 	Events  []*types.ParsedEvent `json:"events"`
 	Total   uint64               `json:"total"`
 	Options *types.QueryOptions  `json:"options"`
+	Next    string               `json:"next,omitempty"`
+}
+
+type ERC20TransfersResp struct {
+	Transfers []*types.ERC20Transfer `json:"transfers"`
+	Total     uint64                 `json:"total"`
+	Options   *types.QueryOptions    `json:"options"`
+	Next      string                 `json:"next,omitempty"`
+}
+
+// ERC721HolderActivityResp holds the transfers of ERC721 tokens to a holder, and those from it,
+// where the holder of each disposed token is who it was transferred to
+type ERC721HolderActivityResp struct {
+	Acquired []types.ERC721Token `json:"acquired"`
+	Disposed []types.ERC721Token `json:"disposed"`
+}
+
+// Portfolio is everything a holder held across all tokens at a block
+type Portfolio struct {
+	Holder  types.Address        `json:"holder"`
+	Block   uint64               `json:"block"`
+	ERC20   []*PortfolioERC20    `json:"erc20"`
+	ERC721  []types.ERC721Token  `json:"erc721"`
+	ERC1155 []types.ERC1155Token `json:"erc1155"`
+}
+
+// PortfolioERC20 is the balance of an ERC20 token in a portfolio, with the metadata of the token if it is known
+type PortfolioERC20 struct {
+	Contract     types.Address        `json:"contract"`
+	Amount       string               `json:"amount"`
+	HeldFrom     uint64               `json:"heldFrom"`
+	ScaledAmount string               `json:"scaledAmount,omitempty"`
+	Metadata     *types.ERC20Metadata `json:"metadata"`
+}
+
+type ERC20ReconciliationResp struct {
+	Checked    int                     `json:"checked"`
+	Mismatches []*ERC20BalanceMismatch `json:"mismatches"`
+	// Last is the last holder checked
+	Last types.Address `json:"last,omitempty"`
+	// Next is the cursor to give as the after option to check the next page of holders
+	Next string `json:"next,omitempty"`
+}
+
+type ERC20BalanceMismatch struct {
+	Holder   types.Address `json:"holder"`
+	Recorded string        `json:"recorded"`
+	Actual   string        `json:"actual"`
+}
+
+// ERC20HolderDistribution is how the balances of a token are spread across its holders at a block, where
+// the share of each holder is of the total of all the balances
+type ERC20HolderDistribution struct {
+	Contract    types.Address       `json:"contract"`
+	Block       uint64              `json:"block"`
+	Holders     int                 `json:"holders"`
+	Total       string              `json:"total"`
+	ScaledTotal string              `json:"scaledTotal,omitempty"`
+	TopHolders  []*ERC20HolderShare `json:"topHolders"`
+	// Gini and Herfindahl are between 0 and 1, and are higher the more the token is held by few holders
+	Gini            float64 `json:"gini"`
+	Herfindahl      float64 `json:"herfindahl"`
+	Top10Percentage float64 `json:"top10Percentage"`
+}
+
+type ERC20HolderShare struct {
+	types.ERC20HolderBalance
+	Percentage float64 `json:"percentage"`
+}
+
+// ERC20HolderBucket is the number of holders of a token at the end of a range of blocks, and how many
+// started and stopped holding it since the end of the previous range. Buckets of time also give the
+// range of timestamps they cover.
+type ERC20HolderBucket struct {
+	StartBlock     uint64 `json:"startBlock"`
+	EndBlock       uint64 `json:"endBlock"`
+	StartTime      uint64 `json:"startTime,omitempty"`
+	EndTime        uint64 `json:"endTime,omitempty"`
+	Holders        int    `json:"holders"`
+	NewHolders     int    `json:"newHolders"`
+	ExitingHolders int    `json:"exitingHolders"`
 }
 
 type RangeQueryResult struct {
@@ -0,0 +1,247 @@
+package storageparsing
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+
+	"quorumengineering/quorum-report/types"
+)
+
+var (
+	mappingPrefix = "t_mapping"
+
+	bigTwoFiftySix = new(big.Int).Lsh(BigOne, 256)
+)
+
+// MappingKeys is a set of candidate keys that are tried against each mapping,
+// since Solidity does not store the keys of a mapping anywhere in storage
+type MappingKeys struct {
+	addresses map[types.Address]bool
+	integers  map[string]*big.Int
+}
+
+func NewMappingKeys() *MappingKeys {
+	return &MappingKeys{
+		addresses: make(map[types.Address]bool),
+		integers:  make(map[string]*big.Int),
+	}
+}
+
+func (mk *MappingKeys) AddAddress(address types.Address) {
+	mk.addresses[address] = true
+}
+
+func (mk *MappingKeys) AddInteger(integer *big.Int) {
+	mk.integers[integer.String()] = integer
+}
+
+// Merge adds all the candidate keys of another set of keys
+func (mk *MappingKeys) Merge(other *MappingKeys) {
+	for address := range other.addresses {
+		mk.addresses[address] = true
+	}
+	for key, integer := range other.integers {
+		mk.integers[key] = integer
+	}
+}
+
+// Addresses returns all the candidate address keys, in sorted order
+func (mk *MappingKeys) Addresses() []types.Address {
+	addresses := make([]types.Address, 0, len(mk.addresses))
+	for address := range mk.addresses {
+		addresses = append(addresses, address)
+	}
+	sort.Slice(addresses, func(i, j int) bool { return addresses[i] < addresses[j] })
+	return addresses
+}
+
+// Integers returns all the candidate integer keys, in sorted order
+func (mk *MappingKeys) Integers() []*big.Int {
+	integers := make([]*big.Int, 0, len(mk.integers))
+	for _, integer := range mk.integers {
+		integers = append(integers, integer)
+	}
+	sort.Slice(integers, func(i, j int) bool { return integers[i].Cmp(integers[j]) < 0 })
+	return integers
+}
+
+// HasMappings reports whether any variable in the storage layout uses a mapping,
+// meaning candidate keys are needed to parse it fully
+func HasMappings(template types.SolidityStorageDocument) bool {
+	for typeName := range template.Types {
+		if strings.HasPrefix(typeName, mappingPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// mappingKey is a candidate key encoded ready for slot derivation, along with
+// how it should be presented in the output
+type mappingKey struct {
+	encoded []byte
+	display string
+}
+
+// ParseMapping tries each candidate key against the mapping, returning the
+// values of all keys that have some non-empty storage. Nested mappings and
+// mappings to structs and arrays are resolved by parsing the value type at
+// the derived slot.
+func (p *Parser) ParseMapping(entry types.SolidityStorageEntry, namedType types.SolidityTypeEntry) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+
+	mappingSlot := p.ResolveSlot(bigN(entry.Slot))
+	for _, key := range p.candidateKeys(namedType.Key) {
+		valueSlot := hashMappingKey(key.encoded, mappingSlot)
+
+		tracker := &trackingStorageManager{StorageManager: p.storageManager}
+		valueTemplate := types.SolidityStorageDocument{
+			Types: p.template.Types,
+		}
+		valueParser := NewParser(tracker, valueTemplate, valueSlot)
+		valueParser.SetMappingKeys(p.mappingKeys)
+
+		value, err := valueParser.parseSingle(types.SolidityStorageEntry{Label: key.display, Type: namedType.Value})
+		if err != nil {
+			return nil, err
+		}
+		if tracker.nonEmpty {
+			result[key.display] = value
+		}
+	}
+
+	return result, nil
+}
+
+// candidateKeys encodes all the candidate keys that are valid for the given key type
+func (p *Parser) candidateKeys(keyType string) []mappingKey {
+	keys := make([]mappingKey, 0)
+
+	switch {
+	case strings.HasPrefix(keyType, boolPrefix):
+		keys = append(keys, encodeBoolKey(false), encodeBoolKey(true))
+
+	case p.mappingKeys == nil:
+		return keys
+
+	case strings.HasPrefix(keyType, addressPrefix), strings.HasPrefix(keyType, contractPrefix):
+		for _, address := range p.mappingKeys.Addresses() {
+			keys = append(keys, encodeAddressKey(address))
+		}
+
+	case strings.HasPrefix(keyType, uintPrefix), strings.HasPrefix(keyType, intPrefix), strings.HasPrefix(keyType, enumPrefix):
+		for _, integer := range p.mappingKeys.Integers() {
+			if key, ok := p.encodeIntegerKey(keyType, integer); ok {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+// encodeMappingKey encodes a single user provided key for the given key type
+func (p *Parser) encodeMappingKey(keyType string, raw string) (mappingKey, error) {
+	switch {
+	case strings.HasPrefix(keyType, boolPrefix):
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return mappingKey{}, fmt.Errorf("invalid bool mapping key %s", raw)
+		}
+		return encodeBoolKey(parsed), nil
+
+	case strings.HasPrefix(keyType, addressPrefix), strings.HasPrefix(keyType, contractPrefix):
+		decoded, err := hex.DecodeString(strings.TrimPrefix(raw, "0x"))
+		if err != nil || len(decoded) != 20 {
+			return mappingKey{}, fmt.Errorf("invalid address mapping key %s", raw)
+		}
+		return encodeAddressKey(types.NewAddress(hex.EncodeToString(decoded))), nil
+
+	case strings.HasPrefix(keyType, uintPrefix), strings.HasPrefix(keyType, intPrefix), strings.HasPrefix(keyType, enumPrefix):
+		parsed, ok := new(big.Int).SetString(raw, 0)
+		if !ok {
+			return mappingKey{}, fmt.Errorf("invalid integer mapping key %s", raw)
+		}
+		key, ok := p.encodeIntegerKey(keyType, parsed)
+		if !ok {
+			return mappingKey{}, fmt.Errorf("mapping key %s out of range for %s", raw, p.template.Types[keyType].Label)
+		}
+		return key, nil
+	}
+	return mappingKey{}, fmt.Errorf("unsupported mapping key type %s", p.template.Types[keyType].Label)
+}
+
+func encodeBoolKey(value bool) mappingKey {
+	if value {
+		return mappingKey{encoded: leftPad32([]byte{1}), display: "true"}
+	}
+	return mappingKey{encoded: leftPad32([]byte{0}), display: "false"}
+}
+
+func encodeAddressKey(address types.Address) mappingKey {
+	decoded, _ := hex.DecodeString(string(address))
+	return mappingKey{encoded: leftPad32(decoded), display: address.String()}
+}
+
+// encodeIntegerKey encodes an integer as a signed or unsigned key of the given
+// type, returning false if the integer does not fit in the type
+func (p *Parser) encodeIntegerKey(keyType string, integer *big.Int) (mappingKey, bool) {
+	bits := uint(8 * p.template.Types[keyType].NumberOfBytes)
+
+	if !strings.HasPrefix(keyType, intPrefix) {
+		if integer.Sign() < 0 || integer.BitLen() > int(bits) {
+			return mappingKey{}, false
+		}
+		return mappingKey{encoded: leftPad32(integer.Bytes()), display: integer.String()}, true
+	}
+
+	limit := new(big.Int).Lsh(BigOne, bits-1)
+	if integer.Cmp(new(big.Int).Neg(limit)) < 0 || integer.Cmp(limit) >= 0 {
+		return mappingKey{}, false
+	}
+	twosComplement := integer
+	if integer.Sign() < 0 {
+		twosComplement = new(big.Int).Add(bigTwoFiftySix, integer)
+	}
+	return mappingKey{encoded: leftPad32(twosComplement.Bytes()), display: integer.String()}, true
+}
+
+// hashMappingKey derives the storage slot of a mapping value, which is
+// keccak256(key . slot) where both are padded to 32 bytes
+func hashMappingKey(key []byte, slot types.Hash) types.Hash {
+	slotBytes, _ := hex.DecodeString(string(slot))
+	hasher := sha3.NewLegacyKeccak256()
+	hasher.Write(key)
+	hasher.Write(leftPad32(slotBytes))
+	return types.NewHash(hex.EncodeToString(hasher.Sum(nil)))
+}
+
+func leftPad32(b []byte) []byte {
+	if len(b) >= 32 {
+		return b[len(b)-32:]
+	}
+	padded := make([]byte, 32)
+	copy(padded[32-len(b):], b)
+	return padded
+}
+
+// trackingStorageManager records whether any storage slot that was read
+// contained a non-zero value, to determine if a mapping key is in use
+type trackingStorageManager struct {
+	StorageManager
+
+	nonEmpty bool
+}
+
+func (tsm *trackingStorageManager) Get(hash types.Hash) []byte {
+	value := tsm.StorageManager.Get(hash)
+	if new(big.Int).SetBytes(value).Sign() != 0 {
+		tsm.nonEmpty = true
+	}
+	return value
+}
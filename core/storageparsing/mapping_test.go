@@ -0,0 +1,217 @@
+package storageparsing
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"quorumengineering/quorum-report/types"
+)
+
+var (
+	mappingTestHolder1 = types.NewAddress("0x0000000000000000000000000000000000000011")
+	mappingTestHolder2 = types.NewAddress("0x0000000000000000000000000000000000000022")
+	mappingTestUnused  = types.NewAddress("0x0000000000000000000000000000000000000033")
+
+	mappingTestLayout = types.SolidityStorageDocument{
+		Storage: []types.SolidityStorageEntry{
+			{Label: "balances", Slot: 0, Type: "t_mapping(t_address,t_uint256)"},
+			{Label: "allowed", Slot: 1, Type: "t_mapping(t_address,t_mapping(t_address,t_uint256))"},
+			{Label: "people", Slot: 2, Type: "t_mapping(t_uint256,t_struct(Person)10_storage)"},
+			{Label: "deltas", Slot: 3, Type: "t_mapping(t_int8,t_uint256)"},
+			{Label: "flags", Slot: 4, Type: "t_mapping(t_bool,t_uint8)"},
+		},
+		Types: map[string]types.SolidityTypeEntry{
+			"t_address": {Encoding: "inplace", Label: "address", NumberOfBytes: 20},
+			"t_bool":    {Encoding: "inplace", Label: "bool", NumberOfBytes: 1},
+			"t_int8":    {Encoding: "inplace", Label: "int8", NumberOfBytes: 1},
+			"t_uint8":   {Encoding: "inplace", Label: "uint8", NumberOfBytes: 1},
+			"t_uint256": {Encoding: "inplace", Label: "uint256", NumberOfBytes: 32},
+			"t_mapping(t_address,t_uint256)": {
+				Encoding: "mapping", Label: "mapping(address => uint256)", NumberOfBytes: 32, Key: "t_address", Value: "t_uint256",
+			},
+			"t_mapping(t_address,t_mapping(t_address,t_uint256))": {
+				Encoding: "mapping", Label: "mapping(address => mapping(address => uint256))", NumberOfBytes: 32, Key: "t_address", Value: "t_mapping(t_address,t_uint256)",
+			},
+			"t_mapping(t_uint256,t_struct(Person)10_storage)": {
+				Encoding: "mapping", Label: "mapping(uint256 => struct Test.Person)", NumberOfBytes: 32, Key: "t_uint256", Value: "t_struct(Person)10_storage",
+			},
+			"t_mapping(t_int8,t_uint256)": {
+				Encoding: "mapping", Label: "mapping(int8 => uint256)", NumberOfBytes: 32, Key: "t_int8", Value: "t_uint256",
+			},
+			"t_mapping(t_bool,t_uint8)": {
+				Encoding: "mapping", Label: "mapping(bool => uint8)", NumberOfBytes: 32, Key: "t_bool", Value: "t_uint8",
+			},
+			"t_struct(Person)10_storage": {
+				Encoding: "inplace", Label: "struct Test.Person", NumberOfBytes: 64,
+				Members: []types.SolidityStorageEntry{
+					{Label: "age", Slot: 0, Type: "t_uint256"},
+					{Label: "wallet", Slot: 1, Type: "t_address"},
+				},
+			},
+		},
+	}
+)
+
+func addressKey(address types.Address) []byte {
+	return encodeAddressKey(address).encoded
+}
+
+func integerKey(i int64) []byte {
+	return leftPad32(big.NewInt(i).Bytes())
+}
+
+func slotAfter(slot types.Hash, n int64) types.Hash {
+	asBytes, _ := hex.DecodeString(string(slot))
+	next := new(big.Int).Add(new(big.Int).SetBytes(asBytes), big.NewInt(n))
+	return types.NewHash(hex.EncodeToString(next.Bytes()))
+}
+
+func setupMappingTest() map[types.Hash]string {
+	allowedHolder1 := hashMappingKey(addressKey(mappingTestHolder1), types.NewHash("01"))
+	person7 := hashMappingKey(integerKey(7), types.NewHash("02"))
+
+	return map[types.Hash]string{
+		hashMappingKey(addressKey(mappingTestHolder1), types.NewHash("00")): "64",
+		hashMappingKey(addressKey(mappingTestHolder2), types.NewHash("00")): "0a",
+		hashMappingKey(addressKey(mappingTestHolder2), allowedHolder1):      "05",
+		person7:               "2a",
+		slotAfter(person7, 1): "22",
+		hashMappingKey(leftPad32(new(big.Int).Add(bigTwoFiftySix, big.NewInt(-2)).Bytes()), types.NewHash("03")): "03",
+		hashMappingKey(integerKey(1), types.NewHash("04")):                                                       "01",
+	}
+}
+
+func setupMappingKeys() *MappingKeys {
+	keys := NewMappingKeys()
+	keys.AddAddress(mappingTestHolder1)
+	keys.AddAddress(mappingTestHolder2)
+	keys.AddAddress(mappingTestUnused)
+	keys.AddInteger(big.NewInt(7))
+	keys.AddInteger(big.NewInt(-2))
+	keys.AddInteger(big.NewInt(300))
+	return keys
+}
+
+func TestHashMappingKey(t *testing.T) {
+	// keccak256 of 64 zero bytes, the slot of key 0 in a mapping at slot 0
+	result := hashMappingKey(integerKey(0), types.NewHash(""))
+
+	assert.Equal(t, "0xad3228b676f7d3cd4284a5443f17f1962b36e491b30a40b2405849e597ba5fb5", result.String())
+}
+
+func TestParseRawStorage_Mappings(t *testing.T) {
+	result, err := ParseRawStorage(setupMappingTest(), mappingTestLayout, setupMappingKeys())
+
+	assert.Nil(t, err)
+	assert.Len(t, result, 5)
+
+	assert.Equal(t, "balances", result[0].VarName)
+	assert.Equal(t, map[string]interface{}{
+		"0x0000000000000000000000000000000000000011": "100",
+		"0x0000000000000000000000000000000000000022": "10",
+	}, result[0].Value)
+
+	assert.Equal(t, "allowed", result[1].VarName)
+	assert.Equal(t, map[string]interface{}{
+		"0x0000000000000000000000000000000000000011": map[string]interface{}{
+			"0x0000000000000000000000000000000000000022": "5",
+		},
+	}, result[1].Value)
+
+	assert.Equal(t, "people", result[2].VarName)
+	assert.Equal(t, map[string]interface{}{
+		"7": []*types.StorageItem{
+			{VarName: "age", VarType: "uint256", Value: "42"},
+			{VarName: "wallet", VarType: "address", Value: mappingTestHolder2},
+		},
+	}, result[2].Value)
+
+	assert.Equal(t, "deltas", result[3].VarName)
+	assert.Equal(t, map[string]interface{}{"-2": "3"}, result[3].Value)
+
+	assert.Equal(t, "flags", result[4].VarName)
+	assert.Equal(t, map[string]interface{}{"true": "1"}, result[4].Value)
+}
+
+func TestParseRawStorage_MappingsWithoutKeys(t *testing.T) {
+	result, err := ParseRawStorage(setupMappingTest(), mappingTestLayout, nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{}, result[0].Value)
+	assert.Equal(t, map[string]interface{}{"true": "1"}, result[4].Value)
+}
+
+func TestParseVariable_MappingKeys(t *testing.T) {
+	storage := setupMappingTest()
+
+	result, err := ParseVariable(storage, mappingTestLayout, "balances[0x0000000000000000000000000000000000000011]", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "uint256", result.VarType)
+	assert.Equal(t, "100", result.Value)
+
+	result, err = ParseVariable(storage, mappingTestLayout, "allowed[0x0000000000000000000000000000000000000011][0x0000000000000000000000000000000000000022]", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "5", result.Value)
+
+	result, err = ParseVariable(storage, mappingTestLayout, "people[7].wallet", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, mappingTestHolder2, result.Value)
+
+	result, err = ParseVariable(storage, mappingTestLayout, "deltas[-2]", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "3", result.Value)
+
+	result, err = ParseVariable(storage, mappingTestLayout, "balances", setupMappingKeys())
+	assert.Nil(t, err)
+	assert.Len(t, result.Value, 2)
+}
+
+func TestParseVariable_InvalidMappingKeys(t *testing.T) {
+	storage := setupMappingTest()
+
+	_, err := ParseVariable(storage, mappingTestLayout, "balances[0x11]", nil)
+	assert.EqualError(t, err, "invalid address mapping key 0x11")
+
+	_, err = ParseVariable(storage, mappingTestLayout, "people[seven]", nil)
+	assert.EqualError(t, err, "invalid integer mapping key seven")
+
+	_, err = ParseVariable(storage, mappingTestLayout, "deltas[128]", nil)
+	assert.EqualError(t, err, "mapping key 128 out of range for int8")
+
+	_, err = ParseVariable(storage, mappingTestLayout, "flags[yes]", nil)
+	assert.EqualError(t, err, "invalid bool mapping key yes")
+}
+
+func TestHasMappings(t *testing.T) {
+	assert.True(t, HasMappings(mappingTestLayout))
+	assert.False(t, HasMappings(types.SolidityStorageDocument{
+		Types: map[string]types.SolidityTypeEntry{"t_uint256": {}},
+	}))
+}
+
+func TestNeedsMappingKeys(t *testing.T) {
+	_, variableLayout := setupVariableTest()
+
+	tests := []struct {
+		layout   types.SolidityStorageDocument
+		path     string
+		expected bool
+	}{
+		{mappingTestLayout, "balances", true},
+		{mappingTestLayout, "balances[0x0000000000000000000000000000000000000011]", false},
+		{mappingTestLayout, "allowed[0x0000000000000000000000000000000000000011]", true},
+		{mappingTestLayout, "people", true},
+		{mappingTestLayout, "people[1]", false},
+		{mappingTestLayout, "people[1].wallet", false},
+		{mappingTestLayout, "unknown", false},
+		{variableLayout, "fundersDyn", false},
+		{variableLayout, "longstruct.otherStruct", false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, NeedsMappingKeys(test.layout, test.path), "wrong result for path %s", test.path)
+	}
+}
@@ -4,8 +4,16 @@ import (
 	"quorumengineering/quorum-report/types"
 )
 
-func ParseRawStorage(rawStorage map[types.Hash]string, template types.SolidityStorageDocument) ([]*types.StorageItem, error) {
+func ParseRawStorage(rawStorage map[types.Hash]string, template types.SolidityStorageDocument, mappingKeys *MappingKeys) ([]*types.StorageItem, error) {
 	initialStorageManager := NewDefaultStorageHandler(rawStorage)
 	parser := NewParser(initialStorageManager, template, types.NewHash(""))
+	parser.SetMappingKeys(mappingKeys)
 	return parser.ParseRawStorage()
 }
+
+func ParseVariable(rawStorage map[types.Hash]string, template types.SolidityStorageDocument, path string, mappingKeys *MappingKeys) (*types.StorageItem, error) {
+	initialStorageManager := NewDefaultStorageHandler(rawStorage)
+	parser := NewParser(initialStorageManager, template, types.NewHash(""))
+	parser.SetMappingKeys(mappingKeys)
+	return parser.ParseVariable(path)
+}
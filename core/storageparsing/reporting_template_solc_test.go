@@ -103,7 +103,7 @@ const rawStorage = `
 }
 `
 
-const expectedOutput = `[{"name":"a","index":0,"type":"uint256","value":"42"},{"name":"b","index":0,"type":"uint8","value":"6"},{"name":"c","index":0,"type":"uint8","value":"9"},{"name":"d","index":0,"type":"int256","value":"-42"},{"name":"d2","index":0,"type":"int256","value":"65"},{"name":"d3","index":0,"type":"int8","value":"120"},{"name":"d4","index":0,"type":"int24","value":"-5445445"},{"name":"e","index":0,"type":"bool","value":true},{"name":"f","index":0,"type":"address","value":"0xdcad3a6d3569df655070ded06cb7a1b2ccd1d3af"},{"name":"g","index":0,"type":"contract SimpleStorage","value":"0xdcad3a6d3569df655070ded06cb7a1b2ccd1d3af"},{"name":"h1","index":0,"type":"bytes1","value":"0x01"},{"name":"h2","index":0,"type":"bytes1","value":"0x00"},{"name":"h3","index":0,"type":"bytes2","value":"0x1000"},{"name":"h4","index":0,"type":"bytes31","value":"0x10000000000000000000000000000000000000000000000000000000000000"},{"name":"h5","index":0,"type":"bytes32","value":"0x1000000000000000000000000000000000000000000000000000000000000000"},{"name":"choice","index":0,"type":"enum SimpleStorage.ActionChoices","value":0},{"name":"lessThan31","index":0,"type":"bytes","value":["0","1","2","3","4","5","6","7","8","9","a","b","c","d","e","f","10","11","12","13"]},{"name":"exactly31","index":0,"type":"bytes","value":["0","1","2","3","4","5","6","7","8","9","a","b","c","d","e","f","10","11","12","13","14","15","16","17","18","19","1a","1b","1c","1d","1e"]},{"name":"exactly32","index":0,"type":"bytes","value":["0","1","2","3","4","5","6","7","8","9","a","b","c","d","e","f","10","11","12","13","14","15","16","17","18","19","1a","1b","1c","1d","1e","1f"]},{"name":"moreThan31","index":0,"type":"bytes","value":["0","1","2","3","4","5","6","7","8","9","a","b","c","d","e","f","10","11","12","13","14","15","16","17","18","19","1a","1b","1c","1d","1e","1f","20","21","22","23","24","25","26","27","28","29","2a","2b","2c","2d","2e","2f","30","31","32","33","34","35","36","37","38","39","3a","3b","3c","3d","3e","3f","40","41","42","43","44","45","46","47","48","49","4a","4b","4c","4d","4e","4f","50","51","52","53","54","55","56","57","58","59","5a","5b","5c","5d","5e","5f","60","61","62","63"]},{"name":"i2","index":0,"type":"string","value":"mystring"},{"name":"i5","index":0,"type":"string","value":"my really long string that is definitely longer than the 32 byte limit"},{"name":"i6","index":0,"type":"string","value":"my really long string that is definitely longer than the 32 byte limit. my really long string that is definitely longer than the 32 byte limit. my really long string that is definitely longer than the 32 byte limit."},{"name":"h6","index":0,"type":"bytes1[]","value":["0x01"]},{"name":"h6long","index":0,"type":"bytes1[]","value":["0x00","0x01","0x02","0x03","0x04","0x05","0x06","0x07","0x08","0x09","0x0a","0x0b","0x0c","0x0d","0x0e","0x0f","0x10","0x11","0x12","0x13","0x14","0x15","0x16","0x17","0x18","0x19","0x1a","0x1b","0x1c","0x1d","0x1e","0x1f","0x20","0x21","0x22","0x23","0x24","0x25","0x26","0x27"]},{"name":"h7","index":0,"type":"bytes1[10]","value":["0x01","0x00","0x01","0x01","0x01","0x00","0x00","0x00","0x00","0x01"]},{"name":"h7long","index":0,"type":"bytes1[60]","value":["0x00","0x01","0x02","0x03","0x04","0x05","0x06","0x07","0x08","0x09","0x0a","0x0b","0x0c","0x0d","0x0e","0x0f","0x10","0x11","0x12","0x13","0x14","0x15","0x16","0x17","0x18","0x19","0x1a","0x1b","0x1c","0x1d","0x1e","0x1f","0x20","0x21","0x22","0x23","0x24","0x25","0x26","0x27","0x28","0x29","0x2a","0x2b","0x2c","0x2d","0x2e","0x2f","0x30","0x31","0x32","0x33","0x34","0x35","0x36","0x37","0x38","0x39","0x3a","0x00"]},{"name":"i3","index":0,"type":"address[]","value":[]},{"name":"i4","index":0,"type":"contract SimpleStorage[]","value":[]},{"name":"doubleArray","index":0,"type":"int256[][]","value":[["10","0","0","0","0","0","0"],["20","0","0","0","0","0","0"]]},{"name":"funder1","index":0,"type":"struct SimpleStorage.Funder","value":[{"name":"addr","index":0,"type":"string","value":"some addr"},{"name":"amount","index":0,"type":"uint256","value":"56"}]},{"name":"fundersFixed","index":0,"type":"struct SimpleStorage.Funder[2]","value":[[{"name":"addr","index":0,"type":"string","value":"some addr fixed 1"},{"name":"amount","index":0,"type":"uint256","value":"85"}],[{"name":"addr","index":0,"type":"string","value":"some addr fixed 2"},{"name":"amount","index":0,"type":"uint256","value":"6565"}]]},{"name":"fundersDyn","index":0,"type":"struct SimpleStorage.Funder[]","value":[[{"name":"addr","index":0,"type":"string","value":"some addr fixed 3"},{"name":"amount","index":0,"type":"uint256","value":"76309"}],[{"name":"addr","index":0,"type":"string","value":"some addr fixed 4"},{"name":"amount","index":0,"type":"uint256","value":"5876"}],[{"name":"addr","index":0,"type":"string","value":"some addr fixed 5"},{"name":"amount","index":0,"type":"uint256","value":"4875443"}]]},{"name":"longstruct","index":0,"type":"struct SimpleStorage.LongerStruct","value":[{"name":"addr","index":0,"type":"string","value":"some addr fixed 6"},{"name":"amount","index":0,"type":"uint256","value":"4875443"},{"name":"val","index":0,"type":"int8","value":"-6"},{"name":"otherval","index":0,"type":"uint8","value":"8"},{"name":"custommessage","index":0,"type":"string","value":"custom message"},{"name":"otherStruct","index":0,"type":"struct SimpleStorage.Funder","value":[{"name":"addr","index":0,"type":"string","value":"some addr"},{"name":"amount","index":0,"type":"uint256","value":"56"}]},{"name":"bigIntArray","index":0,"type":"int256[]","value":["56","0","0","43","32","0","65"]}]},{"name":"longstruct2","index":0,"type":"struct SimpleStorage.LongerStruct","value":[{"name":"addr","index":0,"type":"string","value":"some addr fixed 6"},{"name":"amount","index":0,"type":"uint256","value":"4875443"},{"name":"val","index":0,"type":"int8","value":"-6"},{"name":"otherval","index":0,"type":"uint8","value":"8"},{"name":"custommessage","index":0,"type":"string","value":"custom message"},{"name":"otherStruct","index":0,"type":"struct SimpleStorage.Funder","value":[{"name":"addr","index":0,"type":"string","value":"some addr fixed 1"},{"name":"amount","index":0,"type":"uint256","value":"85"}]},{"name":"bigIntArray","index":0,"type":"int256[]","value":["56","0","0","43","32","0","65"]}]},{"name":"longstruct3","index":0,"type":"struct SimpleStorage.LongerStruct","value":[{"name":"addr","index":0,"type":"string","value":"some addr fixed 6"},{"name":"amount","index":0,"type":"uint256","value":"4875443"},{"name":"val","index":0,"type":"int8","value":"-6"},{"name":"otherval","index":0,"type":"uint8","value":"8"},{"name":"custommessage","index":0,"type":"string","value":"custom message"},{"name":"otherStruct","index":0,"type":"struct SimpleStorage.Funder","value":[{"name":"addr","index":0,"type":"string","value":"mystr"},{"name":"amount","index":0,"type":"uint256","value":"877"}]},{"name":"bigIntArray","index":0,"type":"int256[]","value":["1","0","0","2","1","0","1"]}]}]`
+const expectedOutput = `[{"name":"a","index":0,"type":"uint256","value":"42"},{"name":"b","index":0,"type":"uint8","value":"6"},{"name":"c","index":0,"type":"uint8","value":"9"},{"name":"d","index":0,"type":"int256","value":"-42"},{"name":"d2","index":0,"type":"int256","value":"65"},{"name":"d3","index":0,"type":"int8","value":"120"},{"name":"d4","index":0,"type":"int24","value":"-5445445"},{"name":"e","index":0,"type":"bool","value":true},{"name":"f","index":0,"type":"address","value":"0xdcad3a6d3569df655070ded06cb7a1b2ccd1d3af"},{"name":"g","index":0,"type":"contract SimpleStorage","value":"0xdcad3a6d3569df655070ded06cb7a1b2ccd1d3af"},{"name":"h1","index":0,"type":"bytes1","value":"0x01"},{"name":"h2","index":0,"type":"bytes1","value":"0x00"},{"name":"h3","index":0,"type":"bytes2","value":"0x1000"},{"name":"h4","index":0,"type":"bytes31","value":"0x10000000000000000000000000000000000000000000000000000000000000"},{"name":"h5","index":0,"type":"bytes32","value":"0x1000000000000000000000000000000000000000000000000000000000000000"},{"name":"choice","index":0,"type":"enum SimpleStorage.ActionChoices","value":0},{"name":"lessThan31","index":0,"type":"bytes","value":["0","1","2","3","4","5","6","7","8","9","a","b","c","d","e","f","10","11","12","13"]},{"name":"exactly31","index":0,"type":"bytes","value":["0","1","2","3","4","5","6","7","8","9","a","b","c","d","e","f","10","11","12","13","14","15","16","17","18","19","1a","1b","1c","1d","1e"]},{"name":"exactly32","index":0,"type":"bytes","value":["0","1","2","3","4","5","6","7","8","9","a","b","c","d","e","f","10","11","12","13","14","15","16","17","18","19","1a","1b","1c","1d","1e","1f"]},{"name":"moreThan31","index":0,"type":"bytes","value":["0","1","2","3","4","5","6","7","8","9","a","b","c","d","e","f","10","11","12","13","14","15","16","17","18","19","1a","1b","1c","1d","1e","1f","20","21","22","23","24","25","26","27","28","29","2a","2b","2c","2d","2e","2f","30","31","32","33","34","35","36","37","38","39","3a","3b","3c","3d","3e","3f","40","41","42","43","44","45","46","47","48","49","4a","4b","4c","4d","4e","4f","50","51","52","53","54","55","56","57","58","59","5a","5b","5c","5d","5e","5f","60","61","62","63"]},{"name":"i2","index":0,"type":"string","value":"mystring"},{"name":"i5","index":0,"type":"string","value":"my really long string that is definitely longer than the 32 byte limit"},{"name":"i6","index":0,"type":"string","value":"my really long string that is definitely longer than the 32 byte limit. my really long string that is definitely longer than the 32 byte limit. my really long string that is definitely longer than the 32 byte limit."},{"name":"h6","index":0,"type":"bytes1[]","value":["0x01"]},{"name":"h6long","index":0,"type":"bytes1[]","value":["0x00","0x01","0x02","0x03","0x04","0x05","0x06","0x07","0x08","0x09","0x0a","0x0b","0x0c","0x0d","0x0e","0x0f","0x10","0x11","0x12","0x13","0x14","0x15","0x16","0x17","0x18","0x19","0x1a","0x1b","0x1c","0x1d","0x1e","0x1f","0x20","0x21","0x22","0x23","0x24","0x25","0x26","0x27"]},{"name":"h7","index":0,"type":"bytes1[10]","value":["0x01","0x00","0x01","0x01","0x01","0x00","0x00","0x00","0x00","0x01"]},{"name":"h7long","index":0,"type":"bytes1[60]","value":["0x00","0x01","0x02","0x03","0x04","0x05","0x06","0x07","0x08","0x09","0x0a","0x0b","0x0c","0x0d","0x0e","0x0f","0x10","0x11","0x12","0x13","0x14","0x15","0x16","0x17","0x18","0x19","0x1a","0x1b","0x1c","0x1d","0x1e","0x1f","0x20","0x21","0x22","0x23","0x24","0x25","0x26","0x27","0x28","0x29","0x2a","0x2b","0x2c","0x2d","0x2e","0x2f","0x30","0x31","0x32","0x33","0x34","0x35","0x36","0x37","0x38","0x39","0x3a","0x00"]},{"name":"i3","index":0,"type":"address[]","value":[]},{"name":"i4","index":0,"type":"contract SimpleStorage[]","value":[]},{"name":"doubleArray","index":0,"type":"int256[][]","value":[["10","0","0","0","0","0","0"],["20","0","0","0","0","0","0"]]},{"name":"funder1","index":0,"type":"struct SimpleStorage.Funder","value":[{"name":"addr","index":0,"type":"string","value":"some addr"},{"name":"amount","index":0,"type":"uint256","value":"56"}]},{"name":"fundersFixed","index":0,"type":"struct SimpleStorage.Funder[2]","value":[[{"name":"addr","index":0,"type":"string","value":"some addr fixed 1"},{"name":"amount","index":0,"type":"uint256","value":"85"}],[{"name":"addr","index":0,"type":"string","value":"some addr fixed 2"},{"name":"amount","index":0,"type":"uint256","value":"6565"}]]},{"name":"fundersDyn","index":0,"type":"struct SimpleStorage.Funder[]","value":[[{"name":"addr","index":0,"type":"string","value":"some addr fixed 3"},{"name":"amount","index":0,"type":"uint256","value":"76309"}],[{"name":"addr","index":0,"type":"string","value":"some addr fixed 4"},{"name":"amount","index":0,"type":"uint256","value":"5876"}],[{"name":"addr","index":0,"type":"string","value":"some addr fixed 5"},{"name":"amount","index":0,"type":"uint256","value":"4875443"}]]},{"name":"longstruct","index":0,"type":"struct SimpleStorage.LongerStruct","value":[{"name":"addr","index":0,"type":"string","value":"some addr fixed 6"},{"name":"amount","index":0,"type":"uint256","value":"4875443"},{"name":"val","index":0,"type":"int8","value":"-6"},{"name":"otherval","index":0,"type":"uint8","value":"8"},{"name":"custommessage","index":0,"type":"string","value":"custom message"},{"name":"otherStruct","index":0,"type":"struct SimpleStorage.Funder","value":[{"name":"addr","index":0,"type":"string","value":"some addr"},{"name":"amount","index":0,"type":"uint256","value":"56"}]},{"name":"bigIntArray","index":0,"type":"int256[]","value":["56","0","0","43","32","0","65"]}]},{"name":"longstruct2","index":0,"type":"struct SimpleStorage.LongerStruct","value":[{"name":"addr","index":0,"type":"string","value":"some addr fixed 6"},{"name":"amount","index":0,"type":"uint256","value":"4875443"},{"name":"val","index":0,"type":"int8","value":"-6"},{"name":"otherval","index":0,"type":"uint8","value":"8"},{"name":"custommessage","index":0,"type":"string","value":"custom message"},{"name":"otherStruct","index":0,"type":"struct SimpleStorage.Funder","value":[{"name":"addr","index":0,"type":"string","value":"some addr fixed 1"},{"name":"amount","index":0,"type":"uint256","value":"85"}]},{"name":"bigIntArray","index":0,"type":"int256[]","value":["56","0","0","43","32","0","65"]}]},{"name":"longstruct3","index":0,"type":"struct SimpleStorage.LongerStruct","value":[{"name":"addr","index":0,"type":"string","value":"some addr fixed 6"},{"name":"amount","index":0,"type":"uint256","value":"4875443"},{"name":"val","index":0,"type":"int8","value":"-6"},{"name":"otherval","index":0,"type":"uint8","value":"8"},{"name":"custommessage","index":0,"type":"string","value":"custom message"},{"name":"otherStruct","index":0,"type":"struct SimpleStorage.Funder","value":[{"name":"addr","index":0,"type":"string","value":"mystr"},{"name":"amount","index":0,"type":"uint256","value":"877"}]},{"name":"bigIntArray","index":0,"type":"int256[]","value":["1","0","0","2","1","0","1"]}]},{"name":"map","index":0,"type":"mapping(uint256 => uint256)","value":{}}]`
 
 func TestCorrectParsing(t *testing.T) {
 	var decodedStorage map[string]string
@@ -117,7 +117,7 @@ func TestCorrectParsing(t *testing.T) {
 	var decodedAbi types.SolidityStorageDocument
 	json.Unmarshal([]byte(storageABI), &decodedAbi)
 
-	output, err := ParseRawStorage(convertedStorage, decodedAbi)
+	output, err := ParseRawStorage(convertedStorage, decodedAbi, nil)
 
 	assert.Nil(t, err, "unexpected error")
 
@@ -11,6 +11,6 @@ func (p *Parser) ParseStruct(entry types.SolidityStorageEntry, namedType types.S
 		Types:   p.template.Types,
 	}
 
-	structParser := NewParser(p.storageManager, newTemplate, newOffset)
+	structParser := p.newChildParser(newTemplate, newOffset)
 	return structParser.ParseRawStorage()
 }
@@ -30,6 +30,8 @@ type Parser struct {
 	template       types.SolidityStorageDocument
 
 	slotOffset types.Hash
+
+	mappingKeys *MappingKeys
 }
 
 func NewParser(sm StorageManager, template types.SolidityStorageDocument, slotOffset types.Hash) *Parser {
@@ -44,6 +46,12 @@ func NewParser(sm StorageManager, template types.SolidityStorageDocument, slotOf
 	return parser
 }
 
+// SetMappingKeys sets the candidate keys that are used to find the values of
+// any mappings, which are otherwise skipped
+func (p *Parser) SetMappingKeys(keys *MappingKeys) {
+	p.mappingKeys = keys
+}
+
 func (p *Parser) ParseRawStorage() ([]*types.StorageItem, error) {
 	parsedStorage := []*types.StorageItem{}
 
@@ -127,11 +135,26 @@ func (p *Parser) parseSingle(storageItem types.SolidityStorageEntry) (interface{
 			return nil, err
 		}
 		result = res
+
+	case strings.HasPrefix(storageItem.Type, mappingPrefix):
+		res, err := p.ParseMapping(storageItem, namedType)
+		if err != nil {
+			return nil, err
+		}
+		result = res
 	}
 
 	return result, nil
 }
 
+// newChildParser creates a parser for a nested section of storage, such as a
+// struct or array, that shares the storage and mapping keys of this parser
+func (p *Parser) newChildParser(template types.SolidityStorageDocument, slotOffset types.Hash) *Parser {
+	child := NewParser(p.storageManager, template, slotOffset)
+	child.mappingKeys = p.mappingKeys
+	return child
+}
+
 func (p *Parser) ResolveSlot(givenSlot *big.Int) types.Hash {
 	offsetBytes, _ := hex.DecodeString(string(p.slotOffset))
 	combined := bigN(0).Add(new(big.Int).SetBytes(offsetBytes), givenSlot)
@@ -0,0 +1,246 @@
+package storageparsing
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"quorumengineering/quorum-report/types"
+)
+
+var ErrVariableNotFound = errors.New("variable not found in storage layout")
+
+// pathElement is a single step in a variable path, either a named member
+// (a top level variable or struct member) or an index into an array or mapping
+type pathElement struct {
+	member  string
+	index   string
+	isIndex bool
+}
+
+// ParseVariable parses a single variable from storage, given by a path such
+// as "owner" or "config.limits[2]". Only the storage slots needed to decode the
+// requested variable are read from the storage manager.
+func (p *Parser) ParseVariable(path string) (*types.StorageItem, error) {
+	elements, err := parseVariablePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := findMember(p.template.Storage, elements[0].member)
+	if err != nil {
+		return nil, err
+	}
+
+	currentParser := p
+	for _, element := range elements[1:] {
+		if element.isIndex {
+			currentParser, entry, err = currentParser.resolveIndex(entry, element.index)
+		} else {
+			currentParser, entry, err = currentParser.resolveMember(entry, element.member)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := currentParser.parseSingle(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.StorageItem{
+		VarName: path,
+		VarType: currentParser.template.Types[entry.Type].Label,
+		Value:   result,
+	}, nil
+}
+
+// NeedsMappingKeys reports whether candidate mapping keys are needed to parse the variable at the given path,
+// which is when the variable is, or contains, a mapping whose key is not given in the path. Invalid paths
+// need no keys, as they fail to parse regardless.
+func NeedsMappingKeys(template types.SolidityStorageDocument, path string) bool {
+	elements, err := parseVariablePath(path)
+	if err != nil {
+		return false
+	}
+	entry, err := findMember(template.Storage, elements[0].member)
+	if err != nil {
+		return false
+	}
+
+	typeName := entry.Type
+	for _, element := range elements[1:] {
+		namedType := template.Types[typeName]
+		switch {
+		case element.isIndex && strings.HasPrefix(typeName, mappingPrefix):
+			typeName = namedType.Value
+		case element.isIndex:
+			typeName = namedType.Base
+		default:
+			member, err := findMember(namedType.Members, element.member)
+			if err != nil {
+				return false
+			}
+			typeName = member.Type
+		}
+	}
+	return containsMapping(template.Types, typeName, make(map[string]bool))
+}
+
+// containsMapping reports whether the type is a mapping, or an array or struct with a mapping within it
+func containsMapping(namedTypes map[string]types.SolidityTypeEntry, typeName string, seen map[string]bool) bool {
+	if strings.HasPrefix(typeName, mappingPrefix) {
+		return true
+	}
+	if seen[typeName] {
+		return false
+	}
+	seen[typeName] = true
+
+	namedType := namedTypes[typeName]
+	if namedType.Base != "" && containsMapping(namedTypes, namedType.Base, seen) {
+		return true
+	}
+	for _, member := range namedType.Members {
+		if containsMapping(namedTypes, member.Type, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveMember returns a parser and entry positioned at the given member of a struct
+func (p *Parser) resolveMember(entry types.SolidityStorageEntry, member string) (*Parser, types.SolidityStorageEntry, error) {
+	if !strings.HasPrefix(entry.Type, structPrefix) {
+		return nil, types.SolidityStorageEntry{}, fmt.Errorf("cannot access member %s of non-struct variable %s", member, entry.Label)
+	}
+	namedType := p.template.Types[entry.Type]
+
+	newTemplate := types.SolidityStorageDocument{
+		Storage: namedType.Members,
+		Types:   p.template.Types,
+	}
+	structParser := p.newChildParser(newTemplate, p.ResolveSlot(bigN(entry.Slot)))
+
+	memberEntry, err := findMember(structParser.template.Storage, member)
+	if err != nil {
+		return nil, types.SolidityStorageEntry{}, err
+	}
+	return structParser, memberEntry, nil
+}
+
+// resolveIndex returns a parser and entry positioned at the given element of an array,
+// or the value of the given key in a mapping
+func (p *Parser) resolveIndex(entry types.SolidityStorageEntry, index string) (*Parser, types.SolidityStorageEntry, error) {
+	if strings.HasPrefix(entry.Type, mappingPrefix) {
+		return p.resolveMappingKey(entry, index)
+	}
+	if !strings.HasPrefix(entry.Type, arrayPrefix) {
+		return nil, types.SolidityStorageEntry{}, fmt.Errorf("cannot index variable %s, which is not an array or mapping", entry.Label)
+	}
+	namedType := p.template.Types[entry.Type]
+
+	parsedIndex, err := strconv.ParseUint(index, 10, 0)
+	if err != nil {
+		return nil, types.SolidityStorageEntry{}, fmt.Errorf("invalid array index %s", index)
+	}
+
+	isDynamic := namedType.Encoding == "dynamic_array"
+	sizeOfArray, err := p.determineSize(entry, isDynamic)
+	if err != nil {
+		return nil, types.SolidityStorageEntry{}, err
+	}
+	if parsedIndex >= sizeOfArray {
+		return nil, types.SolidityStorageEntry{}, fmt.Errorf("array index %d out of range for %s of length %d", parsedIndex, entry.Label, sizeOfArray)
+	}
+
+	storageSlot := p.ResolveSlot(bigN(entry.Slot))
+	if isDynamic {
+		storageSlot = hash(storageSlot)
+	}
+
+	sizeOfElement := p.template.Types[namedType.Base].NumberOfBytes
+	slot, offset := arrayElementPosition(parsedIndex, sizeOfElement)
+
+	newTemplate := types.SolidityStorageDocument{
+		Types: p.template.Types,
+	}
+	elementEntry := types.SolidityStorageEntry{
+		Label:  fmt.Sprintf("%s[%d]", entry.Label, parsedIndex),
+		Offset: offset,
+		Slot:   slot,
+		Type:   namedType.Base,
+	}
+	return p.newChildParser(newTemplate, storageSlot), elementEntry, nil
+}
+
+// resolveMappingKey returns a parser and entry positioned at the value of the given key in a mapping
+func (p *Parser) resolveMappingKey(entry types.SolidityStorageEntry, key string) (*Parser, types.SolidityStorageEntry, error) {
+	namedType := p.template.Types[entry.Type]
+
+	encodedKey, err := p.encodeMappingKey(namedType.Key, key)
+	if err != nil {
+		return nil, types.SolidityStorageEntry{}, err
+	}
+
+	newTemplate := types.SolidityStorageDocument{
+		Types: p.template.Types,
+	}
+	valueEntry := types.SolidityStorageEntry{
+		Label: fmt.Sprintf("%s[%s]", entry.Label, encodedKey.display),
+		Type:  namedType.Value,
+	}
+	valueSlot := hashMappingKey(encodedKey.encoded, p.ResolveSlot(bigN(entry.Slot)))
+	return p.newChildParser(newTemplate, valueSlot), valueEntry, nil
+}
+
+// arrayElementPosition calculates the slot and offset of an array element
+// relative to the start of the array, following the same packing rules as
+// createArrayStorageDocument
+func arrayElementPosition(index uint64, sizeOfElement uint64) (uint64, uint64) {
+	if sizeOfElement >= 32 {
+		return index * (roundUpTo32(sizeOfElement) / 32), 0
+	}
+	elementsPerSlot := 32 / sizeOfElement
+	return index / elementsPerSlot, (index % elementsPerSlot) * sizeOfElement
+}
+
+func findMember(entries types.SolidityStorageEntries, name string) (types.SolidityStorageEntry, error) {
+	for _, entry := range entries {
+		if entry.Label == name {
+			return entry, nil
+		}
+	}
+	return types.SolidityStorageEntry{}, ErrVariableNotFound
+}
+
+// parseVariablePath splits a path such as "config.limits[2]" into its members
+// and indices
+func parseVariablePath(path string) ([]pathElement, error) {
+	invalidPathErr := fmt.Errorf("invalid variable path %q", path)
+
+	var elements []pathElement
+	for _, segment := range strings.Split(path, ".") {
+		nameEnd := strings.Index(segment, "[")
+		if nameEnd == -1 {
+			nameEnd = len(segment)
+		}
+		if nameEnd == 0 {
+			return nil, invalidPathErr
+		}
+		elements = append(elements, pathElement{member: segment[:nameEnd]})
+
+		remaining := segment[nameEnd:]
+		for len(remaining) > 0 {
+			closing := strings.Index(remaining, "]")
+			if remaining[0] != '[' || closing < 2 {
+				return nil, invalidPathErr
+			}
+			elements = append(elements, pathElement{index: remaining[1:closing], isIndex: true})
+			remaining = remaining[closing+1:]
+		}
+	}
+	return elements, nil
+}
@@ -0,0 +1,126 @@
+package storageparsing
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"quorumengineering/quorum-report/types"
+)
+
+func setupVariableTest() (map[types.Hash]string, types.SolidityStorageDocument) {
+	var decodedStorage map[string]string
+	json.Unmarshal([]byte(rawStorage), &decodedStorage)
+
+	convertedStorage := make(map[types.Hash]string)
+	for k, v := range decodedStorage {
+		convertedStorage[types.NewHash(k)] = v
+	}
+
+	var decodedAbi types.SolidityStorageDocument
+	json.Unmarshal([]byte(storageABI), &decodedAbi)
+
+	return convertedStorage, decodedAbi
+}
+
+func TestParseVariable(t *testing.T) {
+	storage, layout := setupVariableTest()
+
+	tests := []struct {
+		path         string
+		expectedType string
+		expected     interface{}
+	}{
+		{"a", "uint256", "42"},
+		{"c", "uint8", "9"},
+		{"i5", "string", "my really long string that is definitely longer than the 32 byte limit"},
+		{"h7[9]", "bytes1", "0x01"},
+		{"h7long[58]", "bytes1", "0x3a"},
+		{"doubleArray[1][0]", "int256", "20"},
+		{"funder1.amount", "uint256", "56"},
+		{"fundersFixed[1].addr", "string", "some addr fixed 2"},
+		{"fundersDyn[2].amount", "uint256", "4875443"},
+		{"longstruct.otherStruct.addr", "string", "some addr"},
+		{"longstruct3.bigIntArray[3]", "int256", "2"},
+	}
+
+	for _, test := range tests {
+		result, err := ParseVariable(storage, layout, test.path, nil)
+
+		assert.Nil(t, err, "unexpected error for path %s", test.path)
+		assert.Equal(t, test.path, result.VarName)
+		assert.Equal(t, test.expectedType, result.VarType, "wrong type for path %s", test.path)
+		assert.Equal(t, test.expected, result.Value, "wrong value for path %s", test.path)
+	}
+}
+
+func TestParseVariable_WholeArray(t *testing.T) {
+	storage, layout := setupVariableTest()
+
+	result, err := ParseVariable(storage, layout, "fundersFixed[0]", nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "struct SimpleStorage.Funder", result.VarType)
+	assert.Equal(t, []*types.StorageItem{
+		{VarName: "addr", VarType: "string", Value: "some addr fixed 1"},
+		{VarName: "amount", VarType: "uint256", Value: "85"},
+	}, result.Value)
+}
+
+func TestParseVariable_Errors(t *testing.T) {
+	storage, layout := setupVariableTest()
+
+	_, err := ParseVariable(storage, layout, "doesNotExist", nil)
+	assert.Equal(t, ErrVariableNotFound, err)
+
+	_, err = ParseVariable(storage, layout, "funder1.doesNotExist", nil)
+	assert.Equal(t, ErrVariableNotFound, err)
+
+	_, err = ParseVariable(storage, layout, "a.b", nil)
+	assert.EqualError(t, err, "cannot access member b of non-struct variable a")
+
+	_, err = ParseVariable(storage, layout, "a[0]", nil)
+	assert.EqualError(t, err, "cannot index variable a, which is not an array or mapping")
+
+	_, err = ParseVariable(storage, layout, "h7[10]", nil)
+	assert.EqualError(t, err, "array index 10 out of range for h7 of length 10")
+
+	_, err = ParseVariable(storage, layout, "h7[x]", nil)
+	assert.EqualError(t, err, "invalid array index x")
+}
+
+func TestParseVariablePath(t *testing.T) {
+	elements, err := parseVariablePath("config.limits[2][3]")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []pathElement{
+		{member: "config"},
+		{member: "limits"},
+		{index: "2", isIndex: true},
+		{index: "3", isIndex: true},
+	}, elements)
+}
+
+func TestParseVariablePath_Invalid(t *testing.T) {
+	invalidPaths := []string{"", "a..b", "[1]", "a[]", "a[1", "a]1[", "a[1]b"}
+
+	for _, path := range invalidPaths {
+		_, err := parseVariablePath(path)
+		assert.EqualError(t, err, "invalid variable path \""+path+"\"")
+	}
+}
+
+func TestArrayElementPosition_MatchesArrayStorageDocument(t *testing.T) {
+	parser := NewParser(nil, types.SolidityStorageDocument{}, types.NewHash(""))
+
+	for _, sizeOfElement := range []uint64{1, 2, 11, 16, 17, 20, 32, 64, 224} {
+		document := parser.createArrayStorageDocument(20, sizeOfElement, "")
+
+		for i, entry := range document.Storage {
+			slot, offset := arrayElementPosition(uint64(i), sizeOfElement)
+			assert.Equal(t, entry.Slot, slot, "wrong slot for element %d of size %d", i, sizeOfElement)
+			assert.Equal(t, entry.Offset, offset, "wrong offset for element %d of size %d", i, sizeOfElement)
+		}
+	}
+}
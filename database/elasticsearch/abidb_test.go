@@ -11,6 +11,7 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 
+	"quorumengineering/quorum-report/database"
 	elasticsearchmocks "quorumengineering/quorum-report/database/elasticsearch/mocks"
 	"quorumengineering/quorum-report/types"
 )
@@ -23,10 +24,15 @@ func TestElasticsearchDB_AddTemplate(t *testing.T) {
 
 	template := Template{
 		TemplateName: "test template",
+		Version:      1,
 		ABI:          "test abi",
 		StorageABI:   "test storage",
 	}
 
+	templateSearchRequest := esapi.GetRequest{
+		Index:      TemplateIndex,
+		DocumentID: template.TemplateName,
+	}
 	ex := esapi.IndexRequest{
 		Index:      TemplateIndex,
 		DocumentID: template.TemplateName,
@@ -35,15 +41,101 @@ func TestElasticsearchDB_AddTemplate(t *testing.T) {
 	}
 
 	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().DoRequest(NewGetRequestMatcher(templateSearchRequest)).Return(nil, database.ErrNotFound)
 	mockedClient.EXPECT().DoRequest(NewIndexRequestMatcher(ex))
 
 	db, err := New(mockedClient)
 
-	err = db.AddTemplate(template.TemplateName, template.ABI, template.StorageABI)
+	err = db.AddTemplate(&types.Template{
+		TemplateName:  template.TemplateName,
+		ABI:           template.ABI,
+		StorageLayout: template.StorageABI,
+	})
 
 	assert.Nil(t, err, "expected error to be nil")
 }
 
+func TestElasticsearchDB_AddTemplate_NewVersion(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+
+	templateSearchRequest := esapi.GetRequest{
+		Index:      TemplateIndex,
+		DocumentID: "test template",
+	}
+	templateSearchReturnValue := `{
+        "_source": {
+          "templateName": "test template",
+          "version": 2,
+          "abi": "second abi",
+          "previousVersions": [{"templateName": "test template", "version": 1, "abi": "first abi"}]
+        }
+	}`
+	expectedTemplate := Template{
+		TemplateName: "test template",
+		Version:      3,
+		ABI:          "third abi",
+		PreviousVersions: []Template{
+			{TemplateName: "test template", Version: 1, ABI: "first abi"},
+			{TemplateName: "test template", Version: 2, ABI: "second abi"},
+		},
+	}
+	ex := esapi.IndexRequest{
+		Index:      TemplateIndex,
+		DocumentID: "test template",
+		Body:       esutil.NewJSONReader(expectedTemplate),
+		Refresh:    "true",
+	}
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().DoRequest(NewGetRequestMatcher(templateSearchRequest)).Return([]byte(templateSearchReturnValue), nil)
+	mockedClient.EXPECT().DoRequest(NewIndexRequestMatcher(ex))
+
+	db, _ := New(mockedClient)
+
+	err := db.AddTemplate(&types.Template{TemplateName: "test template", ABI: "third abi"})
+
+	assert.Nil(t, err, "expected error to be nil")
+}
+
+func TestElasticsearchDB_GetTemplateVersion(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+
+	templateSearchRequest := esapi.GetRequest{
+		Index:      TemplateIndex,
+		DocumentID: "test template",
+	}
+	templateSearchReturnValue := `{
+        "_source": {
+          "templateName": "test template",
+          "version": 2,
+          "abi": "second abi",
+          "previousVersions": [{"templateName": "test template", "version": 1, "abi": "first abi", "storageAbi": "first layout"}]
+        }
+	}`
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().DoRequest(NewGetRequestMatcher(templateSearchRequest)).Return([]byte(templateSearchReturnValue), nil).Times(3)
+
+	db, _ := New(mockedClient)
+
+	template, err := db.GetTemplateVersion("test template", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, &types.Template{TemplateName: "test template", Version: 1, ABI: "first abi", StorageLayout: "first layout"}, template)
+
+	template, err = db.GetTemplateVersion("test template", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, &types.Template{TemplateName: "test template", Version: 2, ABI: "second abi"}, template)
+
+	_, err = db.GetTemplateVersion("test template", 3)
+	assert.Equal(t, database.ErrNotFound, err)
+}
+
 func TestElasticsearchDB_AssignTemplate(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -67,7 +159,8 @@ func TestElasticsearchDB_AssignTemplate(t *testing.T) {
 	}`
 	contractQuery := map[string]interface{}{
 		"doc": map[string]interface{}{
-			"templateName": templateName,
+			"templateName":        templateName,
+			"templateAssignments": nil,
 		},
 	}
 	contractUpdateRequest := esapi.UpdateRequest{
@@ -88,6 +181,53 @@ func TestElasticsearchDB_AssignTemplate(t *testing.T) {
 	assert.Nil(t, err, "expected error to be nil")
 }
 
+func TestElasticsearchDB_AssignTemplateFromBlock(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+
+	addr := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+
+	searchContractRequest := esapi.GetRequest{
+		Index:      ContractIndex,
+		DocumentID: addr.String(),
+	}
+	contractSearchReturnValue := `{
+	       "_source": {
+	         "address" : "0x1932c48b2bf8102ba33b4a6b545c32236e342f34",
+	         "creationTx" : "0xd09fc502b74c7e6015e258e3aed2d724cb50317684a46e00355e50b1b21c6446",
+	         "lastFiltered" : 20,
+	         "templateName": "old template"
+	       }
+	}`
+	contractQuery := map[string]interface{}{
+		"doc": map[string]interface{}{
+			"templateName": "new template",
+			"templateAssignments": []*types.TemplateAssignment{
+				{TemplateName: "old template"},
+				{TemplateName: "new template", Version: 2, FromBlock: 10},
+			},
+		},
+	}
+	contractUpdateRequest := esapi.UpdateRequest{
+		Index:      ContractIndex,
+		DocumentID: addr.String(),
+		Body:       esutil.NewJSONReader(contractQuery),
+		Refresh:    "true",
+	}
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().DoRequest(NewGetRequestMatcher(searchContractRequest)).Return([]byte(contractSearchReturnValue), nil).Times(2)
+	mockedClient.EXPECT().DoRequest(NewUpdateRequestMatcher(contractUpdateRequest))
+
+	db, _ := New(mockedClient)
+
+	err := db.AssignTemplateFromBlock(addr, &types.TemplateAssignment{TemplateName: "new template", Version: 2, FromBlock: 10})
+
+	assert.Nil(t, err, "expected error to be nil")
+}
+
 func TestElasticsearchDB_GetContractABI(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -323,3 +323,48 @@ func TestElasticsearchDB_GetAddresses_WithError(t *testing.T) {
 	assert.Nil(t, allAddresses, "error was not nil")
 	assert.EqualError(t, err, "error fetching addresses: test error", "wrong error message")
 }
+
+func TestElasticsearchDB_RecordImplementation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+
+	addr := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+
+	searchContractRequest := esapi.GetRequest{
+		Index:      ContractIndex,
+		DocumentID: addr.String(),
+	}
+	contractSearchReturnValue := `{
+	       "_source": {
+	         "address" : "0x1932c48b2bf8102ba33b4a6b545c32236e342f34",
+	         "lastFiltered" : 20,
+	         "implementations": [{"implementation": "0x0000000000000000000000000000000000000001", "fromBlock": 5}]
+	       }
+	}`
+	contractQuery := map[string]interface{}{
+		"doc": map[string]interface{}{
+			"implementations": []*types.ProxyImplementation{
+				{Implementation: types.NewAddress("0x1"), FromBlock: 5},
+				{Implementation: types.NewAddress("0x2"), FromBlock: 10},
+			},
+		},
+	}
+	contractUpdateRequest := esapi.UpdateRequest{
+		Index:      ContractIndex,
+		DocumentID: addr.String(),
+		Body:       esutil.NewJSONReader(contractQuery),
+		Refresh:    "true",
+	}
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().DoRequest(NewGetRequestMatcher(searchContractRequest)).Return([]byte(contractSearchReturnValue), nil).Times(2)
+	mockedClient.EXPECT().DoRequest(NewUpdateRequestMatcher(contractUpdateRequest))
+
+	db, _ := New(mockedClient)
+
+	err := db.RecordImplementation(addr, &types.ProxyImplementation{Implementation: types.NewAddress("0x2"), FromBlock: 10})
+
+	assert.Nil(t, err, "expected error to be nil")
+}
@@ -1,6 +1,7 @@
 package elasticsearch
 
 import (
+	"quorumengineering/quorum-report/database"
 	"quorumengineering/quorum-report/types"
 )
 
@@ -9,8 +10,11 @@ type DefaultBlockIndexer struct {
 	blocks    []*types.Block
 	// function pointers currently originated from ES database implementation only
 	// TODO: May convert all functions into an interface. DefaultBlockIndexer can then accept all database implementation and move to a util package.
-	createEvents    func([]*types.Event) error
+	createEvents    func([]*IndexedEvent) error
+	updateCalls     func(map[types.Hash]*types.DecodedCall) error
 	readTransaction func(types.Hash) (*types.Transaction, error)
+	decodeEvent     func(*types.Event) (*types.DecodedEvent, error)
+	decodeCall      func(*types.Transaction) (*types.DecodedCall, error)
 }
 
 func NewBlockIndexer(addresses []types.Address, blocks []*types.Block, db *ElasticsearchDB) *DefaultBlockIndexer {
@@ -19,11 +23,15 @@ func NewBlockIndexer(addresses []types.Address, blocks []*types.Block, db *Elast
 		addressMap[address] = true
 	}
 
+	decoder := database.NewTemplateDecoder(db)
 	return &DefaultBlockIndexer{
 		addresses:       addressMap,
 		blocks:          blocks,
 		createEvents:    db.createEvents,
+		updateCalls:     db.updateCalls,
 		readTransaction: db.ReadTransaction,
+		decodeEvent:     decoder.DecodeEvent,
+		decodeCall:      decoder.DecodeCall,
 	}
 }
 
@@ -33,15 +41,43 @@ func (indexer *DefaultBlockIndexer) Index() error {
 		return err
 	}
 
+	if err := indexer.indexCalls(allTransactions); err != nil {
+		return err
+	}
 	return indexer.indexEvents(allTransactions)
 }
 
+// indexCalls adds the decoded function calls to the transactions sent to the indexed addresses
+func (indexer *DefaultBlockIndexer) indexCalls(transactions []*types.Transaction) error {
+	pendingCalls := make(map[types.Hash]*types.DecodedCall)
+	for _, transaction := range transactions {
+		if !indexer.addresses[transaction.To] {
+			continue
+		}
+		decoded, err := indexer.decodeCall(transaction)
+		if err != nil {
+			return err
+		}
+		if decoded != nil {
+			pendingCalls[transaction.Hash] = decoded
+		}
+	}
+	if len(pendingCalls) == 0 {
+		return nil
+	}
+	return indexer.updateCalls(pendingCalls)
+}
+
 func (indexer *DefaultBlockIndexer) indexEvents(transactions []*types.Transaction) error {
-	var pendingIndexEvents []*types.Event
+	var pendingIndexEvents []*IndexedEvent
 	for _, transaction := range transactions {
 		for _, event := range transaction.Events {
 			if indexer.addresses[event.Address] {
-				pendingIndexEvents = append(pendingIndexEvents, event)
+				decoded, err := indexer.decodeEvent(event)
+				if err != nil {
+					return err
+				}
+				pendingIndexEvents = append(pendingIndexEvents, &IndexedEvent{Event: event, DecodedEvent: decoded})
 			}
 		}
 	}
@@ -74,12 +74,12 @@ func TestDefaultBlockIndexer_Index_UnableToReadTransaction(t *testing.T) {
 }
 
 func TestDefaultBlockIndexer_IndexTransaction_AllRelevantEventsIndexed(t *testing.T) {
-	var indexedEvents []*types.Event
+	var indexedEvents []*IndexedEvent
 
 	blockIndexer := &DefaultBlockIndexer{
 		addresses: map[types.Address]bool{types.NewAddress("0x9d13c6d3afe1721beef56b55d303b09e021e27ab"): true},
 		blocks:    []*types.Block{testIndexBlock},
-		createEvents: func(events []*types.Event) error {
+		createEvents: func(events []*IndexedEvent) error {
 			indexedEvents = events
 			return nil
 		},
@@ -89,6 +89,9 @@ func TestDefaultBlockIndexer_IndexTransaction_AllRelevantEventsIndexed(t *testin
 			}
 			return nil, errors.New("test error: not found")
 		},
+		decodeEvent: func(event *types.Event) (*types.DecodedEvent, error) {
+			return nil, nil
+		},
 	}
 
 	err := blockIndexer.Index()
@@ -101,7 +104,7 @@ func TestDefaultBlockIndexer_IndexTransaction_IndexEventsError(t *testing.T) {
 	blockIndexer := &DefaultBlockIndexer{
 		addresses: map[types.Address]bool{types.NewAddress("0x9d13c6d3afe1721beef56b55d303b09e021e27ab"): true},
 		blocks:    []*types.Block{testIndexBlock},
-		createEvents: func(events []*types.Event) error {
+		createEvents: func(events []*IndexedEvent) error {
 			return errors.New("test error: createEvents")
 		},
 		readTransaction: func(hash types.Hash) (*types.Transaction, error) {
@@ -110,9 +113,95 @@ func TestDefaultBlockIndexer_IndexTransaction_IndexEventsError(t *testing.T) {
 			}
 			return nil, errors.New("test error: not found")
 		},
+		decodeEvent: func(event *types.Event) (*types.DecodedEvent, error) {
+			return nil, nil
+		},
 	}
 
 	err := blockIndexer.Index()
 
 	assert.EqualError(t, err, "test error: createEvents")
 }
+
+func TestDefaultBlockIndexer_IndexTransaction_DecodedEventsIndexed(t *testing.T) {
+	var indexedEvents []*IndexedEvent
+	decoded := &types.DecodedEvent{Name: "Transfer", Sig: "Transfer(address,address,uint256)"}
+
+	blockIndexer := &DefaultBlockIndexer{
+		addresses: map[types.Address]bool{types.NewAddress("0x9d13c6d3afe1721beef56b55d303b09e021e27ab"): true},
+		blocks:    []*types.Block{testIndexBlock},
+		createEvents: func(events []*IndexedEvent) error {
+			indexedEvents = events
+			return nil
+		},
+		readTransaction: func(hash types.Hash) (*types.Transaction, error) {
+			if tx, ok := indexTransactionMap[hash.String()]; ok {
+				return tx, nil
+			}
+			return nil, errors.New("test error: not found")
+		},
+		decodeEvent: func(event *types.Event) (*types.DecodedEvent, error) {
+			return decoded, nil
+		},
+	}
+
+	err := blockIndexer.Index()
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(indexedEvents))
+	assert.Equal(t, decoded, indexedEvents[0].DecodedEvent)
+}
+
+func TestDefaultBlockIndexer_IndexTransaction_DecodeEventError(t *testing.T) {
+	blockIndexer := &DefaultBlockIndexer{
+		addresses: map[types.Address]bool{types.NewAddress("0x9d13c6d3afe1721beef56b55d303b09e021e27ab"): true},
+		blocks:    []*types.Block{testIndexBlock},
+		readTransaction: func(hash types.Hash) (*types.Transaction, error) {
+			if tx, ok := indexTransactionMap[hash.String()]; ok {
+				return tx, nil
+			}
+			return nil, errors.New("test error: not found")
+		},
+		decodeEvent: func(event *types.Event) (*types.DecodedEvent, error) {
+			return nil, errors.New("test error: decodeEvent")
+		},
+	}
+
+	err := blockIndexer.Index()
+
+	assert.EqualError(t, err, "test error: decodeEvent")
+}
+
+func TestDefaultBlockIndexer_IndexTransaction_DecodedCallsIndexed(t *testing.T) {
+	var indexedCalls map[types.Hash]*types.DecodedCall
+	contract := types.NewAddress("0x9d13c6d3afe1721beef56b55d303b09e021e27ab")
+	tx := &types.Transaction{
+		Hash: types.NewHash("0x5c83fa5955aff33c61813105851777bcd2adc85deb9af6286ba42c05cd768de0"),
+		To:   contract,
+		Data: types.NewHexData("0x6d4ce63c"),
+	}
+
+	blockIndexer := &DefaultBlockIndexer{
+		addresses: map[types.Address]bool{contract: true},
+		blocks:    []*types.Block{{Number: 10, Transactions: []types.Hash{tx.Hash}}},
+		createEvents: func(events []*IndexedEvent) error {
+			return nil
+		},
+		updateCalls: func(calls map[types.Hash]*types.DecodedCall) error {
+			indexedCalls = calls
+			return nil
+		},
+		readTransaction: func(hash types.Hash) (*types.Transaction, error) {
+			return tx, nil
+		},
+		decodeCall: func(tx *types.Transaction) (*types.DecodedCall, error) {
+			return &types.DecodedCall{Name: "get", Sig: "get()", Selector: "0x6d4ce63c"}, nil
+		},
+	}
+
+	err := blockIndexer.Index()
+
+	assert.Nil(t, err)
+	assert.Len(t, indexedCalls, 1)
+	assert.Equal(t, "get", indexedCalls[tx.Hash].Name)
+}
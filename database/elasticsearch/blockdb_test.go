@@ -332,6 +332,43 @@ func TestElasticsearchDB_ReadBlock(t *testing.T) {
 	assert.Equal(t, &testBlock, block, "unexpected block output")
 }
 
+func TestElasticsearchDB_ReadBlocks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockedClient := elasticsearch_mocks.NewMockAPIClient(ctrl)
+
+	query := `
+{
+	"query": {
+		"terms": { "number": [10, 11] }
+	}
+}
+`
+	size := 2
+	searchRequest := esapi.SearchRequest{
+		Index: []string{BlockIndex},
+		Body:  strings.NewReader(query),
+		Size:  &size,
+	}
+	otherBlock := testBlock
+	otherBlock.Number = 11
+	testBlockAsJson, _ := json.Marshal(testBlock)
+	otherBlockAsJson, _ := json.Marshal(otherBlock)
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().
+		DoRequest(NewSearchRequestMatcher(searchRequest)).
+		Return([]byte(fmt.Sprintf(`{"hits": {"hits": [{"_source": %s}, {"_source": %s}]}}`, otherBlockAsJson, testBlockAsJson)), nil)
+
+	db, _ := New(mockedClient)
+
+	blocks, err := db.ReadBlocks([]uint64{10, 11})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []*types.Block{&testBlock, &otherBlock}, blocks)
+}
+
 func TestElasticsearchDB_WriteBlocks_NoBlock(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
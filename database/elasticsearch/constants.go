@@ -4,21 +4,29 @@ import "errors"
 
 // indices
 const (
-	MetaIndex        = "meta"
-	ContractIndex    = "contract"
-	TemplateIndex    = "template"
-	BlockIndex       = "block"
-	StorageIndex     = "storage"
-	TransactionIndex = "transaction"
-	EventIndex       = "event"
-	ERC20TokenIndex  = "erc20token"
-	ERC721TokenIndex = "erc721token"
+	MetaIndex           = "meta"
+	ContractIndex       = "contract"
+	TemplateIndex       = "template"
+	BlockIndex          = "block"
+	StorageIndex        = "storage"
+	TransactionIndex    = "transaction"
+	EventIndex          = "event"
+	ERC20TokenIndex     = "erc20token"
+	ERC20TransferIndex  = "erc20transfer"
+	ERC20AllowanceIndex = "erc20allowance"
+	ERC20MetadataIndex  = "erc20metadata"
+	ERC20SupplyIndex    = "erc20supply"
+	ERC721MetadataIndex = "erc721metadata"
+	ERC721TokenIndex    = "erc721token"
+	ERC1155TokenIndex   = "erc1155token"
+	SampleIndex         = "sample"
 )
 
 var (
-	AllIndexes = []string{MetaIndex, ContractIndex, TemplateIndex, BlockIndex, StorageIndex, TransactionIndex, EventIndex, ERC20TokenIndex, ERC721TokenIndex}
+	AllIndexes = []string{MetaIndex, ContractIndex, TemplateIndex, BlockIndex, StorageIndex, TransactionIndex, EventIndex, ERC20TokenIndex, ERC20TransferIndex, ERC20AllowanceIndex, ERC20MetadataIndex, ERC20SupplyIndex, ERC721TokenIndex, ERC721MetadataIndex, ERC1155TokenIndex, SampleIndex}
 	// errors
 	ErrCouldNotResolveResp     = errors.New("could not resolve response body")
 	ErrIndexNotFound           = errors.New("index not found")
 	ErrPaginationLimitExceeded = errors.New("pagination limit exceeded")
+	ErrTooManyResults          = errors.New("too many results, the block range must be narrower")
 )
@@ -50,8 +50,15 @@ func NewWithDeps(client APIClient, dataDeleter DeletionCoordinator) (*Elasticsea
 	return db, nil
 }
 
+// the decoded call inputs of transactions and arguments of events are nested, so that each filter matches the name and
+// value of the same argument
+const (
+	transactionProperties = `{"internalCalls": {"type": "nested" }, "call": {"properties": {"name": {"type": "keyword"}, "sig": {"type": "keyword"}, "selector": {"type": "keyword"}, "inputs": {"type": "nested", "properties": {"name": {"type": "keyword"}, "value": {"type": "keyword"}, "number": {"type": "keyword"}}}}}}`
+	eventProperties       = `{"eventName": {"type": "keyword"}, "eventSig": {"type": "keyword"}, "args": {"type": "nested", "properties": {"name": {"type": "keyword"}, "value": {"type": "keyword"}, "number": {"type": "keyword"}}}}`
+)
+
 func (es *ElasticsearchDB) init() error {
-	mapping := `{"mappings":{"properties": {"internalCalls": {"type": "nested" }}}}`
+	mapping := `{"mappings":{"properties": ` + transactionProperties + `}}`
 	createRequest := esapi.IndicesCreateRequest{
 		Index: TransactionIndex,
 		Body:  strings.NewReader(mapping),
@@ -63,10 +70,39 @@ func (es *ElasticsearchDB) init() error {
 	es.apiClient.DoRequest(esapi.IndicesCreateRequest{Index: ContractIndex})
 	es.apiClient.DoRequest(esapi.IndicesCreateRequest{Index: TemplateIndex})
 	es.apiClient.DoRequest(esapi.IndicesCreateRequest{Index: StorageIndex})
-	es.apiClient.DoRequest(esapi.IndicesCreateRequest{Index: EventIndex})
+	eventMapping := `{"mappings":{"properties": ` + eventProperties + `}}`
+	es.apiClient.DoRequest(esapi.IndicesCreateRequest{
+		Index: EventIndex,
+		Body:  strings.NewReader(eventMapping),
+	})
 	es.apiClient.DoRequest(esapi.IndicesCreateRequest{Index: MetaIndex})
 	es.apiClient.DoRequest(esapi.IndicesCreateRequest{Index: ERC20TokenIndex})
+	// amounts are compared by range in their sortable form, so must not be analysed as text
+	transferMapping := `{"mappings":{"properties": {"contract": {"type": "keyword"}, "from": {"type": "keyword"}, "to": {"type": "keyword"}, "kind": {"type": "keyword"}, "sortableAmount": {"type": "keyword"}}}}`
+	es.apiClient.DoRequest(esapi.IndicesCreateRequest{
+		Index: ERC20TransferIndex,
+		Body:  strings.NewReader(transferMapping),
+	})
+	allowanceMapping := `{"mappings":{"properties": {"contract": {"type": "keyword"}, "owner": {"type": "keyword"}, "spender": {"type": "keyword"}, "amount": {"type": "keyword"}}}}`
+	es.apiClient.DoRequest(esapi.IndicesCreateRequest{
+		Index: ERC20AllowanceIndex,
+		Body:  strings.NewReader(allowanceMapping),
+	})
+	es.apiClient.DoRequest(esapi.IndicesCreateRequest{Index: ERC20MetadataIndex})
+	supplyMapping := `{"mappings":{"properties": {"contract": {"type": "keyword"}, "amount": {"type": "keyword"}}}}`
+	es.apiClient.DoRequest(esapi.IndicesCreateRequest{
+		Index: ERC20SupplyIndex,
+		Body:  strings.NewReader(supplyMapping),
+	})
 	es.apiClient.DoRequest(esapi.IndicesCreateRequest{Index: ERC721TokenIndex})
+	// the metadata of each token is decided by its contract, so is stored but not indexed
+	erc721MetadataMapping := `{"mappings":{"properties": {"contract": {"type": "keyword"}, "token": {"type": "keyword"}, "metadata": {"type": "object", "enabled": false}}}}`
+	es.apiClient.DoRequest(esapi.IndicesCreateRequest{
+		Index: ERC721MetadataIndex,
+		Body:  strings.NewReader(erc721MetadataMapping),
+	})
+	es.apiClient.DoRequest(esapi.IndicesCreateRequest{Index: ERC1155TokenIndex})
+	es.apiClient.DoRequest(esapi.IndicesCreateRequest{Index: SampleIndex})
 
 	req := esapi.IndexRequest{
 		Index:      MetaIndex,
@@ -80,6 +116,26 @@ func (es *ElasticsearchDB) init() error {
 	return nil
 }
 
+// UpdateMappings adds the nested mappings of decoded calls and events to the indices of a database created before they
+// were used, as indices are only created with their mappings once. A field that already has data has a dynamic object
+// mapping that cannot be changed to nested, in which case an error is returned and the index must be re-indexed.
+func (es *ElasticsearchDB) UpdateMappings() error {
+	mappings := []struct{ index, properties string }{
+		{TransactionIndex, transactionProperties},
+		{EventIndex, eventProperties},
+	}
+	for _, mapping := range mappings {
+		req := esapi.IndicesPutMappingRequest{
+			Index: []string{mapping.index},
+			Body:  strings.NewReader(`{"properties": ` + mapping.properties + `}`),
+		}
+		if _, err := es.apiClient.DoRequest(req); err != nil {
+			return fmt.Errorf("could not update the mapping of the %s index: %v", mapping.index, err)
+		}
+	}
+	return nil
+}
+
 //AddressDB
 func (es *ElasticsearchDB) AddAddresses(addresses []types.Address) error {
 	if len(addresses) == 0 {
@@ -194,60 +250,103 @@ func (es *ElasticsearchDB) GetContractTemplate(address types.Address) (string, e
 
 //TemplateDB
 func (es *ElasticsearchDB) GetContractABI(address types.Address) (string, error) {
-
-	contract, err := es.getContractByAddress(address)
-	if err != nil && err != database.ErrNotFound {
+	template, err := es.getCurrentTemplate(address)
+	if err != nil {
 		return "", err
 	}
-
-	if contract != nil {
-		template, err := es.getTemplateByName(contract.TemplateName)
-		if err != nil && err != database.ErrNotFound {
-			return "", err
-		}
-		if template != nil {
-			return template.ABI, nil
-		}
+	if template != nil {
+		return template.ABI, nil
 	}
 	return "", nil
 }
 
 func (es *ElasticsearchDB) GetStorageLayout(address types.Address) (string, error) {
-	contract, err := es.getContractByAddress(address)
-	if err != nil && err != database.ErrNotFound {
+	template, err := es.getCurrentTemplate(address)
+	if err != nil {
 		return "", err
 	}
-	if contract != nil {
-		template, err := es.getTemplateByName(contract.TemplateName)
-		if err != nil && err != database.ErrNotFound {
-			return "", err
-		}
-		if template != nil {
-			return template.StorageABI, nil
-		}
+	if template != nil {
+		return template.StorageABI, nil
 	}
 	return "", nil
 }
 
-func (es *ElasticsearchDB) AddTemplate(name string, abi string, layout string) error {
-	template := Template{
-		TemplateName: name,
-		ABI:          abi,
-		StorageABI:   layout,
+func (es *ElasticsearchDB) AddTemplate(template *types.Template) error {
+	converted := Template{
+		TemplateName:        template.TemplateName,
+		Version:             1,
+		ABI:                 template.ABI,
+		StorageABI:          template.StorageLayout,
+		ContractName:        template.ContractName,
+		CompilerVersion:     template.CompilerVersion,
+		RuntimeBytecodeHash: template.RuntimeBytecodeHash,
+	}
+
+	// keep all the existing versions of the template
+	existing, err := es.getTemplateByName(template.TemplateName)
+	if err != nil && err != database.ErrNotFound {
+		return err
+	}
+	if existing != nil {
+		converted.PreviousVersions = existing.PreviousVersions
+		existing.PreviousVersions = nil
+		converted.PreviousVersions = append(converted.PreviousVersions, *existing)
+		converted.Version = existing.Version + 1
 	}
 
 	req := esapi.IndexRequest{
 		Index:      TemplateIndex,
-		DocumentID: name,
-		Body:       esutil.NewJSONReader(template),
+		DocumentID: template.TemplateName,
+		Body:       esutil.NewJSONReader(converted),
 		Refresh:    "true",
 	}
-	_, err := es.apiClient.DoRequest(req)
+	_, err = es.apiClient.DoRequest(req)
 	return err
 }
 
 func (es *ElasticsearchDB) AssignTemplate(address types.Address, name string) error {
-	return es.updateContract(address, "templateName", name)
+	return es.updateContractFields(address, map[string]interface{}{
+		"templateName":        name,
+		"templateAssignments": nil,
+	})
+}
+
+func (es *ElasticsearchDB) AssignTemplateFromBlock(address types.Address, assignment *types.TemplateAssignment) error {
+	assignments, err := es.GetTemplateAssignments(address)
+	if err != nil {
+		return err
+	}
+	assignments = database.InsertTemplateAssignment(assignments, assignment)
+
+	return es.updateContractFields(address, map[string]interface{}{
+		"templateName":        assignments[len(assignments)-1].TemplateName,
+		"templateAssignments": assignments,
+	})
+}
+
+func (es *ElasticsearchDB) GetTemplateAssignments(address types.Address) ([]*types.TemplateAssignment, error) {
+	contract, err := es.getContractByAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	return contractAssignments(contract), nil
+}
+
+func (es *ElasticsearchDB) RecordImplementation(proxy types.Address, implementation *types.ProxyImplementation) error {
+	implementations, err := es.GetImplementationHistory(proxy)
+	if err != nil {
+		return err
+	}
+	implementations = database.InsertProxyImplementation(implementations, implementation)
+	return es.updateContract(proxy, "implementations", implementations)
+}
+
+func (es *ElasticsearchDB) GetImplementationHistory(proxy types.Address) ([]*types.ProxyImplementation, error) {
+	contract, err := es.getContractByAddress(proxy)
+	if err != nil {
+		return nil, err
+	}
+	return contract.Implementations, nil
 }
 
 func (es *ElasticsearchDB) GetTemplates() ([]string, error) {
@@ -264,14 +363,22 @@ func (es *ElasticsearchDB) GetTemplates() ([]string, error) {
 }
 
 func (es *ElasticsearchDB) GetTemplateDetails(templateName string) (*types.Template, error) {
-	template, err := es.getTemplateByName(templateName)
+	return es.GetTemplateVersion(templateName, 0)
+}
+
+func (es *ElasticsearchDB) GetTemplateVersion(templateName string, version uint64) (*types.Template, error) {
+	template, err := es.getTemplateVersion(templateName, version)
 	if err != nil {
 		return nil, err
 	}
 	return &types.Template{
-		TemplateName:  templateName,
-		ABI:           template.ABI,
-		StorageLayout: template.StorageABI,
+		TemplateName:        templateName,
+		Version:             template.Version,
+		ABI:                 template.ABI,
+		StorageLayout:       template.StorageABI,
+		ContractName:        template.ContractName,
+		CompilerVersion:     template.CompilerVersion,
+		RuntimeBytecodeHash: template.RuntimeBytecodeHash,
 	}, nil
 }
 
@@ -351,6 +458,40 @@ func (es *ElasticsearchDB) ReadBlock(number uint64) (*types.Block, error) {
 	return blockResult.Source, nil
 }
 
+func (es *ElasticsearchDB) ReadBlocks(numbers []uint64) ([]*types.Block, error) {
+	if len(numbers) == 0 {
+		return []*types.Block{}, nil
+	}
+	size := len(numbers)
+	searchReq := esapi.SearchRequest{
+		Index: []string{BlockIndex},
+		Body:  strings.NewReader(QueryBlocksByNumber(numbers)),
+		Size:  &size,
+	}
+	results, err := es.doSearchRequest(searchReq)
+	if err != nil {
+		return nil, err
+	}
+
+	byNumber := make(map[uint64]*types.Block, len(results.Hits.Hits))
+	for _, result := range results.Hits.Hits {
+		var block types.Block
+		marshalled, _ := json.Marshal(result.Source)
+		if err := json.Unmarshal(marshalled, &block); err != nil {
+			return nil, err
+		}
+		byNumber[block.Number] = &block
+	}
+
+	blocks := make([]*types.Block, len(numbers))
+	for i, number := range numbers {
+		if blocks[i] = byNumber[number]; blocks[i] == nil {
+			return nil, database.ErrNotFound
+		}
+	}
+	return blocks, nil
+}
+
 func (es *ElasticsearchDB) GetLastPersistedBlockNumber() (uint64, error) {
 	// At this point, we know no data insertions are happening so we can safely
 	// delete data
@@ -522,17 +663,28 @@ func (es *ElasticsearchDB) GetContractCreationTransaction(address types.Address)
 
 func (es *ElasticsearchDB) GetAllTransactionsToAddress(address types.Address, options *types.QueryOptions) ([]types.Hash, error) {
 	queryString := fmt.Sprintf(QueryByToAddressWithOptionsTemplate(options), address.String())
+	return es.searchTransactionHashes(queryString, options)
+}
 
-	from := options.PageSize * options.PageNumber
-	if from+options.PageSize > 1000 {
-		return nil, ErrPaginationLimitExceeded
-	}
-	req := esapi.SearchRequest{
-		Index: []string{TransactionIndex},
-		Body:  strings.NewReader(queryString),
-		From:  &from,
-		Size:  &options.PageSize,
-		Sort:  []string{"blockNumber:desc", "index:asc"},
+func (es *ElasticsearchDB) GetTransactionsToAddressTotal(address types.Address, options *types.QueryOptions) (uint64, error) {
+	queryString := fmt.Sprintf(QueryByToAddressWithOptionsTemplate(options), address.String())
+	return es.countTransactions(queryString)
+}
+
+func (es *ElasticsearchDB) GetFailedTransactionsToAddress(address types.Address, options *types.QueryOptions) ([]types.Hash, error) {
+	queryString := fmt.Sprintf(QueryFailedByToAddressWithOptionsTemplate(options), address.String())
+	return es.searchTransactionHashes(queryString, options)
+}
+
+func (es *ElasticsearchDB) GetFailedTransactionsToAddressTotal(address types.Address, options *types.QueryOptions) (uint64, error) {
+	queryString := fmt.Sprintf(QueryFailedByToAddressWithOptionsTemplate(options), address.String())
+	return es.countTransactions(queryString)
+}
+
+func (es *ElasticsearchDB) searchTransactionHashes(queryString string, options *types.QueryOptions) ([]types.Hash, error) {
+	req, err := newPagedSearchRequest(TransactionIndex, queryString, options)
+	if err != nil {
+		return nil, err
 	}
 	results, err := es.doSearchRequest(req)
 	if err != nil {
@@ -548,9 +700,7 @@ func (es *ElasticsearchDB) GetAllTransactionsToAddress(address types.Address, op
 	return converted, nil
 }
 
-func (es *ElasticsearchDB) GetTransactionsToAddressTotal(address types.Address, options *types.QueryOptions) (uint64, error) {
-	queryString := fmt.Sprintf(QueryByToAddressWithOptionsTemplate(options), address.String())
-
+func (es *ElasticsearchDB) countTransactions(queryString string) (uint64, error) {
 	req := esapi.CountRequest{
 		Index: []string{TransactionIndex},
 		Body:  strings.NewReader(queryString),
@@ -562,32 +712,49 @@ func (es *ElasticsearchDB) GetTransactionsToAddressTotal(address types.Address,
 	return results.Count, nil
 }
 
-func (es *ElasticsearchDB) GetAllTransactionsInternalToAddress(address types.Address, options *types.QueryOptions) ([]types.Hash, error) {
-	queryString := fmt.Sprintf(QueryInternalTransactionsWithOptionsTemplate(options), address.String())
+// newPagedSearchRequest creates a search for a page of transactions or events, ordered by block and then by index
+func newPagedSearchRequest(index string, queryString string, options *types.QueryOptions) (esapi.SearchRequest, error) {
+	cursor, err := options.Cursor()
+	if err != nil {
+		return esapi.SearchRequest{}, err
+	}
+	from, err := pageFrom(options.PageSize, options.PageNumber, cursor != nil)
+	if err != nil {
+		return esapi.SearchRequest{}, err
+	}
+	if cursor != nil {
+		queryString = withSearchAfter(queryString, cursor.BlockNumber, cursor.Index)
+	}
 
-	from := options.PageSize * options.PageNumber
-	if from+options.PageSize > 1000 {
-		return nil, ErrPaginationLimitExceeded
+	direction := types.SortDescending
+	if options.Ascending() {
+		direction = types.SortAscending
 	}
-	req := esapi.SearchRequest{
-		Index: []string{TransactionIndex},
+	return esapi.SearchRequest{
+		Index: []string{index},
 		Body:  strings.NewReader(queryString),
 		From:  &from,
 		Size:  &options.PageSize,
-		Sort:  []string{"blockNumber:desc", "index:asc"},
-	}
-	results, err := es.doSearchRequest(req)
-	if err != nil {
-		return nil, err
-	}
+		Sort:  []string{"blockNumber:" + direction, "index:asc"},
+	}, nil
+}
 
-	converted := make([]types.Hash, len(results.Hits.Hits))
-	for i, result := range results.Hits.Hits {
-		hsh := result.Source["hash"].(string)
-		converted[i] = types.NewHash(hsh)
+// pageFrom returns the offset of the first result of a page. A search after a cursor starts from
+// the cursor instead, so is only limited by the size of the page.
+func pageFrom(pageSize int, pageNumber int, hasCursor bool) (int, error) {
+	from := 0
+	if !hasCursor {
+		from = pageSize * pageNumber
 	}
+	if from+pageSize > 1000 {
+		return 0, ErrPaginationLimitExceeded
+	}
+	return from, nil
+}
 
-	return converted, nil
+func (es *ElasticsearchDB) GetAllTransactionsInternalToAddress(address types.Address, options *types.QueryOptions) ([]types.Hash, error) {
+	queryString := fmt.Sprintf(QueryInternalTransactionsWithOptionsTemplate(options), address.String())
+	return es.searchTransactionHashes(queryString, options)
 }
 
 func (es *ElasticsearchDB) GetTransactionsInternalToAddressTotal(address types.Address, options *types.QueryOptions) (uint64, error) {
@@ -604,19 +771,30 @@ func (es *ElasticsearchDB) GetTransactionsInternalToAddressTotal(address types.A
 	return results.Count, nil
 }
 
+func (es *ElasticsearchDB) GetAllTransactionsFromAddress(address types.Address, options *types.QueryOptions) ([]types.Hash, error) {
+	queryString := fmt.Sprintf(QueryByFromAddressWithOptionsTemplate(options), address.String())
+	return es.searchTransactionHashes(queryString, options)
+}
+
+func (es *ElasticsearchDB) GetTransactionsFromAddressTotal(address types.Address, options *types.QueryOptions) (uint64, error) {
+	queryString := fmt.Sprintf(QueryByFromAddressWithOptionsTemplate(options), address.String())
+	return es.countTransactions(queryString)
+}
+
 func (es *ElasticsearchDB) GetAllEventsFromAddress(address types.Address, options *types.QueryOptions) ([]*types.Event, error) {
 	queryString := fmt.Sprintf(QueryByAddressWithOptionsTemplate(options), address.String())
+	return es.searchEvents(queryString, options)
+}
 
-	from := options.PageSize * options.PageNumber
-	if from+options.PageSize > 1000 {
-		return nil, ErrPaginationLimitExceeded
-	}
-	req := esapi.SearchRequest{
-		Index: []string{EventIndex},
-		Body:  strings.NewReader(queryString),
-		From:  &from,
-		Size:  &options.PageSize,
-		Sort:  []string{"blockNumber:desc", "index:asc"},
+func (es *ElasticsearchDB) GetFilteredEventsFromAddress(address types.Address, filter *types.EventFilter, options *types.QueryOptions) ([]*types.Event, error) {
+	queryString := fmt.Sprintf(QueryEventsWithFilterTemplate(filter, options), address.String())
+	return es.searchEvents(queryString, options)
+}
+
+func (es *ElasticsearchDB) searchEvents(queryString string, options *types.QueryOptions) ([]*types.Event, error) {
+	req, err := newPagedSearchRequest(EventIndex, queryString, options)
+	if err != nil {
+		return nil, err
 	}
 	results, err := es.doSearchRequest(req)
 	if err != nil {
@@ -632,12 +810,25 @@ func (es *ElasticsearchDB) GetAllEventsFromAddress(address types.Address, option
 		}
 		convertedList[i] = &event
 	}
-
 	return convertedList, nil
 }
 
+func (es *ElasticsearchDB) GetFilteredEventsFromAddressTotal(address types.Address, filter *types.EventFilter, options *types.QueryOptions) (uint64, error) {
+	queryString := fmt.Sprintf(QueryEventsWithFilterTemplate(filter, options), address.String())
+
+	req := esapi.CountRequest{
+		Index: []string{EventIndex},
+		Body:  strings.NewReader(queryString),
+	}
+	results, err := es.doCountRequest(req)
+	if err != nil {
+		return 0, err
+	}
+	return results.Count, nil
+}
+
 func (es *ElasticsearchDB) GetStorageWithOptions(address types.Address, options *types.PageOptions) ([]*types.StorageResult, error) {
-	return es.getStorageWithOptionsAndDirection(address, options, false)
+	return es.getStorageWithOptionsAndDirection(address, options, options.Ascending())
 }
 
 func (es *ElasticsearchDB) GetEventsFromAddressTotal(address types.Address, options *types.QueryOptions) (uint64, error) {
@@ -772,7 +963,7 @@ func (es *ElasticsearchDB) GetLastFiltered(address types.Address) (uint64, error
 
 func (es *ElasticsearchDB) checkIsInitialized() (bool, error) {
 	fetchReq := esapi.CatIndicesRequest{
-		Index: []string{MetaIndex, ContractIndex, BlockIndex, StorageIndex, TransactionIndex, EventIndex, ERC20TokenIndex, ERC721TokenIndex},
+		Index: []string{MetaIndex, ContractIndex, BlockIndex, StorageIndex, TransactionIndex, EventIndex, ERC20TokenIndex, ERC20TransferIndex, ERC20AllowanceIndex, ERC20MetadataIndex, ERC20SupplyIndex, ERC721TokenIndex, ERC721MetadataIndex, ERC1155TokenIndex, SampleIndex},
 	}
 
 	if _, err := es.apiClient.DoRequest(fetchReq); err != nil {
@@ -820,6 +1011,59 @@ func (es *ElasticsearchDB) getTemplateByName(name string) (*Template, error) {
 	return &template.Source, nil
 }
 
+// getTemplateVersion fetches a version of a template, where version 0 is the latest version
+func (es *ElasticsearchDB) getTemplateVersion(name string, version uint64) (*Template, error) {
+	template, err := es.getTemplateByName(name)
+	if err != nil {
+		return nil, err
+	}
+	// templates stored before versioning was added have no version, and are the first version
+	if template.Version == 0 {
+		template.Version = 1
+	}
+	if version == 0 || version == template.Version {
+		return template, nil
+	}
+	for _, previous := range template.PreviousVersions {
+		if previous.Version == version {
+			return &previous, nil
+		}
+	}
+	return nil, database.ErrNotFound
+}
+
+// getCurrentTemplate fetches the template version of the latest assignment of the contract,
+// returning nil if the contract or template do not exist
+func (es *ElasticsearchDB) getCurrentTemplate(address types.Address) (*Template, error) {
+	contract, err := es.getContractByAddress(address)
+	if err == database.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	assignments := contractAssignments(contract)
+	if len(assignments) == 0 {
+		return nil, nil
+	}
+	current := assignments[len(assignments)-1]
+	template, err := es.getTemplateVersion(current.TemplateName, current.Version)
+	if err == database.ErrNotFound {
+		return nil, nil
+	}
+	return template, err
+}
+
+// contractAssignments returns the template assignments of a contract. Contracts that only
+// have a template name use the latest version of that template for all blocks.
+func contractAssignments(contract *Contract) []*types.TemplateAssignment {
+	if len(contract.TemplateAssignments) == 0 && contract.TemplateName != "" {
+		return []*types.TemplateAssignment{{TemplateName: contract.TemplateName}}
+	}
+	return contract.TemplateAssignments
+}
+
 func (es *ElasticsearchDB) updateAllLastFiltered(addresses []types.Address, lastFiltered uint64) error {
 	bi := es.apiClient.GetBulkHandler(ContractIndex)
 
@@ -837,6 +1081,10 @@ func (es *ElasticsearchDB) updateAllLastFiltered(addresses []types.Address, last
 }
 
 func (es *ElasticsearchDB) updateContract(address types.Address, property string, value interface{}) error {
+	return es.updateContractFields(address, map[string]interface{}{property: value})
+}
+
+func (es *ElasticsearchDB) updateContractFields(address types.Address, fields map[string]interface{}) error {
 	//check contract exists before updating
 	_, err := es.getContractByAddress(address)
 	if err != nil {
@@ -844,9 +1092,7 @@ func (es *ElasticsearchDB) updateContract(address types.Address, property string
 	}
 
 	query := map[string]interface{}{
-		"doc": map[string]interface{}{
-			property: value,
-		},
+		"doc": fields,
 	}
 
 	updateRequest := esapi.UpdateRequest{
@@ -860,7 +1106,63 @@ func (es *ElasticsearchDB) updateContract(address types.Address, property string
 	return err
 }
 
-func (es *ElasticsearchDB) createEvents(events []*types.Event) error {
+// RedecodeContract decodes the transactions to the contract and its events again, replacing what was decoded when
+// they were indexed. Transactions are only updated with their decoded calls, while events are indexed again.
+func (es *ElasticsearchDB) RedecodeContract(address types.Address) error {
+	if _, err := es.getContractByAddress(address); err != nil {
+		return err
+	}
+	decoder := database.NewTemplateDecoder(es)
+
+	txSources, err := es.scrollSources(TransactionIndex, fmt.Sprintf(QueryMatchFieldTemplate, "to", address.String()))
+	if err != nil {
+		return err
+	}
+	calls := make(map[types.Hash]*types.DecodedCall)
+	for _, source := range txSources {
+		var tx types.Transaction
+		if err := json.Unmarshal(source, &tx); err != nil {
+			return err
+		}
+		decoded, err := decoder.DecodeCall(&tx)
+		if err != nil {
+			return err
+		}
+		if decoded != nil {
+			calls[tx.Hash] = decoded
+		}
+	}
+	if len(calls) > 0 {
+		if err := es.updateCalls(calls); err != nil {
+			return err
+		}
+	}
+
+	eventSources, err := es.scrollSources(EventIndex, fmt.Sprintf(QueryMatchFieldTemplate, "address", address.String()))
+	if err != nil {
+		return err
+	}
+	events := make([]*IndexedEvent, 0, len(eventSources))
+	for _, source := range eventSources {
+		var event types.Event
+		if err := json.Unmarshal(source, &event); err != nil {
+			return err
+		}
+		decoded, err := decoder.DecodeEvent(&event)
+		if err != nil {
+			return err
+		}
+		events = append(events, &IndexedEvent{Event: &event, DecodedEvent: decoded})
+	}
+	return es.writeEvents("index", events)
+}
+
+func (es *ElasticsearchDB) createEvents(events []*IndexedEvent) error {
+	return es.writeEvents("create", events)
+}
+
+// writeEvents indexes the events in bulk with the given action, either creating them or replacing existing ones
+func (es *ElasticsearchDB) writeEvents(action string, events []*IndexedEvent) error {
 	bi := es.apiClient.GetBulkHandler(EventIndex)
 
 	var (
@@ -872,7 +1174,7 @@ func (es *ElasticsearchDB) createEvents(events []*types.Event) error {
 		_ = bi.Add(
 			context.Background(),
 			esutil.BulkIndexerItem{
-				Action:     "create",
+				Action:     action,
 				DocumentID: strconv.FormatUint(event.BlockNumber, 10) + "-" + strconv.FormatUint(event.Index, 10),
 				Body:       esutil.NewJSONReader(event),
 				OnSuccess: func(ctx context.Context, item esutil.BulkIndexerItem, item2 esutil.BulkIndexerResponseItem) {
@@ -889,6 +1191,45 @@ func (es *ElasticsearchDB) createEvents(events []*types.Event) error {
 	return returnErr
 }
 
+// updateCalls adds the decoded function calls to their transactions
+func (es *ElasticsearchDB) updateCalls(calls map[types.Hash]*types.DecodedCall) error {
+	docs := make(map[string]interface{}, len(calls))
+	for hash, call := range calls {
+		docs[hash.String()] = map[string]interface{}{"call": call}
+	}
+	return es.updateDocuments(TransactionIndex, docs)
+}
+
+// updateDocuments updates the documents in bulk with the given fields, keyed by document ID
+func (es *ElasticsearchDB) updateDocuments(index string, docs map[string]interface{}) error {
+	bi := es.apiClient.GetBulkHandler(index)
+
+	var (
+		wg        sync.WaitGroup
+		returnErr error
+	)
+	for id, doc := range docs {
+		wg.Add(1)
+		_ = bi.Add(
+			context.Background(),
+			esutil.BulkIndexerItem{
+				Action:     "update",
+				DocumentID: id,
+				Body:       esutil.NewJSONReader(map[string]interface{}{"doc": doc}),
+				OnSuccess: func(ctx context.Context, item esutil.BulkIndexerItem, item2 esutil.BulkIndexerResponseItem) {
+					wg.Done()
+				},
+				OnFailure: func(ctx context.Context, item esutil.BulkIndexerItem, item2 esutil.BulkIndexerResponseItem, err error) {
+					returnErr = err
+					wg.Done()
+				},
+			},
+		)
+	}
+	wg.Wait()
+	return returnErr
+}
+
 func (es *ElasticsearchDB) Stop() {
 	es.apiClient.CloseIndexers()
 	log.Info("Elasticsearch indexers closed")
@@ -949,15 +1290,23 @@ func (es *ElasticsearchDB) updateLastPersisted(startingBlockNumber uint64) error
 
 func (es *ElasticsearchDB) getStorageWithOptionsAndDirection(address types.Address, options *types.PageOptions, ascending bool) ([]*types.StorageResult, error) {
 	queryString := fmt.Sprintf(QueryByAddressWithBlockRangeOptionsTemplate(options), address.String())
-	from := options.PageSize * options.PageNumber
 
 	direction := "desc"
 	if ascending {
 		direction = "asc"
 	}
 
-	if from+options.PageSize > 1000 {
-		return nil, ErrPaginationLimitExceeded
+	cursor, err := options.Cursor()
+	if err != nil {
+		return nil, err
+	}
+	from, err := pageFrom(options.PageSize, options.PageNumber, cursor != nil)
+	if err != nil {
+		return nil, err
+	}
+	if cursor != nil {
+		// each block has a single storage entry for a contract, so the block number alone is the position
+		queryString = withSearchAfter(queryString, cursor.BlockNumber)
 	}
 	req := esapi.SearchRequest{
 		Index: []string{StorageIndex},
@@ -36,10 +36,10 @@ func (coordinator *DefaultDeletionCoordinator) Delete(contract types.Address) er
 	deleteByAddressQuery := fmt.Sprintf(DeleteQueryAddress, contract.String())
 	deleteByContractQuery := fmt.Sprintf(DeleteQueryContract, contract.String())
 
-	// delete ERC20 & ERC721 tokens
-	log.Debug("Deleting ERC20/ERC721 token data", "contract", contract.String())
+	// delete ERC20, ERC721 & ERC1155 tokens
+	log.Debug("Deleting ERC20/ERC721/ERC1155 token data", "contract", contract.String())
 	erc20Req := esapi.DeleteByQueryRequest{
-		Index:             []string{ERC20TokenIndex, ERC721TokenIndex},
+		Index:             []string{ERC20TokenIndex, ERC20TransferIndex, ERC20AllowanceIndex, ERC20MetadataIndex, ERC20SupplyIndex, ERC721TokenIndex, ERC721MetadataIndex, ERC1155TokenIndex},
 		Body:              strings.NewReader(deleteByContractQuery),
 		Refresh:           &RequestParameterTrue,
 		WaitForCompletion: &RequestParameterTrue,
@@ -48,12 +48,12 @@ func (coordinator *DefaultDeletionCoordinator) Delete(contract types.Address) er
 	if err != nil {
 		return err
 	}
-	log.Debug("Deleted ERC20/ERC721 token data", "contract", contract.String())
+	log.Debug("Deleted ERC20/ERC721/ERC1155 token data", "contract", contract.String())
 
-	//delete event
-	log.Debug("Deleting contract events", "contract", contract.String())
+	//delete events & function samples
+	log.Debug("Deleting contract events and samples", "contract", contract.String())
 	eventReq := esapi.DeleteByQueryRequest{
-		Index:             []string{EventIndex},
+		Index:             []string{EventIndex, SampleIndex},
 		Body:              strings.NewReader(deleteByAddressQuery),
 		Refresh:           &RequestParameterTrue,
 		WaitForCompletion: &RequestParameterTrue,
@@ -62,7 +62,7 @@ func (coordinator *DefaultDeletionCoordinator) Delete(contract types.Address) er
 	if err != nil {
 		return err
 	}
-	log.Debug("Deleted contract events", "contract", contract.String())
+	log.Debug("Deleted contract events and samples", "contract", contract.String())
 
 	log.Debug("Deleting contract storage", "contract", contract.String())
 	storageDeleteReq := esapi.DeleteByQueryRequest{
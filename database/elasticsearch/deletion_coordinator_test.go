@@ -23,12 +23,12 @@ func TestDefaultDeletionCoordinator_Delete(t *testing.T) {
 	addressToDelete := types.NewAddress("1")
 
 	ercDelete := esapi.DeleteByQueryRequest{
-		Index: []string{ERC20TokenIndex, ERC721TokenIndex},
+		Index: []string{ERC20TokenIndex, ERC20TransferIndex, ERC20AllowanceIndex, ERC20MetadataIndex, ERC20SupplyIndex, ERC721TokenIndex, ERC721MetadataIndex, ERC1155TokenIndex},
 		Body:  strings.NewReader(`{ "query": { "match": { "contract": "0x0000000000000000000000000000000000000001" } } }`),
 	}
 	mockedClient.EXPECT().DoRequest(NewDeleteByQueryRequestMatcher(ercDelete)).Return(nil, nil)
 	eventDelete := esapi.DeleteByQueryRequest{
-		Index: []string{EventIndex},
+		Index: []string{EventIndex, SampleIndex},
 		Body:  strings.NewReader(`{ "query": { "match": { "address": "0x0000000000000000000000000000000000000001" } } }`),
 	}
 	mockedClient.EXPECT().DoRequest(NewDeleteByQueryRequestMatcher(eventDelete)).Return(nil, nil)
@@ -3,6 +3,7 @@ package elasticsearch
 import (
 	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"testing"
 
@@ -151,6 +152,68 @@ func TestElasticsearchDB_GetAllTransactionsToAddress_WithError(t *testing.T) {
 	assert.Nil(t, txns, "unexpected returned tx hash")
 }
 
+func TestElasticsearchDB_GetAllTransactionsToAddress_WithCallFilter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+
+	addr := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+
+	from := 0
+	size := 10
+	options := &types.QueryOptions{
+		Function: "transfer",
+		Status:   types.StatusSuccess,
+		Inputs:   []*types.ArgFilter{{Name: "value", Op: "lt", Value: "1000"}},
+	}
+	options.SetDefaults()
+	assert.Nil(t, options.ValidateCallFilter())
+
+	query := `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "match": { "to": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34" } },
+{ "term": { "status": true } },
+{ "term": { "call.name": "transfer" } },
+{ "nested": { "path": "call.inputs", "query": { "bool": { "must": [ { "term": { "call.inputs.name": "value" } }, { "range": { "call.inputs.number": { "lt": "115792089237316195423570985008687907853269984665640564039457584007913129640936" } } } ] } } } },
+{ "range": { "blockNumber": { "gte": 0 } } },
+{ "range": { "timestamp": { "gte": 0 } } }
+			]
+		}
+	}
+}
+`
+	expectedRequest := esapi.SearchRequest{
+		Index: []string{TransactionIndex},
+		Body:  strings.NewReader(query),
+		From:  &from,
+		Size:  &size,
+		Sort:  []string{"blockNumber:desc", "index:asc"},
+	}
+	expectedCountRequest := esapi.CountRequest{
+		Index: []string{TransactionIndex},
+		Body:  strings.NewReader(query),
+	}
+	result := `{"hits": {"hits": [{"_source": {"hash": "0xd838a0eaccb60b0f0c65e55dd8cc36aea9576b8cdf0c947b0a974814d536e891"}}]}}`
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().DoRequest(NewSearchRequestMatcher(expectedRequest)).Return([]byte(result), nil)
+	mockedClient.EXPECT().DoRequest(NewCountRequestMatcher(expectedCountRequest)).Return([]byte(`{"count": 1}`), nil)
+
+	db, _ := New(mockedClient)
+	txns, err := db.GetAllTransactionsToAddress(addr, options)
+
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, []types.Hash{types.NewHash("0xd838a0eaccb60b0f0c65e55dd8cc36aea9576b8cdf0c947b0a974814d536e891")}, txns)
+
+	total, err := db.GetTransactionsToAddressTotal(addr, options)
+	assert.Nil(t, err, "unexpected error")
+	assert.EqualValues(t, 1, total)
+}
+
 func TestElasticsearchDB_GetAllTransactionsToAddress_SingleResult(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -276,6 +339,169 @@ func TestElasticsearchDB_GetAllTransactionsToAddress_MultipleResults(t *testing.
 	assert.Nil(t, err, "unexpected error")
 }
 
+func TestElasticsearchDB_GetAllTransactionsFromAddress(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+
+	addr := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+
+	result := `{"hits": {"hits": [
+  {
+    "_source": {
+      "hash": "0xd838a0eaccb60b0f0c65e55dd8cc36aea9576b8cdf0c947b0a974814d536e891",
+      "from": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34"
+    }
+  }
+]}}`
+
+	from := 0
+	size := 10
+	options := &types.QueryOptions{}
+	options.SetDefaults()
+
+	query := `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "bool": {
+						"should": [
+							{ "match": { "from": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34" } },
+							{ "nested": {
+									"path": "internalCalls",
+									"query": { "match": { "internalCalls.from": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34" } }
+								}
+							}
+						],
+						"minimum_should_match": 1
+					}
+				},
+{ "range": { "blockNumber": { "gte": 0 } } },
+{ "range": { "timestamp": { "gte": 0 } } }
+			]
+		}
+	}
+}
+`
+	expectedRequest := esapi.SearchRequest{
+		Index: []string{TransactionIndex},
+		Body:  strings.NewReader(query),
+		From:  &from,
+		Size:  &size,
+		Sort:  []string{"blockNumber:desc", "index:asc"},
+	}
+	expectedCountRequest := esapi.CountRequest{
+		Index: []string{TransactionIndex},
+		Body:  strings.NewReader(query),
+	}
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().DoRequest(NewSearchRequestMatcher(expectedRequest)).Return([]byte(result), nil)
+	mockedClient.EXPECT().DoRequest(NewCountRequestMatcher(expectedCountRequest)).Return([]byte(`{"count": 1}`), nil)
+
+	db, _ := New(mockedClient)
+	txns, err := db.GetAllTransactionsFromAddress(addr, options)
+
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, 1, len(txns), "wrong number of returned transactions")
+	assert.Equal(t, "0xd838a0eaccb60b0f0c65e55dd8cc36aea9576b8cdf0c947b0a974814d536e891", txns[0].String(), "wrong txn hash returned")
+
+	total, err := db.GetTransactionsFromAddressTotal(addr, options)
+	assert.Nil(t, err, "unexpected error")
+	assert.EqualValues(t, 1, total)
+}
+
+func TestElasticsearchDB_GetFilteredEventsFromAddress(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+
+	addr := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+
+	response := `{"hits": {"hits": [
+  {
+  "_source": {
+    "address": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34",
+    "blockNumber": 9,
+    "data": "0x00000000000000000000000000000000000000000000000000000000000007d0",
+    "index": 0,
+    "topics": [
+      "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+      "0x0000000000000000000000000000000000000000000000000000000000000001",
+      "0x0000000000000000000000009d13c6d3afe1721beef56b55d303b09e021e27ab"
+    ],
+    "transactionHash": "0x223df44de450551b9281d8091913ba7f5aa4ce655f478355be0fc84f39920bc0",
+    "eventName": "Transfer",
+    "eventSig": "Transfer(address,address,uint256)",
+    "args": [
+      {"name": "from", "value": "0x0000000000000000000000000000000000000001"},
+      {"name": "to", "value": "0x9d13c6d3afe1721beef56b55d303b09e021e27ab"},
+      {"name": "value", "value": "2000", "number": "115792089237316195423570985008687907853269984665640564039457584007913129641936"}
+    ]
+  }
+}]}}`
+
+	from := 0
+	size := 10
+	options := &types.QueryOptions{}
+	options.SetDefaults()
+	filter := &types.EventFilter{
+		Event: "Transfer",
+		Args: []*types.ArgFilter{
+			{Name: "to", Value: "0x9D13C6D3AFE1721BEEF56B55D303B09E021E27AB"},
+			{Name: "value", Op: "gt", Value: "1000"},
+		},
+	}
+	assert.Nil(t, filter.Validate())
+
+	queryString := `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "match": { "address": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34" } },
+{ "term": { "eventName": "Transfer" } },
+{ "nested": { "path": "args", "query": { "bool": { "must": [ { "term": { "args.name": "to" } }, { "term": { "args.value": "0x9d13c6d3afe1721beef56b55d303b09e021e27ab" } } ] } } } },
+{ "nested": { "path": "args", "query": { "bool": { "must": [ { "term": { "args.name": "value" } }, { "range": { "args.number": { "gt": "115792089237316195423570985008687907853269984665640564039457584007913129640936" } } } ] } } } },
+{ "range": { "blockNumber": { "gte": 0 } } },
+{ "range": { "timestamp": { "gte": 0 } } }
+			]
+		}
+	}
+}
+`
+	req := esapi.SearchRequest{
+		Index: []string{EventIndex},
+		Body:  strings.NewReader(queryString),
+		From:  &from,
+		Size:  &size,
+		Sort:  []string{"blockNumber:desc", "index:asc"},
+	}
+	countReq := esapi.CountRequest{
+		Index: []string{EventIndex},
+		Body:  strings.NewReader(queryString),
+	}
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().DoRequest(NewSearchRequestMatcher(req)).Return([]byte(response), nil)
+	mockedClient.EXPECT().DoRequest(NewCountRequestMatcher(countReq)).Return([]byte(`{"count": 1}`), nil)
+
+	db, _ := New(mockedClient)
+	events, err := db.GetFilteredEventsFromAddress(addr, filter, options)
+
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, 1, len(events), "wrong number of returned events")
+	assert.EqualValues(t, 9, events[0].BlockNumber)
+	assert.Len(t, events[0].Topics, 3)
+
+	total, err := db.GetFilteredEventsFromAddressTotal(addr, filter, options)
+	assert.Nil(t, err, "unexpected error")
+	assert.EqualValues(t, 1, total)
+}
+
 func TestElasticsearchDB_GetAllEventsByAddress_WithError(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -449,6 +675,46 @@ func TestElasticsearchDB_GetAllEventsByAddress_MultipleResults(t *testing.T) {
 	assert.Nil(t, err, "unexpected error")
 }
 
+func TestElasticsearchDB_GetAllEventsByAddress_WithCursor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+
+	addr := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+
+	response := `{"hits": {"hits": []}}`
+
+	// a cursor starts the search from the beginning, however far into the results it is
+	from := 0
+	size := 10
+	options := &types.QueryOptions{PageNumber: 5000, Sort: types.SortAscending, After: types.NewCursor(9, 1).Encode()}
+	options.SetDefaults()
+
+	queryString := strings.Replace(fmt.Sprintf(QueryByAddressWithOptionsTemplate(options), addr.String()), "{", `{
+	"search_after": [9, 1],`, 1)
+	req := esapi.SearchRequest{
+		Index: []string{EventIndex},
+		Body:  strings.NewReader(queryString),
+		From:  &from,
+		Size:  &size,
+		Sort:  []string{"blockNumber:asc", "index:asc"},
+	}
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().DoRequest(NewSearchRequestMatcher(req)).Return([]byte(response), nil)
+
+	db, _ := New(mockedClient)
+	events, err := db.GetAllEventsFromAddress(addr, options)
+
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, 0, len(events), "wrong number of returned events")
+
+	options.After = ""
+	_, err = db.GetAllEventsFromAddress(addr, options)
+	assert.Equal(t, ErrPaginationLimitExceeded, err)
+}
+
 func TestElasticsearchDB_GetLastFiltered(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -505,3 +771,71 @@ func TestElasticsearchDB_GetLastFiltered_ContractDoesntExist(t *testing.T) {
 	assert.Equal(t, uint64(0), num, "unexpected error")
 	assert.EqualError(t, err, "not found", "unexpected error message")
 }
+
+func TestElasticsearchDB_GetFailedTransactionsToAddress(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+
+	addr := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+
+	result := `{"hits": {"hits": [
+  {
+    "_source": {
+      "hash": "0xd838a0eaccb60b0f0c65e55dd8cc36aea9576b8cdf0c947b0a974814d536e891",
+      "to": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34",
+      "status": false
+    }
+  }
+]}}`
+
+	from := 0
+	size := 10
+	options := &types.QueryOptions{}
+	options.SetDefaults()
+
+	query := fmt.Sprintf(QueryFailedByToAddressWithOptionsTemplate(options), addr.String())
+	expectedRequest := esapi.SearchRequest{
+		Index: []string{TransactionIndex},
+		Body:  strings.NewReader(query),
+		From:  &from,
+		Size:  &size,
+		Sort:  []string{"blockNumber:desc", "index:asc"},
+	}
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().DoRequest(NewSearchRequestMatcher(expectedRequest)).Return([]byte(result), nil)
+
+	db, _ := New(mockedClient)
+	txns, err := db.GetFailedTransactionsToAddress(addr, options)
+
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, []types.Hash{types.NewHash("0xd838a0eaccb60b0f0c65e55dd8cc36aea9576b8cdf0c947b0a974814d536e891")}, txns)
+}
+
+func TestElasticsearchDB_UpdateMappings(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+
+	var updated []string
+	recordMapping := func(req esapi.Request) {
+		putMapping := req.(esapi.IndicesPutMappingRequest)
+		body := new(strings.Builder)
+		_, _ = io.Copy(body, putMapping.Body)
+		assert.Contains(t, body.String(), `"type": "nested"`)
+		updated = append(updated, putMapping.Index[0])
+	}
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().DoRequest(gomock.Any()).Do(recordMapping)
+	mockedClient.EXPECT().DoRequest(gomock.Any()).Do(recordMapping).Return(nil, errors.New("mapper [args] can't be changed from type [object] to [nested]"))
+
+	db, _ := New(mockedClient)
+	err := db.UpdateMappings()
+
+	assert.EqualError(t, err, "could not update the mapping of the event index: mapper [args] can't be changed from type [object] to [nested]")
+	assert.Equal(t, []string{TransactionIndex, EventIndex}, updated)
+}
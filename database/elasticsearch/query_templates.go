@@ -1,9 +1,11 @@
 package elasticsearch
 
 import (
+	"encoding/json"
 	"fmt"
 	"math/big"
 	"strconv"
+	"strings"
 
 	"quorumengineering/quorum-report/types"
 )
@@ -27,6 +29,26 @@ const QueryAllTemplateNamesTemplate = `
 }
 `
 
+// QueryMatchFieldTemplate matches every document with the given value of a field
+const QueryMatchFieldTemplate = `
+{
+	"query": {
+		"match": { "%s": "%s" }
+	}
+}
+`
+
+// QueryMissingFieldTemplate matches every document that does not have the given field
+const QueryMissingFieldTemplate = `
+{
+	"query": {
+		"bool": {
+			"must_not": { "exists": { "field": "%s" } }
+		}
+	}
+}
+`
+
 func QueryByToAddressWithOptionsTemplate(options *types.QueryOptions) string {
 	return `
 {
@@ -34,6 +56,52 @@ func QueryByToAddressWithOptionsTemplate(options *types.QueryOptions) string {
 		"bool": {
 			"must": [
 				{ "match": { "to": "%s" } },
+` + createCallFilterQuery(options) + createRangeQuery("blockNumber", options.BeginBlockNumber, options.EndBlockNumber) + `,
+` + createRangeQuery("timestamp", options.BeginTimestamp, options.EndTimestamp) + `
+			]
+		}
+	}
+}
+`
+}
+
+func QueryFailedByToAddressWithOptionsTemplate(options *types.QueryOptions) string {
+	return `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "match": { "to": "%s" } },
+				{ "match": { "status": false } },
+` + createRangeQuery("blockNumber", options.BeginBlockNumber, options.EndBlockNumber) + `,
+` + createRangeQuery("timestamp", options.BeginTimestamp, options.EndTimestamp) + `
+			]
+		}
+	}
+}
+`
+}
+
+// QueryByFromAddressWithOptionsTemplate matches transactions sent from the address, or in
+// which the address made an internal call
+func QueryByFromAddressWithOptionsTemplate(options *types.QueryOptions) string {
+	return `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "bool": {
+						"should": [
+							{ "match": { "from": "%[1]s" } },
+							{ "nested": {
+									"path": "internalCalls",
+									"query": { "match": { "internalCalls.from": "%[1]s" } }
+								}
+							}
+						],
+						"minimum_should_match": 1
+					}
+				},
 ` + createRangeQuery("blockNumber", options.BeginBlockNumber, options.EndBlockNumber) + `,
 ` + createRangeQuery("timestamp", options.BeginTimestamp, options.EndTimestamp) + `
 			]
@@ -59,6 +127,43 @@ func QueryByAddressWithOptionsTemplate(options *types.QueryOptions) string {
 `
 }
 
+// QueryEventsWithFilterTemplate finds the events of a contract matching a validated filter,
+// where each argument filter is a nested query so that it matches a single argument
+func QueryEventsWithFilterTemplate(filter *types.EventFilter, options *types.QueryOptions) string {
+	return `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "match": { "address": "%s" } },
+` + createEventFilterQuery(filter) + createRangeQuery("blockNumber", options.BeginBlockNumber, options.EndBlockNumber) + `,
+` + createRangeQuery("timestamp", options.BeginTimestamp, options.EndTimestamp) + `
+			]
+		}
+	}
+}
+`
+}
+
+// QuerySamplesWithOptionsTemplate finds the samples of a contract's sampler, where the
+// sampler name is given as a JSON string
+func QuerySamplesWithOptionsTemplate(options *types.QueryOptions) string {
+	return `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "match": { "address": "%s" } },
+				{ "term": { "name.keyword": %s } },
+` + createRangeQuery("blockNumber", options.BeginBlockNumber, options.EndBlockNumber) + `,
+` + createRangeQuery("timestamp", options.BeginTimestamp, options.EndTimestamp) + `
+			]
+		}
+	}
+}
+`
+}
+
 func QueryByAddressWithBlockRangeOptionsTemplate(opt *types.PageOptions) string {
 	return `
 {
@@ -226,6 +331,125 @@ func QueryERC20TokenHoldersAtBlock() string {
 `
 }
 
+// QueryERC20BalancesAtBlock finds the non-zero balance of each holder at a block, optionally only
+// those of at least the given sortable amount, and those that come after the given clause
+func QueryERC20BalancesAtBlock(minAmount string, afterQuery string) string {
+	minQuery := ""
+	if minAmount != "" {
+		minQuery = fmt.Sprintf(`,
+				{ "range": { "sortableAmount.keyword": { "gte": "%s" } } }`, minAmount)
+	}
+	if afterQuery != "" {
+		afterQuery = `,
+				` + afterQuery
+	}
+	return `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "match": { "contract": "%s"} },
+				{ "range": { "blockNumber": { "lte": %d } } }` + minQuery + afterQuery + `
+			],
+			"must_not": [
+				{ "term": { "amount.keyword": "0" } }
+			],
+			"filter": [{
+				"bool": {
+					"should": [
+						{ "range": { "heldUntil": { "gte": %d } } },
+						{ "bool": { "must_not": { "exists": { "field": "heldUntil" } } } }
+					]
+				}
+			}]
+		}
+	}
+}
+`
+}
+
+// QueryBlocksByNumber finds the blocks with the given numbers
+func QueryBlocksByNumber(numbers []uint64) string {
+	formatted := make([]string, len(numbers))
+	for i, number := range numbers {
+		formatted[i] = strconv.FormatUint(number, 10)
+	}
+	return `
+{
+	"query": {
+		"terms": { "number": [` + strings.Join(formatted, ", ") + `] }
+	}
+}
+`
+}
+
+// QueryHoldingsOfHolder finds the entries of a holder across all contracts that were held at any point within
+// the block range, where fromField is the field of the block each entry was held from
+func QueryHoldingsOfHolder(fromField string, excludeZero bool, options *types.TokenQueryOptions) string {
+	return queryHoldings("holder", fromField, excludeZero, options)
+}
+
+// QueryHoldingsOfToken finds the non-zero ERC20 balances of all the holders of a token that were held at any
+// point within the block range
+func QueryHoldingsOfToken(options *types.TokenQueryOptions) string {
+	return queryHoldings("contract", "blockNumber", true, options)
+}
+
+func queryHoldings(field string, fromField string, excludeZero bool, options *types.TokenQueryOptions) string {
+	endQuery := ""
+	if options.EndBlockNumber.Cmp(big.NewInt(-1)) != 0 {
+		endQuery = fmt.Sprintf(`,
+				{ "range": { "%s": { "lte": %s } } }`, fromField, options.EndBlockNumber.String())
+	}
+	zeroQuery := ""
+	if excludeZero {
+		zeroQuery = `
+			"must_not": [
+				{ "term": { "amount.keyword": "0" } }
+			],`
+	}
+	return `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "match": { "` + field + `": "%s" } }` + endQuery + `
+			],` + zeroQuery + `
+			"filter": [{
+				"bool": {
+					"should": [
+						{ "range": { "heldUntil": { "gte": ` + options.BeginBlockNumber.String() + ` } } },
+						{ "bool": { "must_not": { "exists": { "field": "heldUntil" } } } }
+					]
+				}
+			}]
+		}
+	}
+}
+`
+}
+
+// createAfterBalanceQuery selects the balances that come after the given one, when
+// sorted by amount in the given direction and then by holder
+func createAfterBalanceQuery(sortableAmount string, holder string, ascending bool) string {
+	op := "lt"
+	if ascending {
+		op = "gt"
+	}
+	return fmt.Sprintf(`{
+					"bool": {
+						"should": [
+							{ "range": { "sortableAmount.keyword": { "%s": "%s" } } },
+							{ "bool": { "must": [
+								{ "term": { "sortableAmount.keyword": "%s" } },
+								{ "range": { "holder.keyword": { "gt": "%s" } } }
+							] } }
+						],
+						"minimum_should_match": 1
+					}
+				}`, op, sortableAmount, sortableAmount, holder)
+}
+
 func createRangeQuery(name string, start *big.Int, end *big.Int) string {
 	if end.Cmp(big.NewInt(-1)) == 0 {
 		return fmt.Sprintf(`{ "range": { "%s": { "gte": %s } } }`, name, start.String())
@@ -233,6 +457,257 @@ func createRangeQuery(name string, start *big.Int, end *big.Int) string {
 	return fmt.Sprintf(`{ "range": { "%s": { "gte": %s, "lte": %s } } }`, name, start.String(), end.String())
 }
 
+// createEventFilterQuery creates the clauses of an event filter, each followed by a comma.
+// Values are quoted as JSON strings, as they are given by the user.
+func createEventFilterQuery(filter *types.EventFilter) string {
+	var clauses string
+	if filter.IsSignature() {
+		clauses += fmt.Sprintf(`{ "term": { "eventSig": %s } },
+`, quote(filter.EventSig()))
+	} else if filter.Event != "" {
+		clauses += fmt.Sprintf(`{ "term": { "eventName": %s } },
+`, quote(filter.Event))
+	}
+	for _, argFilter := range filter.Args {
+		clauses += createNestedArgQuery("args", argFilter)
+	}
+	return clauses
+}
+
+// createCallFilterQuery creates the clauses of the call filter of validated options, each followed by a comma
+func createCallFilterQuery(options *types.QueryOptions) string {
+	var clauses string
+	if options.Status != "" {
+		clauses += fmt.Sprintf(`{ "term": { "status": %t } },
+`, options.Status == types.StatusSuccess)
+	}
+	if options.IsFunctionSelector() {
+		clauses += fmt.Sprintf(`{ "term": { "call.selector": %s } },
+`, quote(options.FunctionValue()))
+	} else if options.IsFunctionSignature() {
+		clauses += fmt.Sprintf(`{ "term": { "call.sig": %s } },
+`, quote(options.FunctionValue()))
+	} else if options.Function != "" {
+		clauses += fmt.Sprintf(`{ "term": { "call.name": %s } },
+`, quote(options.Function))
+	}
+	for _, argFilter := range options.Inputs {
+		clauses += createNestedArgQuery("call.inputs", argFilter)
+	}
+	return clauses
+}
+
+// createNestedArgQuery creates a nested query on decoded arguments, so that the filter matches
+// the name and value of the same argument
+func createNestedArgQuery(path string, argFilter *types.ArgFilter) string {
+	comparison := fmt.Sprintf(`{ "range": { "%s.number": { "%s": %s } } }`, path, argFilter.Op, quote(argFilter.Operand()))
+	if argFilter.Op == "eq" {
+		comparison = fmt.Sprintf(`{ "term": { "%s.value": %s } }`, path, quote(argFilter.Operand()))
+	}
+	return fmt.Sprintf(`{ "nested": { "path": "%s", "query": { "bool": { "must": [ { "term": { "%s.name": %s } }, %s ] } } } },
+`, path, path, quote(argFilter.Name), comparison)
+}
+
+func quote(value string) string {
+	quoted, _ := json.Marshal(value)
+	return string(quoted)
+}
+
+func QueryERC20AllowanceAtBlock() string {
+	return `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "term": { "contract": "%s" } },
+				{ "term": { "owner": "%s" } },
+				{ "term": { "spender": "%s" } },
+				{ "range": { "blockNumber": { "lte": %d } } }
+			]
+		}
+	},
+	"sort": [
+		{
+			"blockNumber": {
+				"order": "desc",
+				"unmapped_type": "long"
+			}
+		}
+	]
+}
+`
+}
+
+// QueryERC20AllowanceAtBlockRange finds the allowances set within the block range,
+// as well as the allowance that was in place at the start of the range
+func QueryERC20AllowanceAtBlockRange(options *types.TokenQueryOptions) string {
+	return `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "term": { "contract": "%s" } },
+				{ "term": { "owner": "%s" } },
+				{ "term": { "spender": "%s" } }
+			],
+			"filter": [{
+				"bool": {
+					"should": [
+						` + createRangeQuery("blockNumber", options.BeginBlockNumber, options.EndBlockNumber) + `,
+						{
+							"bool": {
+								"must": [{ "range": { "blockNumber": { "lt": ` + options.BeginBlockNumber.String() + ` } } }],
+								"should": [
+									{ "range": { "validUntil": { "gte": ` + options.BeginBlockNumber.String() + ` } } },
+									{ "bool": { "must_not": { "exists": { "field": "validUntil" } } } }
+								],
+								"minimum_should_match": 1
+							}
+						}
+					]
+				}
+			}]
+		}
+	}
+}
+`
+}
+
+func QueryERC20TotalSupplyAtBlock() string {
+	return `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "term": { "contract": "%s" } },
+				{ "range": { "blockNumber": { "lte": %d } } }
+			]
+		}
+	},
+	"sort": [
+		{
+			"blockNumber": {
+				"order": "desc",
+				"unmapped_type": "long"
+			}
+		}
+	]
+}
+`
+}
+
+// QueryERC20TotalSupplyAtBlockRange finds the total supplies recorded within the block range,
+// as well as the total supply at the start of the range
+func QueryERC20TotalSupplyAtBlockRange(options *types.TokenQueryOptions) string {
+	return `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "term": { "contract": "%s" } }
+			],
+			"filter": [{
+				"bool": {
+					"should": [
+						` + createRangeQuery("blockNumber", options.BeginBlockNumber, options.EndBlockNumber) + `,
+						{
+							"bool": {
+								"must": [{ "range": { "blockNumber": { "lt": ` + options.BeginBlockNumber.String() + ` } } }],
+								"should": [
+									{ "range": { "validUntil": { "gte": ` + options.BeginBlockNumber.String() + ` } } },
+									{ "bool": { "must_not": { "exists": { "field": "validUntil" } } } }
+								],
+								"minimum_should_match": 1
+							}
+						}
+					]
+				}
+			}]
+		}
+	}
+}
+`
+}
+
+// QueryERC20ApprovalsForOwnerAtBlock finds the non-zero allowances an owner has given at a block,
+// to the spenders after the given one
+func QueryERC20ApprovalsForOwnerAtBlock(afterSpender string) string {
+	return `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "term": { "contract": "%s" } },
+				{ "term": { "owner": "%s" } },
+				{ "range": { "blockNumber": { "lte": %d } } },
+				{ "range": { "spender": { "gt": ` + quote(afterSpender) + ` } } }
+			],
+			"must_not": [
+				{ "term": { "amount": "0" } }
+			],
+			"filter": [{
+				"bool": {
+					"should": [
+						{ "range": { "validUntil": { "gte": %d } } },
+						{ "bool": { "must_not": { "exists": { "field": "validUntil" } } } }
+					]
+				}
+			}]
+		}
+	}
+}
+`
+}
+
+// QueryERC20TransfersTemplate finds the transfers of a contract matching a validated filter
+func QueryERC20TransfersTemplate(filter *types.ERC20TransferFilter, options *types.QueryOptions) string {
+	return `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "term": { "contract": "%s" } },
+` + createTransferFilterQuery(filter) + createRangeQuery("blockNumber", options.BeginBlockNumber, options.EndBlockNumber) + `,
+` + createRangeQuery("timestamp", options.BeginTimestamp, options.EndTimestamp) + `
+			]
+		}
+	}
+}
+`
+}
+
+// createTransferFilterQuery creates the clauses of a transfer filter, each followed by a comma
+func createTransferFilterQuery(filter *types.ERC20TransferFilter) string {
+	var clauses string
+	if filter.Holder != nil {
+		switch filter.Role {
+		case types.TransferRoleSender:
+			clauses += fmt.Sprintf(`{ "term": { "from": "%s" } },
+`, filter.Holder.String())
+		case types.TransferRoleReceiver:
+			clauses += fmt.Sprintf(`{ "term": { "to": "%s" } },
+`, filter.Holder.String())
+		default:
+			clauses += fmt.Sprintf(`{ "bool": { "should": [ { "term": { "from": "%s" } }, { "term": { "to": "%s" } } ] } },
+`, filter.Holder.String(), filter.Holder.String())
+		}
+	}
+	if filter.Kind != "" {
+		clauses += fmt.Sprintf(`{ "term": { "kind": %s } },
+`, quote(filter.Kind))
+	}
+	min, max := filter.AmountRange()
+	if min != "" {
+		clauses += fmt.Sprintf(`{ "range": { "sortableAmount": { "gte": %s } } },
+`, quote(min))
+	}
+	if max != "" {
+		clauses += fmt.Sprintf(`{ "range": { "sortableAmount": { "lte": %s } } },
+`, quote(max))
+	}
+	return clauses
+}
+
 func QueryERC721TokenAtBlock() string {
 	return `
 {
@@ -257,6 +732,58 @@ func QueryERC721TokenAtBlock() string {
 `
 }
 
+func QueryERC721TokenHistory() string {
+	return `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "match": { "contract": "%s"} },
+				{ "match": { "token": "%s"} }
+			]
+		}
+	}
+}
+`
+}
+
+// QueryERC721TransfersOfHolder finds the transfers of tokens within the block range where the holder
+// is in the given field, which is holder for those it received and from for those it sent
+func QueryERC721TransfersOfHolder(field string, options *types.TokenQueryOptions) string {
+	return `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "match": { "` + field + `": "%s"} },
+				` + createRangeQuery("heldFrom", options.BeginBlockNumber, options.EndBlockNumber) + `
+			]
+		}
+	}
+}
+`
+}
+
+// QueryERC721Metadata finds the metadata of the given tokens of a contract
+func QueryERC721Metadata(tokenIds []string) string {
+	quoted := make([]string, len(tokenIds))
+	for i, tokenId := range tokenIds {
+		quoted[i] = strconv.Quote(tokenId)
+	}
+	return `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "term": { "contract": "%s"} },
+				{ "terms": { "token": [` + strings.Join(quoted, ", ") + `] } }
+			]
+		}
+	}
+}
+`
+}
+
 func QueryERC721HolderAtBlock(start *big.Int) string {
 	return `
 {
@@ -342,6 +869,145 @@ func QueryERC721AllHoldersAtBlock() string {
 `
 }
 
+func QueryERC1155BalanceAtBlock() string {
+	return `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "match": { "contract": "%s"} },
+				{ "match": { "holder": "%s"} },
+				{ "match": { "token": "%s"} },
+				{ "range": { "heldFrom": { "lte": %d } } }
+			]
+		}
+	},
+	"sort": [
+		{
+			"heldFrom": {
+				"order": "desc",
+				"unmapped_type": "long"
+			}
+		}
+	]
+}
+`
+}
+
+func QueryERC1155BalanceAtBlockRange(options *types.TokenQueryOptions) string {
+	rangeQuery := `
+      "filter": [
+        {
+          "bool": {
+            "should": [
+              ` + createRangeQuery("heldFrom", options.BeginBlockNumber, options.EndBlockNumber) + `,
+              {
+                "bool": {
+                  "must": [{"range": {"heldFrom": {"lt": %d}}}],
+                  "filter": [
+                    {
+                      "bool": {
+                        "should": [
+                          {"range": {"heldUntil": {"gte": %d}}},
+                          {"bool": {"must_not": {"exists": {"field": "heldUntil"}}}}
+                        ]
+                      }
+                    }
+                  ]
+                }
+              }
+            ]
+          }
+        }
+      ],
+`
+	rangeQuery = fmt.Sprintf(rangeQuery, options.BeginBlockNumber.Uint64(), options.BeginBlockNumber.Uint64())
+
+	return `
+{
+  "query": {
+    "bool": {
+` + rangeQuery + `
+      "must": [
+        {"match": {"contract": "%s"}},
+        {"match": {"holder": "%s"}},
+        {"match": {"token": "%s"}}
+      ]
+    }
+  }
+}
+`
+}
+
+// QueryERC1155HoldersAtBlock finds the accounts with a non-zero balance of a token ID at a block
+func QueryERC1155HoldersAtBlock() string {
+	return `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "match": { "contract": "%s"} },
+				{ "match": { "token": "%s"} },
+				{ "range": { "heldFrom": { "lte": %d } } }
+			],
+			"must_not": [
+				{ "term": { "amount.keyword": "0" } }
+			],
+			"filter": [{
+                "bool": {
+                    "should": [
+						{ "range": { "heldUntil": { "gte": %d } } }, 
+						{ "bool": { "must_not": { "exists": { "field": "heldUntil" } } } }
+					]
+                }
+            }]
+		}
+	},
+	"size": 0,
+	"aggs" : {
+		"result_buckets": {
+			"composite" : {
+				"size": %d,
+				%s
+				"sources" : [
+					{ "holder": { "terms" : { "field": "holder.keyword" } } }
+				]
+		  	}
+		}
+	}
+}
+`
+}
+
+// QueryERC1155HolderTokensAtBlock finds the token IDs an account has a non-zero balance of at a block
+func QueryERC1155HolderTokensAtBlock(start *big.Int) string {
+	return `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "match": { "contract": "%s"} },
+				{ "match": { "holder": "%s"} },
+				{ "range": { "heldFrom": { "lte": %d } } },
+` + createTokenRangeQuery(start) + `
+			],
+			"must_not": [
+				{ "term": { "amount.keyword": "0" } }
+			],
+			"filter": [{
+                "bool": {
+                    "should": [
+						{ "range": { "heldUntil": { "gte": %d } } }, 
+						{ "bool": { "must_not": { "exists": { "field": "heldUntil" } } } }
+					]
+                }
+            }]
+		}
+	}
+}
+`
+}
+
 func createTokenRangeQuery(start *big.Int) string {
 	next := new(big.Int).Add(start, big.NewInt(1))
 
@@ -361,3 +1027,13 @@ func createTokenRangeQuery(start *big.Int) string {
 		fmt.Sprintf(`{ "range": { "%s": { "gte": %d } } }`, "fifth", startFifth),
 	)
 }
+
+// withSearchAfter continues a search from the sort values of the last result of the previous page
+func withSearchAfter(query string, values ...uint64) string {
+	formatted := make([]string, len(values))
+	for i, value := range values {
+		formatted[i] = strconv.FormatUint(value, 10)
+	}
+	return strings.Replace(query, "{", `{
+	"search_after": [`+strings.Join(formatted, ", ")+`],`, 1)
+}
@@ -0,0 +1,167 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/elastic/go-elasticsearch/v7/esapi"
+	"github.com/elastic/go-elasticsearch/v7/esutil"
+
+	"quorumengineering/quorum-report/database"
+	"quorumengineering/quorum-report/types"
+)
+
+// Sampler DB
+func (es *ElasticsearchDB) AddSampler(address types.Address, sampler *types.FunctionSampler) error {
+	samplers, err := es.GetSamplers(address)
+	if err != nil {
+		return err
+	}
+	return es.updateSamplers(address, database.InsertSampler(samplers, sampler))
+}
+
+func (es *ElasticsearchDB) DeleteSampler(address types.Address, name string) error {
+	samplers, err := es.GetSamplers(address)
+	if err != nil {
+		return err
+	}
+	samplers, err = database.RemoveSampler(samplers, name)
+	if err != nil {
+		return err
+	}
+	return es.updateSamplers(address, samplers)
+}
+
+func (es *ElasticsearchDB) GetSamplers(address types.Address) ([]*types.FunctionSampler, error) {
+	contract, err := es.getContractByAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	samplers := make([]*types.FunctionSampler, len(contract.Samplers))
+	for i, stored := range contract.Samplers {
+		sampler := &types.FunctionSampler{
+			Name:     stored.Name,
+			Function: stored.Function,
+			Interval: stored.Interval,
+			Sig:      stored.Sig,
+			CallData: stored.CallData,
+		}
+		if stored.Args != "" {
+			if err := json.Unmarshal([]byte(stored.Args), &sampler.Args); err != nil {
+				return nil, err
+			}
+		}
+		samplers[i] = sampler
+	}
+	return samplers, nil
+}
+
+func (es *ElasticsearchDB) RecordSamples(samples []*types.FunctionSample) error {
+	bi := es.apiClient.GetBulkHandler(SampleIndex)
+
+	var (
+		wg        sync.WaitGroup
+		returnErr error
+	)
+	for _, sample := range samples {
+		wg.Add(1)
+		_ = bi.Add(
+			context.Background(),
+			esutil.BulkIndexerItem{
+				// samples are overwritten if a batch of blocks is filtered again
+				Action:     "index",
+				DocumentID: fmt.Sprintf("%s-%s-%d", sample.Address.String(), sample.Name, sample.BlockNumber),
+				Body:       esutil.NewJSONReader(sample),
+				OnSuccess: func(ctx context.Context, item esutil.BulkIndexerItem, item2 esutil.BulkIndexerResponseItem) {
+					wg.Done()
+				},
+				OnFailure: func(ctx context.Context, item esutil.BulkIndexerItem, item2 esutil.BulkIndexerResponseItem, err error) {
+					returnErr = err
+					wg.Done()
+				},
+			},
+		)
+	}
+	wg.Wait()
+	return returnErr
+}
+
+func (es *ElasticsearchDB) GetSamples(address types.Address, name string, options *types.QueryOptions) ([]*types.FunctionSample, error) {
+	options = types.SamplesOptions(options)
+	cursor, err := options.Cursor()
+	if err != nil {
+		return nil, err
+	}
+	from, err := pageFrom(options.PageSize, options.PageNumber, cursor != nil)
+	if err != nil {
+		return nil, err
+	}
+	queryString := querySamples(address, name, options)
+	if cursor != nil {
+		// a sampler has a single sample at each block, so the block number alone is the position
+		queryString = withSearchAfter(queryString, cursor.BlockNumber)
+	}
+	req := esapi.SearchRequest{
+		Index: []string{SampleIndex},
+		Body:  strings.NewReader(queryString),
+		From:  &from,
+		Size:  &options.PageSize,
+		Sort:  []string{"blockNumber:" + options.Sort},
+	}
+	results, err := es.doSearchRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	samples := make([]*types.FunctionSample, len(results.Hits.Hits))
+	for i, result := range results.Hits.Hits {
+		marshalled, _ := json.Marshal(result.Source)
+		var sample types.FunctionSample
+		if err = json.Unmarshal(marshalled, &sample); err != nil {
+			return nil, err
+		}
+		samples[i] = &sample
+	}
+	return samples, nil
+}
+
+func (es *ElasticsearchDB) GetSamplesTotal(address types.Address, name string, options *types.QueryOptions) (uint64, error) {
+	req := esapi.CountRequest{
+		Index: []string{SampleIndex},
+		Body:  strings.NewReader(querySamples(address, name, options)),
+	}
+	results, err := es.doCountRequest(req)
+	if err != nil {
+		return 0, err
+	}
+	return results.Count, nil
+}
+
+func (es *ElasticsearchDB) updateSamplers(address types.Address, samplers []*types.FunctionSampler) error {
+	stored := make([]*Sampler, len(samplers))
+	for i, sampler := range samplers {
+		stored[i] = &Sampler{
+			Name:     sampler.Name,
+			Function: sampler.Function,
+			Interval: sampler.Interval,
+			Sig:      sampler.Sig,
+			CallData: sampler.CallData,
+		}
+		if len(sampler.Args) > 0 {
+			args, err := json.Marshal(sampler.Args)
+			if err != nil {
+				return err
+			}
+			stored[i].Args = string(args)
+		}
+	}
+	return es.updateContract(address, "samplers", stored)
+}
+
+func querySamples(address types.Address, name string, options *types.QueryOptions) string {
+	quotedName, _ := json.Marshal(name)
+	return fmt.Sprintf(QuerySamplesWithOptionsTemplate(options), address.String(), quotedName)
+}
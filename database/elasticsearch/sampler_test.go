@@ -0,0 +1,232 @@
+package elasticsearch
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v7/esapi"
+	"github.com/elastic/go-elasticsearch/v7/esutil"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	elasticsearchmocks "quorumengineering/quorum-report/database/elasticsearch/mocks"
+	"quorumengineering/quorum-report/types"
+)
+
+func TestElasticsearchDB_AddSampler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+
+	addr := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+
+	searchContractRequest := esapi.GetRequest{
+		Index:      ContractIndex,
+		DocumentID: addr.String(),
+	}
+	contractSearchReturnValue := `{
+	       "_source": {
+	         "address" : "0x1932c48b2bf8102ba33b4a6b545c32236e342f34",
+	         "lastFiltered" : 20,
+	         "samplers": [{"name": "collateralRatio", "function": "collateralRatio", "interval": 10, "sig": "collateralRatio()", "callData": "0xb4eae1cb"}]
+	       }
+	}`
+	contractQuery := map[string]interface{}{
+		"doc": map[string]interface{}{
+			"samplers": []*Sampler{
+				{Name: "collateralRatio", Function: "collateralRatio", Interval: 10, Sig: "collateralRatio()", CallData: "b4eae1cb"},
+				{Name: "ownerBalance", Function: "balanceOf", Args: `["0x9d13c6d3afe1721beef56b55d303b09e021e27ab"]`, Sig: "balanceOf(address owner)", CallData: "70a082310000000000000000000000009d13c6d3afe1721beef56b55d303b09e021e27ab"},
+			},
+		},
+	}
+	contractUpdateRequest := esapi.UpdateRequest{
+		Index:      ContractIndex,
+		DocumentID: addr.String(),
+		Body:       esutil.NewJSONReader(contractQuery),
+		Refresh:    "true",
+	}
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().DoRequest(NewGetRequestMatcher(searchContractRequest)).Return([]byte(contractSearchReturnValue), nil).Times(2)
+	mockedClient.EXPECT().DoRequest(NewUpdateRequestMatcher(contractUpdateRequest))
+
+	db, _ := New(mockedClient)
+
+	err := db.AddSampler(addr, &types.FunctionSampler{
+		Name:     "ownerBalance",
+		Function: "balanceOf",
+		Args:     []interface{}{"0x9d13c6d3afe1721beef56b55d303b09e021e27ab"},
+		Sig:      "balanceOf(address owner)",
+		CallData: "70a082310000000000000000000000009d13c6d3afe1721beef56b55d303b09e021e27ab",
+	})
+
+	assert.Nil(t, err, "expected error to be nil")
+}
+
+func TestElasticsearchDB_GetSamplers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+
+	addr := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+
+	searchContractRequest := esapi.GetRequest{
+		Index:      ContractIndex,
+		DocumentID: addr.String(),
+	}
+	contractSearchReturnValue := `{
+	       "_source": {
+	         "address" : "0x1932c48b2bf8102ba33b4a6b545c32236e342f34",
+	         "lastFiltered" : 20,
+	         "samplers": [{"name": "ownerBalance", "function": "balanceOf", "args": "[\"0x9d13c6d3afe1721beef56b55d303b09e021e27ab\"]", "interval": 0, "sig": "balanceOf(address owner)", "callData": "0x70a08231"}]
+	       }
+	}`
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().DoRequest(NewGetRequestMatcher(searchContractRequest)).Return([]byte(contractSearchReturnValue), nil)
+
+	db, _ := New(mockedClient)
+
+	samplers, err := db.GetSamplers(addr)
+
+	assert.Nil(t, err, "expected error to be nil")
+	assert.Len(t, samplers, 1)
+	assert.Equal(t, "ownerBalance", samplers[0].Name)
+	assert.Equal(t, []interface{}{"0x9d13c6d3afe1721beef56b55d303b09e021e27ab"}, samplers[0].Args)
+	assert.Equal(t, types.HexData("70a08231"), samplers[0].CallData)
+}
+
+func TestElasticsearchDB_RecordSamples(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+	mockedBulkIndexer := elasticsearchmocks.NewMockBulkIndexer(ctrl)
+
+	sample := &types.FunctionSample{
+		Address:     types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34"),
+		Name:        "collateralRatio",
+		Sig:         "collateralRatio()",
+		BlockNumber: 20,
+		Timestamp:   1000,
+		Output:      "0000000000000000000000000000000000000000000000000000000000000096",
+	}
+	req := esutil.BulkIndexerItem{
+		Action:     "index",
+		DocumentID: "0x1932c48b2bf8102ba33b4a6b545c32236e342f34-collateralRatio-20",
+		Body:       esutil.NewJSONReader(sample),
+	}
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().GetBulkHandler(SampleIndex).Return(mockedBulkIndexer)
+	mockedBulkIndexer.EXPECT().
+		Add(gomock.Any(), NewBulkIndexerItemMatcher(req)).
+		Do(func(ctx context.Context, item esutil.BulkIndexerItem) {
+			item.OnSuccess(context.Background(), req, esutil.BulkIndexerResponseItem{})
+		})
+
+	db, _ := New(mockedClient)
+
+	err := db.RecordSamples([]*types.FunctionSample{sample})
+
+	assert.Nil(t, err, "expected error to be nil")
+}
+
+func TestElasticsearchDB_GetSamples(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+
+	addr := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+
+	response := `{"hits": {"hits": [
+  {
+  "_source": {
+    "address": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34",
+    "name": "collateralRatio",
+    "sig": "collateralRatio()",
+    "blockNumber": 20,
+    "timestamp": 1000,
+    "output": "0x0000000000000000000000000000000000000000000000000000000000000096"
+  }
+}]}}`
+
+	from := 0
+	size := 10
+	options := &types.QueryOptions{}
+	options.SetDefaults()
+
+	queryString := `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "match": { "address": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34" } },
+				{ "term": { "name.keyword": "collateralRatio" } },
+{ "range": { "blockNumber": { "gte": 0 } } },
+{ "range": { "timestamp": { "gte": 0 } } }
+			]
+		}
+	}
+}
+`
+	req := esapi.SearchRequest{
+		Index: []string{SampleIndex},
+		Body:  strings.NewReader(queryString),
+		From:  &from,
+		Size:  &size,
+		Sort:  []string{"blockNumber:asc"},
+	}
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().DoRequest(NewSearchRequestMatcher(req)).Return([]byte(response), nil)
+
+	db, _ := New(mockedClient)
+	samples, err := db.GetSamples(addr, "collateralRatio", options)
+
+	assert.Nil(t, err, "unexpected error")
+	assert.Len(t, samples, 1)
+	assert.EqualValues(t, 20, samples[0].BlockNumber)
+	assert.Equal(t, types.HexData("0000000000000000000000000000000000000000000000000000000000000096"), samples[0].Output)
+}
+
+func TestElasticsearchDB_GetSamples_WithCursor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+
+	addr := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+
+	from := 0
+	size := 10
+	options := &types.QueryOptions{PageNumber: 5000, Sort: types.SortDescending, After: types.NewCursor(20, 0).Encode()}
+	options.SetDefaults()
+
+	queryString := strings.Replace(querySamples(addr, "collateralRatio", options), "{", `{
+	"search_after": [20],`, 1)
+	req := esapi.SearchRequest{
+		Index: []string{SampleIndex},
+		Body:  strings.NewReader(queryString),
+		From:  &from,
+		Size:  &size,
+		Sort:  []string{"blockNumber:desc"},
+	}
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().DoRequest(NewSearchRequestMatcher(req)).Return([]byte(`{"hits": {"hits": []}}`), nil)
+
+	db, _ := New(mockedClient)
+	samples, err := db.GetSamples(addr, "collateralRatio", options)
+
+	assert.Nil(t, err, "unexpected error")
+	assert.Len(t, samples, 0)
+
+	options.After = ""
+	_, err = db.GetSamples(addr, "collateralRatio", options)
+	assert.Equal(t, ErrPaginationLimitExceeded, err)
+}
@@ -59,6 +59,28 @@ func (rm *SearchRequestMatcher) String() string {
 	return fmt.Sprintf("SearchRequestMatcher{%s/%d/%d/%s/%s}", rm.req.Index, rm.req.From, rm.req.Size, rm.req.Sort, rm.body)
 }
 
+type CountRequestMatcher struct {
+	req  esapi.CountRequest
+	body string
+}
+
+func NewCountRequestMatcher(req esapi.CountRequest) *CountRequestMatcher {
+	body, _ := ioutil.ReadAll(req.Body)
+	return &CountRequestMatcher{req: req, body: string(body)}
+}
+
+func (rm *CountRequestMatcher) Matches(x interface{}) bool {
+	if val, ok := x.(esapi.CountRequest); ok {
+		actualBody, _ := ioutil.ReadAll(val.Body)
+		return val.Index[0] == rm.req.Index[0] && string(actualBody) == rm.body
+	}
+	return false
+}
+
+func (rm *CountRequestMatcher) String() string {
+	return fmt.Sprintf("CountRequestMatcher{%s/%s}", rm.req.Index, rm.body)
+}
+
 type DeleteRequestMatcher struct {
 	req esapi.DeleteRequest
 }
@@ -1,20 +1,28 @@
 package elasticsearch
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/elastic/go-elasticsearch/v7/esapi"
 	"github.com/elastic/go-elasticsearch/v7/esutil"
 	"github.com/mitchellh/mapstructure"
 
 	"quorumengineering/quorum-report/database"
+	"quorumengineering/quorum-report/log"
 	"quorumengineering/quorum-report/types"
 )
 
+// maxScrolledResults is the most records of a single holder or token that are fetched at once
+const maxScrolledResults = 10000
+
 // Token DB
 func (es *ElasticsearchDB) RecordNewERC20Balance(contract types.Address, holder types.Address, block uint64, amount *big.Int) error {
 	//find old entry
@@ -25,10 +33,11 @@ func (es *ElasticsearchDB) RecordNewERC20Balance(contract types.Address, holder
 
 	//add new entry
 	tokenInfo := ERC20TokenHolder{
-		Contract:    contract,
-		Holder:      holder,
-		BlockNumber: block,
-		Amount:      amount.String(),
+		Contract:       contract,
+		Holder:         holder,
+		BlockNumber:    block,
+		Amount:         amount.String(),
+		SortableAmount: types.SortableNumber(amount),
 	}
 
 	req := esapi.IndexRequest{
@@ -90,19 +99,42 @@ func (es *ElasticsearchDB) GetERC20EntryAtBlock(contract types.Address, holder t
 	return tokenResult, err
 }
 
+func (es *ElasticsearchDB) GetERC20BalanceAtBlock(contract types.Address, holder types.Address, block uint64) (*big.Int, error) {
+	entry, err := es.GetERC20EntryAtBlock(contract, holder, block)
+	if err != nil {
+		return nil, err
+	}
+	balance, success := new(big.Int).SetString(entry.Amount, 10)
+	if !success {
+		return nil, errors.New("could not parse token value")
+	}
+	return balance, nil
+}
+
 func (es *ElasticsearchDB) GetERC20Balance(contract types.Address, holder types.Address, options *types.TokenQueryOptions) (map[uint64]*big.Int, error) {
 	queryString := fmt.Sprintf(QueryTokenBalanceAtBlockRange(options), contract.String(), holder.String())
 
-	from := options.PageSize * options.PageNumber
-	if from+options.PageSize > 1000 {
-		return nil, ErrPaginationLimitExceeded
+	from, err := pageFrom(options.PageSize, options.PageNumber, options.After != "")
+	if err != nil {
+		return nil, err
+	}
+	if options.After != "" {
+		afterBlock, err := strconv.ParseUint(options.After, 10, 64)
+		if err != nil {
+			return nil, errors.New(`could not parse "after" block number`)
+		}
+		queryString = withSearchAfter(queryString, afterBlock)
+	}
+	direction := "desc"
+	if options.Sort == types.SortAscending {
+		direction = "asc"
 	}
 	req := esapi.SearchRequest{
 		Index: []string{ERC20TokenIndex},
 		Body:  strings.NewReader(queryString),
 		From:  &from,
 		Size:  &options.PageSize,
-		Sort:  []string{"blockNumber:desc"},
+		Sort:  []string{"blockNumber:" + direction},
 	}
 	results, err := es.doSearchRequest(req)
 	if err != nil {
@@ -160,38 +192,233 @@ func (es *ElasticsearchDB) GetAllTokenHolders(contract types.Address, block uint
 	return convertedResults, nil
 }
 
-func (es *ElasticsearchDB) RecordERC721Token(contract types.Address, holder types.Address, block uint64, tokenId *big.Int) error {
+// BackfillSortableAmounts adds the sortable amount to ERC20 balances recorded before it was stored with them,
+// as balances without it cannot be ordered or filtered by amount when listing the balances of a token at a block
+func (es *ElasticsearchDB) BackfillSortableAmounts() error {
+	results, err := es.apiClient.ScrollAllResults(ERC20TokenIndex, fmt.Sprintf(QueryMissingFieldTemplate, "sortableAmount"))
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		return nil
+	}
+
+	docs := make(map[string]interface{}, len(results))
+	for _, result := range results {
+		hit := result.(map[string]interface{})
+		var entry ERC20TokenHolder
+		marshalled, _ := json.Marshal(hit["_source"])
+		if err := json.Unmarshal(marshalled, &entry); err != nil {
+			return err
+		}
+		amount, ok := new(big.Int).SetString(entry.Amount, 10)
+		if !ok {
+			return fmt.Errorf("invalid amount %q in ERC20 balance %v", entry.Amount, hit["_id"])
+		}
+		docs[hit["_id"].(string)] = map[string]interface{}{"sortableAmount": types.SortableNumber(amount)}
+	}
+	log.Info("Adding sortable amounts to ERC20 balances", "balances", len(docs))
+	return es.updateDocuments(ERC20TokenIndex, docs)
+}
+
+func (es *ElasticsearchDB) GetERC20BalancesAtBlock(contract types.Address, block uint64, minBalance *big.Int, options *types.TokenQueryOptions) ([]types.ERC20HolderBalance, error) {
+	from, err := pageFrom(options.PageSize, options.PageNumber, options.After != "")
+	if err != nil {
+		return nil, err
+	}
+
+	minAmount := ""
+	if minBalance != nil {
+		minAmount = types.SortableNumber(minBalance)
+	}
+	afterQuery := ""
+	if options.After != "" {
+		afterHolder := types.NewAddress(options.After)
+		after, err := es.GetERC20EntryAtBlock(contract, afterHolder, block)
+		if err == database.ErrNotFound {
+			return nil, errors.New(`"after" holder has no balance at the block`)
+		}
+		if err != nil {
+			return nil, err
+		}
+		afterAmount, ok := new(big.Int).SetString(after.Amount, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid amount %q in ERC20 balance", after.Amount)
+		}
+		afterQuery = createAfterBalanceQuery(types.SortableNumber(afterAmount), afterHolder.String(), options.Sort == types.SortAscending)
+	}
+	formattedQuery := fmt.Sprintf(QueryERC20BalancesAtBlock(minAmount, afterQuery), contract.String(), block, block)
+
+	direction := "desc"
+	if options.Sort == types.SortAscending {
+		direction = "asc"
+	}
+	searchReq := esapi.SearchRequest{
+		Index: []string{ERC20TokenIndex},
+		Body:  strings.NewReader(formattedQuery),
+		From:  &from,
+		Size:  &options.PageSize,
+		Sort:  []string{"sortableAmount.keyword:" + direction, "holder.keyword:asc"},
+	}
+
+	results, err := es.doSearchRequest(searchReq)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make([]types.ERC20HolderBalance, 0, len(results.Hits.Hits))
+	for _, result := range results.Hits.Hits {
+		var entry ERC20TokenHolder
+		marshalled, _ := json.Marshal(result.Source)
+		if err := json.Unmarshal(marshalled, &entry); err != nil {
+			return nil, err
+		}
+		balances = append(balances, types.ERC20HolderBalance{
+			Holder:   entry.Holder,
+			Amount:   entry.Amount,
+			HeldFrom: entry.BlockNumber,
+		})
+	}
+	return balances, nil
+}
+
+func (es *ElasticsearchDB) GetERC20BalancesOfHolder(holder types.Address, options *types.TokenQueryOptions) ([]types.ERC20Balance, error) {
+	queryString := fmt.Sprintf(QueryHoldingsOfHolder("blockNumber", true, options), holder.String())
+	sources, err := es.scrollCappedSources(ERC20TokenIndex, queryString)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make([]types.ERC20Balance, 0, len(sources))
+	for _, source := range sources {
+		var entry ERC20TokenHolder
+		if err := json.Unmarshal(source, &entry); err != nil {
+			return nil, err
+		}
+		balances = append(balances, types.ERC20Balance{
+			Contract:  entry.Contract,
+			Holder:    entry.Holder,
+			Amount:    entry.Amount,
+			HeldFrom:  entry.BlockNumber,
+			HeldUntil: entry.HeldUntil,
+		})
+	}
+	return balances, nil
+}
+
+func (es *ElasticsearchDB) GetERC20BalancesOfToken(contract types.Address, options *types.TokenQueryOptions) ([]types.ERC20Balance, error) {
+	queryString := fmt.Sprintf(QueryHoldingsOfToken(options), contract.String())
+	sources, err := es.scrollCappedSources(ERC20TokenIndex, queryString)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make([]types.ERC20Balance, 0, len(sources))
+	for _, source := range sources {
+		var entry ERC20TokenHolder
+		if err := json.Unmarshal(source, &entry); err != nil {
+			return nil, err
+		}
+		balances = append(balances, types.ERC20Balance{
+			Contract:  entry.Contract,
+			Holder:    entry.Holder,
+			Amount:    entry.Amount,
+			HeldFrom:  entry.BlockNumber,
+			HeldUntil: entry.HeldUntil,
+		})
+	}
+	return balances, nil
+}
+
+func (es *ElasticsearchDB) GetERC721TokensOfHolder(holder types.Address, options *types.TokenQueryOptions) ([]types.ERC721Token, error) {
+	queryString := fmt.Sprintf(QueryHoldingsOfHolder("heldFrom", false, options), holder.String())
+	sources, err := es.scrollCappedSources(ERC721TokenIndex, queryString)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]types.ERC721Token, len(sources))
+	for i, source := range sources {
+		if err := json.Unmarshal(source, &tokens[i]); err != nil {
+			return nil, err
+		}
+	}
+	return tokens, nil
+}
+
+func (es *ElasticsearchDB) GetERC1155TokensOfHolder(holder types.Address, options *types.TokenQueryOptions) ([]types.ERC1155Token, error) {
+	queryString := fmt.Sprintf(QueryHoldingsOfHolder("heldFrom", true, options), holder.String())
+	sources, err := es.scrollCappedSources(ERC1155TokenIndex, queryString)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]types.ERC1155Token, len(sources))
+	for i, source := range sources {
+		if err := json.Unmarshal(source, &tokens[i]); err != nil {
+			return nil, err
+		}
+	}
+	return tokens, nil
+}
+
+// scrollCappedSources fetches the source of every document in the index matching the query, as with scrollSources,
+// unless there are more than maxScrolledResults of them. The records of a very active holder, such as an exchange,
+// or of a widely held token, could otherwise take more memory than the server has.
+func (es *ElasticsearchDB) scrollCappedSources(index string, query string) ([][]byte, error) {
+	counted, err := es.doCountRequest(esapi.CountRequest{
+		Index: []string{index},
+		Body:  strings.NewReader(query),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if counted.Count > maxScrolledResults {
+		return nil, ErrTooManyResults
+	}
+	return es.scrollSources(index, query)
+}
+
+// scrollSources fetches the source of every document in the index matching the query, each as JSON
+func (es *ElasticsearchDB) scrollSources(index string, query string) ([][]byte, error) {
+	results, err := es.apiClient.ScrollAllResults(index, query)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make([][]byte, 0, len(results))
+	for _, result := range results {
+		source, err := json.Marshal(result.(map[string]interface{})["_source"])
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+func (es *ElasticsearchDB) RecordNewERC20Allowance(contract types.Address, owner types.Address, spender types.Address, block uint64, amount *big.Int) error {
 	//find old entry
-	existingTokenEntry, errExisting := es.ERC721TokenByTokenID(contract, block-1, tokenId)
+	existingEntry, errExisting := es.getERC20AllowanceAtBlock(contract, owner, spender, block-1)
 	if errExisting != nil && errExisting != database.ErrNotFound {
 		return errExisting
 	}
 
-	paddedTokenId := fmt.Sprintf("%085d", tokenId)
-	first, _ := strconv.ParseUint(paddedTokenId[0:17], 10, 64)
-	second, _ := strconv.ParseUint(paddedTokenId[17:34], 10, 64)
-	third, _ := strconv.ParseUint(paddedTokenId[34:51], 10, 64)
-	fourth, _ := strconv.ParseUint(paddedTokenId[51:68], 10, 64)
-	fifth, _ := strconv.ParseUint(paddedTokenId[68:85], 10, 64)
-
 	//add new entry
-	tokenHolderInfo := SortableERC721Token{
-		types.ERC721Token{
-			Contract:  contract,
-			Holder:    holder,
-			Token:     tokenId.String(),
-			HeldFrom:  block,
-			HeldUntil: nil,
-		},
-		first, second, third, fourth, fifth,
+	allowance := types.ERC20Allowance{
+		Contract:    contract,
+		Owner:       owner,
+		Spender:     spender,
+		Amount:      amount.String(),
+		BlockNumber: block,
 	}
 
 	req := esapi.IndexRequest{
-		Index:      ERC721TokenIndex,
-		DocumentID: fmt.Sprintf("%s-%s-%d", contract.String(), tokenId.String(), block),
-		Body:       esutil.NewJSONReader(tokenHolderInfo),
+		Index:      ERC20AllowanceIndex,
+		DocumentID: fmt.Sprintf("%s-%s-%s-%d", contract.String(), owner.String(), spender.String(), block),
+		Body:       esutil.NewJSONReader(allowance),
 		Refresh:    "true",
-		OpType:     "create", //This will only create if the contract does not exist
+		OpType:     "create",
 	}
 
 	if _, err := es.apiClient.DoRequest(req); err != nil {
@@ -207,13 +434,13 @@ func (es *ElasticsearchDB) RecordERC721Token(contract types.Address, holder type
 	//update the older entry
 	query := map[string]interface{}{
 		"doc": map[string]interface{}{
-			"heldUntil": block - 1,
+			"validUntil": block - 1,
 		},
 	}
 
 	updateRequest := esapi.UpdateRequest{
-		Index:      ERC721TokenIndex,
-		DocumentID: fmt.Sprintf("%s-%s-%d", contract.String(), tokenId.String(), existingTokenEntry.HeldFrom),
+		Index:      ERC20AllowanceIndex,
+		DocumentID: fmt.Sprintf("%s-%s-%s-%d", contract.String(), owner.String(), spender.String(), existingEntry.BlockNumber),
 		Body:       esutil.NewJSONReader(query),
 		Refresh:    "true",
 	}
@@ -222,99 +449,71 @@ func (es *ElasticsearchDB) RecordERC721Token(contract types.Address, holder type
 	return err
 }
 
-func (es *ElasticsearchDB) ERC721TokenByTokenID(contract types.Address, block uint64, tokenId *big.Int) (types.ERC721Token, error) {
-	formattedQuery := fmt.Sprintf(QueryERC721TokenAtBlock(), contract.String(), tokenId.String(), block)
-
-	pageSize := 1
-	searchReq := esapi.SearchRequest{
-		Index: []string{ERC721TokenIndex},
-		Body:  strings.NewReader(formattedQuery),
-		Size:  &pageSize,
-	}
+func (es *ElasticsearchDB) GetERC20Allowance(contract types.Address, owner types.Address, spender types.Address, options *types.TokenQueryOptions) (map[uint64]*big.Int, error) {
+	queryString := fmt.Sprintf(QueryERC20AllowanceAtBlockRange(options), contract.String(), owner.String(), spender.String())
 
-	results, err := es.doSearchRequest(searchReq)
+	from, err := pageFrom(options.PageSize, options.PageNumber, options.After != "")
 	if err != nil {
-		return types.ERC721Token{}, err
-	}
-
-	if len(results.Hits.Hits) == 0 {
-		return types.ERC721Token{}, database.ErrNotFound
-	}
-
-	var tokenResult types.ERC721Token
-	if err = mapstructure.Decode(results.Hits.Hits[0].Source, &tokenResult); err != nil {
-		return types.ERC721Token{}, err
+		return nil, err
 	}
-	tokenResult.Contract = contract
-	tokenResult.Holder = types.NewAddress(string(tokenResult.Holder))
-	return tokenResult, nil
-}
-
-func (es *ElasticsearchDB) ERC721TokensForAccountAtBlock(contract types.Address, holder types.Address, block uint64, options *types.TokenQueryOptions) ([]types.ERC721Token, error) {
-	startTokenId := big.NewInt(-1)
 	if options.After != "" {
-		parsed, success := new(big.Int).SetString(options.After, 10)
-		if !success {
-			return nil, errors.New(`could not parse "after" token ID`)
+		afterBlock, err := strconv.ParseUint(options.After, 10, 64)
+		if err != nil {
+			return nil, errors.New(`could not parse "after" block number`)
 		}
-		startTokenId = parsed
+		queryString = withSearchAfter(queryString, afterBlock)
 	}
-
-	formattedQuery := fmt.Sprintf(QueryERC721HolderAtBlock(startTokenId), contract.String(), holder.String(), block, block)
-
-	from := options.PageSize * options.PageNumber
-	if from+options.PageSize > 1000 {
-		return nil, ErrPaginationLimitExceeded
+	direction := "desc"
+	if options.Sort == types.SortAscending {
+		direction = "asc"
 	}
-
-	searchReq := esapi.SearchRequest{
-		Index: []string{ERC721TokenIndex},
-		Body:  strings.NewReader(formattedQuery),
+	req := esapi.SearchRequest{
+		Index: []string{ERC20AllowanceIndex},
+		Body:  strings.NewReader(queryString),
 		From:  &from,
 		Size:  &options.PageSize,
-		Sort:  []string{"first:asc", "second:asc", "third:asc", "fourth:asc", "fifth:asc"},
+		Sort:  []string{"blockNumber:" + direction},
 	}
-
-	results, err := es.doSearchRequest(searchReq)
+	results, err := es.doSearchRequest(req)
 	if err != nil {
 		return nil, err
 	}
 
-	convertedResults := make([]types.ERC721Token, 0, len(results.Hits.Hits))
+	allowanceMap := make(map[uint64]*big.Int)
 	for _, result := range results.Hits.Hits {
-		tokenResult := new(types.ERC721Token)
-		if err := mapstructure.Decode(result.Source, tokenResult); err != nil {
-			return nil, err
+		blockNumber := uint64(result.Source["blockNumber"].(float64))
+		amount, success := new(big.Int).SetString(result.Source["amount"].(string), 10)
+		if !success {
+			return nil, errors.New("could not parse allowance value")
+		}
+		allowanceMap[blockNumber] = amount
+		if blockNumber < options.BeginBlockNumber.Uint64() {
+			allowanceMap[options.BeginBlockNumber.Uint64()] = amount
 		}
-		tokenResult.Holder = types.NewAddress(string(tokenResult.Holder))
-		tokenResult.Contract = types.NewAddress(string(tokenResult.Contract))
-		convertedResults = append(convertedResults, *tokenResult)
 	}
-	return convertedResults, nil
+
+	return allowanceMap, nil
 }
 
-func (es *ElasticsearchDB) AllERC721TokensAtBlock(contract types.Address, block uint64, options *types.TokenQueryOptions) ([]types.ERC721Token, error) {
-	startTokenId := big.NewInt(-1)
+func (es *ElasticsearchDB) GetERC20ApprovalsForOwner(contract types.Address, owner types.Address, block uint64, options *types.TokenQueryOptions) ([]types.ERC20Allowance, error) {
+	afterSpender := ""
 	if options.After != "" {
-		parsed, success := new(big.Int).SetString(options.After, 10)
-		if !success {
-			return nil, errors.New(`could not parse "after" token ID`)
-		}
-		startTokenId = parsed
+		after := types.NewAddress(options.After)
+		afterSpender = after.String()
 	}
-	formattedQuery := fmt.Sprintf(QueryERC721AllTokensAtBlock(startTokenId), contract.String(), block, block)
+	formattedQuery := fmt.Sprintf(QueryERC20ApprovalsForOwnerAtBlock(afterSpender), contract.String(), owner.String(), block, block)
 
-	from := options.PageSize * options.PageNumber
-	if from+options.PageSize > 1000 {
-		return nil, ErrPaginationLimitExceeded
+	from, err := pageFrom(options.PageSize, options.PageNumber, options.After != "")
+	if err != nil {
+		return nil, err
 	}
 
 	searchReq := esapi.SearchRequest{
-		Index: []string{ERC721TokenIndex},
+		Index: []string{ERC20AllowanceIndex},
 		Body:  strings.NewReader(formattedQuery),
 		From:  &from,
 		Size:  &options.PageSize,
-		Sort:  []string{"first:asc", "second:asc", "third:asc", "fourth:asc", "fifth:asc"},
+		Sort:  []string{"spender:asc"},
 	}
 
 	results, err := es.doSearchRequest(searchReq)
@@ -322,50 +521,783 @@ func (es *ElasticsearchDB) AllERC721TokensAtBlock(contract types.Address, block
 		return nil, err
 	}
 
-	convertedResults := make([]types.ERC721Token, 0, len(results.Hits.Hits))
-	for _, result := range results.Hits.Hits {
-		tokenResult := new(types.ERC721Token)
-		if err := mapstructure.Decode(result.Source, tokenResult); err != nil {
+	allowances := make([]types.ERC20Allowance, len(results.Hits.Hits))
+	for i, result := range results.Hits.Hits {
+		marshalled, _ := json.Marshal(result.Source)
+		if err = json.Unmarshal(marshalled, &allowances[i]); err != nil {
 			return nil, err
 		}
-		tokenResult.Holder = types.NewAddress(string(tokenResult.Holder))
-		tokenResult.Contract = types.NewAddress(string(tokenResult.Contract))
-		convertedResults = append(convertedResults, *tokenResult)
 	}
-	return convertedResults, nil
+	return allowances, nil
 }
 
-func (es *ElasticsearchDB) AllHoldersAtBlock(contract types.Address, block uint64, options *types.TokenQueryOptions) ([]types.Address, error) {
-	if options.PageSize > 1000 {
-		return nil, ErrPaginationLimitExceeded
+// getERC20AllowanceAtBlock finds the allowance an owner had given a spender at the given block
+func (es *ElasticsearchDB) getERC20AllowanceAtBlock(contract types.Address, owner types.Address, spender types.Address, block uint64) (types.ERC20Allowance, error) {
+	queryString := fmt.Sprintf(QueryERC20AllowanceAtBlock(), contract.String(), owner.String(), spender.String(), block)
+
+	size := 1
+	req := esapi.SearchRequest{
+		Index: []string{ERC20AllowanceIndex},
+		Body:  strings.NewReader(queryString),
+		Size:  &size,
+	}
+	results, err := es.doSearchRequest(req)
+	if err != nil {
+		return types.ERC20Allowance{}, err
 	}
 
-	afterQuery := ""
-	if options.After != "" {
-		afterQuery = fmt.Sprintf(`"after": { "holder": "%s"},`, options.After)
+	if len(results.Hits.Hits) == 0 {
+		return types.ERC20Allowance{}, database.ErrNotFound
 	}
 
-	formattedQuery := fmt.Sprintf(QueryERC721AllHoldersAtBlock(), contract.String(), block, block, options.PageSize, afterQuery)
+	var allowance types.ERC20Allowance
+	marshalled, _ := json.Marshal(results.Hits.Hits[0].Source)
+	err = json.Unmarshal(marshalled, &allowance)
+	return allowance, err
+}
 
-	searchReq := esapi.SearchRequest{
-		Index: []string{ERC721TokenIndex},
-		Body:  strings.NewReader(formattedQuery),
+func (es *ElasticsearchDB) RecordERC20Metadata(metadata *types.ERC20Metadata) error {
+	req := esapi.IndexRequest{
+		Index:      ERC20MetadataIndex,
+		DocumentID: metadata.Contract.String(),
+		Body:       esutil.NewJSONReader(metadata),
+		Refresh:    "true",
 	}
 
-	results, err := es.doSearchRequest(searchReq)
+	_, err := es.apiClient.DoRequest(req)
+	return err
+}
+
+func (es *ElasticsearchDB) GetERC20Metadata(contract types.Address) (*types.ERC20Metadata, error) {
+	fetchReq := esapi.GetRequest{
+		Index:      ERC20MetadataIndex,
+		DocumentID: contract.String(),
+	}
+
+	body, err := es.apiClient.DoRequest(fetchReq)
 	if err != nil {
 		return nil, err
 	}
 
-	var aggResult ERC721HolderAggregateResult
-	rawAggResult := results.Aggregations.Results
-	if err := mapstructure.Decode(rawAggResult, &aggResult); err != nil {
+	var metadataResult ERC20MetadataQueryResult
+	if err = json.Unmarshal(body, &metadataResult); err != nil {
 		return nil, err
 	}
+	return metadataResult.Source, nil
+}
 
-	convertedResults := make([]types.Address, 0, len(aggResult.Buckets))
-	for _, result := range aggResult.Buckets {
-		convertedResults = append(convertedResults, types.NewAddress(result.Key.Holder))
+func (es *ElasticsearchDB) RecordNewERC20TotalSupply(contract types.Address, block uint64, amount *big.Int) error {
+	//find old entry
+	existingEntry, errExisting := es.getERC20TotalSupplyAtBlock(contract, block-1)
+	if errExisting != nil && errExisting != database.ErrNotFound {
+		return errExisting
 	}
-	return convertedResults, nil
+
+	//add new entry
+	supply := types.ERC20TotalSupply{
+		Contract:    contract,
+		Amount:      amount.String(),
+		BlockNumber: block,
+	}
+
+	req := esapi.IndexRequest{
+		Index:      ERC20SupplyIndex,
+		DocumentID: fmt.Sprintf("%s-%d", contract.String(), block),
+		Body:       esutil.NewJSONReader(supply),
+		Refresh:    "true",
+		OpType:     "create",
+	}
+
+	if _, err := es.apiClient.DoRequest(req); err != nil {
+		return err
+	}
+
+	/////
+
+	if errExisting == database.ErrNotFound {
+		return nil
+	}
+
+	//update the older entry
+	query := map[string]interface{}{
+		"doc": map[string]interface{}{
+			"validUntil": block - 1,
+		},
+	}
+
+	updateRequest := esapi.UpdateRequest{
+		Index:      ERC20SupplyIndex,
+		DocumentID: fmt.Sprintf("%s-%d", contract.String(), existingEntry.BlockNumber),
+		Body:       esutil.NewJSONReader(query),
+		Refresh:    "true",
+	}
+
+	_, err := es.apiClient.DoRequest(updateRequest)
+	return err
+}
+
+func (es *ElasticsearchDB) GetERC20TotalSupply(contract types.Address, options *types.TokenQueryOptions) (map[uint64]*big.Int, error) {
+	queryString := fmt.Sprintf(QueryERC20TotalSupplyAtBlockRange(options), contract.String())
+
+	from, err := pageFrom(options.PageSize, options.PageNumber, options.After != "")
+	if err != nil {
+		return nil, err
+	}
+	if options.After != "" {
+		afterBlock, err := strconv.ParseUint(options.After, 10, 64)
+		if err != nil {
+			return nil, errors.New(`could not parse "after" block number`)
+		}
+		queryString = withSearchAfter(queryString, afterBlock)
+	}
+	direction := "desc"
+	if options.Sort == types.SortAscending {
+		direction = "asc"
+	}
+	req := esapi.SearchRequest{
+		Index: []string{ERC20SupplyIndex},
+		Body:  strings.NewReader(queryString),
+		From:  &from,
+		Size:  &options.PageSize,
+		Sort:  []string{"blockNumber:" + direction},
+	}
+	results, err := es.doSearchRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	supplyMap := make(map[uint64]*big.Int)
+	for _, result := range results.Hits.Hits {
+		blockNumber := uint64(result.Source["blockNumber"].(float64))
+		amount, success := new(big.Int).SetString(result.Source["amount"].(string), 10)
+		if !success {
+			return nil, errors.New("could not parse total supply value")
+		}
+		supplyMap[blockNumber] = amount
+		if blockNumber < options.BeginBlockNumber.Uint64() {
+			supplyMap[options.BeginBlockNumber.Uint64()] = amount
+		}
+	}
+
+	return supplyMap, nil
+}
+
+// getERC20TotalSupplyAtBlock finds the total supply of a token that was recorded as of the given block
+func (es *ElasticsearchDB) getERC20TotalSupplyAtBlock(contract types.Address, block uint64) (types.ERC20TotalSupply, error) {
+	queryString := fmt.Sprintf(QueryERC20TotalSupplyAtBlock(), contract.String(), block)
+
+	size := 1
+	req := esapi.SearchRequest{
+		Index: []string{ERC20SupplyIndex},
+		Body:  strings.NewReader(queryString),
+		Size:  &size,
+	}
+	results, err := es.doSearchRequest(req)
+	if err != nil {
+		return types.ERC20TotalSupply{}, err
+	}
+
+	if len(results.Hits.Hits) == 0 {
+		return types.ERC20TotalSupply{}, database.ErrNotFound
+	}
+
+	var supply types.ERC20TotalSupply
+	marshalled, _ := json.Marshal(results.Hits.Hits[0].Source)
+	err = json.Unmarshal(marshalled, &supply)
+	return supply, err
+}
+
+func (es *ElasticsearchDB) RecordERC20Transfers(transfers []*types.ERC20Transfer) error {
+	bi := es.apiClient.GetBulkHandler(ERC20TransferIndex)
+
+	var (
+		wg        sync.WaitGroup
+		returnErr error
+	)
+	for _, transfer := range transfers {
+		wg.Add(1)
+		_ = bi.Add(
+			context.Background(),
+			esutil.BulkIndexerItem{
+				// transfers are overwritten if a batch of blocks is filtered again
+				Action:     "index",
+				DocumentID: fmt.Sprintf("%s-%d", transfer.TransactionHash.String(), transfer.Index),
+				Body:       esutil.NewJSONReader(StoredERC20Transfer{transfer, transfer.SortableAmount()}),
+				OnSuccess: func(ctx context.Context, item esutil.BulkIndexerItem, item2 esutil.BulkIndexerResponseItem) {
+					wg.Done()
+				},
+				OnFailure: func(ctx context.Context, item esutil.BulkIndexerItem, item2 esutil.BulkIndexerResponseItem, err error) {
+					returnErr = err
+					wg.Done()
+				},
+			},
+		)
+	}
+	wg.Wait()
+	return returnErr
+}
+
+func (es *ElasticsearchDB) GetERC20Transfers(contract types.Address, filter *types.ERC20TransferFilter, options *types.QueryOptions) ([]*types.ERC20Transfer, error) {
+	queryString := fmt.Sprintf(QueryERC20TransfersTemplate(filter, options), contract.String())
+	req, err := newPagedSearchRequest(ERC20TransferIndex, queryString, options)
+	if err != nil {
+		return nil, err
+	}
+	results, err := es.doSearchRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	transfers := make([]*types.ERC20Transfer, len(results.Hits.Hits))
+	for i, result := range results.Hits.Hits {
+		marshalled, _ := json.Marshal(result.Source)
+		var transfer types.ERC20Transfer
+		if err = json.Unmarshal(marshalled, &transfer); err != nil {
+			return nil, err
+		}
+		transfers[i] = &transfer
+	}
+	return transfers, nil
+}
+
+func (es *ElasticsearchDB) GetERC20TransfersTotal(contract types.Address, filter *types.ERC20TransferFilter, options *types.QueryOptions) (uint64, error) {
+	queryString := fmt.Sprintf(QueryERC20TransfersTemplate(filter, options), contract.String())
+
+	req := esapi.CountRequest{
+		Index: []string{ERC20TransferIndex},
+		Body:  strings.NewReader(queryString),
+	}
+	results, err := es.doCountRequest(req)
+	if err != nil {
+		return 0, err
+	}
+	return results.Count, nil
+}
+
+func (es *ElasticsearchDB) RecordERC721Token(token *types.ERC721Token) error {
+	contract, block := token.Contract, token.HeldFrom
+	tokenId, success := new(big.Int).SetString(token.Token, 10)
+	if !success {
+		return errors.New("could not parse token ID")
+	}
+
+	//find old entry
+	existingTokenEntry, errExisting := es.ERC721TokenByTokenID(contract, block-1, tokenId)
+	if errExisting != nil && errExisting != database.ErrNotFound {
+		return errExisting
+	}
+
+	paddedTokenId := fmt.Sprintf("%085d", tokenId)
+	first, _ := strconv.ParseUint(paddedTokenId[0:17], 10, 64)
+	second, _ := strconv.ParseUint(paddedTokenId[17:34], 10, 64)
+	third, _ := strconv.ParseUint(paddedTokenId[34:51], 10, 64)
+	fourth, _ := strconv.ParseUint(paddedTokenId[51:68], 10, 64)
+	fifth, _ := strconv.ParseUint(paddedTokenId[68:85], 10, 64)
+
+	//add new entry
+	newEntry := *token
+	newEntry.HeldUntil = nil
+	tokenHolderInfo := SortableERC721Token{
+		newEntry,
+		first, second, third, fourth, fifth,
+	}
+
+	req := esapi.IndexRequest{
+		Index:      ERC721TokenIndex,
+		DocumentID: fmt.Sprintf("%s-%s-%d", contract.String(), tokenId.String(), block),
+		Body:       esutil.NewJSONReader(tokenHolderInfo),
+		Refresh:    "true",
+		OpType:     "create", //This will only create if the contract does not exist
+	}
+
+	if _, err := es.apiClient.DoRequest(req); err != nil {
+		return err
+	}
+
+	/////
+
+	if errExisting == database.ErrNotFound {
+		return nil
+	}
+
+	//update the older entry
+	query := map[string]interface{}{
+		"doc": map[string]interface{}{
+			"heldUntil": block - 1,
+		},
+	}
+
+	updateRequest := esapi.UpdateRequest{
+		Index:      ERC721TokenIndex,
+		DocumentID: fmt.Sprintf("%s-%s-%d", contract.String(), tokenId.String(), existingTokenEntry.HeldFrom),
+		Body:       esutil.NewJSONReader(query),
+		Refresh:    "true",
+	}
+
+	_, err := es.apiClient.DoRequest(updateRequest)
+	return err
+}
+
+func (es *ElasticsearchDB) ERC721TokenByTokenID(contract types.Address, block uint64, tokenId *big.Int) (types.ERC721Token, error) {
+	formattedQuery := fmt.Sprintf(QueryERC721TokenAtBlock(), contract.String(), tokenId.String(), block)
+
+	pageSize := 1
+	searchReq := esapi.SearchRequest{
+		Index: []string{ERC721TokenIndex},
+		Body:  strings.NewReader(formattedQuery),
+		Size:  &pageSize,
+	}
+
+	results, err := es.doSearchRequest(searchReq)
+	if err != nil {
+		return types.ERC721Token{}, err
+	}
+
+	if len(results.Hits.Hits) == 0 {
+		return types.ERC721Token{}, database.ErrNotFound
+	}
+
+	tokenResult, err := decodeERC721Token(results.Hits.Hits[0].Source)
+	if err != nil {
+		return types.ERC721Token{}, err
+	}
+	tokenResult.Contract = contract
+	return tokenResult, nil
+}
+
+func (es *ElasticsearchDB) GetERC721TokenHistory(contract types.Address, tokenId *big.Int, options *types.TokenQueryOptions) ([]types.ERC721Token, error) {
+	queryString := fmt.Sprintf(QueryERC721TokenHistory(), contract.String(), tokenId.String())
+
+	from, err := pageFrom(options.PageSize, options.PageNumber, options.After != "")
+	if err != nil {
+		return nil, err
+	}
+	if options.After != "" {
+		afterBlock, err := strconv.ParseUint(options.After, 10, 64)
+		if err != nil {
+			return nil, errors.New(`could not parse "after" block number`)
+		}
+		queryString = withSearchAfter(queryString, afterBlock)
+	}
+	direction := "desc"
+	if options.Sort == types.SortAscending {
+		direction = "asc"
+	}
+	req := esapi.SearchRequest{
+		Index: []string{ERC721TokenIndex},
+		Body:  strings.NewReader(queryString),
+		From:  &from,
+		Size:  &options.PageSize,
+		Sort:  []string{"heldFrom:" + direction},
+	}
+	results, err := es.doSearchRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]types.ERC721Token, 0, len(results.Hits.Hits))
+	for _, result := range results.Hits.Hits {
+		tokenResult, err := decodeERC721Token(result.Source)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, tokenResult)
+	}
+	return history, nil
+}
+
+func (es *ElasticsearchDB) GetERC721TokensAcquiredByHolder(holder types.Address, options *types.TokenQueryOptions) ([]types.ERC721Token, error) {
+	return es.getERC721TransfersOfHolder("holder", holder, options)
+}
+
+func (es *ElasticsearchDB) GetERC721TokensDisposedByHolder(holder types.Address, options *types.TokenQueryOptions) ([]types.ERC721Token, error) {
+	return es.getERC721TransfersOfHolder("from", holder, options)
+}
+
+func (es *ElasticsearchDB) RecordERC721Metadata(metadata *types.ERC721Metadata) error {
+	req := esapi.IndexRequest{
+		Index:      ERC721MetadataIndex,
+		DocumentID: fmt.Sprintf("%s-%s", metadata.Contract.String(), metadata.Token),
+		Body:       esutil.NewJSONReader(metadata),
+		Refresh:    "true",
+	}
+
+	_, err := es.apiClient.DoRequest(req)
+	return err
+}
+
+func (es *ElasticsearchDB) GetERC721Metadata(contract types.Address, tokenIds []string) ([]*types.ERC721Metadata, error) {
+	if len(tokenIds) == 0 {
+		return []*types.ERC721Metadata{}, nil
+	}
+
+	queryString := fmt.Sprintf(QueryERC721Metadata(tokenIds), contract.String())
+	sources, err := es.scrollSources(ERC721MetadataIndex, queryString)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := make([]*types.ERC721Metadata, len(sources))
+	for i, source := range sources {
+		if err := json.Unmarshal(source, &metadata[i]); err != nil {
+			return nil, err
+		}
+	}
+	return metadata, nil
+}
+
+// getERC721TransfersOfHolder fetches all the transfers within the block range where the holder is in the given field
+func (es *ElasticsearchDB) getERC721TransfersOfHolder(field string, holder types.Address, options *types.TokenQueryOptions) ([]types.ERC721Token, error) {
+	queryString := fmt.Sprintf(QueryERC721TransfersOfHolder(field, options), holder.String())
+	sources, err := es.scrollCappedSources(ERC721TokenIndex, queryString)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]types.ERC721Token, len(sources))
+	for i, source := range sources {
+		if err := json.Unmarshal(source, &tokens[i]); err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(tokens, func(i, j int) bool { return tokens[i].HeldFrom < tokens[j].HeldFrom })
+	return tokens, nil
+}
+
+// decodeERC721Token decodes a stored token entry, whose addresses and hash are in their JSON form
+func decodeERC721Token(source map[string]interface{}) (types.ERC721Token, error) {
+	var token types.ERC721Token
+	marshalled, err := json.Marshal(source)
+	if err != nil {
+		return types.ERC721Token{}, err
+	}
+	err = json.Unmarshal(marshalled, &token)
+	return token, err
+}
+
+func (es *ElasticsearchDB) ERC721TokensForAccountAtBlock(contract types.Address, holder types.Address, block uint64, options *types.TokenQueryOptions) ([]types.ERC721Token, error) {
+	startTokenId := big.NewInt(-1)
+	if options.After != "" {
+		parsed, success := new(big.Int).SetString(options.After, 10)
+		if !success {
+			return nil, errors.New(`could not parse "after" token ID`)
+		}
+		startTokenId = parsed
+	}
+
+	formattedQuery := fmt.Sprintf(QueryERC721HolderAtBlock(startTokenId), contract.String(), holder.String(), block, block)
+
+	from, err := pageFrom(options.PageSize, options.PageNumber, options.After != "")
+	if err != nil {
+		return nil, err
+	}
+
+	searchReq := esapi.SearchRequest{
+		Index: []string{ERC721TokenIndex},
+		Body:  strings.NewReader(formattedQuery),
+		From:  &from,
+		Size:  &options.PageSize,
+		Sort:  []string{"first:asc", "second:asc", "third:asc", "fourth:asc", "fifth:asc"},
+	}
+
+	results, err := es.doSearchRequest(searchReq)
+	if err != nil {
+		return nil, err
+	}
+
+	convertedResults := make([]types.ERC721Token, 0, len(results.Hits.Hits))
+	for _, result := range results.Hits.Hits {
+		tokenResult, err := decodeERC721Token(result.Source)
+		if err != nil {
+			return nil, err
+		}
+		convertedResults = append(convertedResults, tokenResult)
+	}
+	return convertedResults, nil
+}
+
+func (es *ElasticsearchDB) AllERC721TokensAtBlock(contract types.Address, block uint64, options *types.TokenQueryOptions) ([]types.ERC721Token, error) {
+	startTokenId := big.NewInt(-1)
+	if options.After != "" {
+		parsed, success := new(big.Int).SetString(options.After, 10)
+		if !success {
+			return nil, errors.New(`could not parse "after" token ID`)
+		}
+		startTokenId = parsed
+	}
+	formattedQuery := fmt.Sprintf(QueryERC721AllTokensAtBlock(startTokenId), contract.String(), block, block)
+
+	from, err := pageFrom(options.PageSize, options.PageNumber, options.After != "")
+	if err != nil {
+		return nil, err
+	}
+
+	searchReq := esapi.SearchRequest{
+		Index: []string{ERC721TokenIndex},
+		Body:  strings.NewReader(formattedQuery),
+		From:  &from,
+		Size:  &options.PageSize,
+		Sort:  []string{"first:asc", "second:asc", "third:asc", "fourth:asc", "fifth:asc"},
+	}
+
+	results, err := es.doSearchRequest(searchReq)
+	if err != nil {
+		return nil, err
+	}
+
+	convertedResults := make([]types.ERC721Token, 0, len(results.Hits.Hits))
+	for _, result := range results.Hits.Hits {
+		tokenResult, err := decodeERC721Token(result.Source)
+		if err != nil {
+			return nil, err
+		}
+		convertedResults = append(convertedResults, tokenResult)
+	}
+	return convertedResults, nil
+}
+
+func (es *ElasticsearchDB) AllHoldersAtBlock(contract types.Address, block uint64, options *types.TokenQueryOptions) ([]types.Address, error) {
+	if options.PageSize > 1000 {
+		return nil, ErrPaginationLimitExceeded
+	}
+
+	afterQuery := ""
+	if options.After != "" {
+		afterQuery = fmt.Sprintf(`"after": { "holder": "%s"},`, options.After)
+	}
+
+	formattedQuery := fmt.Sprintf(QueryERC721AllHoldersAtBlock(), contract.String(), block, block, options.PageSize, afterQuery)
+
+	searchReq := esapi.SearchRequest{
+		Index: []string{ERC721TokenIndex},
+		Body:  strings.NewReader(formattedQuery),
+	}
+
+	results, err := es.doSearchRequest(searchReq)
+	if err != nil {
+		return nil, err
+	}
+
+	var aggResult ERC721HolderAggregateResult
+	rawAggResult := results.Aggregations.Results
+	if err := mapstructure.Decode(rawAggResult, &aggResult); err != nil {
+		return nil, err
+	}
+
+	convertedResults := make([]types.Address, 0, len(aggResult.Buckets))
+	for _, result := range aggResult.Buckets {
+		convertedResults = append(convertedResults, types.NewAddress(result.Key.Holder))
+	}
+	return convertedResults, nil
+}
+
+func (es *ElasticsearchDB) RecordNewERC1155Balance(contract types.Address, holder types.Address, tokenId *big.Int, block uint64, amount *big.Int) error {
+	//find old entry
+	existingTokenEntry, errExisting := es.getERC1155EntryAtBlock(contract, holder, tokenId, block-1)
+	if errExisting != nil && errExisting != database.ErrNotFound {
+		return errExisting
+	}
+
+	paddedTokenId := fmt.Sprintf("%085d", tokenId)
+	first, _ := strconv.ParseUint(paddedTokenId[0:17], 10, 64)
+	second, _ := strconv.ParseUint(paddedTokenId[17:34], 10, 64)
+	third, _ := strconv.ParseUint(paddedTokenId[34:51], 10, 64)
+	fourth, _ := strconv.ParseUint(paddedTokenId[51:68], 10, 64)
+	fifth, _ := strconv.ParseUint(paddedTokenId[68:85], 10, 64)
+
+	//add new entry
+	tokenInfo := SortableERC1155Token{
+		types.ERC1155Token{
+			Contract:  contract,
+			Holder:    holder,
+			Token:     tokenId.String(),
+			Amount:    amount.String(),
+			HeldFrom:  block,
+			HeldUntil: nil,
+		},
+		first, second, third, fourth, fifth,
+	}
+
+	req := esapi.IndexRequest{
+		Index:      ERC1155TokenIndex,
+		DocumentID: fmt.Sprintf("%s-%s-%s-%d", contract.String(), tokenId.String(), holder.String(), block),
+		Body:       esutil.NewJSONReader(tokenInfo),
+		Refresh:    "true",
+		OpType:     "create",
+	}
+
+	if _, err := es.apiClient.DoRequest(req); err != nil {
+		return err
+	}
+
+	/////
+
+	if errExisting == database.ErrNotFound {
+		return nil
+	}
+
+	//update the older entry
+	query := map[string]interface{}{
+		"doc": map[string]interface{}{
+			"heldUntil": block - 1,
+		},
+	}
+
+	updateRequest := esapi.UpdateRequest{
+		Index:      ERC1155TokenIndex,
+		DocumentID: fmt.Sprintf("%s-%s-%s-%d", contract.String(), tokenId.String(), holder.String(), existingTokenEntry.HeldFrom),
+		Body:       esutil.NewJSONReader(query),
+		Refresh:    "true",
+	}
+
+	_, err := es.apiClient.DoRequest(updateRequest)
+	return err
+}
+
+func (es *ElasticsearchDB) GetERC1155Balance(contract types.Address, holder types.Address, tokenId *big.Int, options *types.TokenQueryOptions) (map[uint64]*big.Int, error) {
+	queryString := fmt.Sprintf(QueryERC1155BalanceAtBlockRange(options), contract.String(), holder.String(), tokenId.String())
+
+	from, err := pageFrom(options.PageSize, options.PageNumber, options.After != "")
+	if err != nil {
+		return nil, err
+	}
+	if options.After != "" {
+		afterBlock, err := strconv.ParseUint(options.After, 10, 64)
+		if err != nil {
+			return nil, errors.New(`could not parse "after" block number`)
+		}
+		queryString = withSearchAfter(queryString, afterBlock)
+	}
+	direction := "desc"
+	if options.Sort == types.SortAscending {
+		direction = "asc"
+	}
+	req := esapi.SearchRequest{
+		Index: []string{ERC1155TokenIndex},
+		Body:  strings.NewReader(queryString),
+		From:  &from,
+		Size:  &options.PageSize,
+		Sort:  []string{"heldFrom:" + direction},
+	}
+	results, err := es.doSearchRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	balanceMap := make(map[uint64]*big.Int)
+	for _, result := range results.Hits.Hits {
+		heldFrom := uint64(result.Source["heldFrom"].(float64))
+		tokenAmount, success := new(big.Int).SetString(result.Source["amount"].(string), 10)
+		if !success {
+			return nil, errors.New("could not parse token value")
+		}
+		balanceMap[heldFrom] = tokenAmount
+		if heldFrom < options.BeginBlockNumber.Uint64() {
+			balanceMap[options.BeginBlockNumber.Uint64()] = tokenAmount
+		}
+	}
+
+	return balanceMap, nil
+}
+
+func (es *ElasticsearchDB) GetERC1155HoldersAtBlock(contract types.Address, tokenId *big.Int, block uint64, options *types.TokenQueryOptions) ([]types.Address, error) {
+	if options.PageSize > 1000 {
+		return nil, ErrPaginationLimitExceeded
+	}
+
+	afterQuery := ""
+	if options.After != "" {
+		afterQuery = fmt.Sprintf(`"after": { "holder": "%s"},`, options.After)
+	}
+
+	formattedQuery := fmt.Sprintf(QueryERC1155HoldersAtBlock(), contract.String(), tokenId.String(), block, block, options.PageSize, afterQuery)
+
+	searchReq := esapi.SearchRequest{
+		Index: []string{ERC1155TokenIndex},
+		Body:  strings.NewReader(formattedQuery),
+	}
+
+	results, err := es.doSearchRequest(searchReq)
+	if err != nil {
+		return nil, err
+	}
+
+	var aggResult ERC721HolderAggregateResult
+	rawAggResult := results.Aggregations.Results
+	if err := mapstructure.Decode(rawAggResult, &aggResult); err != nil {
+		return nil, err
+	}
+
+	convertedResults := make([]types.Address, 0, len(aggResult.Buckets))
+	for _, result := range aggResult.Buckets {
+		convertedResults = append(convertedResults, types.NewAddress(result.Key.Holder))
+	}
+	return convertedResults, nil
+}
+
+func (es *ElasticsearchDB) GetERC1155TokensForHolderAtBlock(contract types.Address, holder types.Address, block uint64, options *types.TokenQueryOptions) ([]types.ERC1155Token, error) {
+	startTokenId := big.NewInt(-1)
+	if options.After != "" {
+		parsed, success := new(big.Int).SetString(options.After, 10)
+		if !success {
+			return nil, errors.New(`could not parse "after" token ID`)
+		}
+		startTokenId = parsed
+	}
+
+	formattedQuery := fmt.Sprintf(QueryERC1155HolderTokensAtBlock(startTokenId), contract.String(), holder.String(), block, block)
+
+	from, err := pageFrom(options.PageSize, options.PageNumber, options.After != "")
+	if err != nil {
+		return nil, err
+	}
+
+	searchReq := esapi.SearchRequest{
+		Index: []string{ERC1155TokenIndex},
+		Body:  strings.NewReader(formattedQuery),
+		From:  &from,
+		Size:  &options.PageSize,
+		Sort:  []string{"first:asc", "second:asc", "third:asc", "fourth:asc", "fifth:asc"},
+	}
+
+	results, err := es.doSearchRequest(searchReq)
+	if err != nil {
+		return nil, err
+	}
+
+	convertedResults := make([]types.ERC1155Token, 0, len(results.Hits.Hits))
+	for _, result := range results.Hits.Hits {
+		tokenResult := new(types.ERC1155Token)
+		if err := mapstructure.Decode(result.Source, tokenResult); err != nil {
+			return nil, err
+		}
+		tokenResult.Holder = types.NewAddress(string(tokenResult.Holder))
+		tokenResult.Contract = types.NewAddress(string(tokenResult.Contract))
+		convertedResults = append(convertedResults, *tokenResult)
+	}
+	return convertedResults, nil
+}
+
+// getERC1155EntryAtBlock finds the balance of a token ID an account had at the given block
+func (es *ElasticsearchDB) getERC1155EntryAtBlock(contract types.Address, holder types.Address, tokenId *big.Int, block uint64) (types.ERC1155Token, error) {
+	queryString := fmt.Sprintf(QueryERC1155BalanceAtBlock(), contract.String(), holder.String(), tokenId.String(), block)
+
+	size := 1
+	req := esapi.SearchRequest{
+		Index: []string{ERC1155TokenIndex},
+		Body:  strings.NewReader(queryString),
+		Size:  &size,
+	}
+	results, err := es.doSearchRequest(req)
+	if err != nil {
+		return types.ERC1155Token{}, err
+	}
+
+	if len(results.Hits.Hits) == 0 {
+		return types.ERC1155Token{}, database.ErrNotFound
+	}
+
+	var tokenResult types.ERC1155Token
+	err = mapstructure.Decode(results.Hits.Hits[0].Source, &tokenResult)
+	return tokenResult, err
 }
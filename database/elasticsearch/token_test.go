@@ -1,6 +1,8 @@
 package elasticsearch
 
 import (
+	"context"
+	"encoding/json"
 	"math/big"
 	"strings"
 	"testing"
@@ -26,10 +28,11 @@ func TestElasticsearchDB_RecordNewERC20Balance_NoPrevious(t *testing.T) {
 	balance := big.NewInt(1989)
 
 	token := ERC20TokenHolder{
-		Contract:    tokenContractAddress,
-		Holder:      holderAddress,
-		BlockNumber: blockNumber,
-		Amount:      balance.String(),
+		Contract:       tokenContractAddress,
+		Holder:         holderAddress,
+		BlockNumber:    blockNumber,
+		Amount:         balance.String(),
+		SortableAmount: types.SortableNumber(balance),
 	}
 	ex := esapi.IndexRequest{
 		Index:      ERC20TokenIndex,
@@ -89,10 +92,11 @@ func TestElasticsearchDB_RecordNewERC20Balance_WithPrevious(t *testing.T) {
 	balance := big.NewInt(1989)
 
 	token := ERC20TokenHolder{
-		Contract:    tokenContractAddress,
-		Holder:      holderAddress,
-		BlockNumber: blockNumber,
-		Amount:      balance.String(),
+		Contract:       tokenContractAddress,
+		Holder:         holderAddress,
+		BlockNumber:    blockNumber,
+		Amount:         balance.String(),
+		SortableAmount: types.SortableNumber(balance),
 	}
 	ex := esapi.IndexRequest{
 		Index:      ERC20TokenIndex,
@@ -506,3 +510,732 @@ func TestElasticsearchDB_ERC721TokenByTokenID(t *testing.T) {
 	assert.Nil(t, err)
 	assert.EqualValues(t, expected, result)
 }
+
+func TestElasticsearchDB_GetERC721TokenHistory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+
+	tokenContractAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+
+	expectedQuery := `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "match": { "contract": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34"} },
+				{ "match": { "token": "500"} }
+			]
+		}
+	}
+}
+`
+	from, size := 0, 10
+	req := esapi.SearchRequest{
+		Index: []string{ERC721TokenIndex},
+		Body:  strings.NewReader(expectedQuery),
+		From:  &from,
+		Size:  &size,
+	}
+
+	resultJson := `{"hits": {"hits": [
+{"_source": {"contract": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34", "holder": "0x0000000000000000000000000000000000000000", "token": "500", "heldFrom": 9, "from": "0x1349f3e1b8d71effb47b840594ff27da7e603d17", "transactionHash": "0xf4f803b8d6c6b38e0b15d6cfe80fd1dcea4270ad24e93385fca36512bb9c2c59", "kind": "burn"}},
+{"_source": {"contract": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34", "holder": "0x1349f3e1b8d71effb47b840594ff27da7e603d17", "token": "500", "heldFrom": 1, "heldUntil": 8}}
+]}}`
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().DoRequest(NewSearchRequestMatcher(req)).Return([]byte(resultJson), nil)
+
+	db, _ := New(mockedClient)
+	options := &types.TokenQueryOptions{}
+	options.SetDefaults()
+	result, err := db.GetERC721TokenHistory(tokenContractAddress, big.NewInt(500), options)
+
+	heldUntil := uint64(8)
+	expected := []types.ERC721Token{
+		{
+			Contract:        tokenContractAddress,
+			Holder:          types.NewAddress("0x0000000000000000000000000000000000000000"),
+			Token:           "500",
+			HeldFrom:        9,
+			From:            types.NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17"),
+			TransactionHash: types.NewHash("0xf4f803b8d6c6b38e0b15d6cfe80fd1dcea4270ad24e93385fca36512bb9c2c59"),
+			Kind:            types.TransferKindBurn,
+		},
+		{
+			Contract:  tokenContractAddress,
+			Holder:    types.NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17"),
+			Token:     "500",
+			HeldFrom:  1,
+			HeldUntil: &heldUntil,
+		},
+	}
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestElasticsearchDB_GetERC721Metadata(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+
+	tokenContractAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+
+	expectedQuery := `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "term": { "contract": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34"} },
+				{ "terms": { "token": ["1", "2"] } }
+			]
+		}
+	}
+}
+`
+	var results []interface{}
+	_ = json.Unmarshal([]byte(`[
+{"_source": {"contract": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34", "token": "1", "name": "Tokens", "symbol": "TKN", "tokenURI": "data:application/json,{}", "metadata": {}, "blockNumber": 3}}
+]`), &results)
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().ScrollAllResults(ERC721MetadataIndex, expectedQuery).Return(results, nil)
+
+	db, _ := New(mockedClient)
+	result, err := db.GetERC721Metadata(tokenContractAddress, []string{"1", "2"})
+
+	expected := []*types.ERC721Metadata{
+		{
+			Contract:    tokenContractAddress,
+			Token:       "1",
+			Name:        "Tokens",
+			Symbol:      "TKN",
+			TokenURI:    "data:application/json,{}",
+			Metadata:    []byte(`{}`),
+			BlockNumber: 3,
+		},
+	}
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestElasticsearchDB_RecordNewERC1155Balance_WithPrevious(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+
+	tokenContractAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	holderAddress := types.NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17")
+	tokenId := big.NewInt(5)
+	blockNumber := uint64(10)
+	balance := big.NewInt(1989)
+
+	token := SortableERC1155Token{
+		types.ERC1155Token{
+			Contract: tokenContractAddress,
+			Holder:   holderAddress,
+			Token:    "5",
+			Amount:   "1989",
+			HeldFrom: blockNumber,
+		},
+		0, 0, 0, 0, 5,
+	}
+	ex := esapi.IndexRequest{
+		Index:      ERC1155TokenIndex,
+		DocumentID: "0x1932c48b2bf8102ba33b4a6b545c32236e342f34-5-0x1349f3e1b8d71effb47b840594ff27da7e603d17-10",
+		Body:       esutil.NewJSONReader(token),
+	}
+
+	searchQuery := `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "match": { "contract": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34"} },
+				{ "match": { "holder": "0x1349f3e1b8d71effb47b840594ff27da7e603d17"} },
+				{ "match": { "token": "5"} },
+				{ "range": { "heldFrom": { "lte": 9 } } }
+			]
+		}
+	},
+	"sort": [
+		{
+			"heldFrom": {
+				"order": "desc",
+				"unmapped_type": "long"
+			}
+		}
+	]
+}
+`
+	size := 1
+	req := esapi.SearchRequest{
+		Index: []string{ERC1155TokenIndex},
+		Body:  strings.NewReader(searchQuery),
+		Size:  &size,
+	}
+	searchResult := `{"hits": {"hits": [
+{"_source": {
+		"contract": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34",
+		"holder": "0x1349f3e1b8d71effb47b840594ff27da7e603d17",
+		"token": "5",
+		"amount": "500",
+		"heldFrom": 7
+	}
+}
+]}}`
+
+	oldTokenUpdateReq := esapi.UpdateRequest{
+		Index:      ERC1155TokenIndex,
+		DocumentID: "0x1932c48b2bf8102ba33b4a6b545c32236e342f34-5-0x1349f3e1b8d71effb47b840594ff27da7e603d17-7",
+		Body: strings.NewReader(`{"doc":{"heldUntil":9}}
+`),
+	}
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().DoRequest(NewSearchRequestMatcher(req)).Return([]byte(searchResult), nil)
+	mockedClient.EXPECT().DoRequest(NewIndexRequestMatcher(ex)).Do(func(input esapi.IndexRequest) {
+		assert.Equal(t, "create", input.OpType)
+	})
+	mockedClient.EXPECT().DoRequest(NewUpdateRequestMatcher(oldTokenUpdateReq)).Return(nil, nil)
+
+	db, _ := New(mockedClient)
+	err := db.RecordNewERC1155Balance(tokenContractAddress, holderAddress, tokenId, blockNumber, balance)
+	assert.Nil(t, err, "expected error to be nil")
+}
+
+func TestElasticsearchDB_GetERC1155HoldersAtBlock(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+
+	tokenContractAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+
+	expectedQuery := `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "match": { "contract": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34"} },
+				{ "match": { "token": "5"} },
+				{ "range": { "heldFrom": { "lte": 12 } } }
+			],
+			"must_not": [
+				{ "term": { "amount.keyword": "0" } }
+			],
+			"filter": [{
+                "bool": {
+                    "should": [
+						{ "range": { "heldUntil": { "gte": 12 } } }, 
+						{ "bool": { "must_not": { "exists": { "field": "heldUntil" } } } }
+					]
+                }
+            }]
+		}
+	},
+	"size": 0,
+	"aggs" : {
+		"result_buckets": {
+			"composite" : {
+				"size": 10,
+				"after": { "holder": "0x1349f3e1b8d71effb47b840594ff27da7e603d17"},
+				"sources" : [
+					{ "holder": { "terms" : { "field": "holder.keyword" } } }
+				]
+		  	}
+		}
+	}
+}
+`
+	req := esapi.SearchRequest{
+		Index: []string{ERC1155TokenIndex},
+		Body:  strings.NewReader(expectedQuery),
+	}
+
+	resultJson := `{"hits": {"hits": []}, "aggregations": {"result_buckets": {"buckets": [
+{"key": {"holder": "0x8a5e2a6343108babed07899510fb42297938d41f"}}
+]}}}`
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().DoRequest(NewSearchRequestMatcher(req)).Return([]byte(resultJson), nil)
+
+	db, _ := New(mockedClient)
+	options := &types.TokenQueryOptions{After: "0x1349f3e1b8d71effb47b840594ff27da7e603d17"}
+	options.SetDefaults()
+	result, err := db.GetERC1155HoldersAtBlock(tokenContractAddress, big.NewInt(5), 12, options)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []types.Address{types.NewAddress("0x8a5e2a6343108babed07899510fb42297938d41f")}, result)
+}
+
+func TestElasticsearchDB_GetERC20Transfers_WithFilter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+
+	tokenContractAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	holder := types.NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17")
+
+	expectedQuery := `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "term": { "contract": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34" } },
+{ "bool": { "should": [ { "term": { "from": "0x1349f3e1b8d71effb47b840594ff27da7e603d17" } }, { "term": { "to": "0x1349f3e1b8d71effb47b840594ff27da7e603d17" } } ] } },
+{ "range": { "sortableAmount": { "gte": "115792089237316195423570985008687907853269984665640564039457584007913129640936" } } },
+{ "range": { "blockNumber": { "gte": 0 } } },
+{ "range": { "timestamp": { "gte": 0 } } }
+			]
+		}
+	}
+}
+`
+	from, size := 0, 10
+	req := esapi.SearchRequest{
+		Index: []string{ERC20TransferIndex},
+		Body:  strings.NewReader(expectedQuery),
+		From:  &from,
+		Size:  &size,
+	}
+
+	resultJson := `{"hits": {"hits": [{"_source": {
+"contract": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34",
+"from": "0x0000000000000000000000000000000000000000",
+"to": "0x1349f3e1b8d71effb47b840594ff27da7e603d17",
+"amount": "5000",
+"sortableAmount": "115792089237316195423570985008687907853269984665640564039457584007913129644936",
+"kind": "mint",
+"transactionHash": "0xf4f803b8d6c6b38e0b15d6cfe80fd1dcea4270ad24e93385fca36512bb9c2c59",
+"index": 2,
+"blockNumber": 7,
+"timestamp": 1000
+}}]}}`
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().DoRequest(NewSearchRequestMatcher(req)).Return([]byte(resultJson), nil)
+
+	db, _ := New(mockedClient)
+	filter := &types.ERC20TransferFilter{Holder: &holder, MinAmount: big.NewInt(1000)}
+	options := &types.QueryOptions{}
+	options.SetDefaults()
+	transfers, err := db.GetERC20Transfers(tokenContractAddress, filter, options)
+
+	expected := &types.ERC20Transfer{
+		Contract:        tokenContractAddress,
+		From:            types.NewAddress("0x0"),
+		To:              holder,
+		Amount:          "5000",
+		Kind:            types.TransferKindMint,
+		TransactionHash: types.NewHash("0xf4f803b8d6c6b38e0b15d6cfe80fd1dcea4270ad24e93385fca36512bb9c2c59"),
+		Index:           2,
+		BlockNumber:     7,
+		Timestamp:       1000,
+	}
+	assert.Nil(t, err)
+	assert.Equal(t, []*types.ERC20Transfer{expected}, transfers)
+}
+
+func TestElasticsearchDB_GetERC20ApprovalsForOwner(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+
+	tokenContractAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	owner := types.NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17")
+
+	expectedQuery := `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "term": { "contract": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34" } },
+				{ "term": { "owner": "0x1349f3e1b8d71effb47b840594ff27da7e603d17" } },
+				{ "range": { "blockNumber": { "lte": 12 } } },
+				{ "range": { "spender": { "gt": "0x0000000000000000000000000000000000000001" } } }
+			],
+			"must_not": [
+				{ "term": { "amount": "0" } }
+			],
+			"filter": [{
+				"bool": {
+					"should": [
+						{ "range": { "validUntil": { "gte": 12 } } },
+						{ "bool": { "must_not": { "exists": { "field": "validUntil" } } } }
+					]
+				}
+			}]
+		}
+	}
+}
+`
+	from, size := 0, 10
+	req := esapi.SearchRequest{
+		Index: []string{ERC20AllowanceIndex},
+		Body:  strings.NewReader(expectedQuery),
+		From:  &from,
+		Size:  &size,
+	}
+
+	resultJson := `{"hits": {"hits": [{"_source": {
+"contract": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34",
+"owner": "0x1349f3e1b8d71effb47b840594ff27da7e603d17",
+"spender": "0x8a5e2a6343108babed07899510fb42297938d41f",
+"amount": "2000",
+"blockNumber": 7
+}}]}}`
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().DoRequest(NewSearchRequestMatcher(req)).Return([]byte(resultJson), nil)
+
+	db, _ := New(mockedClient)
+	options := &types.TokenQueryOptions{After: "0x0000000000000000000000000000000000000001"}
+	options.SetDefaults()
+	result, err := db.GetERC20ApprovalsForOwner(tokenContractAddress, owner, 12, options)
+
+	expected := []types.ERC20Allowance{{
+		Contract:    tokenContractAddress,
+		Owner:       owner,
+		Spender:     types.NewAddress("0x8a5e2a6343108babed07899510fb42297938d41f"),
+		Amount:      "2000",
+		BlockNumber: 7,
+	}}
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestElasticsearchDB_RecordNewERC20TotalSupply_NoPrevious(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+
+	tokenContractAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	blockNumber := uint64(10)
+	supply := big.NewInt(1000000)
+
+	ex := esapi.IndexRequest{
+		Index:      ERC20SupplyIndex,
+		DocumentID: "0x1932c48b2bf8102ba33b4a6b545c32236e342f34-10",
+		Body: esutil.NewJSONReader(types.ERC20TotalSupply{
+			Contract:    tokenContractAddress,
+			Amount:      supply.String(),
+			BlockNumber: blockNumber,
+		}),
+	}
+
+	searchQuery := `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "term": { "contract": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34" } },
+				{ "range": { "blockNumber": { "lte": 9 } } }
+			]
+		}
+	},
+	"sort": [
+		{
+			"blockNumber": {
+				"order": "desc",
+				"unmapped_type": "long"
+			}
+		}
+	]
+}
+`
+	size := 1
+	req := esapi.SearchRequest{
+		Index: []string{ERC20SupplyIndex},
+		Body:  strings.NewReader(searchQuery),
+		Size:  &size,
+	}
+	searchResult := `{"hits": {"hits": []}}`
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().DoRequest(NewSearchRequestMatcher(req)).Return([]byte(searchResult), nil)
+	mockedClient.EXPECT().DoRequest(NewIndexRequestMatcher(ex)).Do(func(input esapi.IndexRequest) {
+		assert.Equal(t, "create", input.OpType)
+	})
+
+	db, _ := New(mockedClient)
+	err := db.RecordNewERC20TotalSupply(tokenContractAddress, blockNumber, supply)
+	assert.Nil(t, err, "expected error to be nil")
+}
+
+func TestElasticsearchDB_GetERC20Metadata(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+
+	tokenContractAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+
+	req := esapi.GetRequest{
+		Index:      ERC20MetadataIndex,
+		DocumentID: "0x1932c48b2bf8102ba33b4a6b545c32236e342f34",
+	}
+	result := `{"_source": {"contract": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34", "name": "Test Token", "symbol": "TST", "decimals": 18, "blockNumber": 5}}`
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().DoRequest(NewGetRequestMatcher(req)).Return([]byte(result), nil)
+
+	db, _ := New(mockedClient)
+	metadata, err := db.GetERC20Metadata(tokenContractAddress)
+
+	assert.Nil(t, err)
+	assert.Equal(t, tokenContractAddress, metadata.Contract)
+	assert.Equal(t, "Test Token", metadata.Name)
+	assert.Equal(t, "TST", metadata.Symbol)
+	assert.EqualValues(t, 18, *metadata.Decimals)
+	assert.EqualValues(t, 5, metadata.BlockNumber)
+}
+
+func TestElasticsearchDB_GetERC20BalancesAtBlock_WithMinBalance(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+
+	tokenContractAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+
+	expectedQuery := `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "match": { "contract": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34"} },
+				{ "range": { "blockNumber": { "lte": 12 } } },
+				{ "range": { "sortableAmount.keyword": { "gte": "115792089237316195423570985008687907853269984665640564039457584007913129640036" } } }
+			],
+			"must_not": [
+				{ "term": { "amount.keyword": "0" } }
+			],
+			"filter": [{
+				"bool": {
+					"should": [
+						{ "range": { "heldUntil": { "gte": 12 } } },
+						{ "bool": { "must_not": { "exists": { "field": "heldUntil" } } } }
+					]
+				}
+			}]
+		}
+	}
+}
+`
+	from, size := 0, 10
+	req := esapi.SearchRequest{
+		Index: []string{ERC20TokenIndex},
+		Body:  strings.NewReader(expectedQuery),
+		From:  &from,
+		Size:  &size,
+	}
+
+	resultJson := `{"hits": {"hits": [
+{"_source": {"contract": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34", "holder": "0x8a5e2a6343108babed07899510fb42297938d41f", "amount": "2000", "blockNumber": 7}},
+{"_source": {"contract": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34", "holder": "0x1349f3e1b8d71effb47b840594ff27da7e603d17", "amount": "150", "blockNumber": 3, "heldUntil": 20}}
+]}}`
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().DoRequest(NewSearchRequestMatcher(req)).Return([]byte(resultJson), nil)
+
+	db, _ := New(mockedClient)
+	options := &types.TokenQueryOptions{}
+	options.SetDefaults()
+	result, err := db.GetERC20BalancesAtBlock(tokenContractAddress, 12, big.NewInt(100), options)
+
+	expected := []types.ERC20HolderBalance{
+		{Holder: types.NewAddress("0x8a5e2a6343108babed07899510fb42297938d41f"), Amount: "2000", HeldFrom: 7},
+		{Holder: types.NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17"), Amount: "150", HeldFrom: 3},
+	}
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestElasticsearchDB_GetERC20BalancesOfHolder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+
+	holder := types.NewAddress("0x8a5e2a6343108babed07899510fb42297938d41f")
+
+	expectedQuery := `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "match": { "holder": "0x8a5e2a6343108babed07899510fb42297938d41f" } },
+				{ "range": { "blockNumber": { "lte": 20 } } }
+			],
+			"must_not": [
+				{ "term": { "amount.keyword": "0" } }
+			],
+			"filter": [{
+				"bool": {
+					"should": [
+						{ "range": { "heldUntil": { "gte": 10 } } },
+						{ "bool": { "must_not": { "exists": { "field": "heldUntil" } } } }
+					]
+				}
+			}]
+		}
+	}
+}
+`
+	var results []interface{}
+	_ = json.Unmarshal([]byte(`[
+{"_source": {"contract": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34", "holder": "0x8a5e2a6343108babed07899510fb42297938d41f", "amount": "2000", "blockNumber": 7, "heldUntil": 14}},
+{"_source": {"contract": "0x9d13c6d3afe1721beef56b55d303b09e021e27ab", "holder": "0x8a5e2a6343108babed07899510fb42297938d41f", "amount": "150", "blockNumber": 3}}
+]`), &results)
+
+	expectedCountRequest := esapi.CountRequest{
+		Index: []string{ERC20TokenIndex},
+		Body:  strings.NewReader(expectedQuery),
+	}
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().DoRequest(NewCountRequestMatcher(expectedCountRequest)).Return([]byte(`{"count": 2}`), nil)
+	mockedClient.EXPECT().ScrollAllResults(ERC20TokenIndex, expectedQuery).Return(results, nil)
+
+	db, _ := New(mockedClient)
+	options := &types.TokenQueryOptions{BeginBlockNumber: big.NewInt(10), EndBlockNumber: big.NewInt(20)}
+	options.SetDefaults()
+	result, err := db.GetERC20BalancesOfHolder(holder, options)
+
+	heldUntil := uint64(14)
+	expected := []types.ERC20Balance{
+		{Contract: types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34"), Holder: holder, Amount: "2000", HeldFrom: 7, HeldUntil: &heldUntil},
+		{Contract: types.NewAddress("0x9d13c6d3afe1721beef56b55d303b09e021e27ab"), Holder: holder, Amount: "150", HeldFrom: 3},
+	}
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestElasticsearchDB_GetERC20BalancesOfHolder_TooManyResults(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().DoRequest(gomock.Any()).Return([]byte(`{"count": 10001}`), nil)
+
+	db, _ := New(mockedClient)
+	options := &types.TokenQueryOptions{}
+	options.SetDefaults()
+	_, err := db.GetERC20BalancesOfHolder(types.NewAddress("0x8a5e2a6343108babed07899510fb42297938d41f"), options)
+
+	assert.Equal(t, ErrTooManyResults, err)
+}
+
+func TestElasticsearchDB_GetERC20BalancesOfToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+
+	tokenContractAddress := types.NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+
+	expectedQuery := `
+{
+	"query": {
+		"bool": {
+			"must": [
+				{ "match": { "contract": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34" } },
+				{ "range": { "blockNumber": { "lte": 20 } } }
+			],
+			"must_not": [
+				{ "term": { "amount.keyword": "0" } }
+			],
+			"filter": [{
+				"bool": {
+					"should": [
+						{ "range": { "heldUntil": { "gte": 10 } } },
+						{ "bool": { "must_not": { "exists": { "field": "heldUntil" } } } }
+					]
+				}
+			}]
+		}
+	}
+}
+`
+	var results []interface{}
+	_ = json.Unmarshal([]byte(`[
+{"_source": {"contract": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34", "holder": "0x8a5e2a6343108babed07899510fb42297938d41f", "amount": "2000", "blockNumber": 7, "heldUntil": 14}},
+{"_source": {"contract": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34", "holder": "0x1349f3e1b8d71effb47b840594ff27da7e603d17", "amount": "150", "blockNumber": 3}}
+]`), &results)
+
+	expectedCountRequest := esapi.CountRequest{
+		Index: []string{ERC20TokenIndex},
+		Body:  strings.NewReader(expectedQuery),
+	}
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().DoRequest(NewCountRequestMatcher(expectedCountRequest)).Return([]byte(`{"count": 2}`), nil)
+	mockedClient.EXPECT().ScrollAllResults(ERC20TokenIndex, expectedQuery).Return(results, nil)
+
+	db, _ := New(mockedClient)
+	options := &types.TokenQueryOptions{BeginBlockNumber: big.NewInt(10), EndBlockNumber: big.NewInt(20)}
+	options.SetDefaults()
+	result, err := db.GetERC20BalancesOfToken(tokenContractAddress, options)
+
+	heldUntil := uint64(14)
+	expected := []types.ERC20Balance{
+		{Contract: tokenContractAddress, Holder: types.NewAddress("0x8a5e2a6343108babed07899510fb42297938d41f"), Amount: "2000", HeldFrom: 7, HeldUntil: &heldUntil},
+		{Contract: tokenContractAddress, Holder: types.NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17"), Amount: "150", HeldFrom: 3},
+	}
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestElasticsearchDB_BackfillSortableAmounts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+	mockedBulkIndexer := elasticsearchmocks.NewMockBulkIndexer(ctrl)
+
+	expectedQuery := `
+{
+	"query": {
+		"bool": {
+			"must_not": { "exists": { "field": "sortableAmount" } }
+		}
+	}
+}
+`
+	var results []interface{}
+	_ = json.Unmarshal([]byte(`[
+{"_id": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34-0x1349f3e1b8d71effb47b840594ff27da7e603d17-3", "_source": {"contract": "0x1932c48b2bf8102ba33b4a6b545c32236e342f34", "holder": "0x1349f3e1b8d71effb47b840594ff27da7e603d17", "amount": "150", "blockNumber": 3}}
+]`), &results)
+
+	req := esutil.BulkIndexerItem{
+		Action:     "update",
+		DocumentID: "0x1932c48b2bf8102ba33b4a6b545c32236e342f34-0x1349f3e1b8d71effb47b840594ff27da7e603d17-3",
+		Body:       esutil.NewJSONReader(map[string]interface{}{"doc": map[string]interface{}{"sortableAmount": types.SortableNumber(big.NewInt(150))}}),
+	}
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().ScrollAllResults(ERC20TokenIndex, expectedQuery).Return(results, nil)
+	mockedClient.EXPECT().GetBulkHandler(ERC20TokenIndex).Return(mockedBulkIndexer)
+	mockedBulkIndexer.EXPECT().
+		Add(gomock.Any(), NewBulkIndexerItemMatcher(req)).
+		Do(func(ctx context.Context, item esutil.BulkIndexerItem) {
+			item.OnSuccess(context.Background(), req, esutil.BulkIndexerResponseItem{})
+		})
+
+	db, _ := New(mockedClient)
+	err := db.BackfillSortableAmounts()
+
+	assert.Nil(t, err)
+}
+
+func TestElasticsearchDB_BackfillSortableAmounts_NoneMissing(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockedClient := elasticsearchmocks.NewMockAPIClient(ctrl)
+
+	mockedClient.EXPECT().DoRequest(gomock.Any()) //for setup, not relevant to test
+	mockedClient.EXPECT().ScrollAllResults(ERC20TokenIndex, gomock.Any()).Return(make([]interface{}, 0), nil)
+
+	db, _ := New(mockedClient)
+	err := db.BackfillSortableAmounts()
+
+	assert.Nil(t, err)
+}
@@ -5,16 +5,43 @@ import (
 )
 
 type Contract struct {
-	Address             types.Address `json:"address"`
-	TemplateName        string        `json:"templateName"`
-	CreationTransaction types.Hash    `json:"creationTx"`
-	LastFiltered        uint64        `json:"lastFiltered"`
+	Address             types.Address                `json:"address"`
+	TemplateName        string                       `json:"templateName"`
+	TemplateAssignments []*types.TemplateAssignment  `json:"templateAssignments"`
+	Implementations     []*types.ProxyImplementation `json:"implementations,omitempty"`
+	Samplers            []*Sampler                   `json:"samplers,omitempty"`
+	CreationTransaction types.Hash                   `json:"creationTx"`
+	LastFiltered        uint64                       `json:"lastFiltered"`
 }
 
 type Template struct {
-	TemplateName string `json:"templateName"`
-	ABI          string `json:"abi"`
-	StorageABI   string `json:"storageAbi"`
+	TemplateName        string `json:"templateName"`
+	Version             uint64 `json:"version"`
+	ABI                 string `json:"abi"`
+	StorageABI          string `json:"storageAbi"`
+	ContractName        string `json:"contractName,omitempty"`
+	CompilerVersion     string `json:"compilerVersion,omitempty"`
+	RuntimeBytecodeHash string `json:"runtimeBytecodeHash,omitempty"`
+	// PreviousVersions holds all the versions before this one, oldest first
+	PreviousVersions []Template `json:"previousVersions,omitempty"`
+}
+
+// Sampler is a function sampler as it is stored, with its arguments as JSON,
+// as they can be of any type and so cannot share a field mapping
+type Sampler struct {
+	Name     string        `json:"name"`
+	Function string        `json:"function"`
+	Args     string        `json:"args,omitempty"`
+	Interval uint64        `json:"interval"`
+	Sig      string        `json:"sig"`
+	CallData types.HexData `json:"callData"`
+}
+
+// IndexedEvent is an event as it is stored, along with its arguments if it could be
+// decoded with the template of the contract, so that events can be filtered by them
+type IndexedEvent struct {
+	*types.Event
+	*types.DecodedEvent
 }
 
 type Storage struct {
@@ -35,6 +62,8 @@ type ERC20TokenHolder struct {
 	BlockNumber uint64        `json:"blockNumber"`
 	Amount      string        `json:"amount"`
 	HeldUntil   *uint64       `json:"heldUntil"`
+	// SortableAmount is the amount in a form that can be sorted and compared by range
+	SortableAmount string `json:"sortableAmount"`
 }
 
 type SortableERC721Token struct {
@@ -48,6 +77,23 @@ type SortableERC721Token struct {
 	Fifth  uint64 `json:"fifth"`
 }
 
+// StoredERC20Transfer is a transfer along with its amount in a form that can be compared by range
+type StoredERC20Transfer struct {
+	*types.ERC20Transfer
+	SortableAmount string `json:"sortableAmount"`
+}
+
+type SortableERC1155Token struct {
+	types.ERC1155Token
+
+	//Allows the token to be sortable by splitting it into component parts
+	First  uint64 `json:"first"`
+	Second uint64 `json:"second"`
+	Third  uint64 `json:"third"`
+	Fourth uint64 `json:"fourth"`
+	Fifth  uint64 `json:"fifth"`
+}
+
 //
 
 type ContractQueryResult struct {
@@ -70,6 +116,10 @@ type TokenHolderQueryResult struct {
 	Source ERC20TokenHolder `json:"_source"`
 }
 
+type ERC20MetadataQueryResult struct {
+	Source *types.ERC20Metadata `json:"_source"`
+}
+
 type StorageQueryResult struct {
 	Source Storage `json:"_source"`
 }
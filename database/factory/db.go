@@ -44,5 +44,15 @@ func (dbFactory *Factory) NewElasticsearchDatabase(config *types.ElasticsearchCo
 	if err != nil {
 		return nil, err
 	}
-	return elasticsearch.New(apiClient)
+	db, err := elasticsearch.New(apiClient)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.UpdateMappings(); err != nil {
+		log.Error("Decoded calls and events cannot be filtered until the index is re-indexed, see the Elasticsearch README", "err", err)
+	}
+	if err := db.BackfillSortableAmounts(); err != nil {
+		log.Error("ERC20 balances recorded by earlier versions cannot be ordered or filtered by amount", "err", err)
+	}
+	return db, nil
 }
@@ -112,14 +112,30 @@ func (cachingDB *DatabaseWithCache) GetStorageLayout(address types.Address) (str
 	return cachingDB.db.GetStorageLayout(address)
 }
 
-func (cachingDB *DatabaseWithCache) AddTemplate(name string, abi string, layout string) error {
-	return cachingDB.db.AddTemplate(name, abi, layout)
+func (cachingDB *DatabaseWithCache) AddTemplate(template *types.Template) error {
+	return cachingDB.db.AddTemplate(template)
 }
 
 func (cachingDB *DatabaseWithCache) AssignTemplate(address types.Address, name string) error {
 	return cachingDB.db.AssignTemplate(address, name)
 }
 
+func (cachingDB *DatabaseWithCache) AssignTemplateFromBlock(address types.Address, assignment *types.TemplateAssignment) error {
+	return cachingDB.db.AssignTemplateFromBlock(address, assignment)
+}
+
+func (cachingDB *DatabaseWithCache) GetTemplateAssignments(address types.Address) ([]*types.TemplateAssignment, error) {
+	return cachingDB.db.GetTemplateAssignments(address)
+}
+
+func (cachingDB *DatabaseWithCache) RecordImplementation(proxy types.Address, implementation *types.ProxyImplementation) error {
+	return cachingDB.db.RecordImplementation(proxy, implementation)
+}
+
+func (cachingDB *DatabaseWithCache) GetImplementationHistory(proxy types.Address) ([]*types.ProxyImplementation, error) {
+	return cachingDB.db.GetImplementationHistory(proxy)
+}
+
 func (cachingDB *DatabaseWithCache) GetTemplates() ([]string, error) {
 	return cachingDB.db.GetTemplates()
 }
@@ -128,6 +144,10 @@ func (cachingDB *DatabaseWithCache) GetTemplateDetails(templateName string) (*ty
 	return cachingDB.db.GetTemplateDetails(templateName)
 }
 
+func (cachingDB *DatabaseWithCache) GetTemplateVersion(templateName string, version uint64) (*types.Template, error) {
+	return cachingDB.db.GetTemplateVersion(templateName, version)
+}
+
 func (cachingDB *DatabaseWithCache) WriteBlocks(blocks []*types.Block) error {
 	cachingDB.blockMux.Lock()
 	defer cachingDB.blockMux.Unlock()
@@ -152,6 +172,35 @@ func (cachingDB *DatabaseWithCache) ReadBlock(blockNumber uint64) (*types.Block,
 	return block, nil
 }
 
+func (cachingDB *DatabaseWithCache) ReadBlocks(blockNumbers []uint64) ([]*types.Block, error) {
+	blocks := make([]*types.Block, len(blockNumbers))
+	missing := make([]uint64, 0)
+	for i, blockNumber := range blockNumbers {
+		if cachedBlock, err := cachingDB.blockCache.Get(blockNumber); err == nil {
+			blocks[i] = cachedBlock.(*types.Block)
+		} else {
+			missing = append(missing, blockNumber)
+		}
+	}
+	if len(missing) == 0 {
+		return blocks, nil
+	}
+
+	fetched, err := cachingDB.db.ReadBlocks(missing)
+	if err != nil {
+		return nil, err
+	}
+	next := 0
+	for i := range blocks {
+		if blocks[i] == nil {
+			blocks[i] = fetched[next]
+			cachingDB.blockCache.Set(fetched[next].Number, fetched[next])
+			next++
+		}
+	}
+	return blocks, nil
+}
+
 func (cachingDB *DatabaseWithCache) GetLastPersistedBlockNumber() (uint64, error) {
 	cachingDB.blockMux.RLock()
 	defer cachingDB.blockMux.RUnlock()
@@ -215,6 +264,14 @@ func (cachingDB *DatabaseWithCache) GetAllTransactionsInternalToAddress(address
 	return cachingDB.db.GetAllTransactionsInternalToAddress(address, options)
 }
 
+func (cachingDB *DatabaseWithCache) GetAllTransactionsFromAddress(address types.Address, options *types.QueryOptions) ([]types.Hash, error) {
+	return cachingDB.db.GetAllTransactionsFromAddress(address, options)
+}
+
+func (cachingDB *DatabaseWithCache) GetTransactionsFromAddressTotal(address types.Address, options *types.QueryOptions) (uint64, error) {
+	return cachingDB.db.GetTransactionsFromAddressTotal(address, options)
+}
+
 func (cachingDB *DatabaseWithCache) GetAllEventsFromAddress(address types.Address, options *types.QueryOptions) ([]*types.Event, error) {
 	return cachingDB.db.GetAllEventsFromAddress(address, options)
 }
@@ -223,6 +280,14 @@ func (cachingDB *DatabaseWithCache) GetTransactionsToAddressTotal(address types.
 	return cachingDB.db.GetTransactionsToAddressTotal(address, options)
 }
 
+func (cachingDB *DatabaseWithCache) GetFailedTransactionsToAddress(address types.Address, options *types.QueryOptions) ([]types.Hash, error) {
+	return cachingDB.db.GetFailedTransactionsToAddress(address, options)
+}
+
+func (cachingDB *DatabaseWithCache) GetFailedTransactionsToAddressTotal(address types.Address, options *types.QueryOptions) (uint64, error) {
+	return cachingDB.db.GetFailedTransactionsToAddressTotal(address, options)
+}
+
 func (cachingDB *DatabaseWithCache) GetTransactionsInternalToAddressTotal(address types.Address, options *types.QueryOptions) (uint64, error) {
 	return cachingDB.db.GetTransactionsInternalToAddressTotal(address, options)
 }
@@ -231,6 +296,14 @@ func (cachingDB *DatabaseWithCache) GetEventsFromAddressTotal(address types.Addr
 	return cachingDB.db.GetEventsFromAddressTotal(address, options)
 }
 
+func (cachingDB *DatabaseWithCache) GetFilteredEventsFromAddress(address types.Address, filter *types.EventFilter, options *types.QueryOptions) ([]*types.Event, error) {
+	return cachingDB.db.GetFilteredEventsFromAddress(address, filter, options)
+}
+
+func (cachingDB *DatabaseWithCache) GetFilteredEventsFromAddressTotal(address types.Address, filter *types.EventFilter, options *types.QueryOptions) (uint64, error) {
+	return cachingDB.db.GetFilteredEventsFromAddressTotal(address, filter, options)
+}
+
 func (cachingDB *DatabaseWithCache) GetStorage(address types.Address, blockNumber uint64) (*types.StorageResult, error) {
 	return cachingDB.db.GetStorage(address, blockNumber)
 }
@@ -251,6 +324,10 @@ func (cachingDB *DatabaseWithCache) GetLastFiltered(address types.Address) (uint
 	return cachingDB.db.GetLastFiltered(address)
 }
 
+func (cachingDB *DatabaseWithCache) RedecodeContract(address types.Address) error {
+	return cachingDB.db.RedecodeContract(address)
+}
+
 func (cachingDB *DatabaseWithCache) RecordNewERC20Balance(contract types.Address, holder types.Address, block uint64, amount *big.Int) error {
 	return cachingDB.db.RecordNewERC20Balance(contract, holder, block, amount)
 }
@@ -263,8 +340,28 @@ func (cachingDB *DatabaseWithCache) GetAllTokenHolders(contract types.Address, b
 	return cachingDB.db.GetAllTokenHolders(contract, block, options)
 }
 
-func (cachingDB *DatabaseWithCache) RecordERC721Token(contract types.Address, holder types.Address, block uint64, tokenId *big.Int) error {
-	return cachingDB.db.RecordERC721Token(contract, holder, block, tokenId)
+func (cachingDB *DatabaseWithCache) GetERC721TokenHistory(contract types.Address, tokenId *big.Int, options *types.TokenQueryOptions) ([]types.ERC721Token, error) {
+	return cachingDB.db.GetERC721TokenHistory(contract, tokenId, options)
+}
+
+func (cachingDB *DatabaseWithCache) GetERC721TokensAcquiredByHolder(holder types.Address, options *types.TokenQueryOptions) ([]types.ERC721Token, error) {
+	return cachingDB.db.GetERC721TokensAcquiredByHolder(holder, options)
+}
+
+func (cachingDB *DatabaseWithCache) GetERC721TokensDisposedByHolder(holder types.Address, options *types.TokenQueryOptions) ([]types.ERC721Token, error) {
+	return cachingDB.db.GetERC721TokensDisposedByHolder(holder, options)
+}
+
+func (cachingDB *DatabaseWithCache) RecordERC721Metadata(metadata *types.ERC721Metadata) error {
+	return cachingDB.db.RecordERC721Metadata(metadata)
+}
+
+func (cachingDB *DatabaseWithCache) GetERC721Metadata(contract types.Address, tokenIds []string) ([]*types.ERC721Metadata, error) {
+	return cachingDB.db.GetERC721Metadata(contract, tokenIds)
+}
+
+func (cachingDB *DatabaseWithCache) RecordERC721Token(token *types.ERC721Token) error {
+	return cachingDB.db.RecordERC721Token(token)
 }
 
 func (cachingDB *DatabaseWithCache) ERC721TokenByTokenID(contract types.Address, block uint64, tokenId *big.Int) (types.ERC721Token, error) {
@@ -283,6 +380,110 @@ func (cachingDB *DatabaseWithCache) AllHoldersAtBlock(contract types.Address, bl
 	return cachingDB.db.AllHoldersAtBlock(contract, block, options)
 }
 
+func (cachingDB *DatabaseWithCache) GetERC20BalanceAtBlock(contract types.Address, holder types.Address, block uint64) (*big.Int, error) {
+	return cachingDB.db.GetERC20BalanceAtBlock(contract, holder, block)
+}
+
+func (cachingDB *DatabaseWithCache) GetERC20BalancesOfHolder(holder types.Address, options *types.TokenQueryOptions) ([]types.ERC20Balance, error) {
+	return cachingDB.db.GetERC20BalancesOfHolder(holder, options)
+}
+
+func (cachingDB *DatabaseWithCache) GetERC721TokensOfHolder(holder types.Address, options *types.TokenQueryOptions) ([]types.ERC721Token, error) {
+	return cachingDB.db.GetERC721TokensOfHolder(holder, options)
+}
+
+func (cachingDB *DatabaseWithCache) GetERC1155TokensOfHolder(holder types.Address, options *types.TokenQueryOptions) ([]types.ERC1155Token, error) {
+	return cachingDB.db.GetERC1155TokensOfHolder(holder, options)
+}
+
+func (cachingDB *DatabaseWithCache) GetERC20BalancesOfToken(contract types.Address, options *types.TokenQueryOptions) ([]types.ERC20Balance, error) {
+	return cachingDB.db.GetERC20BalancesOfToken(contract, options)
+}
+
+func (cachingDB *DatabaseWithCache) GetERC20BalancesAtBlock(contract types.Address, block uint64, minBalance *big.Int, options *types.TokenQueryOptions) ([]types.ERC20HolderBalance, error) {
+	return cachingDB.db.GetERC20BalancesAtBlock(contract, block, minBalance, options)
+}
+
+func (cachingDB *DatabaseWithCache) RecordNewERC20Allowance(contract types.Address, owner types.Address, spender types.Address, block uint64, amount *big.Int) error {
+	return cachingDB.db.RecordNewERC20Allowance(contract, owner, spender, block, amount)
+}
+
+func (cachingDB *DatabaseWithCache) GetERC20Allowance(contract types.Address, owner types.Address, spender types.Address, options *types.TokenQueryOptions) (map[uint64]*big.Int, error) {
+	return cachingDB.db.GetERC20Allowance(contract, owner, spender, options)
+}
+
+func (cachingDB *DatabaseWithCache) GetERC20ApprovalsForOwner(contract types.Address, owner types.Address, block uint64, options *types.TokenQueryOptions) ([]types.ERC20Allowance, error) {
+	return cachingDB.db.GetERC20ApprovalsForOwner(contract, owner, block, options)
+}
+
+func (cachingDB *DatabaseWithCache) RecordERC20Metadata(metadata *types.ERC20Metadata) error {
+	return cachingDB.db.RecordERC20Metadata(metadata)
+}
+
+func (cachingDB *DatabaseWithCache) GetERC20Metadata(contract types.Address) (*types.ERC20Metadata, error) {
+	return cachingDB.db.GetERC20Metadata(contract)
+}
+
+func (cachingDB *DatabaseWithCache) RecordNewERC20TotalSupply(contract types.Address, block uint64, amount *big.Int) error {
+	return cachingDB.db.RecordNewERC20TotalSupply(contract, block, amount)
+}
+
+func (cachingDB *DatabaseWithCache) GetERC20TotalSupply(contract types.Address, options *types.TokenQueryOptions) (map[uint64]*big.Int, error) {
+	return cachingDB.db.GetERC20TotalSupply(contract, options)
+}
+
+func (cachingDB *DatabaseWithCache) RecordERC20Transfers(transfers []*types.ERC20Transfer) error {
+	return cachingDB.db.RecordERC20Transfers(transfers)
+}
+
+func (cachingDB *DatabaseWithCache) GetERC20Transfers(contract types.Address, filter *types.ERC20TransferFilter, options *types.QueryOptions) ([]*types.ERC20Transfer, error) {
+	return cachingDB.db.GetERC20Transfers(contract, filter, options)
+}
+
+func (cachingDB *DatabaseWithCache) GetERC20TransfersTotal(contract types.Address, filter *types.ERC20TransferFilter, options *types.QueryOptions) (uint64, error) {
+	return cachingDB.db.GetERC20TransfersTotal(contract, filter, options)
+}
+
+func (cachingDB *DatabaseWithCache) RecordNewERC1155Balance(contract types.Address, holder types.Address, tokenId *big.Int, block uint64, amount *big.Int) error {
+	return cachingDB.db.RecordNewERC1155Balance(contract, holder, tokenId, block, amount)
+}
+
+func (cachingDB *DatabaseWithCache) GetERC1155Balance(contract types.Address, holder types.Address, tokenId *big.Int, options *types.TokenQueryOptions) (map[uint64]*big.Int, error) {
+	return cachingDB.db.GetERC1155Balance(contract, holder, tokenId, options)
+}
+
+func (cachingDB *DatabaseWithCache) GetERC1155HoldersAtBlock(contract types.Address, tokenId *big.Int, block uint64, options *types.TokenQueryOptions) ([]types.Address, error) {
+	return cachingDB.db.GetERC1155HoldersAtBlock(contract, tokenId, block, options)
+}
+
+func (cachingDB *DatabaseWithCache) GetERC1155TokensForHolderAtBlock(contract types.Address, holder types.Address, block uint64, options *types.TokenQueryOptions) ([]types.ERC1155Token, error) {
+	return cachingDB.db.GetERC1155TokensForHolderAtBlock(contract, holder, block, options)
+}
+
+func (cachingDB *DatabaseWithCache) AddSampler(address types.Address, sampler *types.FunctionSampler) error {
+	return cachingDB.db.AddSampler(address, sampler)
+}
+
+func (cachingDB *DatabaseWithCache) DeleteSampler(address types.Address, name string) error {
+	return cachingDB.db.DeleteSampler(address, name)
+}
+
+func (cachingDB *DatabaseWithCache) GetSamplers(address types.Address) ([]*types.FunctionSampler, error) {
+	return cachingDB.db.GetSamplers(address)
+}
+
+func (cachingDB *DatabaseWithCache) RecordSamples(samples []*types.FunctionSample) error {
+	return cachingDB.db.RecordSamples(samples)
+}
+
+func (cachingDB *DatabaseWithCache) GetSamples(address types.Address, name string, options *types.QueryOptions) ([]*types.FunctionSample, error) {
+	return cachingDB.db.GetSamples(address, name, options)
+}
+
+func (cachingDB *DatabaseWithCache) GetSamplesTotal(address types.Address, name string, options *types.QueryOptions) (uint64, error) {
+	return cachingDB.db.GetSamplesTotal(address, name, options)
+}
+
 func (cachingDB *DatabaseWithCache) Stop() {
 	cachingDB.db.Stop()
 }
@@ -8,10 +8,12 @@ import (
 type Database interface {
 	AddressDB
 	TemplateDB
+	ProxyDB
 	BlockDB
 	TransactionDB
 	IndexDB
 	TokenDB
+	SamplerDB
 	Stop()
 }
 
@@ -26,18 +28,40 @@ type AddressDB interface {
 
 // TemplateDB stores contract ABI/ Storage Layout of registered address
 type TemplateDB interface {
-	AddTemplate(string, string, string) error
+	// AddTemplate stores a new version of a template, keeping all the previous versions
+	AddTemplate(*types.Template) error
+	// AssignTemplate assigns the latest version of a template to a contract for all blocks,
+	// removing any existing assignments
 	AssignTemplate(types.Address, string) error
+	// AssignTemplateFromBlock assigns a template to a contract from a block onwards, up to
+	// the next assignment
+	AssignTemplateFromBlock(types.Address, *types.TemplateAssignment) error
+	// GetTemplateAssignments fetches the template assignments of a contract, ordered by block
+	GetTemplateAssignments(types.Address) ([]*types.TemplateAssignment, error)
+	// GetContractABI and GetStorageLayout fetch the ABI and storage layout of the latest assignment
 	GetContractABI(types.Address) (string, error)
 	GetStorageLayout(types.Address) (string, error)
 	GetTemplates() ([]string, error)
+	// GetTemplateDetails fetches the latest version of a template
 	GetTemplateDetails(string) (*types.Template, error)
+	// GetTemplateVersion fetches a given version of a template, where version 0 is the latest
+	GetTemplateVersion(string, uint64) (*types.Template, error)
+}
+
+// ProxyDB stores the implementations that registered proxy contracts delegate to
+type ProxyDB interface {
+	// RecordImplementation records that a proxy uses an implementation from a block onwards
+	RecordImplementation(types.Address, *types.ProxyImplementation) error
+	// GetImplementationHistory fetches the implementations of a proxy, ordered by block
+	GetImplementationHistory(types.Address) ([]*types.ProxyImplementation, error)
 }
 
 // BlockDB stores the block details for all blocks.
 type BlockDB interface {
 	WriteBlocks([]*types.Block) error
 	ReadBlock(uint64) (*types.Block, error)
+	// ReadBlocks fetches several blocks at once, in the order given, failing if any of them do not exist
+	ReadBlocks([]uint64) ([]*types.Block, error)
 	GetLastPersistedBlockNumber() (uint64, error)
 }
 
@@ -61,10 +85,21 @@ type IndexDB interface {
 
 	GetAllTransactionsToAddress(types.Address, *types.QueryOptions) ([]types.Hash, error)
 	GetTransactionsToAddressTotal(types.Address, *types.QueryOptions) (uint64, error)
+	// GetFailedTransactionsToAddress fetches the transactions to a contract that failed
+	GetFailedTransactionsToAddress(types.Address, *types.QueryOptions) ([]types.Hash, error)
+	GetFailedTransactionsToAddressTotal(types.Address, *types.QueryOptions) (uint64, error)
 	GetAllTransactionsInternalToAddress(types.Address, *types.QueryOptions) ([]types.Hash, error)
 	GetTransactionsInternalToAddressTotal(types.Address, *types.QueryOptions) (uint64, error)
+	// GetAllTransactionsFromAddress fetches the transactions sent from an address, or in which it made an
+	// internal call. As all transactions are stored, the address does not need to be registered.
+	GetAllTransactionsFromAddress(types.Address, *types.QueryOptions) ([]types.Hash, error)
+	GetTransactionsFromAddressTotal(types.Address, *types.QueryOptions) (uint64, error)
 	GetAllEventsFromAddress(types.Address, *types.QueryOptions) ([]*types.Event, error)
 	GetEventsFromAddressTotal(types.Address, *types.QueryOptions) (uint64, error)
+	// GetFilteredEventsFromAddress fetches the events of a contract matching a filter on the event and the values
+	// of its arguments, which are decoded with the contract's template as the events are indexed
+	GetFilteredEventsFromAddress(types.Address, *types.EventFilter, *types.QueryOptions) ([]*types.Event, error)
+	GetFilteredEventsFromAddressTotal(types.Address, *types.EventFilter, *types.QueryOptions) (uint64, error)
 
 	GetStorage(types.Address, uint64) (*types.StorageResult, error)
 	GetStorageTotal(types.Address, *types.PageOptions) (uint64, error)
@@ -72,16 +107,76 @@ type IndexDB interface {
 	GetStorageRanges(types.Address, *types.PageOptions) ([]types.RangeResult, error)
 
 	GetLastFiltered(types.Address) (uint64, error)
+
+	// RedecodeContract decodes the indexed transactions and events of a contract again with the templates assigned to
+	// it now, so that data indexed before its templates were added or changed can be filtered by its decoded values
+	RedecodeContract(types.Address) error
 }
 
 type TokenDB interface {
 	RecordNewERC20Balance(contract types.Address, holder types.Address, block uint64, amount *big.Int) error
 	GetERC20Balance(contract types.Address, holder types.Address, options *types.TokenQueryOptions) (map[uint64]*big.Int, error)
+	// GetERC20BalanceAtBlock fetches the balance of a holder as of a block, or ErrNotFound if none was ever recorded
+	GetERC20BalanceAtBlock(contract types.Address, holder types.Address, block uint64) (*big.Int, error)
 	GetAllTokenHolders(contract types.Address, block uint64, options *types.TokenQueryOptions) ([]types.Address, error)
+	// GetERC20BalancesAtBlock fetches the non-zero balances of the holders of a token at a block, ordered by amount
+	GetERC20BalancesAtBlock(contract types.Address, block uint64, minBalance *big.Int, options *types.TokenQueryOptions) ([]types.ERC20HolderBalance, error)
 
-	RecordERC721Token(contract types.Address, holder types.Address, block uint64, tokenId *big.Int) error
+	// GetERC20BalancesOfHolder, GetERC721TokensOfHolder and GetERC1155TokensOfHolder fetch the non-zero holdings
+	// of a holder across all tokens, that were held at any point within the block range of the options
+	GetERC20BalancesOfHolder(holder types.Address, options *types.TokenQueryOptions) ([]types.ERC20Balance, error)
+	GetERC721TokensOfHolder(holder types.Address, options *types.TokenQueryOptions) ([]types.ERC721Token, error)
+	GetERC1155TokensOfHolder(holder types.Address, options *types.TokenQueryOptions) ([]types.ERC1155Token, error)
+	// GetERC20BalancesOfToken fetches the non-zero balances of every holder of a token, that were held at any
+	// point within the block range of the options
+	GetERC20BalancesOfToken(contract types.Address, options *types.TokenQueryOptions) ([]types.ERC20Balance, error)
+
+	RecordERC721Token(token *types.ERC721Token) error
 	ERC721TokenByTokenID(contract types.Address, block uint64, tokenId *big.Int) (types.ERC721Token, error)
 	ERC721TokensForAccountAtBlock(contract types.Address, holder types.Address, block uint64, options *types.TokenQueryOptions) ([]types.ERC721Token, error)
 	AllERC721TokensAtBlock(contract types.Address, block uint64, options *types.TokenQueryOptions) ([]types.ERC721Token, error)
 	AllHoldersAtBlock(contract types.Address, block uint64, options *types.TokenQueryOptions) ([]types.Address, error)
+	// GetERC721TokenHistory fetches every holder of a token, ordered by the block they received it at
+	GetERC721TokenHistory(contract types.Address, tokenId *big.Int, options *types.TokenQueryOptions) ([]types.ERC721Token, error)
+	// GetERC721TokensAcquiredByHolder and GetERC721TokensDisposedByHolder fetch the transfers of tokens of
+	// any contract to and from a holder within the block range of the options, ordered by block
+	GetERC721TokensAcquiredByHolder(holder types.Address, options *types.TokenQueryOptions) ([]types.ERC721Token, error)
+	GetERC721TokensDisposedByHolder(holder types.Address, options *types.TokenQueryOptions) ([]types.ERC721Token, error)
+	// RecordERC721Metadata stores the metadata of a token, replacing any existing metadata of the token
+	RecordERC721Metadata(metadata *types.ERC721Metadata) error
+	// GetERC721Metadata fetches the metadata of the given tokens of a contract, skipping any not recorded
+	GetERC721Metadata(contract types.Address, tokenIds []string) ([]*types.ERC721Metadata, error)
+
+	RecordNewERC20Allowance(contract types.Address, owner types.Address, spender types.Address, block uint64, amount *big.Int) error
+	GetERC20Allowance(contract types.Address, owner types.Address, spender types.Address, options *types.TokenQueryOptions) (map[uint64]*big.Int, error)
+	// GetERC20ApprovalsForOwner fetches the non-zero allowances an owner has given at a block, ordered by spender
+	GetERC20ApprovalsForOwner(contract types.Address, owner types.Address, block uint64, options *types.TokenQueryOptions) ([]types.ERC20Allowance, error)
+
+	RecordERC20Metadata(metadata *types.ERC20Metadata) error
+	GetERC20Metadata(contract types.Address) (*types.ERC20Metadata, error)
+	RecordNewERC20TotalSupply(contract types.Address, block uint64, amount *big.Int) error
+	GetERC20TotalSupply(contract types.Address, options *types.TokenQueryOptions) (map[uint64]*big.Int, error)
+
+	// RecordERC20Transfers stores transfers, replacing any existing transfer from the same event
+	RecordERC20Transfers(transfers []*types.ERC20Transfer) error
+	GetERC20Transfers(contract types.Address, filter *types.ERC20TransferFilter, options *types.QueryOptions) ([]*types.ERC20Transfer, error)
+	GetERC20TransfersTotal(contract types.Address, filter *types.ERC20TransferFilter, options *types.QueryOptions) (uint64, error)
+
+	RecordNewERC1155Balance(contract types.Address, holder types.Address, tokenId *big.Int, block uint64, amount *big.Int) error
+	GetERC1155Balance(contract types.Address, holder types.Address, tokenId *big.Int, options *types.TokenQueryOptions) (map[uint64]*big.Int, error)
+	GetERC1155HoldersAtBlock(contract types.Address, tokenId *big.Int, block uint64, options *types.TokenQueryOptions) ([]types.Address, error)
+	GetERC1155TokensForHolderAtBlock(contract types.Address, holder types.Address, block uint64, options *types.TokenQueryOptions) ([]types.ERC1155Token, error)
+}
+
+// SamplerDB stores the view functions sampled for registered contracts, and the samples taken of them
+type SamplerDB interface {
+	// AddSampler adds a sampler to a contract, replacing any existing sampler with the same name
+	AddSampler(types.Address, *types.FunctionSampler) error
+	DeleteSampler(types.Address, string) error
+	GetSamplers(types.Address) ([]*types.FunctionSampler, error)
+	// RecordSamples stores samples, replacing any existing sample from the same sampler at the same block
+	RecordSamples([]*types.FunctionSample) error
+	// GetSamples fetches the samples from a sampler of a contract, ordered by block
+	GetSamples(types.Address, string, *types.QueryOptions) ([]*types.FunctionSample, error)
+	GetSamplesTotal(types.Address, string, *types.QueryOptions) (uint64, error)
 }
@@ -3,6 +3,7 @@ package memory
 import (
 	"errors"
 	"math/big"
+	"sort"
 	"sync"
 
 	"quorumengineering/quorum-report/database"
@@ -13,10 +14,11 @@ import (
 // MemoryDB is a sample memory database for dev only.
 type MemoryDB struct {
 	// registered contract data
-	addressDB       []types.Address
-	templateDB      map[types.Address]string
-	abiDB           map[string]string
-	storageLayoutDB map[string]string
+	addressDB    []types.Address
+	assignmentDB map[types.Address][]*types.TemplateAssignment
+	templateDB   map[string][]*types.Template
+	proxyDB      map[types.Address][]*types.ProxyImplementation
+	samplerDB    map[types.Address][]*types.FunctionSampler
 	// blockchain data
 	blockDB                  map[uint64]*types.Block
 	txDB                     map[types.Hash]*types.Transaction
@@ -24,7 +26,10 @@ type MemoryDB struct {
 	// index data
 	txIndexDB      map[types.Address]*TxIndexer
 	eventIndexDB   map[types.Address][]*types.Event
+	decodedEventDB map[*types.Event]*types.DecodedEvent
+	decodedCallDB  map[types.Hash]*types.DecodedCall
 	storageIndexDB map[types.Address]*StorageIndexer
+	sampleDB       map[types.Address]map[string][]*types.FunctionSample
 	lastFiltered   map[types.Address]uint64
 	// mutex lock
 	mux sync.RWMutex
@@ -33,14 +38,18 @@ type MemoryDB struct {
 func NewMemoryDB() *MemoryDB {
 	return &MemoryDB{
 		addressDB:                []types.Address{},
-		templateDB:               make(map[types.Address]string),
-		abiDB:                    make(map[string]string),
-		storageLayoutDB:          make(map[string]string),
+		assignmentDB:             make(map[types.Address][]*types.TemplateAssignment),
+		templateDB:               make(map[string][]*types.Template),
+		proxyDB:                  make(map[types.Address][]*types.ProxyImplementation),
+		samplerDB:                make(map[types.Address][]*types.FunctionSampler),
 		blockDB:                  make(map[uint64]*types.Block),
 		txDB:                     make(map[types.Hash]*types.Transaction),
 		txIndexDB:                make(map[types.Address]*TxIndexer),
 		eventIndexDB:             make(map[types.Address][]*types.Event),
+		decodedEventDB:           make(map[*types.Event]*types.DecodedEvent),
+		decodedCallDB:            make(map[types.Hash]*types.DecodedCall),
 		storageIndexDB:           make(map[types.Address]*StorageIndexer),
+		sampleDB:                 make(map[types.Address]map[string][]*types.FunctionSample),
 		lastPersistedBlockNumber: 0,
 		lastFiltered:             make(map[types.Address]uint64),
 	}
@@ -147,67 +156,110 @@ func (db *MemoryDB) GetAddresses() ([]types.Address, error) {
 func (db *MemoryDB) GetContractTemplate(address types.Address) (string, error) {
 	db.mux.RLock()
 	defer db.mux.RUnlock()
-	return db.templateDB[address], nil
+	assignments := db.assignmentDB[address]
+	if len(assignments) == 0 {
+		return "", nil
+	}
+	return assignments[len(assignments)-1].TemplateName, nil
 }
 
 func (db *MemoryDB) GetContractABI(address types.Address) (string, error) {
 	db.mux.RLock()
 	defer db.mux.RUnlock()
-	return db.abiDB[db.templateDB[address]], nil
+	if template := db.currentTemplate(address); template != nil {
+		return template.ABI, nil
+	}
+	return "", nil
 }
 
 func (db *MemoryDB) GetStorageLayout(address types.Address) (string, error) {
 	db.mux.RLock()
 	defer db.mux.RUnlock()
-	return db.storageLayoutDB[db.templateDB[address]], nil
+	if template := db.currentTemplate(address); template != nil {
+		return template.StorageLayout, nil
+	}
+	return "", nil
 }
 
-func (db *MemoryDB) AddTemplate(name string, abi string, layout string) error {
+func (db *MemoryDB) AddTemplate(template *types.Template) error {
 	db.mux.Lock()
 	defer db.mux.Unlock()
-	db.abiDB[name] = abi
-	db.storageLayoutDB[name] = layout
+	stored := *template
+	stored.Version = uint64(len(db.templateDB[template.TemplateName]) + 1)
+	db.templateDB[template.TemplateName] = append(db.templateDB[template.TemplateName], &stored)
 	return nil
 }
 
 func (db *MemoryDB) AssignTemplate(address types.Address, name string) error {
 	db.mux.Lock()
 	defer db.mux.Unlock()
-	db.templateDB[address] = name
+	db.assignmentDB[address] = []*types.TemplateAssignment{{TemplateName: name}}
 	return nil
 }
 
-func (db *MemoryDB) GetTemplates() ([]string, error) {
+func (db *MemoryDB) AssignTemplateFromBlock(address types.Address, assignment *types.TemplateAssignment) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+	stored := *assignment
+	db.assignmentDB[address] = database.InsertTemplateAssignment(db.assignmentDB[address], &stored)
+	return nil
+}
+
+func (db *MemoryDB) GetTemplateAssignments(address types.Address) ([]*types.TemplateAssignment, error) {
 	db.mux.RLock()
 	defer db.mux.RUnlock()
-	// merge abiDB and storageLayoutDB to find the full template name list
-	templateNames := make(map[string]bool)
-	for template := range db.abiDB {
-		templateNames[template] = true
-	}
-	for template := range db.storageLayoutDB {
-		templateNames[template] = true
+	res := make([]*types.TemplateAssignment, len(db.assignmentDB[address]))
+	for i, assignment := range db.assignmentDB[address] {
+		copied := *assignment
+		res[i] = &copied
 	}
+	return res, nil
+}
+
+func (db *MemoryDB) GetTemplates() ([]string, error) {
+	db.mux.RLock()
+	defer db.mux.RUnlock()
 	res := make([]string, 0)
-	for template := range templateNames {
+	for template := range db.templateDB {
 		res = append(res, template)
 	}
 	return res, nil
 }
 
 func (db *MemoryDB) GetTemplateDetails(templateName string) (*types.Template, error) {
+	return db.GetTemplateVersion(templateName, 0)
+}
+
+func (db *MemoryDB) GetTemplateVersion(templateName string, version uint64) (*types.Template, error) {
 	db.mux.RLock()
 	defer db.mux.RUnlock()
 
-	if (db.abiDB[templateName] == "") && (db.storageLayoutDB[templateName] == "") {
+	template := db.templateVersion(templateName, version)
+	if template == nil || (template.ABI == "" && template.StorageLayout == "") {
 		return nil, database.ErrNotFound
 	}
 
-	return &types.Template{
-		TemplateName:  templateName,
-		ABI:           db.abiDB[templateName],
-		StorageLayout: db.storageLayoutDB[templateName],
-	}, nil
+	details := *template
+	return &details, nil
+}
+
+func (db *MemoryDB) RecordImplementation(proxy types.Address, implementation *types.ProxyImplementation) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+	stored := *implementation
+	db.proxyDB[proxy] = database.InsertProxyImplementation(db.proxyDB[proxy], &stored)
+	return nil
+}
+
+func (db *MemoryDB) GetImplementationHistory(proxy types.Address) ([]*types.ProxyImplementation, error) {
+	db.mux.RLock()
+	defer db.mux.RUnlock()
+	res := make([]*types.ProxyImplementation, len(db.proxyDB[proxy]))
+	for i, implementation := range db.proxyDB[proxy] {
+		copied := *implementation
+		res[i] = &copied
+	}
+	return res, nil
 }
 
 func (db *MemoryDB) WriteBlocks(blocks []*types.Block) error {
@@ -246,6 +298,20 @@ func (db *MemoryDB) ReadBlock(blockNumber uint64) (*types.Block, error) {
 	return nil, errors.New("block does not exist")
 }
 
+func (db *MemoryDB) ReadBlocks(blockNumbers []uint64) ([]*types.Block, error) {
+	db.mux.RLock()
+	defer db.mux.RUnlock()
+	blocks := make([]*types.Block, len(blockNumbers))
+	for i, blockNumber := range blockNumbers {
+		block, ok := db.blockDB[blockNumber]
+		if !ok {
+			return nil, errors.New("block does not exist")
+		}
+		blocks[i] = block
+	}
+	return blocks, nil
+}
+
 func (db *MemoryDB) GetLastPersistedBlockNumber() (uint64, error) {
 	db.mux.RLock()
 	defer db.mux.RUnlock()
@@ -288,8 +354,13 @@ func (db *MemoryDB) IndexStorage(rawStorage map[types.Address]*types.AccountStat
 }
 
 func (db *MemoryDB) IndexBlocks(addresses []types.Address, blocks []*types.Block) error {
+	// events and calls are decoded before indexing, as finding the templates of contracts takes the lock
+	decoded, err := db.decodeBlocks(addresses, blocks)
+	if err != nil {
+		return err
+	}
 	for _, block := range blocks {
-		db.indexBlock(addresses, block)
+		db.indexBlock(addresses, block, decoded)
 	}
 	return nil
 }
@@ -312,6 +383,56 @@ func (db *MemoryDB) SetContractCreationTransaction(creationTxns map[types.Hash][
 	return nil
 }
 
+func (db *MemoryDB) RedecodeContract(address types.Address) error {
+	db.mux.RLock()
+	if !db.addressIsRegistered(address) {
+		db.mux.RUnlock()
+		return errors.New("address is not registered")
+	}
+	txsTo := append([]types.Hash{}, db.txIndexDB[address].txsTo...)
+	events := append([]*types.Event{}, db.eventIndexDB[address]...)
+	db.mux.RUnlock()
+
+	// decoded before taking the lock, as finding the templates of contracts takes the lock
+	decoder := database.NewTemplateDecoder(db)
+	calls := make(map[types.Hash]*types.DecodedCall, len(txsTo))
+	for _, txHash := range txsTo {
+		tx, err := db.ReadTransaction(txHash)
+		if err != nil {
+			return err
+		}
+		if calls[txHash], err = decoder.DecodeCall(tx); err != nil {
+			return err
+		}
+	}
+	decodedEvents := make(map[*types.Event]*types.DecodedEvent, len(events))
+	for _, event := range events {
+		decoded, err := decoder.DecodeEvent(event)
+		if err != nil {
+			return err
+		}
+		decodedEvents[event] = decoded
+	}
+
+	db.mux.Lock()
+	defer db.mux.Unlock()
+	for txHash, call := range calls {
+		if call == nil {
+			delete(db.decodedCallDB, txHash)
+		} else {
+			db.decodedCallDB[txHash] = call
+		}
+	}
+	for event, decoded := range decodedEvents {
+		if decoded == nil {
+			delete(db.decodedEventDB, event)
+		} else {
+			db.decodedEventDB[event] = decoded
+		}
+	}
+	return nil
+}
+
 func (db *MemoryDB) GetContractCreationTransaction(address types.Address) (types.Hash, error) {
 	db.mux.RLock()
 	defer db.mux.RUnlock()
@@ -327,7 +448,7 @@ func (db *MemoryDB) GetAllTransactionsToAddress(address types.Address, options *
 	if !db.addressIsRegistered(address) {
 		return nil, errors.New("address is not registered")
 	}
-	return db.txIndexDB[address].txsTo, nil
+	return db.pageOfTransactions(db.transactionsTo(address, options), options)
 }
 
 func (db *MemoryDB) GetTransactionsToAddressTotal(address types.Address, options *types.QueryOptions) (uint64, error) {
@@ -336,7 +457,25 @@ func (db *MemoryDB) GetTransactionsToAddressTotal(address types.Address, options
 	if !db.addressIsRegistered(address) {
 		return 0, errors.New("address is not registered")
 	}
-	return uint64(len(db.txIndexDB[address].txsTo)), nil
+	return uint64(len(db.transactionsTo(address, options))), nil
+}
+
+func (db *MemoryDB) GetFailedTransactionsToAddress(address types.Address, options *types.QueryOptions) ([]types.Hash, error) {
+	db.mux.RLock()
+	defer db.mux.RUnlock()
+	if !db.addressIsRegistered(address) {
+		return nil, errors.New("address is not registered")
+	}
+	return db.pageOfTransactions(db.failedTransactionsTo(address, options), options)
+}
+
+func (db *MemoryDB) GetFailedTransactionsToAddressTotal(address types.Address, options *types.QueryOptions) (uint64, error) {
+	db.mux.RLock()
+	defer db.mux.RUnlock()
+	if !db.addressIsRegistered(address) {
+		return 0, errors.New("address is not registered")
+	}
+	return uint64(len(db.failedTransactionsTo(address, options))), nil
 }
 
 func (db *MemoryDB) GetAllTransactionsInternalToAddress(address types.Address, options *types.QueryOptions) ([]types.Hash, error) {
@@ -345,7 +484,7 @@ func (db *MemoryDB) GetAllTransactionsInternalToAddress(address types.Address, o
 	if !db.addressIsRegistered(address) {
 		return nil, errors.New("address is not registered")
 	}
-	return db.txIndexDB[address].txsInternalTo, nil
+	return db.pageOfTransactions(db.transactionsInternalTo(address, options), options)
 }
 
 func (db *MemoryDB) GetTransactionsInternalToAddressTotal(address types.Address, options *types.QueryOptions) (uint64, error) {
@@ -354,7 +493,19 @@ func (db *MemoryDB) GetTransactionsInternalToAddressTotal(address types.Address,
 	if !db.addressIsRegistered(address) {
 		return 0, errors.New("address is not registered")
 	}
-	return uint64(len(db.txIndexDB[address].txsInternalTo)), nil
+	return uint64(len(db.transactionsInternalTo(address, options))), nil
+}
+
+func (db *MemoryDB) GetAllTransactionsFromAddress(address types.Address, options *types.QueryOptions) ([]types.Hash, error) {
+	db.mux.RLock()
+	defer db.mux.RUnlock()
+	return db.pageOfTransactions(db.transactionsFrom(address, options), options)
+}
+
+func (db *MemoryDB) GetTransactionsFromAddressTotal(address types.Address, options *types.QueryOptions) (uint64, error) {
+	db.mux.RLock()
+	defer db.mux.RUnlock()
+	return uint64(len(db.transactionsFrom(address, options))), nil
 }
 
 func (db *MemoryDB) GetAllEventsFromAddress(address types.Address, options *types.QueryOptions) ([]*types.Event, error) {
@@ -363,7 +514,7 @@ func (db *MemoryDB) GetAllEventsFromAddress(address types.Address, options *type
 	if !db.addressIsRegistered(address) {
 		return nil, errors.New("address is not registered")
 	}
-	return db.eventIndexDB[address], nil
+	return pageOfEvents(db.filterEvents(address, &types.EventFilter{}, options), options)
 }
 
 func (db *MemoryDB) GetEventsFromAddressTotal(address types.Address, options *types.QueryOptions) (uint64, error) {
@@ -372,7 +523,25 @@ func (db *MemoryDB) GetEventsFromAddressTotal(address types.Address, options *ty
 	if !db.addressIsRegistered(address) {
 		return 0, errors.New("address is not registered")
 	}
-	return uint64(len(db.eventIndexDB[address])), nil
+	return uint64(len(db.filterEvents(address, &types.EventFilter{}, options))), nil
+}
+
+func (db *MemoryDB) GetFilteredEventsFromAddress(address types.Address, filter *types.EventFilter, options *types.QueryOptions) ([]*types.Event, error) {
+	db.mux.RLock()
+	defer db.mux.RUnlock()
+	if !db.addressIsRegistered(address) {
+		return nil, errors.New("address is not registered")
+	}
+	return pageOfEvents(db.filterEvents(address, filter, options), options)
+}
+
+func (db *MemoryDB) GetFilteredEventsFromAddressTotal(address types.Address, filter *types.EventFilter, options *types.QueryOptions) (uint64, error) {
+	db.mux.RLock()
+	defer db.mux.RUnlock()
+	if !db.addressIsRegistered(address) {
+		return 0, errors.New("address is not registered")
+	}
+	return uint64(len(db.filterEvents(address, filter, options))), nil
 }
 
 func (db *MemoryDB) GetStorageWithOptions(types.Address, *types.PageOptions) ([]*types.StorageResult, error) {
@@ -469,10 +638,236 @@ func (db *MemoryDB) GetLastFiltered(address types.Address) (uint64, error) {
 	return db.lastFiltered[address], nil
 }
 
+func (db *MemoryDB) AddSampler(address types.Address, sampler *types.FunctionSampler) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+	if !db.addressIsRegistered(address) {
+		return errors.New("address is not registered")
+	}
+	stored := *sampler
+	db.samplerDB[address] = database.InsertSampler(db.samplerDB[address], &stored)
+	return nil
+}
+
+func (db *MemoryDB) DeleteSampler(address types.Address, name string) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+	samplers, err := database.RemoveSampler(db.samplerDB[address], name)
+	if err != nil {
+		return err
+	}
+	db.samplerDB[address] = samplers
+	return nil
+}
+
+func (db *MemoryDB) GetSamplers(address types.Address) ([]*types.FunctionSampler, error) {
+	db.mux.RLock()
+	defer db.mux.RUnlock()
+	res := make([]*types.FunctionSampler, len(db.samplerDB[address]))
+	for i, sampler := range db.samplerDB[address] {
+		copied := *sampler
+		res[i] = &copied
+	}
+	return res, nil
+}
+
+func (db *MemoryDB) RecordSamples(samples []*types.FunctionSample) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+	for _, sample := range samples {
+		if db.sampleDB[sample.Address] == nil {
+			db.sampleDB[sample.Address] = make(map[string][]*types.FunctionSample)
+		}
+		existing := db.sampleDB[sample.Address][sample.Name]
+		stored := *sample
+		i := sort.Search(len(existing), func(i int) bool { return existing[i].BlockNumber >= sample.BlockNumber })
+		if i < len(existing) && existing[i].BlockNumber == sample.BlockNumber {
+			existing[i] = &stored
+			continue
+		}
+		existing = append(existing, nil)
+		copy(existing[i+1:], existing[i:])
+		existing[i] = &stored
+		db.sampleDB[sample.Address][sample.Name] = existing
+	}
+	return nil
+}
+
+func (db *MemoryDB) GetSamples(address types.Address, name string, options *types.QueryOptions) ([]*types.FunctionSample, error) {
+	db.mux.RLock()
+	defer db.mux.RUnlock()
+	if !db.addressIsRegistered(address) {
+		return nil, errors.New("address is not registered")
+	}
+	// samples are kept oldest first, which is the order they are returned in unless sorted desc
+	matching := db.samplesInRange(address, name, options)
+	sorted := types.SamplesOptions(options)
+	if !sorted.Ascending() {
+		for i, j := 0, len(matching)-1; i < j; i, j = i+1, j-1 {
+			matching[i], matching[j] = matching[j], matching[i]
+		}
+	}
+	start, end, err := pageBounds(len(matching), sorted, func(i int) (uint64, uint64) { return matching[i].BlockNumber, 0 })
+	if err != nil {
+		return nil, err
+	}
+	return matching[start:end], nil
+}
+
+func (db *MemoryDB) GetSamplesTotal(address types.Address, name string, options *types.QueryOptions) (uint64, error) {
+	db.mux.RLock()
+	defer db.mux.RUnlock()
+	if !db.addressIsRegistered(address) {
+		return 0, errors.New("address is not registered")
+	}
+	return uint64(len(db.samplesInRange(address, name, options))), nil
+}
+
 func (db *MemoryDB) Stop() {}
 
 // internal functions
 
+// transactionsFrom finds the transactions sent from the address or in which it made an internal call,
+// within the ranges of the options, ordered by block in the direction of the options and then by index
+func (db *MemoryDB) transactionsFrom(address types.Address, options *types.QueryOptions) []types.Hash {
+	var matching []*types.Transaction
+	for _, tx := range db.txDB {
+		if !inRange(tx.BlockNumber, options.BeginBlockNumber, options.EndBlockNumber) || !inRange(tx.Timestamp, options.BeginTimestamp, options.EndTimestamp) {
+			continue
+		}
+		sent := tx.From == address
+		for _, internalCall := range tx.InternalCalls {
+			sent = sent || internalCall.From == address
+		}
+		if sent {
+			matching = append(matching, tx)
+		}
+	}
+	sort.Slice(matching, func(i, j int) bool {
+		return types.NewCursor(matching[i].BlockNumber, matching[i].Index).Precedes(matching[j].BlockNumber, matching[j].Index, options.Ascending())
+	})
+
+	hashes := make([]types.Hash, len(matching))
+	for i, tx := range matching {
+		hashes[i] = tx.Hash
+	}
+	return hashes
+}
+
+// filterEvents finds the events of a contract matching the filter and the ranges of the options,
+// ordered the same as the other backends, by block in the direction of the options then by index
+func (db *MemoryDB) filterEvents(address types.Address, filter *types.EventFilter, options *types.QueryOptions) []*types.Event {
+	options = withDefaults(options)
+	matching := []*types.Event{}
+	for _, event := range db.eventIndexDB[address] {
+		if !inRange(event.BlockNumber, options.BeginBlockNumber, options.EndBlockNumber) || !inRange(event.Timestamp, options.BeginTimestamp, options.EndTimestamp) {
+			continue
+		}
+		if filter.Matches(db.decodedEventDB[event]) {
+			matching = append(matching, event)
+		}
+	}
+	sort.SliceStable(matching, func(i, j int) bool {
+		return types.NewCursor(matching[i].BlockNumber, matching[i].Index).Precedes(matching[j].BlockNumber, matching[j].Index, options.Ascending())
+	})
+	return matching
+}
+
+// decodedBlocks holds the events and function calls of a batch of blocks that were decoded to be indexed
+type decodedBlocks struct {
+	events map[*types.Event]*types.DecodedEvent
+	calls  map[types.Hash]*types.DecodedCall
+}
+
+// decodeBlocks decodes the events emitted by the given addresses in the blocks, and the transactions sent to them
+func (db *MemoryDB) decodeBlocks(addresses []types.Address, blocks []*types.Block) (*decodedBlocks, error) {
+	isIndexed := make(map[types.Address]bool)
+	for _, address := range addresses {
+		isIndexed[address] = true
+	}
+
+	decoder := database.NewTemplateDecoder(db)
+	decoded := &decodedBlocks{
+		events: make(map[*types.Event]*types.DecodedEvent),
+		calls:  make(map[types.Hash]*types.DecodedCall),
+	}
+	for _, block := range blocks {
+		for _, txHash := range block.Transactions {
+			tx, err := db.ReadTransaction(txHash)
+			if err != nil {
+				return nil, err
+			}
+			if isIndexed[tx.To] {
+				decodedCall, err := decoder.DecodeCall(tx)
+				if err != nil {
+					return nil, err
+				}
+				if decodedCall != nil {
+					decoded.calls[tx.Hash] = decodedCall
+				}
+			}
+			for _, event := range tx.Events {
+				if !isIndexed[event.Address] {
+					continue
+				}
+				decodedEvent, err := decoder.DecodeEvent(event)
+				if err != nil {
+					return nil, err
+				}
+				if decodedEvent != nil {
+					decoded.events[event] = decodedEvent
+				}
+			}
+		}
+	}
+	return decoded, nil
+}
+
+// samplesInRange filters the samples of a sampler to the block and timestamp ranges of the options
+func (db *MemoryDB) samplesInRange(address types.Address, name string, options *types.QueryOptions) []*types.FunctionSample {
+	var matching []*types.FunctionSample
+	for _, sample := range db.sampleDB[address][name] {
+		if inRange(sample.BlockNumber, options.BeginBlockNumber, options.EndBlockNumber) && inRange(sample.Timestamp, options.BeginTimestamp, options.EndTimestamp) {
+			copied := *sample
+			matching = append(matching, &copied)
+		}
+	}
+	return matching
+}
+
+// inRange checks a value is within an inclusive range, where an end of -1 is unbounded
+func inRange(value uint64, begin *big.Int, end *big.Int) bool {
+	asBig := new(big.Int).SetUint64(value)
+	if asBig.Cmp(begin) < 0 {
+		return false
+	}
+	return end.Sign() < 0 || asBig.Cmp(end) <= 0
+}
+
+// pageBounds finds the page of sorted results given by the options, which continues after the cursor if
+// one is given. position returns the block number and index of the result at i.
+func pageBounds(count int, options *types.QueryOptions, position func(i int) (uint64, uint64)) (int, int, error) {
+	cursor, err := options.Cursor()
+	if err != nil {
+		return 0, 0, err
+	}
+	start := options.PageSize * options.PageNumber
+	if cursor != nil {
+		start = sort.Search(count, func(i int) bool {
+			blockNumber, index := position(i)
+			return cursor.Precedes(blockNumber, index, options.Ascending())
+		})
+	}
+	if start >= count {
+		return count, count, nil
+	}
+	end := start + options.PageSize
+	if end > count {
+		end = count
+	}
+	return start, end, nil
+}
+
 func (db *MemoryDB) addressIsRegistered(address types.Address) bool {
 	for _, a := range db.addressDB {
 		if address == a {
@@ -482,7 +877,7 @@ func (db *MemoryDB) addressIsRegistered(address types.Address) bool {
 	return false
 }
 
-func (db *MemoryDB) indexBlock(addresses []types.Address, block *types.Block) error {
+func (db *MemoryDB) indexBlock(addresses []types.Address, block *types.Block, decoded *decodedBlocks) error {
 	db.mux.Lock()
 	defer db.mux.Unlock()
 	// filter out registered and unfiltered address only
@@ -496,7 +891,7 @@ func (db *MemoryDB) indexBlock(addresses []types.Address, block *types.Block) er
 
 	// index transactions and events
 	for _, txHash := range block.Transactions {
-		db.indexTransaction(filteredAddresses, db.txDB[txHash])
+		db.indexTransaction(filteredAddresses, db.txDB[txHash], decoded)
 	}
 
 	for address := range filteredAddresses {
@@ -505,9 +900,12 @@ func (db *MemoryDB) indexBlock(addresses []types.Address, block *types.Block) er
 	return nil
 }
 
-func (db *MemoryDB) indexTransaction(filteredAddresses map[types.Address]bool, tx *types.Transaction) {
+func (db *MemoryDB) indexTransaction(filteredAddresses map[types.Address]bool, tx *types.Transaction, decoded *decodedBlocks) {
 	if filteredAddresses[tx.To] {
 		db.txIndexDB[tx.To].txsTo = append(db.txIndexDB[tx.To].txsTo, tx.Hash)
+		if decodedCall, ok := decoded.calls[tx.Hash]; ok {
+			db.decodedCallDB[tx.Hash] = decodedCall
+		}
 		log.Debug("Indexed tx recipient", "tx", tx.Hash.Hex(), "recipient", tx.To.Hex())
 	}
 
@@ -522,19 +920,130 @@ func (db *MemoryDB) indexTransaction(filteredAddresses map[types.Address]bool, t
 		addr := event.Address
 		if filteredAddresses[addr] {
 			db.eventIndexDB[addr] = append(db.eventIndexDB[addr], event)
+			if decodedEvent, ok := decoded.events[event]; ok {
+				db.decodedEventDB[event] = decodedEvent
+			}
 			log.Debug("Indexed emitted event", "tx", event.TransactionHash.Hex(), "address", event.Address.Hex())
 		}
 	}
 }
 
 func (db *MemoryDB) removeAllIndices(address types.Address) error {
+	for _, event := range db.eventIndexDB[address] {
+		delete(db.decodedEventDB, event)
+	}
+	if indexer, ok := db.txIndexDB[address]; ok {
+		for _, hash := range indexer.txsTo {
+			delete(db.decodedCallDB, hash)
+		}
+	}
 	delete(db.txIndexDB, address)
 	delete(db.eventIndexDB, address)
 	delete(db.storageIndexDB, address)
+	delete(db.proxyDB, address)
+	delete(db.samplerDB, address)
+	delete(db.sampleDB, address)
 	db.lastFiltered[address] = 0
 	return nil
 }
 
+// transactionsTo finds the transactions to a contract that match the call filter of the options
+func (db *MemoryDB) transactionsTo(address types.Address, options *types.QueryOptions) []types.Hash {
+	options = withDefaults(options)
+	return db.sortTransactions(db.txIndexDB[address].txsTo, options, func(tx *types.Transaction) bool {
+		return !options.HasCallFilter() || options.MatchesCall(tx, db.decodedCallDB[tx.Hash])
+	})
+}
+
+func (db *MemoryDB) failedTransactionsTo(address types.Address, options *types.QueryOptions) []types.Hash {
+	return db.sortTransactions(db.txIndexDB[address].txsTo, withDefaults(options), func(tx *types.Transaction) bool {
+		return !tx.Status
+	})
+}
+
+func (db *MemoryDB) transactionsInternalTo(address types.Address, options *types.QueryOptions) []types.Hash {
+	return db.sortTransactions(db.txIndexDB[address].txsInternalTo, withDefaults(options), func(*types.Transaction) bool {
+		return true
+	})
+}
+
+// sortTransactions finds the transactions that match and are within the ranges of the options, ordered the
+// same as the other backends, by block in the direction of the options then by index
+func (db *MemoryDB) sortTransactions(hashes []types.Hash, options *types.QueryOptions, matches func(*types.Transaction) bool) []types.Hash {
+	matching := []*types.Transaction{}
+	for _, hash := range hashes {
+		tx := db.txDB[hash]
+		if !inRange(tx.BlockNumber, options.BeginBlockNumber, options.EndBlockNumber) || !inRange(tx.Timestamp, options.BeginTimestamp, options.EndTimestamp) {
+			continue
+		}
+		if matches(tx) {
+			matching = append(matching, tx)
+		}
+	}
+	sort.SliceStable(matching, func(i, j int) bool {
+		return types.NewCursor(matching[i].BlockNumber, matching[i].Index).Precedes(matching[j].BlockNumber, matching[j].Index, options.Ascending())
+	})
+
+	sorted := make([]types.Hash, len(matching))
+	for i, tx := range matching {
+		sorted[i] = tx.Hash
+	}
+	return sorted
+}
+
+// pageOfTransactions takes the page given by the options from the sorted transactions
+func (db *MemoryDB) pageOfTransactions(hashes []types.Hash, options *types.QueryOptions) ([]types.Hash, error) {
+	start, end, err := pageBounds(len(hashes), withDefaults(options), func(i int) (uint64, uint64) {
+		tx := db.txDB[hashes[i]]
+		return tx.BlockNumber, tx.Index
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hashes[start:end], nil
+}
+
+// pageOfEvents takes the page given by the options from the sorted events
+func pageOfEvents(events []*types.Event, options *types.QueryOptions) ([]*types.Event, error) {
+	start, end, err := pageBounds(len(events), withDefaults(options), func(i int) (uint64, uint64) {
+		return events[i].BlockNumber, events[i].Index
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events[start:end], nil
+}
+
+// withDefaults gives a copy of the options with their defaults set, or the default options if none are given
+func withDefaults(options *types.QueryOptions) *types.QueryOptions {
+	withDefaults := types.QueryOptions{}
+	if options != nil {
+		withDefaults = *options
+	}
+	withDefaults.SetDefaults()
+	return &withDefaults
+}
+
+func (db *MemoryDB) currentTemplate(address types.Address) *types.Template {
+	assignments := db.assignmentDB[address]
+	if len(assignments) == 0 {
+		return nil
+	}
+	current := assignments[len(assignments)-1]
+	return db.templateVersion(current.TemplateName, current.Version)
+}
+
+func (db *MemoryDB) templateVersion(templateName string, version uint64) *types.Template {
+	versions := db.templateDB[templateName]
+	if len(versions) == 0 || version > uint64(len(versions)) {
+		return nil
+	}
+	if version == 0 {
+		return versions[len(versions)-1]
+	}
+	return versions[version-1]
+}
+
 func (db *MemoryDB) RecordNewERC20Balance(contract types.Address, holder types.Address, block uint64, amount *big.Int) error {
 	return nil
 }
@@ -547,7 +1056,27 @@ func (db *MemoryDB) GetAllTokenHolders(contract types.Address, block uint64, opt
 	return nil, database.ErrNotImplemented
 }
 
-func (db *MemoryDB) RecordERC721Token(contract types.Address, holder types.Address, block uint64, tokenId *big.Int) error {
+func (db *MemoryDB) GetERC721TokenHistory(contract types.Address, tokenId *big.Int, options *types.TokenQueryOptions) ([]types.ERC721Token, error) {
+	return nil, database.ErrNotImplemented
+}
+
+func (db *MemoryDB) GetERC721TokensAcquiredByHolder(holder types.Address, options *types.TokenQueryOptions) ([]types.ERC721Token, error) {
+	return nil, database.ErrNotImplemented
+}
+
+func (db *MemoryDB) GetERC721TokensDisposedByHolder(holder types.Address, options *types.TokenQueryOptions) ([]types.ERC721Token, error) {
+	return nil, database.ErrNotImplemented
+}
+
+func (db *MemoryDB) RecordERC721Metadata(metadata *types.ERC721Metadata) error {
+	return nil
+}
+
+func (db *MemoryDB) GetERC721Metadata(contract types.Address, tokenIds []string) ([]*types.ERC721Metadata, error) {
+	return nil, database.ErrNotImplemented
+}
+
+func (db *MemoryDB) RecordERC721Token(token *types.ERC721Token) error {
 	return nil
 }
 
@@ -566,3 +1095,83 @@ func (db *MemoryDB) AllERC721TokensAtBlock(contract types.Address, block uint64,
 func (db *MemoryDB) AllHoldersAtBlock(contract types.Address, block uint64, options *types.TokenQueryOptions) ([]types.Address, error) {
 	return nil, database.ErrNotImplemented
 }
+
+func (db *MemoryDB) GetERC20BalanceAtBlock(contract types.Address, holder types.Address, block uint64) (*big.Int, error) {
+	return nil, database.ErrNotFound
+}
+
+func (db *MemoryDB) GetERC20BalancesOfHolder(holder types.Address, options *types.TokenQueryOptions) ([]types.ERC20Balance, error) {
+	return nil, database.ErrNotImplemented
+}
+
+func (db *MemoryDB) GetERC721TokensOfHolder(holder types.Address, options *types.TokenQueryOptions) ([]types.ERC721Token, error) {
+	return nil, database.ErrNotImplemented
+}
+
+func (db *MemoryDB) GetERC1155TokensOfHolder(holder types.Address, options *types.TokenQueryOptions) ([]types.ERC1155Token, error) {
+	return nil, database.ErrNotImplemented
+}
+
+func (db *MemoryDB) GetERC20BalancesOfToken(contract types.Address, options *types.TokenQueryOptions) ([]types.ERC20Balance, error) {
+	return nil, database.ErrNotImplemented
+}
+
+func (db *MemoryDB) GetERC20BalancesAtBlock(contract types.Address, block uint64, minBalance *big.Int, options *types.TokenQueryOptions) ([]types.ERC20HolderBalance, error) {
+	return nil, database.ErrNotImplemented
+}
+
+func (db *MemoryDB) RecordNewERC20Allowance(contract types.Address, owner types.Address, spender types.Address, block uint64, amount *big.Int) error {
+	return nil
+}
+
+func (db *MemoryDB) GetERC20Allowance(contract types.Address, owner types.Address, spender types.Address, options *types.TokenQueryOptions) (map[uint64]*big.Int, error) {
+	return nil, database.ErrNotImplemented
+}
+
+func (db *MemoryDB) GetERC20ApprovalsForOwner(contract types.Address, owner types.Address, block uint64, options *types.TokenQueryOptions) ([]types.ERC20Allowance, error) {
+	return nil, database.ErrNotImplemented
+}
+
+func (db *MemoryDB) RecordERC20Metadata(metadata *types.ERC20Metadata) error {
+	return nil
+}
+
+func (db *MemoryDB) GetERC20Metadata(contract types.Address) (*types.ERC20Metadata, error) {
+	return nil, database.ErrNotFound
+}
+
+func (db *MemoryDB) RecordNewERC20TotalSupply(contract types.Address, block uint64, amount *big.Int) error {
+	return nil
+}
+
+func (db *MemoryDB) GetERC20TotalSupply(contract types.Address, options *types.TokenQueryOptions) (map[uint64]*big.Int, error) {
+	return nil, database.ErrNotImplemented
+}
+
+func (db *MemoryDB) RecordERC20Transfers(transfers []*types.ERC20Transfer) error {
+	return nil
+}
+
+func (db *MemoryDB) GetERC20Transfers(contract types.Address, filter *types.ERC20TransferFilter, options *types.QueryOptions) ([]*types.ERC20Transfer, error) {
+	return nil, database.ErrNotImplemented
+}
+
+func (db *MemoryDB) GetERC20TransfersTotal(contract types.Address, filter *types.ERC20TransferFilter, options *types.QueryOptions) (uint64, error) {
+	return 0, database.ErrNotImplemented
+}
+
+func (db *MemoryDB) RecordNewERC1155Balance(contract types.Address, holder types.Address, tokenId *big.Int, block uint64, amount *big.Int) error {
+	return nil
+}
+
+func (db *MemoryDB) GetERC1155Balance(contract types.Address, holder types.Address, tokenId *big.Int, options *types.TokenQueryOptions) (map[uint64]*big.Int, error) {
+	return nil, database.ErrNotImplemented
+}
+
+func (db *MemoryDB) GetERC1155HoldersAtBlock(contract types.Address, tokenId *big.Int, block uint64, options *types.TokenQueryOptions) ([]types.Address, error) {
+	return nil, database.ErrNotImplemented
+}
+
+func (db *MemoryDB) GetERC1155TokensForHolderAtBlock(contract types.Address, holder types.Address, block uint64, options *types.TokenQueryOptions) ([]types.ERC1155Token, error) {
+	return nil, database.ErrNotImplemented
+}
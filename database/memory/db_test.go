@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"fmt"
 	"math/big"
 	"testing"
 
@@ -92,6 +93,20 @@ func TestMemoryDB_WriteBlocks(t *testing.T) {
 	assert.Equal(t, block, retrievedblock, "unexpected block from db: %s", retrievedblock)
 }
 
+func TestMemoryDB_ReadBlocks(t *testing.T) {
+	db := NewMemoryDB()
+
+	err := db.WriteBlocks([]*types.Block{block})
+	assert.Nil(t, err, "unexpected err")
+
+	retrievedBlocks, err := db.ReadBlocks([]uint64{block.Number})
+	assert.Nil(t, err, "unexpected err")
+	assert.Equal(t, []*types.Block{block}, retrievedBlocks)
+
+	_, err = db.ReadBlocks([]uint64{block.Number, block.Number + 1})
+	assert.EqualError(t, err, "block does not exist")
+}
+
 func TestMemoryDB(t *testing.T) {
 	// test data
 	db := NewMemoryDB()
@@ -185,7 +200,7 @@ func testGetStorageLayout(t *testing.T, db database.Database, address types.Addr
 }
 
 func testAddTemplate(t *testing.T, db database.Database, testTemplateName, testABI, testStorageLayout string, expectedErr bool) {
-	err := db.AddTemplate(testTemplateName, testABI, testStorageLayout)
+	err := db.AddTemplate(&types.Template{TemplateName: testTemplateName, ABI: testABI, StorageLayout: testStorageLayout})
 	if err != nil && !expectedErr {
 		t.Fatalf("expected no error, but got %v", err)
 	}
@@ -466,3 +481,398 @@ func TestMemoryDB_GetStorageRanges(t *testing.T) {
 		assert.Equal(t, test.expectedResult, res)
 	}
 }
+
+func TestMemoryDB_TemplateVersions(t *testing.T) {
+	db := NewMemoryDB()
+
+	assert.Nil(t, db.AddTemplate(&types.Template{TemplateName: "test", ABI: "first abi"}))
+	assert.Nil(t, db.AddTemplate(&types.Template{TemplateName: "test", ABI: "second abi"}))
+
+	latest, err := db.GetTemplateDetails("test")
+	assert.Nil(t, err)
+	assert.Equal(t, &types.Template{TemplateName: "test", Version: 2, ABI: "second abi"}, latest)
+
+	first, err := db.GetTemplateVersion("test", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, &types.Template{TemplateName: "test", Version: 1, ABI: "first abi"}, first)
+
+	_, err = db.GetTemplateVersion("test", 3)
+	assert.Equal(t, database.ErrNotFound, err)
+
+	templates, err := db.GetTemplates()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"test"}, templates)
+}
+
+func TestMemoryDB_TemplateAssignments(t *testing.T) {
+	db := NewMemoryDB()
+	assert.Nil(t, db.AddTemplate(&types.Template{TemplateName: "test", ABI: "first abi"}))
+	assert.Nil(t, db.AddTemplate(&types.Template{TemplateName: "test", ABI: "second abi"}))
+
+	assert.Nil(t, db.AssignTemplate(addr, "test"))
+	assert.Nil(t, db.AssignTemplateFromBlock(addr, &types.TemplateAssignment{TemplateName: "test", Version: 1, FromBlock: 20}))
+	assert.Nil(t, db.AssignTemplateFromBlock(addr, &types.TemplateAssignment{TemplateName: "other", FromBlock: 10}))
+
+	assignments, err := db.GetTemplateAssignments(addr)
+	assert.Nil(t, err)
+	assert.Equal(t, []*types.TemplateAssignment{
+		{TemplateName: "test"},
+		{TemplateName: "other", FromBlock: 10},
+		{TemplateName: "test", Version: 1, FromBlock: 20},
+	}, assignments)
+
+	// the latest assignment is pinned to the first version
+	abi, err := db.GetContractABI(addr)
+	assert.Nil(t, err)
+	assert.Equal(t, "first abi", abi)
+
+	// re-assigning for all history removes the existing assignments
+	assert.Nil(t, db.AssignTemplate(addr, "test"))
+	assignments, err = db.GetTemplateAssignments(addr)
+	assert.Nil(t, err)
+	assert.Equal(t, []*types.TemplateAssignment{{TemplateName: "test"}}, assignments)
+
+	abi, err = db.GetContractABI(addr)
+	assert.Nil(t, err)
+	assert.Equal(t, "second abi", abi)
+}
+
+func TestMemoryDB_Samplers(t *testing.T) {
+	db := NewMemoryDB()
+	assert.Nil(t, db.AddAddresses([]types.Address{addr}))
+
+	assert.Nil(t, db.AddSampler(addr, &types.FunctionSampler{Name: "ratio", Function: "collateralRatio", Interval: 10}))
+	assert.Nil(t, db.AddSampler(addr, &types.FunctionSampler{Name: "supply", Function: "totalSupply"}))
+	// a sampler with the same name replaces the existing one
+	assert.Nil(t, db.AddSampler(addr, &types.FunctionSampler{Name: "ratio", Function: "collateralRatio", Interval: 5}))
+
+	samplers, err := db.GetSamplers(addr)
+	assert.Nil(t, err)
+	assert.Equal(t, []*types.FunctionSampler{
+		{Name: "ratio", Function: "collateralRatio", Interval: 5},
+		{Name: "supply", Function: "totalSupply"},
+	}, samplers)
+
+	assert.Nil(t, db.DeleteSampler(addr, "supply"))
+	assert.Equal(t, database.ErrNotFound, db.DeleteSampler(addr, "supply"))
+	samplers, err = db.GetSamplers(addr)
+	assert.Nil(t, err)
+	assert.Len(t, samplers, 1)
+
+	assert.Nil(t, db.RecordSamples([]*types.FunctionSample{
+		{Address: addr, Name: "ratio", BlockNumber: 20, Timestamp: 2000, Output: "02"},
+		{Address: addr, Name: "ratio", BlockNumber: 10, Timestamp: 1000, Output: "01"},
+		{Address: addr, Name: "ratio", BlockNumber: 30, Timestamp: 3000, Output: "03"},
+		{Address: addr, Name: "other", BlockNumber: 10, Timestamp: 1000, Output: "ff"},
+	}))
+	// samples taken again at the same block replace the existing ones
+	assert.Nil(t, db.RecordSamples([]*types.FunctionSample{{Address: addr, Name: "ratio", BlockNumber: 20, Timestamp: 2000, Output: "22"}}))
+
+	options := &types.QueryOptions{}
+	options.SetDefaults()
+	samples, err := db.GetSamples(addr, "ratio", options)
+	assert.Nil(t, err)
+	assert.Len(t, samples, 3)
+	assert.EqualValues(t, 10, samples[0].BlockNumber)
+	assert.Equal(t, types.HexData("22"), samples[1].Output)
+
+	options = &types.QueryOptions{BeginTimestamp: big.NewInt(1500), EndBlockNumber: big.NewInt(25)}
+	options.SetDefaults()
+	samples, err = db.GetSamples(addr, "ratio", options)
+	assert.Nil(t, err)
+	assert.Len(t, samples, 1)
+	assert.EqualValues(t, 20, samples[0].BlockNumber)
+	total, err := db.GetSamplesTotal(addr, "ratio", options)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, total)
+
+	options = &types.QueryOptions{PageSize: 2, PageNumber: 1}
+	options.SetDefaults()
+	samples, err = db.GetSamples(addr, "ratio", options)
+	assert.Nil(t, err)
+	assert.Len(t, samples, 1)
+	assert.EqualValues(t, 30, samples[0].BlockNumber)
+
+	// pages continue after the cursor, in either direction
+	options = &types.QueryOptions{PageSize: 1, PageNumber: 1, After: types.NewCursor(10, 0).Encode()}
+	options.SetDefaults()
+	samples, err = db.GetSamples(addr, "ratio", options)
+	assert.Nil(t, err)
+	assert.Len(t, samples, 1)
+	assert.EqualValues(t, 20, samples[0].BlockNumber)
+
+	options = &types.QueryOptions{PageSize: 2, Sort: types.SortDescending, After: types.NewCursor(30, 0).Encode()}
+	options.SetDefaults()
+	samples, err = db.GetSamples(addr, "ratio", options)
+	assert.Nil(t, err)
+	assert.Len(t, samples, 2)
+	assert.EqualValues(t, 20, samples[0].BlockNumber)
+	assert.EqualValues(t, 10, samples[1].BlockNumber)
+}
+
+func TestMemoryDB_GetAllTransactionsFromAddress(t *testing.T) {
+	db := NewMemoryDB()
+	sender := types.NewAddress("0x9d13c6d3afe1721beef56b55d303b09e021e27ab")
+
+	assert.Nil(t, db.WriteTransactions([]*types.Transaction{
+		{Hash: types.NewHash("0x01"), BlockNumber: 1, Timestamp: 100, From: sender, To: addr},
+		{Hash: types.NewHash("0x02"), BlockNumber: 2, Timestamp: 200, Index: 1, From: addr, InternalCalls: []*types.InternalCall{{From: sender, To: addr}}},
+		{Hash: types.NewHash("0x03"), BlockNumber: 2, Timestamp: 200, Index: 0, From: sender},
+		{Hash: types.NewHash("0x04"), BlockNumber: 3, Timestamp: 300, From: addr},
+	}))
+
+	// the sender does not need to be registered
+	options := &types.QueryOptions{}
+	options.SetDefaults()
+	txs, err := db.GetAllTransactionsFromAddress(sender, options)
+	assert.Nil(t, err)
+	assert.Equal(t, []types.Hash{types.NewHash("0x03"), types.NewHash("0x02"), types.NewHash("0x01")}, txs)
+
+	options = &types.QueryOptions{BeginTimestamp: big.NewInt(150), PageSize: 1}
+	options.SetDefaults()
+	txs, err = db.GetAllTransactionsFromAddress(sender, options)
+	assert.Nil(t, err)
+	assert.Equal(t, []types.Hash{types.NewHash("0x03")}, txs)
+	total, err := db.GetTransactionsFromAddressTotal(sender, options)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, total)
+}
+
+func TestMemoryDB_GetAllTransactionsFromAddress_WithCursor(t *testing.T) {
+	db := NewMemoryDB()
+	sender := types.NewAddress("0x9d13c6d3afe1721beef56b55d303b09e021e27ab")
+
+	assert.Nil(t, db.WriteTransactions([]*types.Transaction{
+		{Hash: types.NewHash("0x01"), BlockNumber: 1, From: sender},
+		{Hash: types.NewHash("0x02"), BlockNumber: 2, Index: 1, From: sender},
+		{Hash: types.NewHash("0x03"), BlockNumber: 2, Index: 0, From: sender},
+		{Hash: types.NewHash("0x04"), BlockNumber: 3, From: sender},
+	}))
+
+	// the page number is ignored when continuing from a cursor
+	options := &types.QueryOptions{PageSize: 2, PageNumber: 5, After: types.NewCursor(2, 0).Encode()}
+	options.SetDefaults()
+	txs, err := db.GetAllTransactionsFromAddress(sender, options)
+	assert.Nil(t, err)
+	assert.Equal(t, []types.Hash{types.NewHash("0x02"), types.NewHash("0x01")}, txs)
+
+	options = &types.QueryOptions{PageSize: 2, Sort: types.SortAscending}
+	options.SetDefaults()
+	txs, err = db.GetAllTransactionsFromAddress(sender, options)
+	assert.Nil(t, err)
+	assert.Equal(t, []types.Hash{types.NewHash("0x01"), types.NewHash("0x03")}, txs)
+
+	options.After = types.NewCursor(2, 0).Encode()
+	txs, err = db.GetAllTransactionsFromAddress(sender, options)
+	assert.Nil(t, err)
+	assert.Equal(t, []types.Hash{types.NewHash("0x02"), types.NewHash("0x04")}, txs)
+
+	options.After = "invalid"
+	_, err = db.GetAllTransactionsFromAddress(sender, options)
+	assert.Equal(t, types.ErrInvalidCursor, err)
+}
+
+func TestMemoryDB_PagesTransactionsAndEventsOfAddress(t *testing.T) {
+	db := NewMemoryDB()
+	assert.Nil(t, db.AddAddresses([]types.Address{addr}))
+	sender := types.NewAddress("0x9d13c6d3afe1721beef56b55d303b09e021e27ab")
+
+	assert.Nil(t, db.WriteTransactions([]*types.Transaction{
+		{Hash: types.NewHash("0x01"), BlockNumber: 1, Timestamp: 100, To: addr, Events: []*types.Event{{Address: addr, BlockNumber: 1}}},
+		{Hash: types.NewHash("0x02"), BlockNumber: 2, Timestamp: 200, Index: 1, To: addr, Status: true, Events: []*types.Event{{Address: addr, BlockNumber: 2, Index: 1}}},
+		{Hash: types.NewHash("0x03"), BlockNumber: 2, Timestamp: 200, Index: 0, To: sender, InternalCalls: []*types.InternalCall{{To: addr}}, Events: []*types.Event{{Address: addr, BlockNumber: 2}}},
+		{Hash: types.NewHash("0x04"), BlockNumber: 3, Timestamp: 300, To: addr, InternalCalls: []*types.InternalCall{{To: addr}}, Events: []*types.Event{{Address: addr, BlockNumber: 3}}},
+	}))
+	assert.Nil(t, db.IndexBlocks([]types.Address{addr}, []*types.Block{
+		{Number: 1, Transactions: []types.Hash{types.NewHash("0x01")}},
+		{Number: 2, Transactions: []types.Hash{types.NewHash("0x02"), types.NewHash("0x03")}},
+		{Number: 3, Transactions: []types.Hash{types.NewHash("0x04")}},
+	}))
+
+	options := &types.QueryOptions{PageSize: 2, After: types.NewCursor(3, 0).Encode()}
+	txs, err := db.GetAllTransactionsToAddress(addr, options)
+	assert.Nil(t, err)
+	assert.Equal(t, []types.Hash{types.NewHash("0x02"), types.NewHash("0x01")}, txs)
+
+	failed, err := db.GetFailedTransactionsToAddress(addr, &types.QueryOptions{PageSize: 1, Sort: types.SortAscending})
+	assert.Nil(t, err)
+	assert.Equal(t, []types.Hash{types.NewHash("0x01")}, failed)
+
+	options = &types.QueryOptions{BeginTimestamp: big.NewInt(150), PageSize: 1}
+	internal, err := db.GetAllTransactionsInternalToAddress(addr, options)
+	assert.Nil(t, err)
+	assert.Equal(t, []types.Hash{types.NewHash("0x04")}, internal)
+	total, err := db.GetTransactionsInternalToAddressTotal(addr, options)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, total)
+
+	options = &types.QueryOptions{BeginBlockNumber: big.NewInt(2), EndBlockNumber: big.NewInt(2), Sort: types.SortAscending}
+	events, err := db.GetAllEventsFromAddress(addr, options)
+	assert.Nil(t, err)
+	assert.Len(t, events, 2)
+	assert.EqualValues(t, 0, events[0].Index)
+	assert.EqualValues(t, 1, events[1].Index)
+	eventsTotal, err := db.GetEventsFromAddressTotal(addr, options)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, eventsTotal)
+}
+
+func TestMemoryDB_GetAllTransactionsToAddress_WithCallFilter(t *testing.T) {
+	db := NewMemoryDB()
+	assert.Nil(t, db.AddAddresses([]types.Address{addr}))
+	assert.Nil(t, db.AddTemplate(&types.Template{
+		TemplateName: "erc20",
+		ABI:          `[{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]}]`,
+	}))
+	assert.Nil(t, db.AssignTemplate(addr, "erc20"))
+
+	transfer := func(hash string, status bool, value string) *types.Transaction {
+		return &types.Transaction{
+			Hash:        types.NewHash(hash),
+			BlockNumber: 1,
+			To:          addr,
+			Status:      status,
+			Data:        types.HexData(fmt.Sprintf("a9059cbb%064s%064s", "02", value[2:])),
+		}
+	}
+	txs := []*types.Transaction{
+		transfer("0x01", true, "0x0a"),
+		transfer("0x02", false, "0x03e8"),
+		transfer("0x03", true, "0x2710"),
+		{Hash: types.NewHash("0x04"), BlockNumber: 1, To: addr, Status: true, Data: types.NewHexData("0x12345678")},
+	}
+	assert.Nil(t, db.WriteTransactions(txs))
+	assert.Nil(t, db.IndexBlocks([]types.Address{addr}, []*types.Block{
+		{Number: 1, Transactions: []types.Hash{txs[0].Hash, txs[1].Hash, txs[2].Hash, txs[3].Hash}},
+	}))
+
+	options := &types.QueryOptions{Function: "transfer", Status: types.StatusSuccess, Inputs: []*types.ArgFilter{{Name: "value", Op: "gte", Value: "1000"}}}
+	options.SetDefaults()
+	assert.Nil(t, options.ValidateCallFilter())
+	hashes, err := db.GetAllTransactionsToAddress(addr, options)
+	assert.Nil(t, err)
+	assert.Equal(t, []types.Hash{txs[2].Hash}, hashes)
+	total, err := db.GetTransactionsToAddressTotal(addr, options)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, total)
+
+	// calls not in the ABI can still be matched by selector
+	options = &types.QueryOptions{Function: "0x12345678"}
+	options.SetDefaults()
+	assert.Nil(t, options.ValidateCallFilter())
+	hashes, err = db.GetAllTransactionsToAddress(addr, options)
+	assert.Nil(t, err)
+	assert.Equal(t, []types.Hash{txs[3].Hash}, hashes)
+
+	options = &types.QueryOptions{Function: "transfer(address,uint256)"}
+	options.SetDefaults()
+	assert.Nil(t, options.ValidateCallFilter())
+	total, err = db.GetTransactionsToAddressTotal(addr, options)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 3, total)
+}
+
+func TestMemoryDB_GetFilteredEventsFromAddress(t *testing.T) {
+	db := NewMemoryDB()
+	assert.Nil(t, db.AddAddresses([]types.Address{addr}))
+	assert.Nil(t, db.AddTemplate(&types.Template{
+		TemplateName: "erc20",
+		ABI:          `[{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256","indexed":false}]}]`,
+	}))
+	assert.Nil(t, db.AssignTemplate(addr, "erc20"))
+
+	transfer := func(block uint64, index uint64, to string, value string) *types.Event {
+		return &types.Event{
+			Index:       index,
+			Address:     addr,
+			BlockNumber: block,
+			Topics: []types.Hash{
+				types.NewHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"),
+				types.NewHash("0x01"),
+				types.NewHash(to),
+			},
+			Data: types.HexData(fmt.Sprintf("%064s", value[2:])),
+		}
+	}
+	undecodable := transfer(1, 2, "0x02", "0x00")
+	undecodable.Data = ""
+	txs := []*types.Transaction{
+		{Hash: types.NewHash("0x01"), BlockNumber: 1, Events: []*types.Event{transfer(1, 0, "0x02", "0x0a"), transfer(1, 1, "0x03", "0x03e8"), undecodable}},
+		{Hash: types.NewHash("0x02"), BlockNumber: 2, Events: []*types.Event{transfer(2, 0, "0x02", "0x2710")}},
+	}
+	assert.Nil(t, db.WriteTransactions(txs))
+	assert.Nil(t, db.IndexBlocks([]types.Address{addr}, []*types.Block{
+		{Number: 1, Transactions: []types.Hash{txs[0].Hash}},
+		{Number: 2, Transactions: []types.Hash{txs[1].Hash}},
+	}))
+
+	options := &types.QueryOptions{}
+	options.SetDefaults()
+	// the last event has no data, so could not be decoded and is never matched
+	filter := &types.EventFilter{Event: "Transfer", Args: []*types.ArgFilter{{Name: "to", Value: "0x0000000000000000000000000000000000000002"}}}
+	assert.Nil(t, filter.Validate())
+
+	events, err := db.GetFilteredEventsFromAddress(addr, filter, options)
+	assert.Nil(t, err)
+	assert.Equal(t, []*types.Event{txs[1].Events[0], txs[0].Events[0]}, events)
+	total, err := db.GetFilteredEventsFromAddressTotal(addr, filter, options)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, total)
+
+	filter = &types.EventFilter{Args: []*types.ArgFilter{{Name: "value", Op: "gte", Value: "1000"}}}
+	assert.Nil(t, filter.Validate())
+	events, err = db.GetFilteredEventsFromAddress(addr, filter, options)
+	assert.Nil(t, err)
+	assert.Equal(t, []*types.Event{txs[1].Events[0], txs[0].Events[1]}, events)
+
+	options = &types.QueryOptions{EndBlockNumber: big.NewInt(1)}
+	options.SetDefaults()
+	total, err = db.GetFilteredEventsFromAddressTotal(addr, filter, options)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, total)
+
+	_, err = db.GetFilteredEventsFromAddress(types.NewAddress("0x02"), filter, options)
+	assert.EqualError(t, err, "address is not registered")
+}
+
+func TestMemoryDB_RedecodeContract(t *testing.T) {
+	db := NewMemoryDB()
+	assert.Nil(t, db.AddAddresses([]types.Address{addr}))
+
+	event := &types.Event{
+		Address:     addr,
+		BlockNumber: 1,
+		Topics: []types.Hash{
+			types.NewHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"),
+			types.NewHash("0x01"),
+			types.NewHash("0x02"),
+		},
+		Data: types.HexData(fmt.Sprintf("%064s", "0a")),
+	}
+	tx := &types.Transaction{Hash: types.NewHash("0x01"), BlockNumber: 1, Events: []*types.Event{event}}
+	assert.Nil(t, db.WriteTransactions([]*types.Transaction{tx}))
+	assert.Nil(t, db.IndexBlocks([]types.Address{addr}, []*types.Block{{Number: 1, Transactions: []types.Hash{tx.Hash}}}))
+
+	options := &types.QueryOptions{}
+	options.SetDefaults()
+	filter := &types.EventFilter{Event: "Transfer"}
+	assert.Nil(t, filter.Validate())
+
+	// the event was indexed before the contract had a template, so it is only matched once decoded again
+	assert.Nil(t, db.AddTemplate(&types.Template{
+		TemplateName: "erc20",
+		ABI:          `[{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256","indexed":false}]}]`,
+	}))
+	assert.Nil(t, db.AssignTemplate(addr, "erc20"))
+	total, err := db.GetFilteredEventsFromAddressTotal(addr, filter, options)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, total)
+
+	assert.Nil(t, db.RedecodeContract(addr))
+	events, err := db.GetFilteredEventsFromAddress(addr, filter, options)
+	assert.Nil(t, err)
+	assert.Equal(t, []*types.Event{event}, events)
+
+	err = db.RedecodeContract(types.NewAddress("0x0000000000000000000000000000000000000099"))
+	assert.EqualError(t, err, "address is not registered")
+}
@@ -0,0 +1,150 @@
+package database
+
+import (
+	"quorumengineering/quorum-report/log"
+	"quorumengineering/quorum-report/types"
+)
+
+type templateVersionKey struct {
+	name    string
+	version uint64
+}
+
+// TemplateDecoder decodes events and function calls as they are indexed, with the template assigned
+// to the contract at the block of each one. For proxies, the template of the implementation that was
+// live at the block is used, if it has one. Assignments and parsed ABIs are cached, as events are
+// decoded a batch of blocks at a time.
+type TemplateDecoder struct {
+	db interface {
+		TemplateDB
+		ProxyDB
+	}
+	assignments     map[types.Address][]*types.TemplateAssignment
+	implementations map[types.Address][]*types.ProxyImplementation
+	abis            map[templateVersionKey]*types.ContractABI
+}
+
+func NewTemplateDecoder(db interface {
+	TemplateDB
+	ProxyDB
+}) *TemplateDecoder {
+	return &TemplateDecoder{
+		db:              db,
+		assignments:     make(map[types.Address][]*types.TemplateAssignment),
+		implementations: make(map[types.Address][]*types.ProxyImplementation),
+		abis:            make(map[templateVersionKey]*types.ContractABI),
+	}
+}
+
+// DecodeEvent decodes the event, returning nil if the contract has no ABI for it at the block.
+// Events that fail to decode are logged and left undecoded, so they do not stop the contract
+// from being indexed.
+func (decoder *TemplateDecoder) DecodeEvent(event *types.Event) (*types.DecodedEvent, error) {
+	contractABI, err := decoder.abiAt(event.Address, event.BlockNumber)
+	if err != nil || contractABI == nil {
+		return nil, err
+	}
+	decoded, err := types.DecodeEvent(event, contractABI)
+	if err != nil {
+		log.Warn("Could not decode event", "address", event.Address.Hex(), "tx", event.TransactionHash.Hex(), "err", err)
+		return nil, nil
+	}
+	return decoded, nil
+}
+
+// DecodeCall decodes the function called by a transaction to a contract. Only the selector is kept if
+// the contract has no ABI for it at the block, or if the call fails to decode, which is logged.
+func (decoder *TemplateDecoder) DecodeCall(tx *types.Transaction) (*types.DecodedCall, error) {
+	if tx.To.IsEmpty() {
+		return nil, nil
+	}
+	contractABI, err := decoder.abiAt(tx.To, tx.BlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := types.DecodeCall(tx, contractABI)
+	if err != nil {
+		log.Warn("Could not decode transaction", "address", tx.To.Hex(), "tx", tx.Hash.Hex(), "err", err)
+		return types.DecodeCall(tx, nil)
+	}
+	return decoded, nil
+}
+
+func (decoder *TemplateDecoder) abiAt(address types.Address, blockNumber uint64) (*types.ContractABI, error) {
+	implementations, ok := decoder.implementations[address]
+	if !ok {
+		var err error
+		implementations, err = decoder.db.GetImplementationHistory(address)
+		if err != nil && err != ErrNotFound {
+			return nil, err
+		}
+		decoder.implementations[address] = implementations
+	}
+
+	var (
+		assignment *types.TemplateAssignment
+		err        error
+	)
+	if implementation := implementationAt(implementations, blockNumber); implementation != nil {
+		if assignment, err = decoder.assignmentAt(implementation.Implementation, blockNumber); err != nil {
+			return nil, err
+		}
+	}
+	if assignment == nil {
+		if assignment, err = decoder.assignmentAt(address, blockNumber); err != nil || assignment == nil {
+			return nil, err
+		}
+	}
+
+	key := templateVersionKey{assignment.TemplateName, assignment.Version}
+	if contractABI, ok := decoder.abis[key]; ok {
+		return contractABI, nil
+	}
+	template, err := decoder.db.GetTemplateVersion(assignment.TemplateName, assignment.Version)
+	if err != nil && err != ErrNotFound {
+		return nil, err
+	}
+	var contractABI *types.ContractABI
+	if template != nil && template.ABI != "" {
+		structure, err := types.NewABIStructureFromJSON(template.ABI)
+		if err != nil {
+			return nil, err
+		}
+		contractABI = structure.ToInternalABI()
+	}
+	decoder.abis[key] = contractABI
+	return contractABI, nil
+}
+
+// assignmentAt returns the template assignment of a contract in use at the given block, or nil if there is none
+func (decoder *TemplateDecoder) assignmentAt(address types.Address, blockNumber uint64) (*types.TemplateAssignment, error) {
+	assignments, ok := decoder.assignments[address]
+	if !ok {
+		var err error
+		assignments, err = decoder.db.GetTemplateAssignments(address)
+		if err != nil && err != ErrNotFound {
+			return nil, err
+		}
+		decoder.assignments[address] = assignments
+	}
+	var assignment *types.TemplateAssignment
+	for _, candidate := range assignments {
+		if candidate.FromBlock > blockNumber {
+			break
+		}
+		assignment = candidate
+	}
+	return assignment, nil
+}
+
+// implementationAt returns the implementation of a proxy live at the given block, or nil if there is none
+func implementationAt(implementations []*types.ProxyImplementation, blockNumber uint64) *types.ProxyImplementation {
+	var implementation *types.ProxyImplementation
+	for _, candidate := range implementations {
+		if candidate.FromBlock > blockNumber {
+			break
+		}
+		implementation = candidate
+	}
+	return implementation
+}
@@ -1,8 +1,67 @@
 package database
 
-import "errors"
+import (
+	"errors"
+	"sort"
+
+	"quorumengineering/quorum-report/types"
+)
 
 var (
 	ErrNotFound       = errors.New("not found")
 	ErrNotImplemented = errors.New("not implemented")
 )
+
+// InsertTemplateAssignment adds an assignment to a list of assignments ordered by block,
+// replacing any existing assignment from the same block
+func InsertTemplateAssignment(assignments []*types.TemplateAssignment, assignment *types.TemplateAssignment) []*types.TemplateAssignment {
+	updated := []*types.TemplateAssignment{assignment}
+	for _, existing := range assignments {
+		if existing.FromBlock != assignment.FromBlock {
+			updated = append(updated, existing)
+		}
+	}
+	sort.Slice(updated, func(i, j int) bool {
+		return updated[i].FromBlock < updated[j].FromBlock
+	})
+	return updated
+}
+
+// InsertProxyImplementation adds an implementation to a list of implementations ordered by block,
+// replacing any existing implementation from the same block
+func InsertProxyImplementation(implementations []*types.ProxyImplementation, implementation *types.ProxyImplementation) []*types.ProxyImplementation {
+	updated := []*types.ProxyImplementation{implementation}
+	for _, existing := range implementations {
+		if existing.FromBlock != implementation.FromBlock {
+			updated = append(updated, existing)
+		}
+	}
+	sort.Slice(updated, func(i, j int) bool {
+		return updated[i].FromBlock < updated[j].FromBlock
+	})
+	return updated
+}
+
+// InsertSampler adds a sampler to a list of samplers, replacing any existing sampler with the same name
+func InsertSampler(samplers []*types.FunctionSampler, sampler *types.FunctionSampler) []*types.FunctionSampler {
+	for i, existing := range samplers {
+		if existing.Name == sampler.Name {
+			updated := append([]*types.FunctionSampler{}, samplers...)
+			updated[i] = sampler
+			return updated
+		}
+	}
+	return append(samplers, sampler)
+}
+
+// RemoveSampler removes the sampler with the given name from a list of samplers,
+// returning ErrNotFound if there is no sampler with that name
+func RemoveSampler(samplers []*types.FunctionSampler, name string) ([]*types.FunctionSampler, error) {
+	for i, existing := range samplers {
+		if existing.Name == name {
+			updated := append([]*types.FunctionSampler{}, samplers[:i]...)
+			return append(updated, samplers[i+1:]...), nil
+		}
+	}
+	return nil, ErrNotFound
+}
@@ -2,6 +2,7 @@ package types
 
 import (
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"strings"
 )
@@ -10,6 +11,38 @@ type ContractABI struct {
 	Constructor ContractABIFunction
 	Functions   []ContractABIFunction
 	Events      []ContractABIEvent
+	Errors      []ContractABIFunction
+}
+
+// FindFunction finds the function with the given name, or signature if the name is overloaded,
+// preferring the overload that takes the given number of arguments
+func (contractABI *ContractABI) FindFunction(function string, numArgs int) (*ContractABIFunction, error) {
+	var candidates []ContractABIFunction
+	for _, candidate := range contractABI.Functions {
+		if candidate.StringNoName() == function {
+			return &candidate, nil
+		}
+		if candidate.Name == function {
+			candidates = append(candidates, candidate)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, errors.New("function not found: " + function)
+	}
+	if len(candidates) == 1 {
+		return &candidates[0], nil
+	}
+
+	var matching []ContractABIFunction
+	for _, candidate := range candidates {
+		if len(candidate.Inputs) == numArgs {
+			matching = append(matching, candidate)
+		}
+	}
+	if len(matching) != 1 {
+		return nil, errors.New("function is overloaded, give its signature instead: " + function)
+	}
+	return &matching[0], nil
 }
 
 type ContractABIFunction struct {
@@ -43,6 +76,19 @@ func (function ContractABIFunction) Parse(data []byte) (map[string]interface{},
 	return ParseAllData(function.Inputs, data)
 }
 
+// ParseOutput decodes the data returned by the function. Return values are often
+// unnamed, so these are named by their position, e.g. "output0".
+func (function ContractABIFunction) ParseOutput(data []byte) (map[string]interface{}, error) {
+	outputs := make([]ContractABIArgument, len(function.Outputs))
+	for i, output := range function.Outputs {
+		if output.Name == "" {
+			output.Name = fmt.Sprintf("output%d", i)
+		}
+		outputs[i] = output
+	}
+	return ParseAllData(outputs, data)
+}
+
 type ContractABIArgument struct {
 	Name       string
 	Type       string
@@ -0,0 +1,268 @@
+package types
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+/*
+Rules for encoding can be found at: https://solidity.readthedocs.io/en/develop/abi-spec.html#argument-encoding
+
+EncodeAllData is the reverse of ParseAllData: static elements are written in place in the "head", and dynamic
+elements are written in the "tail", with their head being the offset of the tail from the start of the encoding.
+
+Values are given in the form that they would be decoded from JSON:
+- integers as numbers, or as decimal or 0x-prefixed hex strings for values too large for a JSON number
+- addresses, bytes and bytes<x> as 0x-prefixed hex strings
+- bools as booleans, strings as strings
+- arrays as lists, and tuples as either lists in component order or objects by component name
+*/
+func EncodeAllData(args []ContractABIArgument, values []interface{}) ([]byte, error) {
+	if len(args) != len(values) {
+		return nil, fmt.Errorf("expected %d values, got %d", len(args), len(values))
+	}
+
+	headSize := 0
+	for _, arg := range args {
+		if arg.IsDynamic() {
+			headSize += 32
+		} else {
+			headSize += staticSize(arg)
+		}
+	}
+
+	var head, tail []byte
+	for i, arg := range args {
+		encoded, err := encodeElement(arg, values[i])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", arg.Name, err)
+		}
+		if !arg.IsDynamic() {
+			head = append(head, encoded...)
+			continue
+		}
+		head = append(head, encodeUint(big.NewInt(int64(headSize+len(tail))))...)
+		tail = append(tail, encoded...)
+	}
+	return append(head, tail...), nil
+}
+
+// EncodeCall encodes a call to the function, with the arguments given in the order of the function inputs
+func (function ContractABIFunction) EncodeCall(values []interface{}) ([]byte, error) {
+	selector, _ := hex.DecodeString(function.Signature())
+	encoded, err := EncodeAllData(function.Inputs, values)
+	if err != nil {
+		return nil, err
+	}
+	return append(selector, encoded...), nil
+}
+
+// staticSize is the number of bytes a static element takes up in the head
+func staticSize(arg ContractABIArgument) int {
+	if strings.HasSuffix(arg.Type, "]") {
+		start := strings.LastIndex(arg.Type, "[")
+		length, _ := strconv.Atoi(arg.Type[start+1 : len(arg.Type)-1])
+		return length * staticSize(ContractABIArgument{Type: arg.Type[:start], Components: arg.Components})
+	}
+	if arg.Type == "tuple" {
+		size := 0
+		for _, component := range arg.Components {
+			size += staticSize(component)
+		}
+		return size
+	}
+	return 32
+}
+
+// encodeElement encodes a single element, giving the in place encoding of static elements,
+// or the tail of dynamic elements
+func encodeElement(arg ContractABIArgument, value interface{}) ([]byte, error) {
+	// arrays are encoded as a tuple of their elements, with dynamic arrays prefixed with their length
+	if strings.HasSuffix(arg.Type, "]") {
+		list, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected a list for %s", arg.Type)
+		}
+		start := strings.LastIndex(arg.Type, "[")
+		elements := make([]ContractABIArgument, len(list))
+		for i := range list {
+			elements[i] = ContractABIArgument{Name: strconv.Itoa(i), Type: arg.Type[:start], Components: arg.Components}
+		}
+		if strings.HasSuffix(arg.Type, "[]") {
+			encoded, err := EncodeAllData(elements, list)
+			if err != nil {
+				return nil, err
+			}
+			return append(encodeUint(big.NewInt(int64(len(list)))), encoded...), nil
+		}
+		if length := arg.Type[start+1 : len(arg.Type)-1]; length != strconv.Itoa(len(list)) {
+			return nil, fmt.Errorf("expected %s elements for %s, got %d", length, arg.Type, len(list))
+		}
+		return EncodeAllData(elements, list)
+	}
+
+	switch {
+	case arg.Type == "tuple":
+		return encodeTuple(arg, value)
+	case arg.Type == "string":
+		str, ok := value.(string)
+		if !ok {
+			return nil, errors.New("expected a string")
+		}
+		return encodeBytes([]byte(str)), nil
+	case arg.Type == "bytes":
+		b, err := toBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		return encodeBytes(b), nil
+	case strings.HasPrefix(arg.Type, "bytes"):
+		b, err := toBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		if size, _ := strconv.Atoi(arg.Type[5:]); len(b) > size {
+			return nil, fmt.Errorf("value too long for %s", arg.Type)
+		}
+		padded := make([]byte, 32)
+		copy(padded, b)
+		return padded, nil
+	case arg.Type == "bool":
+		b, ok := value.(bool)
+		if !ok {
+			return nil, errors.New("expected a bool")
+		}
+		if b {
+			return encodeUint(big.NewInt(1)), nil
+		}
+		return encodeUint(big.NewInt(0)), nil
+	case arg.Type == "address":
+		str, ok := value.(string)
+		if !ok {
+			return nil, errors.New("expected a hex address")
+		}
+		b, err := hex.DecodeString(strings.TrimPrefix(str, "0x"))
+		if err != nil || len(b) != 20 {
+			return nil, fmt.Errorf("invalid address %s", str)
+		}
+		return leftPad(b), nil
+	case strings.HasPrefix(arg.Type, "uint"), strings.HasPrefix(arg.Type, "int"):
+		return encodeInteger(arg.Type, value)
+	}
+	return nil, errors.New("unknown type: " + arg.Type)
+}
+
+func encodeTuple(arg ContractABIArgument, value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case []interface{}:
+		return EncodeAllData(arg.Components, v)
+	case map[string]interface{}:
+		values := make([]interface{}, len(arg.Components))
+		for i, component := range arg.Components {
+			componentValue, ok := v[component.Name]
+			if !ok {
+				return nil, fmt.Errorf("missing tuple component %s", component.Name)
+			}
+			values[i] = componentValue
+		}
+		return EncodeAllData(arg.Components, values)
+	}
+	return nil, errors.New("expected a list or object for tuple")
+}
+
+// encodeInteger encodes a uint<x> or int<x>, checking that the value fits in the type.
+// Negative values are encoded in two's complement.
+func encodeInteger(typeName string, value interface{}) ([]byte, error) {
+	n, err := toBigInt(value)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := strings.HasPrefix(typeName, "int")
+	bits := 256
+	if size := strings.TrimPrefix(strings.TrimPrefix(typeName, "u"), "int"); size != "" {
+		if bits, err = strconv.Atoi(size); err != nil {
+			return nil, errors.New("unknown type: " + typeName)
+		}
+	}
+
+	min, max := big.NewInt(0), new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	if signed {
+		max.Rsh(max, 1)
+		min.Neg(max)
+	}
+	if n.Cmp(min) < 0 || n.Cmp(max) >= 0 {
+		return nil, fmt.Errorf("value %s out of range for %s", n.String(), typeName)
+	}
+	if n.Sign() < 0 {
+		n = new(big.Int).Add(n, new(big.Int).Lsh(big.NewInt(1), 256))
+	}
+	return encodeUint(n), nil
+}
+
+func toBigInt(value interface{}) (*big.Int, error) {
+	switch v := value.(type) {
+	case *big.Int:
+		return v, nil
+	case int:
+		return big.NewInt(int64(v)), nil
+	case int64:
+		return big.NewInt(v), nil
+	case uint64:
+		return new(big.Int).SetUint64(v), nil
+	case float64:
+		// JSON numbers are only exact up to 2^53
+		if v != math.Trunc(v) || math.Abs(v) > 1<<53 {
+			return nil, fmt.Errorf("number %v is not an exact integer, give it as a string instead", v)
+		}
+		return big.NewInt(int64(v)), nil
+	case json.Number:
+		return toBigInt(string(v))
+	case string:
+		n, ok := new(big.Int).SetString(v, 0)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer %s", v)
+		}
+		return n, nil
+	}
+	return nil, errors.New("expected an integer")
+}
+
+func toBytes(value interface{}) ([]byte, error) {
+	str, ok := value.(string)
+	if !ok {
+		return nil, errors.New("expected a hex string")
+	}
+	b, err := hex.DecodeString(strings.TrimPrefix(str, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex %s", str)
+	}
+	return b, nil
+}
+
+// encodeBytes encodes the length of the bytes followed by the bytes, right-padded to a multiple of 32
+func encodeBytes(b []byte) []byte {
+	return append(encodeUint(big.NewInt(int64(len(b)))), rightPad(b)...)
+}
+
+func encodeUint(n *big.Int) []byte {
+	return leftPad(n.Bytes())
+}
+
+func leftPad(b []byte) []byte {
+	padded := make([]byte, 32)
+	copy(padded[32-len(b):], b)
+	return padded
+}
+
+func rightPad(b []byte) []byte {
+	padded := make([]byte, (len(b)+31)/32*32)
+	copy(padded, b)
+	return padded
+}
@@ -0,0 +1,111 @@
+package types
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// examples from https://solidity.readthedocs.io/en/develop/abi-spec.html#examples
+
+func TestContractABIFunction_EncodeCall_Static(t *testing.T) {
+	function := ContractABIFunction{
+		Type:   "function",
+		Name:   "baz",
+		Inputs: []ContractABIArgument{{Name: "x", Type: "uint32"}, {Name: "y", Type: "bool"}},
+	}
+
+	encoded, err := function.EncodeCall([]interface{}{float64(69), true})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "cdcd77c0"+
+		"0000000000000000000000000000000000000000000000000000000000000045"+
+		"0000000000000000000000000000000000000000000000000000000000000001", hex.EncodeToString(encoded))
+}
+
+func TestContractABIFunction_EncodeCall_Dynamic(t *testing.T) {
+	function := ContractABIFunction{
+		Type: "function",
+		Name: "f",
+		Inputs: []ContractABIArgument{
+			{Name: "a", Type: "uint256"},
+			{Name: "b", Type: "uint32[]"},
+			{Name: "c", Type: "bytes10"},
+			{Name: "d", Type: "bytes"},
+		},
+	}
+
+	encoded, err := function.EncodeCall([]interface{}{
+		"0x123",
+		[]interface{}{"0x456", "0x789"},
+		"0x31323334353637383930",
+		"0x48656c6c6f2c20776f726c6421",
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "8be65246"+
+		"0000000000000000000000000000000000000000000000000000000000000123"+
+		"0000000000000000000000000000000000000000000000000000000000000080"+
+		"3132333435363738393000000000000000000000000000000000000000000000"+
+		"00000000000000000000000000000000000000000000000000000000000000e0"+
+		"0000000000000000000000000000000000000000000000000000000000000002"+
+		"0000000000000000000000000000000000000000000000000000000000000456"+
+		"0000000000000000000000000000000000000000000000000000000000000789"+
+		"000000000000000000000000000000000000000000000000000000000000000d"+
+		"48656c6c6f2c20776f726c642100000000000000000000000000000000000000", hex.EncodeToString(encoded))
+}
+
+func TestEncodeAllData_RoundTrip(t *testing.T) {
+	args := []ContractABIArgument{
+		{Name: "owner", Type: "address"},
+		{Name: "delta", Type: "int16"},
+		{Name: "name", Type: "string"},
+		{Name: "pair", Type: "tuple", Components: []ContractABIArgument{{Name: "id", Type: "uint64"}, {Name: "label", Type: "string"}}},
+		{Name: "fixed", Type: "uint8[2]"},
+	}
+	values := []interface{}{
+		"0x1932c48b2bf8102ba33b4a6b545c32236e342f34",
+		float64(-5),
+		"some really large string that will go over the thirty-two byte limit",
+		map[string]interface{}{"id": "12345678901234567890", "label": "first"},
+		[]interface{}{float64(1), float64(2)},
+	}
+
+	encoded, err := EncodeAllData(args, values)
+	assert.Nil(t, err)
+
+	parsed, err := ParseAllData(args, encoded)
+	assert.Nil(t, err)
+	assert.Equal(t, "0x1932c48b2bf8102ba33b4a6b545c32236e342f34", parsed["owner"])
+	assert.Equal(t, big.NewInt(-5), parsed["delta"])
+	assert.Equal(t, values[2], parsed["name"])
+	id, _ := new(big.Int).SetString("12345678901234567890", 10)
+	assert.Equal(t, map[string]interface{}{"id": id, "label": "first"}, parsed["pair"])
+	assert.Equal(t, []interface{}{big.NewInt(1), big.NewInt(2)}, parsed["fixed"])
+}
+
+func TestEncodeAllData_Invalid(t *testing.T) {
+	tests := []struct {
+		arg   ContractABIArgument
+		value interface{}
+		err   string
+	}{
+		{ContractABIArgument{Name: "x", Type: "uint8"}, float64(256), "x: value 256 out of range for uint8"},
+		{ContractABIArgument{Name: "x", Type: "uint256"}, float64(-1), "x: value -1 out of range for uint256"},
+		{ContractABIArgument{Name: "x", Type: "int8"}, float64(-129), "x: value -129 out of range for int8"},
+		{ContractABIArgument{Name: "x", Type: "uint256"}, 1.5, "x: number 1.5 is not an exact integer, give it as a string instead"},
+		{ContractABIArgument{Name: "x", Type: "address"}, "0x1234", "x: invalid address 0x1234"},
+		{ContractABIArgument{Name: "x", Type: "bytes2"}, "0x123456", "x: value too long for bytes2"},
+		{ContractABIArgument{Name: "x", Type: "uint8[2]"}, []interface{}{float64(1)}, "x: expected 2 elements for uint8[2], got 1"},
+		{ContractABIArgument{Name: "x", Type: "bool"}, "true", "x: expected a bool"},
+	}
+	for _, test := range tests {
+		_, err := EncodeAllData([]ContractABIArgument{test.arg}, []interface{}{test.value})
+		assert.EqualError(t, err, test.err)
+	}
+
+	_, err := EncodeAllData([]ContractABIArgument{{Name: "x", Type: "bool"}}, nil)
+	assert.EqualError(t, err, "expected 1 values, got 0")
+}
@@ -2,6 +2,7 @@ package types
 
 import (
 	"encoding/json"
+	"math/big"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -69,3 +70,41 @@ func TestContractABIArgument_IsDynamic(t *testing.T) {
 		assert.EqualValues(t, test.expectedDynamic, isDynamic, "Test index %d failed", idx)
 	}
 }
+
+func TestContractABIFunction_ParseOutput(t *testing.T) {
+	function := ContractABIFunction{
+		Type:    "function",
+		Name:    "getPair",
+		Outputs: []ContractABIArgument{{Name: "", Type: "uint256"}, {Name: "flag", Type: "bool"}},
+	}
+	data := NewHexData("0x00000000000000000000000000000000000000000000000000000000000003e80000000000000000000000000000000000000000000000000000000000000001")
+
+	result, err := function.ParseOutput(data.AsBytes())
+
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(1000), result["output0"])
+	assert.Equal(t, true, result["flag"])
+}
+
+func TestContractABI_FindFunction(t *testing.T) {
+	overloadedABI := `[
+		{"type":"function","name":"safeTransferFrom","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"tokenId","type":"uint256"}],"outputs":[]},
+		{"type":"function","name":"safeTransferFrom","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"tokenId","type":"uint256"},{"name":"data","type":"bytes"}],"outputs":[]},
+		{"type":"function","name":"pick","inputs":[{"name":"a","type":"uint256"}],"outputs":[]},
+		{"type":"function","name":"pick","inputs":[{"name":"a","type":"address"}],"outputs":[]}
+	]`
+	structure, err := NewABIStructureFromJSON(overloadedABI)
+	assert.Nil(t, err)
+	contractABI := structure.ToInternalABI()
+
+	function, err := contractABI.FindFunction("safeTransferFrom", 4)
+	assert.Nil(t, err)
+	assert.Len(t, function.Inputs, 4)
+
+	function, err = contractABI.FindFunction("pick(address)", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "address", function.Inputs[0].Type)
+
+	_, err = contractABI.FindFunction("pick", 1)
+	assert.EqualError(t, err, "function is overloaded, give its signature instead: pick")
+}
@@ -0,0 +1,150 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// numberOffset shifts all 256 bit integers, signed or unsigned, to be positive, so that
+// they can be compared as zero-padded strings
+var numberOffset = new(big.Int).Lsh(big.NewInt(1), 256)
+
+// numberWidth is the number of digits of the largest shifted number, 2^257
+const numberWidth = 78
+
+// DecodedArg is a decoded argument of an event or function call. Values are kept as strings so that arguments
+// of all types can be indexed together, with numbers also kept in a sortable form to be compared by range.
+type DecodedArg struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Number string `json:"number,omitempty"`
+}
+
+// newDecodedArg converts a decoded value to an indexable argument, or nil if it is not a scalar value
+func newDecodedArg(name string, value interface{}) *DecodedArg {
+	switch v := value.(type) {
+	case *big.Int:
+		return &DecodedArg{Name: name, Value: v.String(), Number: SortableNumber(v)}
+	case string:
+		return &DecodedArg{Name: name, Value: normalizeValue(v)}
+	case bool:
+		return &DecodedArg{Name: name, Value: fmt.Sprint(v)}
+	}
+	return nil
+}
+
+// ArgFilter compares a decoded argument to a value. Numbers are given in decimal,
+// either as a string or, if small enough to be exact, as a number.
+type ArgFilter struct {
+	Name string `json:"name"`
+	// Op is one of eq, gt, gte, lt or lte, and is eq if not given
+	Op    string      `json:"op"`
+	Value interface{} `json:"value"`
+
+	operand string
+}
+
+// resolve checks the filter is well-formed, and resolves the value the argument is compared to
+func (argFilter *ArgFilter) resolve() error {
+	if argFilter.Name == "" {
+		return errors.New("no argument name given")
+	}
+	if argFilter.Op == "" {
+		argFilter.Op = "eq"
+	}
+	value, err := filterValueString(argFilter.Value)
+	if err != nil {
+		return fmt.Errorf("invalid value for argument %s: %s", argFilter.Name, err.Error())
+	}
+
+	switch argFilter.Op {
+	case "eq":
+		argFilter.operand = normalizeValue(value)
+	case "gt", "gte", "lt", "lte":
+		number, ok := new(big.Int).SetString(value, 10)
+		if !ok {
+			return fmt.Errorf("value for argument %s must be a number to use operator %s", argFilter.Name, argFilter.Op)
+		}
+		argFilter.operand = SortableNumber(number)
+	default:
+		return fmt.Errorf("unknown operator %s for argument %s", argFilter.Op, argFilter.Name)
+	}
+	return nil
+}
+
+// Operand returns the value the argument is compared to, which is the sortable
+// form of the number for range operators. The filter must have been validated.
+func (argFilter *ArgFilter) Operand() string {
+	return argFilter.operand
+}
+
+func (argFilter *ArgFilter) matches(arg *DecodedArg) bool {
+	if argFilter.Op == "eq" {
+		return arg.Value == argFilter.operand
+	}
+	if arg.Number == "" {
+		return false
+	}
+	switch argFilter.Op {
+	case "gt":
+		return arg.Number > argFilter.operand
+	case "gte":
+		return arg.Number >= argFilter.operand
+	case "lt":
+		return arg.Number < argFilter.operand
+	case "lte":
+		return arg.Number <= argFilter.operand
+	}
+	return false
+}
+
+// matchArgs reports whether each of the filters matches one of the decoded arguments
+func matchArgs(argFilters []*ArgFilter, args []*DecodedArg) bool {
+	for _, argFilter := range argFilters {
+		matched := false
+		for _, arg := range args {
+			if arg.Name == argFilter.Name && argFilter.matches(arg) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// filterValueString converts a value given in JSON to a string
+func filterValueString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return fmt.Sprint(v), nil
+	case float64:
+		if v != math.Trunc(v) {
+			return "", errors.New("numbers must be integers")
+		}
+		number, _ := big.NewFloat(v).Int(nil)
+		return number.String(), nil
+	}
+	return "", errors.New("value must be a string, number or boolean")
+}
+
+// normalizeValue lowercases hex values, such as addresses, so they are compared regardless of case
+func normalizeValue(value string) string {
+	if strings.HasPrefix(value, "0x") || strings.HasPrefix(value, "0X") {
+		return strings.ToLower(value)
+	}
+	return value
+}
+
+// SortableNumber formats a number so that numbers compare the same way as strings,
+// by offsetting it to be positive and padding it to a fixed width
+func SortableNumber(number *big.Int) string {
+	return fmt.Sprintf("%0*s", numberWidth, new(big.Int).Add(number, numberOffset).String())
+}
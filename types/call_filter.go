@@ -0,0 +1,130 @@
+package types
+
+import (
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+const (
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+)
+
+var selectorPattern = regexp.MustCompile("^0x[0-9a-fA-F]{8}$")
+
+// DecodedCall holds the function called by a transaction, decoded with the ABI of the contract
+// it was sent to, in a form that can be indexed so that transactions can be filtered by it.
+// The selector is kept even if the function could not be decoded.
+type DecodedCall struct {
+	Name     string        `json:"name,omitempty"`
+	Sig      string        `json:"sig,omitempty"`
+	Selector string        `json:"selector"`
+	Inputs   []*DecodedArg `json:"inputs,omitempty"`
+}
+
+// DecodeCall decodes the function call of a transaction to a contract, with the given ABI if there is one.
+// Array and tuple inputs are left out as they cannot be compared. nil is returned for contract deployments
+// and transactions without call data.
+func DecodeCall(tx *Transaction, contractABI *ContractABI) (*DecodedCall, error) {
+	data := tx.Data.AsBytes()
+	if len(tx.PrivateData) > 0 {
+		data = tx.PrivateData.AsBytes()
+	}
+	if tx.To.IsEmpty() || len(data) < 4 {
+		return nil, nil
+	}
+
+	decoded := &DecodedCall{Selector: "0x" + hex.EncodeToString(data[:4])}
+	if contractABI == nil {
+		return decoded, nil
+	}
+	for _, function := range contractABI.Functions {
+		if "0x"+function.Signature() != decoded.Selector {
+			continue
+		}
+		// the call may not match the ABI if the template is wrong, and the parser does not check bounds
+		parsed, err := parseCandidate(function.Parse, data[4:])
+		if err != nil {
+			return nil, err
+		}
+		decoded.Name = function.Name
+		decoded.Sig = function.StringNoName()
+		for _, input := range function.Inputs {
+			if arg := newDecodedArg(input.Name, parsed[input.Name]); arg != nil {
+				decoded.Inputs = append(decoded.Inputs, arg)
+			}
+		}
+		break
+	}
+	return decoded, nil
+}
+
+// HasCallFilter reports whether transactions are filtered by the call made
+func (opts *QueryOptions) HasCallFilter() bool {
+	return opts.Function != "" || opts.Status != "" || len(opts.Inputs) > 0
+}
+
+// ValidateCallFilter checks the call filter is well-formed, and resolves the values inputs are compared to.
+// The function is given by its name, its signature such as transfer(address,uint256), or its 0x-prefixed
+// selector, and the status is either "success" or "failure".
+func (opts *QueryOptions) ValidateCallFilter() error {
+	if opts.Status != "" && opts.Status != StatusSuccess && opts.Status != StatusFailure {
+		return errors.New("status must be success or failure")
+	}
+	for _, argFilter := range opts.Inputs {
+		if err := argFilter.resolve(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsFunctionSelector reports whether the function is given by its selector
+func (opts *QueryOptions) IsFunctionSelector() bool {
+	return selectorPattern.MatchString(opts.Function)
+}
+
+// IsFunctionSignature reports whether the function is given by its signature
+func (opts *QueryOptions) IsFunctionSignature() bool {
+	return strings.Contains(opts.Function, "(")
+}
+
+// FunctionValue returns the function as it is indexed: a lowercase selector, or a signature without spaces
+func (opts *QueryOptions) FunctionValue() string {
+	if opts.IsFunctionSelector() {
+		return strings.ToLower(opts.Function)
+	}
+	return strings.Replace(opts.Function, " ", "", -1)
+}
+
+// MatchesCall reports whether a transaction and its decoded call match the call filter.
+// Transactions that could not be decoded only match on their selector and status.
+func (opts *QueryOptions) MatchesCall(tx *Transaction, decoded *DecodedCall) bool {
+	if opts.Status != "" && tx.Status != (opts.Status == StatusSuccess) {
+		return false
+	}
+	if opts.Function == "" && len(opts.Inputs) == 0 {
+		return true
+	}
+	if decoded == nil {
+		return false
+	}
+	switch {
+	case opts.Function == "":
+	case opts.IsFunctionSelector():
+		if opts.FunctionValue() != decoded.Selector {
+			return false
+		}
+	case opts.IsFunctionSignature():
+		if opts.FunctionValue() != decoded.Sig {
+			return false
+		}
+	default:
+		if opts.Function != decoded.Name {
+			return false
+		}
+	}
+	return matchArgs(opts.Inputs, decoded.Inputs)
+}
@@ -0,0 +1,77 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func decodedTransferCall(t *testing.T) *DecodedCall {
+	structure, err := NewABIStructureFromJSON(registryERC20ABI)
+	assert.Nil(t, err)
+	decoded, err := DecodeCall(&Transaction{To: NewAddress("0x01"), Data: transferCallData}, structure.ToInternalABI())
+	assert.Nil(t, err)
+	return decoded
+}
+
+func TestDecodeCall(t *testing.T) {
+	decoded := decodedTransferCall(t)
+
+	assert.Equal(t, "transfer", decoded.Name)
+	assert.Equal(t, "transfer(address,uint256)", decoded.Sig)
+	assert.Equal(t, "0xa9059cbb", decoded.Selector)
+	assert.Len(t, decoded.Inputs, 2)
+	assert.Equal(t, &DecodedArg{Name: "to", Value: "0x0000000000000000000000000000000000000002"}, decoded.Inputs[0])
+	assert.Equal(t, "1000", decoded.Inputs[1].Value)
+}
+
+func TestDecodeCall_WithoutABI(t *testing.T) {
+	decoded, err := DecodeCall(&Transaction{To: NewAddress("0x01"), Data: transferCallData}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, &DecodedCall{Selector: "0xa9059cbb"}, decoded)
+
+	decoded, err = DecodeCall(&Transaction{Data: transferCallData}, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, decoded)
+
+	decoded, err = DecodeCall(&Transaction{To: NewAddress("0x01")}, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, decoded)
+}
+
+func TestQueryOptions_ValidateCallFilter(t *testing.T) {
+	assert.Nil(t, (&QueryOptions{Status: StatusFailure, Function: "transfer"}).ValidateCallFilter())
+	assert.EqualError(t, (&QueryOptions{Status: "reverted"}).ValidateCallFilter(), "status must be success or failure")
+	assert.EqualError(t, (&QueryOptions{Inputs: []*ArgFilter{{Name: "value", Op: "gt", Value: "ten"}}}).ValidateCallFilter(), "value for argument value must be a number to use operator gt")
+}
+
+func TestQueryOptions_MatchesCall(t *testing.T) {
+	decoded := decodedTransferCall(t)
+	tx := &Transaction{Status: true}
+
+	cases := []struct {
+		options *QueryOptions
+		matches bool
+	}{
+		{&QueryOptions{}, true},
+		{&QueryOptions{Status: StatusSuccess}, true},
+		{&QueryOptions{Status: StatusFailure}, false},
+		{&QueryOptions{Function: "transfer"}, true},
+		{&QueryOptions{Function: "approve"}, false},
+		{&QueryOptions{Function: "transfer(address, uint256)"}, true},
+		{&QueryOptions{Function: "0xA9059CBB"}, true},
+		{&QueryOptions{Function: "0x095ea7b3"}, false},
+		{&QueryOptions{Function: "transfer", Inputs: []*ArgFilter{{Name: "value", Op: "gte", Value: float64(1000)}}}, true},
+		{&QueryOptions{Inputs: []*ArgFilter{{Name: "to", Value: "0x0000000000000000000000000000000000000003"}}}, false},
+	}
+	for _, c := range cases {
+		assert.Nil(t, c.options.ValidateCallFilter())
+		assert.Equal(t, c.matches, c.options.MatchesCall(tx, decoded), c.options.Function)
+	}
+
+	selectorOnly := &DecodedCall{Selector: "0xa9059cbb"}
+	assert.True(t, (&QueryOptions{Function: "0xa9059cbb"}).MatchesCall(tx, selectorOnly))
+	assert.False(t, (&QueryOptions{Function: "transfer"}).MatchesCall(tx, selectorOnly))
+	assert.False(t, (&QueryOptions{Function: "transfer"}).MatchesCall(tx, nil))
+	assert.True(t, (&QueryOptions{Status: StatusSuccess}).MatchesCall(tx, nil))
+}
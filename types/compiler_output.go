@@ -0,0 +1,220 @@
+package types
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Compiler output is accepted in two forms:
+// - the solc standard-JSON output, see https://solidity.readthedocs.io/en/develop/using-the-compiler.html#output-description
+// - a metadata bundle, which is the contract metadata (https://solidity.readthedocs.io/en/develop/metadata.html),
+//   either on its own or wrapped alongside the storageLayout and deployedBytecode for the contract
+
+type solcStandardOutput struct {
+	Contracts map[string]map[string]solcContractOutput `json:"contracts"`
+}
+
+type solcContractOutput struct {
+	ABI           json.RawMessage `json:"abi"`
+	StorageLayout json.RawMessage `json:"storageLayout"`
+	Metadata      string          `json:"metadata"`
+	EVM           struct {
+		DeployedBytecode struct {
+			Object string `json:"object"`
+		} `json:"deployedBytecode"`
+	} `json:"evm"`
+}
+
+type solcMetadataBundle struct {
+	Metadata         json.RawMessage `json:"metadata"`
+	StorageLayout    json.RawMessage `json:"storageLayout"`
+	DeployedBytecode json.RawMessage `json:"deployedBytecode"`
+}
+
+type solcMetadata struct {
+	Compiler *struct {
+		Version string `json:"version"`
+	} `json:"compiler"`
+	Output struct {
+		ABI json.RawMessage `json:"abi"`
+	} `json:"output"`
+	Settings struct {
+		CompilationTarget map[string]string `json:"compilationTarget"`
+	} `json:"settings"`
+}
+
+// IsCompilerOutput reports whether the given JSON is solc standard-JSON output
+// or a metadata bundle, rather than a plain ABI or storage layout
+func IsCompilerOutput(data string) bool {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(data), &fields); err != nil {
+		return false
+	}
+	_, hasContracts := fields["contracts"]
+	_, hasMetadata := fields["metadata"]
+	_, hasCompiler := fields["compiler"]
+	return hasContracts || hasMetadata || hasCompiler
+}
+
+// NewTemplateFromCompilerOutput creates a template for a single contract out of
+// the given compiler output. The contract name may be given as either "Name" or
+// "path/to/file.sol:Name", and may be omitted if the output only has one contract.
+func NewTemplateFromCompilerOutput(templateName string, output string, contractName string) (*Template, error) {
+	var standardOutput solcStandardOutput
+	if err := json.Unmarshal([]byte(output), &standardOutput); err != nil {
+		return nil, errors.New("invalid compiler output: " + err.Error())
+	}
+
+	var template *Template
+	var err error
+	if standardOutput.Contracts != nil {
+		template, err = templateFromStandardOutput(standardOutput, contractName)
+	} else {
+		template, err = templateFromMetadataBundle(output, contractName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	template.TemplateName = templateName
+
+	if template.ABI == "" {
+		return nil, fmt.Errorf("compiler output has no ABI for contract %s", template.ContractName)
+	}
+	if _, err := NewABIStructureFromJSON(template.ABI); err != nil {
+		return nil, fmt.Errorf("invalid ABI for contract %s: %s", template.ContractName, err.Error())
+	}
+	if template.StorageLayout != "" {
+		var layout SolidityStorageDocument
+		if err := json.Unmarshal([]byte(template.StorageLayout), &layout); err != nil {
+			return nil, fmt.Errorf("invalid storage layout for contract %s: %s", template.ContractName, err.Error())
+		}
+	}
+	return template, nil
+}
+
+func templateFromStandardOutput(output solcStandardOutput, contractName string) (*Template, error) {
+	var available []string
+	var matches []string
+	for file, contracts := range output.Contracts {
+		for name := range contracts {
+			fullName := file + ":" + name
+			available = append(available, fullName)
+			if contractName == "" || contractName == name || contractName == fullName {
+				matches = append(matches, fullName)
+			}
+		}
+	}
+	sort.Strings(available)
+	sort.Strings(matches)
+
+	if len(available) == 0 {
+		return nil, errors.New("compiler output contains no contracts")
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("contract %s not found in compiler output", contractName)
+	}
+	if len(matches) > 1 {
+		if contractName == "" {
+			return nil, fmt.Errorf("compiler output contains multiple contracts, a contract name is required: %s", strings.Join(matches, ", "))
+		}
+		return nil, fmt.Errorf("contract name %s is ambiguous, use one of: %s", contractName, strings.Join(matches, ", "))
+	}
+
+	separator := strings.LastIndex(matches[0], ":")
+	file, name := matches[0][:separator], matches[0][separator+1:]
+	contract := output.Contracts[file][name]
+
+	template := &Template{
+		ContractName:        name,
+		ABI:                 rawJSONString(contract.ABI),
+		StorageLayout:       rawJSONString(contract.StorageLayout),
+		RuntimeBytecodeHash: bytecodeHash(contract.EVM.DeployedBytecode.Object),
+	}
+	if contract.Metadata != "" {
+		var metadata solcMetadata
+		if err := json.Unmarshal([]byte(contract.Metadata), &metadata); err != nil {
+			return nil, fmt.Errorf("invalid metadata for contract %s: %s", name, err.Error())
+		}
+		if metadata.Compiler != nil {
+			template.CompilerVersion = metadata.Compiler.Version
+		}
+	}
+	return template, nil
+}
+
+func templateFromMetadataBundle(output string, contractName string) (*Template, error) {
+	var bundle solcMetadataBundle
+	if err := json.Unmarshal([]byte(output), &bundle); err != nil {
+		return nil, errors.New("invalid metadata bundle: " + err.Error())
+	}
+
+	// the metadata may be on its own, or wrapped in a bundle as either an object or an escaped JSON string
+	rawMetadata := []byte(output)
+	if bundle.Metadata != nil {
+		rawMetadata = bundle.Metadata
+		var asString string
+		if err := json.Unmarshal(bundle.Metadata, &asString); err == nil {
+			rawMetadata = []byte(asString)
+		}
+	}
+	var metadata solcMetadata
+	if err := json.Unmarshal(rawMetadata, &metadata); err != nil || metadata.Compiler == nil {
+		return nil, errors.New("compiler output is neither solc standard-JSON output nor a metadata bundle")
+	}
+
+	var name string
+	for _, target := range metadata.Settings.CompilationTarget {
+		name = target
+	}
+	if contractName != "" {
+		shortName := contractName[strings.LastIndex(contractName, ":")+1:]
+		if shortName != name {
+			return nil, fmt.Errorf("contract %s not found in metadata bundle, which is for contract %s", contractName, name)
+		}
+	}
+
+	// the deployed bytecode may be given directly, or as a solc "evm.deployedBytecode" object
+	var deployedBytecode string
+	if err := json.Unmarshal(bundle.DeployedBytecode, &deployedBytecode); err != nil {
+		var bytecodeObject struct {
+			Object string `json:"object"`
+		}
+		_ = json.Unmarshal(bundle.DeployedBytecode, &bytecodeObject)
+		deployedBytecode = bytecodeObject.Object
+	}
+
+	return &Template{
+		ContractName:        name,
+		CompilerVersion:     metadata.Compiler.Version,
+		ABI:                 rawJSONString(metadata.Output.ABI),
+		StorageLayout:       rawJSONString(bundle.StorageLayout),
+		RuntimeBytecodeHash: bytecodeHash(deployedBytecode),
+	}, nil
+}
+
+// rawJSONString returns the given JSON as a string, treating "null" the same as a missing value
+func rawJSONString(raw json.RawMessage) string {
+	if raw == nil || string(raw) == "null" {
+		return ""
+	}
+	return string(raw)
+}
+
+// bytecodeHash returns the keccak256 hash of the given runtime bytecode. No hash is
+// given for contracts without any bytecode, such as interfaces, or for bytecode that
+// still has library placeholders in it, as it does not match what will be deployed.
+func bytecodeHash(bytecode string) string {
+	decoded, err := hex.DecodeString(strings.TrimPrefix(bytecode, "0x"))
+	if err != nil || len(decoded) == 0 {
+		return ""
+	}
+	hasher := sha3.NewLegacyKeccak256()
+	hasher.Write(decoded)
+	return "0x" + hex.EncodeToString(hasher.Sum(nil))
+}
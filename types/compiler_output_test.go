@@ -0,0 +1,141 @@
+package types
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testCompilerABI    = `[{"inputs":[],"name":"storedData","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"}]`
+	testCompilerLayout = `{"storage":[{"astId":3,"contract":"contracts/SimpleStorage.sol:SimpleStorage","label":"storedData","offset":0,"slot":"0","type":"t_uint256"}],"types":{"t_uint256":{"encoding":"inplace","label":"uint256","numberOfBytes":"32"}}}`
+	testMetadata       = `{"compiler":{"version":"0.6.12+commit.27d51765"},"language":"Solidity","output":{"abi":` + testCompilerABI + `},"settings":{"compilationTarget":{"contracts/SimpleStorage.sol":"SimpleStorage"}},"version":1}`
+
+	// keccak256 of 0x6080604052
+	testBytecodeHash = "0x1c3374235d773b2189aed115aa13143020fcdbbe86e38f358cf3e4771b2f0244"
+)
+
+var testStandardOutput = `{
+	"contracts": {
+		"contracts/SimpleStorage.sol": {
+			"SimpleStorage": {
+				"abi": ` + testCompilerABI + `,
+				"storageLayout": ` + testCompilerLayout + `,
+				"metadata": ` + strconv.Quote(testMetadata) + `,
+				"evm": {"deployedBytecode": {"object": "6080604052"}}
+			}
+		},
+		"contracts/Storage.sol": {
+			"IStorage": {"abi": [], "evm": {"deployedBytecode": {"object": ""}}},
+			"Storage": {"abi": [], "evm": {"deployedBytecode": {"object": "__$2b5d4d4a33c3c3e3a8c9ea1d4d1cd0f8c1$__"}}}
+		},
+		"contracts/other/Storage.sol": {
+			"Storage": {"abi": []}
+		}
+	},
+	"sources": {}
+}`
+
+func TestIsCompilerOutput(t *testing.T) {
+	assert.True(t, IsCompilerOutput(testStandardOutput))
+	assert.True(t, IsCompilerOutput(testMetadata))
+	assert.True(t, IsCompilerOutput(`{"metadata": {}}`))
+
+	assert.False(t, IsCompilerOutput(testCompilerABI))
+	assert.False(t, IsCompilerOutput(testCompilerLayout))
+	assert.False(t, IsCompilerOutput("not json"))
+}
+
+func TestNewTemplateFromCompilerOutput_StandardOutput(t *testing.T) {
+	for _, contractName := range []string{"SimpleStorage", "contracts/SimpleStorage.sol:SimpleStorage"} {
+		template, err := NewTemplateFromCompilerOutput("test template", testStandardOutput, contractName)
+
+		assert.Nil(t, err)
+		assert.Equal(t, &Template{
+			TemplateName:        "test template",
+			ABI:                 testCompilerABI,
+			StorageLayout:       testCompilerLayout,
+			ContractName:        "SimpleStorage",
+			CompilerVersion:     "0.6.12+commit.27d51765",
+			RuntimeBytecodeHash: testBytecodeHash,
+		}, template)
+	}
+}
+
+func TestNewTemplateFromCompilerOutput_NoBytecodeHash(t *testing.T) {
+	// interfaces have no bytecode, and unlinked bytecode is not what is deployed
+	for _, contractName := range []string{"IStorage", "contracts/Storage.sol:Storage"} {
+		template, err := NewTemplateFromCompilerOutput("test template", testStandardOutput, contractName)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "[]", template.ABI)
+		assert.Equal(t, "", template.StorageLayout)
+		assert.Equal(t, "", template.RuntimeBytecodeHash)
+	}
+}
+
+func TestNewTemplateFromCompilerOutput_ChoosingContract(t *testing.T) {
+	_, err := NewTemplateFromCompilerOutput("test template", testStandardOutput, "")
+	assert.EqualError(t, err, "compiler output contains multiple contracts, a contract name is required: contracts/SimpleStorage.sol:SimpleStorage, contracts/Storage.sol:IStorage, contracts/Storage.sol:Storage, contracts/other/Storage.sol:Storage")
+
+	_, err = NewTemplateFromCompilerOutput("test template", testStandardOutput, "Storage")
+	assert.EqualError(t, err, "contract name Storage is ambiguous, use one of: contracts/Storage.sol:Storage, contracts/other/Storage.sol:Storage")
+
+	_, err = NewTemplateFromCompilerOutput("test template", testStandardOutput, "Missing")
+	assert.EqualError(t, err, "contract Missing not found in compiler output")
+
+	_, err = NewTemplateFromCompilerOutput("test template", `{"contracts": {}}`, "")
+	assert.EqualError(t, err, "compiler output contains no contracts")
+}
+
+func TestNewTemplateFromCompilerOutput_MetadataBundle(t *testing.T) {
+	bundles := []string{
+		`{"metadata": ` + testMetadata + `, "storageLayout": ` + testCompilerLayout + `, "deployedBytecode": "0x6080604052"}`,
+		`{"metadata": ` + strconv.Quote(testMetadata) + `, "storageLayout": ` + testCompilerLayout + `, "deployedBytecode": {"object": "6080604052"}}`,
+	}
+
+	for _, bundle := range bundles {
+		template, err := NewTemplateFromCompilerOutput("test template", bundle, "")
+
+		assert.Nil(t, err)
+		assert.Equal(t, &Template{
+			TemplateName:        "test template",
+			ABI:                 testCompilerABI,
+			StorageLayout:       testCompilerLayout,
+			ContractName:        "SimpleStorage",
+			CompilerVersion:     "0.6.12+commit.27d51765",
+			RuntimeBytecodeHash: testBytecodeHash,
+		}, template)
+	}
+}
+
+func TestNewTemplateFromCompilerOutput_MetadataOnly(t *testing.T) {
+	template, err := NewTemplateFromCompilerOutput("test template", testMetadata, "contracts/SimpleStorage.sol:SimpleStorage")
+
+	assert.Nil(t, err)
+	assert.Equal(t, testCompilerABI, template.ABI)
+	assert.Equal(t, "", template.StorageLayout)
+	assert.Equal(t, "0.6.12+commit.27d51765", template.CompilerVersion)
+	assert.Equal(t, "", template.RuntimeBytecodeHash)
+
+	_, err = NewTemplateFromCompilerOutput("test template", testMetadata, "Storage")
+	assert.EqualError(t, err, "contract Storage not found in metadata bundle, which is for contract SimpleStorage")
+}
+
+func TestNewTemplateFromCompilerOutput_Invalid(t *testing.T) {
+	_, err := NewTemplateFromCompilerOutput("test template", "not json", "")
+	assert.EqualError(t, err, "invalid compiler output: invalid character 'o' in literal null (expecting 'u')")
+
+	_, err = NewTemplateFromCompilerOutput("test template", testCompilerLayout, "")
+	assert.EqualError(t, err, "compiler output is neither solc standard-JSON output nor a metadata bundle")
+
+	_, err = NewTemplateFromCompilerOutput("test template", `{"contracts": {"a.sol": {"A": {}}}}`, "")
+	assert.EqualError(t, err, "compiler output has no ABI for contract A")
+
+	_, err = NewTemplateFromCompilerOutput("test template", `{"contracts": {"a.sol": {"A": {"abi": {}}}}}`, "")
+	assert.EqualError(t, err, "invalid ABI for contract A: json: cannot unmarshal object into Go value of type types.ABIStructure")
+
+	_, err = NewTemplateFromCompilerOutput("test template", `{"contracts": {"a.sol": {"A": {"abi": [], "storageLayout": {"storage": {}}}}}}`, "")
+	assert.EqualError(t, err, "invalid storage layout for contract A: json: cannot unmarshal object into Go struct field SolidityStorageDocument.storage of type types.SolidityStorageEntries")
+}
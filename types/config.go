@@ -1,6 +1,7 @@
 package types
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -32,16 +33,44 @@ type TuningConfig struct {
 	BlockProcessingFlushPeriod int `toml:"blockProcessingFlushPeriod"`
 }
 
+type TokenConfig struct {
+	// How often, in blocks, the total supply of ERC20 tokens is sampled, on top of whenever
+	// tokens are minted or burned. 0 only records the total supply on mints and burns.
+	TotalSupplySampleInterval uint64 `toml:"totalSupplySampleInterval,omitempty"`
+	// Compute ERC20 balances by applying Transfer events to the previous balance, instead of calling
+	// balanceOf for every holder whose balance changed
+	EventSourcedBalances bool `toml:"eventSourcedBalances,omitempty"`
+	// How often, in blocks, the event-sourced balances of every holder are checked against balanceOf. 0 never checks.
+	BalanceReconcileInterval uint64 `toml:"balanceReconcileInterval,omitempty"`
+}
+
 type AddressConfig struct {
-	Address      Address `toml:"address,omitempty"`
-	TemplateName string  `toml:"templateName,omitempty"`
-	From         uint64  `toml:"from,omitempty"`
+	Address      Address            `toml:"address,omitempty"`
+	TemplateName string             `toml:"templateName,omitempty"`
+	From         uint64             `toml:"from,omitempty"`
+	Samplers     []*FunctionSampler `toml:"samplers,omitempty"`
 }
 
 type TemplateConfig struct {
 	TemplateName  string `toml:"templateName,omitempty"`
 	ABI           string `toml:"abi,omitempty"`
 	StorageLayout string `toml:"storageLayout,omitempty"`
+	// solc standard-JSON output or a metadata bundle, used instead of the ABI and storage layout
+	CompilerOutput string `toml:"compilerOutput,omitempty"`
+	ContractName   string `toml:"contractName,omitempty"`
+}
+
+// ToTemplate creates the template described by the config, extracting
+// the ABI and storage layout from the compiler output if it is given
+func (tc *TemplateConfig) ToTemplate() (*Template, error) {
+	if tc.CompilerOutput != "" {
+		return NewTemplateFromCompilerOutput(tc.TemplateName, tc.CompilerOutput, tc.ContractName)
+	}
+	return &Template{
+		TemplateName:  tc.TemplateName,
+		ABI:           tc.ABI,
+		StorageLayout: tc.StorageLayout,
+	}, nil
 }
 
 type RuleConfig struct {
@@ -52,12 +81,13 @@ type RuleConfig struct {
 }
 
 type ReportingConfig struct {
-	Title     string
-	Addresses []*AddressConfig  `toml:"addresses,omitempty"`
-	Templates []*TemplateConfig `toml:"templates,omitempty"`
-	Rules     []*RuleConfig     `toml:"rules,omitempty"`
-	Database  *DatabaseConfig   `toml:"database,omitempty"`
-	Server    struct {
+	Title         string
+	Addresses     []*AddressConfig  `toml:"addresses,omitempty"`
+	Templates     []*TemplateConfig `toml:"templates,omitempty"`
+	Rules         []*RuleConfig     `toml:"rules,omitempty"`
+	SignatureFile string            `toml:"signatureFile,omitempty"`
+	Database      *DatabaseConfig   `toml:"database,omitempty"`
+	Server        struct {
 		RPCAddr     string   `toml:"rpcAddr"`
 		RPCCorsList []string `toml:"rpcCorsList,omitempty"`
 		RPCVHosts   []string `toml:"rpcvHosts,omitempty"`
@@ -70,6 +100,7 @@ type ReportingConfig struct {
 		MaxReconnectTries int    `toml:"maxReconnectTries,omitempty"`
 	}
 	Tuning TuningConfig `toml:"tuning,omitempty"`
+	Tokens TokenConfig  `toml:"tokens,omitempty"`
 }
 
 func ReadConfig(configFile string) (ReportingConfig, error) {
@@ -114,9 +145,46 @@ func (rc *ReportingConfig) Validate() error {
 		if template.TemplateName == "" {
 			return errors.New(fmt.Sprintf("empty template name: %v", template))
 		}
+		if template.CompilerOutput != "" {
+			if template.ABI != "" || template.StorageLayout != "" {
+				return errors.New(fmt.Sprintf("template %s has compiler output along with an ABI or storage layout", template.TemplateName))
+			}
+			if _, err := template.ToTemplate(); err != nil {
+				return errors.New(fmt.Sprintf("invalid compiler output for template %s: %v", template.TemplateName, err))
+			}
+			continue
+		}
 		if template.ABI == "" {
 			return errors.New(fmt.Sprintf("empty template ABI: %v", template))
 		}
+		if _, err := NewABIStructureFromJSON(template.ABI); err != nil {
+			return errors.New(fmt.Sprintf("invalid ABI for template %s: %v", template.TemplateName, err))
+		}
+		if template.StorageLayout != "" {
+			var layout SolidityStorageDocument
+			if err := json.Unmarshal([]byte(template.StorageLayout), &layout); err != nil {
+				return errors.New(fmt.Sprintf("invalid storage layout for template %s: %v", template.TemplateName, err))
+			}
+		}
+	}
+	for _, address := range rc.Addresses {
+		names := make(map[string]bool)
+		for _, sampler := range address.Samplers {
+			if sampler.Function == "" {
+				return errors.New(fmt.Sprintf("empty sampler function for address %s", address.Address.Hex()))
+			}
+			if address.TemplateName == "" {
+				return errors.New(fmt.Sprintf("samplers given for address %s without a template", address.Address.Hex()))
+			}
+			name := sampler.Name
+			if name == "" {
+				name = sampler.Function
+			}
+			if names[name] {
+				return errors.New(fmt.Sprintf("duplicate sampler %s for address %s", name, address.Address.Hex()))
+			}
+			names[name] = true
+		}
 	}
 	for _, rule := range rc.Rules {
 		if rule.Scope != AllScope && rule.Scope != InternalScope && rule.Scope != ExternalScope {
@@ -95,3 +95,82 @@ func TestConfigFile(t *testing.T) {
 	_, err = ReadConfig("../config.sample.toml")
 	assert.Nil(t, err, "error reading sample config file")
 }
+
+func TestValidateTemplates(t *testing.T) {
+	validLayout := `{"storage":[],"types":{}}`
+
+	tests := []struct {
+		template    *TemplateConfig
+		expectedErr string
+	}{
+		{&TemplateConfig{TemplateName: "Valid", ABI: "[]", StorageLayout: validLayout}, ""},
+		{&TemplateConfig{TemplateName: "NoLayout", ABI: "[]"}, ""},
+		{&TemplateConfig{TemplateName: "Compiled", CompilerOutput: testStandardOutput, ContractName: "SimpleStorage"}, ""},
+		{&TemplateConfig{TemplateName: "BadABI", ABI: "{}"}, "invalid ABI for template BadABI: json: cannot unmarshal object into Go value of type types.ABIStructure"},
+		{&TemplateConfig{TemplateName: "BadLayout", ABI: "[]", StorageLayout: "[]"}, "invalid storage layout for template BadLayout: json: cannot unmarshal array into Go value of type types.SolidityStorageDocument"},
+		{&TemplateConfig{TemplateName: "Both", ABI: "[]", CompilerOutput: testStandardOutput}, "template Both has compiler output along with an ABI or storage layout"},
+		{&TemplateConfig{TemplateName: "BadOutput", CompilerOutput: testStandardOutput, ContractName: "Missing"}, "invalid compiler output for template BadOutput: contract Missing not found in compiler output"},
+	}
+
+	for _, test := range tests {
+		config := ReportingConfig{Templates: []*TemplateConfig{test.template}}
+		err := config.Validate()
+		if test.expectedErr == "" {
+			assert.Nil(t, err, "unexpected error for template %s", test.template.TemplateName)
+		} else {
+			assert.EqualError(t, err, test.expectedErr)
+		}
+	}
+}
+
+func TestValidateSamplers(t *testing.T) {
+	address := NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+
+	tests := []struct {
+		address     *AddressConfig
+		expectedErr string
+	}{
+		{&AddressConfig{Address: address, TemplateName: "Vault", Samplers: []*FunctionSampler{{Function: "collateralRatio"}, {Name: "ratio", Function: "collateralRatio"}}}, ""},
+		{&AddressConfig{Address: address, TemplateName: "Vault", Samplers: []*FunctionSampler{{Function: "collateralRatio"}, {Function: "collateralRatio"}}}, "duplicate sampler collateralRatio for address 0x1932c48b2bf8102ba33b4a6b545c32236e342f34"},
+		{&AddressConfig{Address: address, TemplateName: "Vault", Samplers: []*FunctionSampler{{Name: "ratio"}}}, "empty sampler function for address 0x1932c48b2bf8102ba33b4a6b545c32236e342f34"},
+		{&AddressConfig{Address: address, Samplers: []*FunctionSampler{{Function: "collateralRatio"}}}, "samplers given for address 0x1932c48b2bf8102ba33b4a6b545c32236e342f34 without a template"},
+	}
+
+	for _, test := range tests {
+		config := ReportingConfig{Addresses: []*AddressConfig{test.address}}
+		err := config.Validate()
+		if test.expectedErr == "" {
+			assert.Nil(t, err)
+		} else {
+			assert.EqualError(t, err, test.expectedErr)
+		}
+	}
+}
+
+func TestConfigFile_Samplers(t *testing.T) {
+	d, _ := ioutil.TempDir("", "test")
+	defer os.RemoveAll(d)
+	fileName := d + "/config.toml"
+
+	contents := `
+addresses = [
+    { address = "0x1932c48b2bf8102ba33b4a6b545c32236e342f34", templateName = "Vault", samplers = [
+        { function = "collateralRatio", interval = 100 },
+        { name = "ownerBalance", function = "balanceOf", args = ["0x9d13c6d3afe1721beef56b55d303b09e021e27ab"] }
+    ] }
+]
+`
+	err := ioutil.WriteFile(fileName, []byte(contents), 0644)
+	assert.Nil(t, err)
+
+	config, err := ReadConfig(fileName)
+	assert.Nil(t, err)
+	assert.Len(t, config.Addresses, 1)
+	samplers := config.Addresses[0].Samplers
+	assert.Len(t, samplers, 2)
+	assert.Equal(t, "collateralRatio", samplers[0].Function)
+	assert.EqualValues(t, 100, samplers[0].Interval)
+	assert.Equal(t, "ownerBalance", samplers[1].Name)
+	assert.EqualValues(t, 0, samplers[1].Interval)
+	assert.Equal(t, []interface{}{"0x9d13c6d3afe1721beef56b55d303b09e021e27ab"}, samplers[1].Args)
+}
@@ -0,0 +1,91 @@
+package types
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+const (
+	SortAscending  = "asc"
+	SortDescending = "desc"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is the position of the last result of a page, from which the next page continues.
+// It is given to clients as an opaque token, so that what it holds can change without breaking them.
+type Cursor struct {
+	BlockNumber uint64 `json:"b"`
+	Index       uint64 `json:"i"`
+}
+
+func NewCursor(blockNumber uint64, index uint64) *Cursor {
+	return &Cursor{BlockNumber: blockNumber, Index: index}
+}
+
+// DecodeCursor parses a token returned by Encode
+func DecodeCursor(token string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+func (cursor *Cursor) Encode() string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// Precedes reports whether the cursor comes before a result at the given position. Results are
+// ordered by block number in the given direction, and then always by index ascending.
+func (cursor *Cursor) Precedes(blockNumber uint64, index uint64, ascending bool) bool {
+	if blockNumber != cursor.BlockNumber {
+		return (blockNumber > cursor.BlockNumber) == ascending
+	}
+	return index > cursor.Index
+}
+
+// TokenCursor is the key of the last result of a page of token results: the holder, token ID or block
+// number they are ordered by. It is given to clients as an opaque token in the same way as Cursor.
+type TokenCursor struct {
+	Key string `json:"k"`
+}
+
+func NewTokenCursor(key string) *TokenCursor {
+	return &TokenCursor{Key: key}
+}
+
+// DecodeTokenCursor parses a token returned by Encode
+func DecodeTokenCursor(token string) (*TokenCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor TokenCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.Key == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+func (cursor *TokenCursor) Encode() string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// parsePagination checks the sort direction is valid, and decodes the cursor if one is given
+func parsePagination(sort string, after string) (*Cursor, error) {
+	if sort != "" && sort != SortAscending && sort != SortDescending {
+		return nil, errors.New("sort must be asc or desc")
+	}
+	if after == "" {
+		return nil, nil
+	}
+	return DecodeCursor(after)
+}
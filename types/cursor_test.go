@@ -0,0 +1,63 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursor_EncodeDecode(t *testing.T) {
+	cursor := NewCursor(1500, 3)
+
+	decoded, err := DecodeCursor(cursor.Encode())
+
+	assert.Nil(t, err)
+	assert.Equal(t, cursor, decoded)
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	_, err := DecodeCursor("not a cursor")
+	assert.Equal(t, ErrInvalidCursor, err)
+
+	_, err = DecodeCursor("bm90IGpzb24")
+	assert.Equal(t, ErrInvalidCursor, err)
+}
+
+func TestCursor_Precedes(t *testing.T) {
+	cursor := NewCursor(10, 2)
+
+	assert.True(t, cursor.Precedes(9, 0, false))
+	assert.True(t, cursor.Precedes(10, 3, false))
+	assert.False(t, cursor.Precedes(10, 2, false))
+	assert.False(t, cursor.Precedes(11, 0, false))
+
+	assert.True(t, cursor.Precedes(11, 0, true))
+	assert.True(t, cursor.Precedes(10, 3, true))
+	assert.False(t, cursor.Precedes(10, 1, true))
+	assert.False(t, cursor.Precedes(9, 5, true))
+}
+
+func TestQueryOptions_ValidatePagination(t *testing.T) {
+	assert.Nil(t, (&QueryOptions{}).ValidatePagination())
+	assert.Nil(t, (&QueryOptions{Sort: SortAscending, After: NewCursor(1, 0).Encode()}).ValidatePagination())
+	assert.EqualError(t, (&QueryOptions{Sort: "newest"}).ValidatePagination(), "sort must be asc or desc")
+	assert.Equal(t, ErrInvalidCursor, (&PageOptions{After: "%%"}).ValidatePagination())
+}
+
+func TestTokenQueryOptions_ValidatePagination(t *testing.T) {
+	holder := "0x1349f3e1b8d71effb47b840594ff27da7e603d17"
+
+	options := &TokenQueryOptions{After: NewTokenCursor(holder).Encode()}
+	assert.Nil(t, options.ValidatePagination())
+	assert.Equal(t, holder, options.After)
+
+	// the key of the last result is still accepted in place of a cursor
+	for _, key := range []string{holder, "1500", ""} {
+		options = &TokenQueryOptions{After: key}
+		assert.Nil(t, options.ValidatePagination())
+		assert.Equal(t, key, options.After)
+	}
+
+	options = &TokenQueryOptions{Sort: "newest"}
+	assert.EqualError(t, options.ValidatePagination(), "sort must be asc or desc")
+}
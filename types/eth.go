@@ -160,12 +160,21 @@ func (data *HexData) IsEmpty() bool {
 	return len(data.AsBytes()) == 0
 }
 
-// Call args for checking a contract for EIP165 interfaces
+// Call args for a read-only contract call, such as checking a contract for EIP165 interfaces
 type EIP165Call struct {
 	To   Address `json:"to"`
 	Data HexData `json:"data"`
 }
 
+// Call args for replaying a transaction with eth_call
+type ReplayCall struct {
+	From  Address   `json:"from"`
+	To    *Address  `json:"to,omitempty"`
+	Gas   HexNumber `json:"gas"`
+	Value HexNumber `json:"value"`
+	Data  HexData   `json:"data"`
+}
+
 type HexNumber uint64
 
 func (num HexNumber) MarshalJSON() ([]byte, error) {
@@ -0,0 +1,102 @@
+package types
+
+import (
+	"errors"
+	"strings"
+)
+
+// DecodedEvent holds the arguments of an event decoded with the ABI of the contract that
+// emitted it, in a form that can be indexed so that events can be filtered by their values
+type DecodedEvent struct {
+	Name string        `json:"eventName"`
+	Sig  string        `json:"eventSig"`
+	Args []*DecodedArg `json:"args"`
+}
+
+// DecodeEvent decodes the arguments of an event with the given ABI, including indexed arguments.
+// Arrays, tuples and indexed arguments of dynamic types, which are only kept as a hash, are left out
+// as they cannot be compared. nil is returned if the event is not in the ABI.
+func DecodeEvent(event *Event, contractABI *ContractABI) (*DecodedEvent, error) {
+	if len(event.Topics) == 0 {
+		return nil, nil
+	}
+	for _, abiEvent := range contractABI.Events {
+		if abiEvent.Anonymous || "0x"+abiEvent.Signature() != event.Topics[0].String() {
+			continue
+		}
+		// the event may not match the ABI if the template is wrong, and the parser does not check bounds
+		parsed, err := parseCandidate(abiEvent.Parse, event.Data.AsBytes())
+		if err != nil {
+			return nil, err
+		}
+
+		decoded := &DecodedEvent{Name: abiEvent.Name, Sig: abiEvent.StringNoName()}
+		topic := 1
+		for _, input := range abiEvent.Inputs {
+			value, ok := parsed[input.Name]
+			if input.Indexed {
+				if topic >= len(event.Topics) {
+					return nil, errors.New("event has fewer topics than indexed arguments")
+				}
+				value, ok = nil, false
+				topicData := HexData(event.Topics[topic])
+				if !input.IsDynamic() && len(topicData.AsBytes()) == 32 {
+					value, _, err = ParseStaticType(input.ContractABIArgument, topicData.AsBytes(), 0)
+					ok = err == nil
+				}
+				topic++
+			}
+			if arg := newDecodedArg(input.Name, value); ok && arg != nil {
+				decoded.Args = append(decoded.Args, arg)
+			}
+		}
+		return decoded, nil
+	}
+	return nil, nil
+}
+
+// EventFilter selects events by their name or signature, and by the values of their decoded arguments.
+// An event must match all of the argument filters.
+type EventFilter struct {
+	// Event is the name of the event, or its signature such as Transfer(address,address,uint256)
+	Event string       `json:"event"`
+	Args  []*ArgFilter `json:"args"`
+}
+
+// Validate checks the filter is well-formed, and resolves the values that arguments are compared to
+func (filter *EventFilter) Validate() error {
+	for _, argFilter := range filter.Args {
+		if err := argFilter.resolve(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsSignature reports whether the event is given by its signature rather than its name
+func (filter *EventFilter) IsSignature() bool {
+	return strings.Contains(filter.Event, "(")
+}
+
+// EventSig returns the signature of the event as it is indexed, without spaces
+func (filter *EventFilter) EventSig() string {
+	return strings.Replace(filter.Event, " ", "", -1)
+}
+
+// Matches reports whether a decoded event matches the filter. Events that could not be
+// decoded only match a filter that does not select on the event or its arguments.
+func (filter *EventFilter) Matches(decoded *DecodedEvent) bool {
+	if filter.Event == "" && len(filter.Args) == 0 {
+		return true
+	}
+	if decoded == nil {
+		return false
+	}
+	if filter.IsSignature() && filter.EventSig() != decoded.Sig {
+		return false
+	}
+	if filter.Event != "" && !filter.IsSignature() && filter.Event != decoded.Name {
+		return false
+	}
+	return matchArgs(filter.Args, decoded.Args)
+}
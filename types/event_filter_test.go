@@ -0,0 +1,102 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTransferEvent(from, to string, value string) *Event {
+	return &Event{
+		Topics: []Hash{NewHash(transferTopic), NewHash(from), NewHash(to)},
+		Data:   NewHexData(value),
+	}
+}
+
+func decodedTransfer(t *testing.T, from, to string, value string) *DecodedEvent {
+	structure, err := NewABIStructureFromJSON(registryERC20ABI)
+	assert.Nil(t, err)
+	decoded, err := DecodeEvent(newTransferEvent(from, to, value), structure.ToInternalABI())
+	assert.Nil(t, err)
+	return decoded
+}
+
+func TestDecodeEvent(t *testing.T) {
+	decoded := decodedTransfer(t, "0x0000000000000000000000009D13C6D3AFE1721BEEF56B55D303B09E021E27AB", "0x02", "0x00000000000000000000000000000000000000000000000000000000000003e8")
+
+	assert.Equal(t, "Transfer", decoded.Name)
+	assert.Equal(t, "Transfer(address,address,uint256)", decoded.Sig)
+	assert.Len(t, decoded.Args, 3)
+	assert.Equal(t, &DecodedArg{Name: "from", Value: "0x9d13c6d3afe1721beef56b55d303b09e021e27ab"}, decoded.Args[0])
+	assert.Equal(t, &DecodedArg{Name: "to", Value: "0x0000000000000000000000000000000000000002"}, decoded.Args[1])
+	assert.Equal(t, "value", decoded.Args[2].Name)
+	assert.Equal(t, "1000", decoded.Args[2].Value)
+	assert.Len(t, decoded.Args[2].Number, numberWidth)
+}
+
+func TestDecodeEvent_NotInABI(t *testing.T) {
+	structure, err := NewABIStructureFromJSON(registryOtherABI)
+	assert.Nil(t, err)
+
+	decoded, err := DecodeEvent(newTransferEvent("0x01", "0x02", "0x00"), structure.ToInternalABI())
+
+	assert.Nil(t, err)
+	assert.Nil(t, decoded)
+}
+
+func TestEventFilter_Validate(t *testing.T) {
+	cases := []struct {
+		filter *EventFilter
+		err    string
+	}{
+		{&EventFilter{Event: "Transfer", Args: []*ArgFilter{{Name: "value", Value: float64(1000)}}}, ""},
+		{&EventFilter{Args: []*ArgFilter{{Name: "value", Op: "gte", Value: "1000"}}}, ""},
+		{&EventFilter{Args: []*ArgFilter{{Value: "1000"}}}, "no argument name given"},
+		{&EventFilter{Args: []*ArgFilter{{Name: "value", Op: "ne", Value: "1000"}}}, "unknown operator ne for argument value"},
+		{&EventFilter{Args: []*ArgFilter{{Name: "to", Op: "gt", Value: "0x02"}}}, "value for argument to must be a number to use operator gt"},
+		{&EventFilter{Args: []*ArgFilter{{Name: "value", Value: 1.5}}}, "invalid value for argument value: numbers must be integers"},
+		{&EventFilter{Args: []*ArgFilter{{Name: "value"}}}, "invalid value for argument value: value must be a string, number or boolean"},
+	}
+
+	for _, c := range cases {
+		err := c.filter.Validate()
+		if c.err == "" {
+			assert.Nil(t, err)
+		} else {
+			assert.EqualError(t, err, c.err)
+		}
+	}
+}
+
+func TestEventFilter_Matches(t *testing.T) {
+	decoded := decodedTransfer(t, "0x01", "0x9d13c6d3afe1721beef56b55d303b09e021e27ab", "0x00000000000000000000000000000000000000000000000000000000000003e8")
+
+	cases := []struct {
+		filter  *EventFilter
+		matches bool
+	}{
+		{&EventFilter{}, true},
+		{&EventFilter{Event: "Transfer"}, true},
+		{&EventFilter{Event: "Approval"}, false},
+		{&EventFilter{Event: "Transfer(address, address, uint256)"}, true},
+		{&EventFilter{Event: "Transfer(address,uint256)"}, false},
+		{&EventFilter{Event: "Transfer", Args: []*ArgFilter{{Name: "to", Value: "0x9D13C6D3AFE1721BEEF56B55D303B09E021E27AB"}}}, true},
+		{&EventFilter{Event: "Transfer", Args: []*ArgFilter{{Name: "from", Value: "0x9d13c6d3afe1721beef56b55d303b09e021e27ab"}}}, false},
+		{&EventFilter{Args: []*ArgFilter{{Name: "value", Value: float64(1000)}}}, true},
+		{&EventFilter{Args: []*ArgFilter{{Name: "value", Op: "gt", Value: "999"}}}, true},
+		{&EventFilter{Args: []*ArgFilter{{Name: "value", Op: "gt", Value: "1000"}}}, false},
+		{&EventFilter{Args: []*ArgFilter{{Name: "value", Op: "gte", Value: "1000"}, {Name: "value", Op: "lt", Value: "10000"}}}, true},
+		{&EventFilter{Args: []*ArgFilter{{Name: "value", Op: "gt", Value: "-5"}}}, true},
+		{&EventFilter{Args: []*ArgFilter{{Name: "value", Op: "lte", Value: "-5"}}}, false},
+		{&EventFilter{Args: []*ArgFilter{{Name: "to", Op: "gt", Value: "5"}}}, false},
+		{&EventFilter{Args: []*ArgFilter{{Name: "missing", Value: "5"}}}, false},
+	}
+
+	for _, c := range cases {
+		assert.Nil(t, c.filter.Validate())
+		assert.Equal(t, c.matches, c.filter.Matches(decoded), c.filter.Event)
+	}
+
+	assert.True(t, (&EventFilter{}).Matches(nil))
+	assert.False(t, (&EventFilter{Event: "Transfer"}).Matches(nil))
+}
@@ -9,11 +9,18 @@ import (
 )
 
 type ParsedTransaction struct {
-	Sig            string                 `json:"txSig"`
-	Func4Bytes     HexData                `json:"func4Bytes"`
-	ParsedData     map[string]interface{} `json:"parsedData"`
-	ParsedEvents   []*ParsedEvent         `json:"parsedEvents"`
-	RawTransaction *Transaction           `json:"rawTransaction"`
+	Sig                 string                 `json:"txSig"`
+	Func4Bytes          HexData                `json:"func4Bytes"`
+	ParsedData          map[string]interface{} `json:"parsedData"`
+	ParsedEvents        []*ParsedEvent         `json:"parsedEvents"`
+	ParsedInternalCalls []*ParsedInternalCall  `json:"parsedInternalCalls"`
+	Revert              *ParsedRevert          `json:"revert,omitempty"`
+	RawTransaction      *Transaction           `json:"rawTransaction"`
+
+	// set if the transaction was decoded from the signature registry rather than the contract's ABI,
+	// with all the definitions it could have been made with if there was more than one
+	RegistryDecoded bool     `json:"registryDecoded,omitempty"`
+	AmbiguousSigs   []string `json:"ambiguousSigs,omitempty"`
 }
 
 func (ptx *ParsedTransaction) ParseTransaction(rawABI string) error {
@@ -81,10 +88,124 @@ func (ptx *ParsedTransaction) ParseTransaction(rawABI string) error {
 	return nil
 }
 
+// ParseRevert decodes the reason a failed transaction reverted with, if it gave one.
+// The contract ABI is only needed for custom errors, and may be nil.
+func (ptx *ParsedTransaction) ParseRevert(contractABI *ContractABI) error {
+	if ptx.RawTransaction == nil {
+		return errors.New("transaction is nil or invalid")
+	}
+	if ptx.RawTransaction.Status {
+		return nil
+	}
+
+	ptx.Revert = ParseRevertData(ptx.RawTransaction.RevertData.AsBytes(), contractABI)
+	return nil
+}
+
+// ParseTransactionFromRegistry decodes the function call of a transaction to a contract that has
+// no ABI, using the signature registry. Contract deployments cannot be decoded this way.
+func (ptx *ParsedTransaction) ParseTransactionFromRegistry(registry *SignatureRegistry) error {
+	if ptx.RawTransaction == nil {
+		return errors.New("transaction is nil or invalid")
+	}
+	if ptx.RawTransaction.To.IsEmpty() {
+		return nil
+	}
+
+	data := ptx.RawTransaction.Data.AsBytes()
+	if len(ptx.RawTransaction.PrivateData) > 0 {
+		data = ptx.RawTransaction.PrivateData.AsBytes()
+	}
+	function, result, ambiguousSigs := decodeCallFromRegistry(registry, data)
+	if function == nil {
+		return nil
+	}
+	ptx.Func4Bytes = HexData(hex.EncodeToString(data[:4]))
+	ptx.Sig = function.String()
+	ptx.ParsedData = result
+	ptx.RegistryDecoded = true
+	ptx.AmbiguousSigs = ambiguousSigs
+	return nil
+}
+
+type ParsedInternalCall struct {
+	Sig          string                 `json:"callSig"`
+	Func4Bytes   HexData                `json:"func4Bytes"`
+	ParsedInput  map[string]interface{} `json:"parsedInput"`
+	ParsedOutput map[string]interface{} `json:"parsedOutput"`
+	RawCall      *InternalCall          `json:"rawCall"`
+
+	RegistryDecoded bool     `json:"registryDecoded,omitempty"`
+	AmbiguousSigs   []string `json:"ambiguousSigs,omitempty"`
+}
+
+// ParseCall decodes the input and output of an internal call using the ABI of the callee.
+// The output is only decoded if the call succeeded, as otherwise it holds the revert data.
+func (pc *ParsedInternalCall) ParseCall(contractABI *ContractABI) error {
+	if pc.RawCall == nil {
+		return errors.New("internal call is nil or invalid")
+	}
+
+	data := pc.RawCall.Input.AsBytes()
+	if contractABI == nil || len(data) < 4 {
+		return nil
+	}
+
+	pc.Func4Bytes = HexData(hex.EncodeToString(data[:4]))
+	for _, method := range contractABI.Functions {
+		if method.Signature() != string(pc.Func4Bytes) {
+			continue
+		}
+		pc.Sig = method.String()
+		result, err := method.Parse(data[4:])
+		if err != nil {
+			return err
+		}
+		pc.ParsedInput = result
+
+		if output := pc.RawCall.Output.AsBytes(); pc.RawCall.Error == "" && len(output) > 0 {
+			result, err := method.ParseOutput(output)
+			if err != nil {
+				return err
+			}
+			pc.ParsedOutput = result
+		}
+		return nil
+	}
+	return nil
+}
+
+// ParseCallFromRegistry decodes an internal call to a contract that has no ABI, using the signature registry
+func (pc *ParsedInternalCall) ParseCallFromRegistry(registry *SignatureRegistry) error {
+	if pc.RawCall == nil {
+		return errors.New("internal call is nil or invalid")
+	}
+
+	data := pc.RawCall.Input.AsBytes()
+	function, result, ambiguousSigs := decodeCallFromRegistry(registry, data)
+	if function == nil {
+		return nil
+	}
+	pc.Func4Bytes = HexData(hex.EncodeToString(data[:4]))
+	pc.Sig = function.String()
+	pc.ParsedInput = result
+	pc.RegistryDecoded = true
+	pc.AmbiguousSigs = ambiguousSigs
+
+	if output := pc.RawCall.Output.AsBytes(); pc.RawCall.Error == "" && len(output) > 0 {
+		// the output is left undecoded if it does not match, as the input may have matched by chance
+		pc.ParsedOutput, _ = parseCandidate(function.ParseOutput, output)
+	}
+	return nil
+}
+
 type ParsedEvent struct {
 	Sig        string                 `json:"eventSig"`
 	ParsedData map[string]interface{} `json:"parsedData"`
 	RawEvent   *Event                 `json:"rawEvent"`
+
+	RegistryDecoded bool     `json:"registryDecoded,omitempty"`
+	AmbiguousSigs   []string `json:"ambiguousSigs,omitempty"`
 }
 
 func (pe *ParsedEvent) ParseEvent(rawABI string) error {
@@ -112,3 +233,31 @@ func (pe *ParsedEvent) ParseEvent(rawABI string) error {
 	}
 	return nil
 }
+
+// ParseEventFromRegistry decodes an event from a contract that has no ABI, using the signature registry.
+// Only events with the same number of indexed arguments as the event has topics are considered.
+func (pe *ParsedEvent) ParseEventFromRegistry(registry *SignatureRegistry) error {
+	if pe.RawEvent == nil || len(pe.RawEvent.Topics) == 0 {
+		return errors.New("event is nil or invalid")
+	}
+
+	candidates := registry.Events(pe.RawEvent.Topics[0], len(pe.RawEvent.Topics))
+	var ambiguousSigs []string
+	if len(candidates) > 1 {
+		for _, candidate := range candidates {
+			ambiguousSigs = append(ambiguousSigs, "event "+candidate.String())
+		}
+	}
+	for _, candidate := range candidates {
+		result, err := parseCandidate(candidate.Parse, pe.RawEvent.Data.AsBytes())
+		if err != nil {
+			continue
+		}
+		pe.Sig = "event " + candidate.String()
+		pe.ParsedData = result
+		pe.RegistryDecoded = true
+		pe.AmbiguousSigs = ambiguousSigs
+		return nil
+	}
+	return nil
+}
@@ -29,6 +29,17 @@ type QueryOptions struct {
 
 	PageSize   int `json:"pageSize"`
 	PageNumber int `json:"pageNumber"`
+
+	// After is the cursor returned with the previous page. When given, PageNumber is ignored and the
+	// results continue from the cursor, which can page through any number of results.
+	After string `json:"after,omitempty"`
+	// Sort orders the results by block number, either asc or desc, and is desc if not given
+	Sort string `json:"sort,omitempty"`
+
+	// Function, Status and Inputs filter transactions to a contract by the call made, see ValidateCallFilter
+	Function string       `json:"function,omitempty"`
+	Status   string       `json:"status,omitempty"`
+	Inputs   []*ArgFilter `json:"inputs,omitempty"`
 }
 
 func (opts *QueryOptions) SetDefaults() {
@@ -52,11 +63,28 @@ func (opts *QueryOptions) SetDefaults() {
 	}
 }
 
+// ValidatePagination checks the sort direction and the cursor, if one is given
+func (opts *QueryOptions) ValidatePagination() error {
+	_, err := opts.Cursor()
+	return err
+}
+
+// Cursor returns the position to continue from, or nil if the results start from PageNumber
+func (opts *QueryOptions) Cursor() (*Cursor, error) {
+	return parsePagination(opts.Sort, opts.After)
+}
+
+func (opts *QueryOptions) Ascending() bool {
+	return opts.Sort == SortAscending
+}
+
 type PageOptions struct {
 	BeginBlockNumber *big.Int `json:"beginBlockNumber"`
 	EndBlockNumber   *big.Int `json:"endBlockNumber"`
 	PageSize         int      `json:"pageSize"`
 	PageNumber       int      `json:"pageNumber"`
+	After            string   `json:"after,omitempty"`
+	Sort             string   `json:"sort,omitempty"`
 }
 
 func (opts *PageOptions) SetDefaults() {
@@ -75,3 +103,18 @@ func (opts *PageOptions) SetDefaults() {
 		opts.PageNumber = defaultPageOptions.PageNumber
 	}
 }
+
+// ValidatePagination checks the sort direction and the cursor, if one is given
+func (opts *PageOptions) ValidatePagination() error {
+	_, err := opts.Cursor()
+	return err
+}
+
+// Cursor returns the position to continue from, or nil if the results start from PageNumber
+func (opts *PageOptions) Cursor() (*Cursor, error) {
+	return parsePagination(opts.Sort, opts.After)
+}
+
+func (opts *PageOptions) Ascending() bool {
+	return opts.Sort == SortAscending
+}
@@ -16,7 +16,13 @@ type TokenQueryOptions struct {
 	BeginBlockNumber *big.Int `json:"beginBlockNumber"`
 	EndBlockNumber   *big.Int `json:"endBlockNumber"`
 
+	// After is the cursor returned with the previous page, or for backward compatibility the key of
+	// its last result: the holder, token ID or, for balances, block number. When given, PageNumber is
+	// ignored and the results continue after it.
 	After string `json:"after"`
+	// Sort orders balance histories by block number, or the balances of holders at a block by
+	// amount, either asc or desc, and is desc if not given
+	Sort string `json:"sort,omitempty"`
 
 	PageSize   int `json:"pageSize"`
 	PageNumber int `json:"pageNumber"`
@@ -36,3 +42,15 @@ func (opts *TokenQueryOptions) SetDefaults() {
 		opts.PageNumber = defaultTokenQueryOptions.PageNumber
 	}
 }
+
+// ValidatePagination checks the direction results are sorted in, and replaces a cursor given as After
+// with the key it holds. Anything that is not a cursor is taken to be the key itself.
+func (opts *TokenQueryOptions) ValidatePagination() error {
+	if _, err := parsePagination(opts.Sort, ""); err != nil {
+		return err
+	}
+	if cursor, err := DecodeTokenCursor(opts.After); err == nil {
+		opts.After = cursor.Key
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package types
+
+import (
+	"encoding/hex"
+	"math/big"
+)
+
+var (
+	// the built-in errors that Solidity reverts with, see
+	// https://docs.soliditylang.org/en/latest/control-structures.html#panic-via-assert-and-error-via-require
+	revertErrorABI = ContractABIFunction{"error", "Error", []ContractABIArgument{{Name: "message", Type: "string"}}, nil}
+	revertPanicABI = ContractABIFunction{"error", "Panic", []ContractABIArgument{{Name: "code", Type: "uint256"}}, nil}
+
+	panicReasons = map[uint64]string{
+		0x00: "generic compiler panic",
+		0x01: "assertion failed",
+		0x11: "arithmetic overflow or underflow",
+		0x12: "division or modulo by zero",
+		0x21: "invalid enum value",
+		0x22: "incorrectly encoded storage byte array",
+		0x31: "pop on an empty array",
+		0x32: "array index out of bounds",
+		0x41: "out of memory",
+		0x51: "call to an uninitialised function",
+	}
+)
+
+// ParsedRevert is the decoded reason that a transaction failed
+type ParsedRevert struct {
+	Sig        string                 `json:"errorSig"`
+	Message    string                 `json:"message,omitempty"`
+	ParsedData map[string]interface{} `json:"parsedData"`
+}
+
+// ParseRevertData decodes the data a transaction reverted with, as either the built-in Error(string)
+// or Panic(uint256), or a custom error from the contract ABI. The contract ABI may be nil, and nil
+// is returned if the data does not match any known error, or does not decode as the error with its
+// selector, leaving the raw revert data as the only reason given.
+func ParseRevertData(data []byte, contractABI *ContractABI) *ParsedRevert {
+	if len(data) < 4 {
+		return nil
+	}
+	selector := hex.EncodeToString(data[:4])
+
+	candidates := []ContractABIFunction{revertErrorABI, revertPanicABI}
+	if contractABI != nil {
+		candidates = append(candidates, contractABI.Errors...)
+	}
+	for _, candidate := range candidates {
+		if candidate.Signature() != selector {
+			continue
+		}
+		result, err := parseCandidate(candidate.Parse, data[4:])
+		if err != nil {
+			continue
+		}
+		parsed := &ParsedRevert{
+			Sig:        candidate.String(),
+			ParsedData: result,
+		}
+		switch candidate.Signature() {
+		case revertErrorABI.Signature():
+			parsed.Message, _ = result["message"].(string)
+		case revertPanicABI.Signature():
+			if code, ok := result["code"].(*big.Int); ok && code.IsUint64() {
+				parsed.Message = panicReasons[code.Uint64()]
+			}
+		}
+		return parsed
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRevertData_Error(t *testing.T) {
+	// Error("not enough funds")
+	data := NewHexData("0x08c379a0" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"0000000000000000000000000000000000000000000000000000000000000010" +
+		"6e6f7420656e6f7567682066756e647300000000000000000000000000000000")
+
+	revert := ParseRevertData(data.AsBytes(), nil)
+
+	assert.Equal(t, "Error(string message)", revert.Sig)
+	assert.Equal(t, "not enough funds", revert.Message)
+	assert.Equal(t, "not enough funds", revert.ParsedData["message"])
+}
+
+func TestParseRevertData_Panic(t *testing.T) {
+	data := NewHexData("0x4e487b710000000000000000000000000000000000000000000000000000000000000011")
+
+	revert := ParseRevertData(data.AsBytes(), nil)
+
+	assert.Equal(t, "Panic(uint256 code)", revert.Sig)
+	assert.Equal(t, "arithmetic overflow or underflow", revert.Message)
+	assert.Equal(t, big.NewInt(0x11), revert.ParsedData["code"])
+}
+
+func TestParseRevertData_CustomError(t *testing.T) {
+	structure, err := NewABIStructureFromJSON(`[{"inputs":[{"name":"available","type":"uint256"},{"name":"required","type":"uint256"}],"name":"InsufficientBalance","type":"error"}]`)
+	assert.Nil(t, err)
+	contractABI := structure.ToInternalABI()
+	assert.Len(t, contractABI.Errors, 1)
+
+	data := NewHexData("0x" + contractABI.Errors[0].Signature() +
+		"0000000000000000000000000000000000000000000000000000000000000001" +
+		"0000000000000000000000000000000000000000000000000000000000000002")
+
+	revert := ParseRevertData(data.AsBytes(), contractABI)
+
+	assert.Equal(t, "InsufficientBalance(uint256 available,uint256 required)", revert.Sig)
+	assert.Equal(t, "", revert.Message)
+	assert.Equal(t, big.NewInt(1), revert.ParsedData["available"])
+	assert.Equal(t, big.NewInt(2), revert.ParsedData["required"])
+
+	// unknown errors are not decoded
+	revert = ParseRevertData(data.AsBytes(), nil)
+	assert.Nil(t, revert)
+}
+
+func TestParseRevertData_Malformed(t *testing.T) {
+	// Error(string) with an offset past the end of the data
+	data := NewHexData("0x08c379a0" +
+		"00000000000000000000000000000000000000000000000000000000000000ff")
+
+	assert.Nil(t, ParseRevertData(data.AsBytes(), nil))
+}
@@ -0,0 +1,78 @@
+package types
+
+import (
+	"encoding/hex"
+	"errors"
+)
+
+// FunctionSampler is a view function of a contract that is called as blocks are indexed, so that
+// state only reachable through view functions can be followed over time. The function is sampled
+// every Interval blocks, or if Interval is 0, at every block the contract is called in, as that is
+// when its state can change.
+type FunctionSampler struct {
+	// Name identifies the sampler and its samples, and is the function given if not set
+	Name string `json:"name" toml:"name,omitempty"`
+	// Function is the name of the function, or its signature if the name is overloaded
+	Function string        `json:"function" toml:"function"`
+	Args     []interface{} `json:"args,omitempty" toml:"args,omitempty"`
+	Interval uint64        `json:"interval" toml:"interval,omitempty"`
+
+	// Sig and CallData are found from the contract ABI when the sampler is added
+	Sig      string  `json:"sig" toml:"-"`
+	CallData HexData `json:"callData" toml:"-"`
+}
+
+// Resolve finds the sampled function in the contract ABI and encodes the call that samples it
+func (sampler *FunctionSampler) Resolve(contractABI *ContractABI) error {
+	if sampler.Function == "" {
+		return errors.New("no function given")
+	}
+	if contractABI == nil {
+		return errors.New("no ABI found for contract")
+	}
+	function, err := contractABI.FindFunction(sampler.Function, len(sampler.Args))
+	if err != nil {
+		return err
+	}
+	callData, err := function.EncodeCall(sampler.Args)
+	if err != nil {
+		return err
+	}
+
+	if sampler.Name == "" {
+		sampler.Name = sampler.Function
+	}
+	sampler.Sig = function.String()
+	sampler.CallData = HexData(hex.EncodeToString(callData))
+	return nil
+}
+
+// IsDue reports whether the sampler should be called at the given block, given whether
+// the contract was called in it
+func (sampler *FunctionSampler) IsDue(blockNumber uint64, called bool) bool {
+	if sampler.Interval == 0 {
+		return called
+	}
+	return blockNumber%sampler.Interval == 0
+}
+
+// FunctionSample is the raw output of a sampled function at a block. If the call
+// failed, the error is kept instead so that gaps in the samples can be explained.
+type FunctionSample struct {
+	Address     Address `json:"address"`
+	Name        string  `json:"name"`
+	Sig         string  `json:"sig"`
+	BlockNumber uint64  `json:"blockNumber"`
+	Timestamp   uint64  `json:"timestamp"`
+	Output      HexData `json:"output"`
+	Error       string  `json:"error,omitempty"`
+}
+
+// SamplesOptions gives the options samples are fetched with, which are ordered oldest first unless sorted desc
+func SamplesOptions(options *QueryOptions) *QueryOptions {
+	sorted := *options
+	if sorted.Sort == "" {
+		sorted.Sort = SortAscending
+	}
+	return &sorted
+}
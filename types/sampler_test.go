@@ -0,0 +1,49 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFunctionSampler_Resolve(t *testing.T) {
+	structure, err := NewABIStructureFromJSON(`[
+		{"type":"function","name":"collateralRatio","inputs":[],"outputs":[{"name":"","type":"uint256"}],"stateMutability":"view"},
+		{"type":"function","name":"balanceOf","inputs":[{"name":"owner","type":"address"}],"outputs":[{"name":"","type":"uint256"}],"stateMutability":"view"}
+	]`)
+	assert.Nil(t, err)
+	contractABI := structure.ToInternalABI()
+
+	sampler := &FunctionSampler{Function: "collateralRatio"}
+	err = sampler.Resolve(contractABI)
+	assert.Nil(t, err)
+	assert.Equal(t, "collateralRatio", sampler.Name)
+	assert.Equal(t, "collateralRatio()", sampler.Sig)
+	assert.Equal(t, HexData("b4eae1cb"), sampler.CallData)
+
+	sampler = &FunctionSampler{Name: "ownerBalance", Function: "balanceOf", Args: []interface{}{"0x9d13c6d3afe1721beef56b55d303b09e021e27ab"}}
+	err = sampler.Resolve(contractABI)
+	assert.Nil(t, err)
+	assert.Equal(t, "ownerBalance", sampler.Name)
+	assert.Equal(t, "balanceOf(address owner)", sampler.Sig)
+	assert.Equal(t, HexData("70a082310000000000000000000000009d13c6d3afe1721beef56b55d303b09e021e27ab"), sampler.CallData)
+
+	err = (&FunctionSampler{Function: "missing"}).Resolve(contractABI)
+	assert.EqualError(t, err, "function not found: missing")
+
+	err = (&FunctionSampler{Function: "balanceOf", Args: []interface{}{"0x01"}}).Resolve(contractABI)
+	assert.EqualError(t, err, "owner: invalid address 0x01")
+
+	err = (&FunctionSampler{Function: "collateralRatio"}).Resolve(nil)
+	assert.EqualError(t, err, "no ABI found for contract")
+}
+
+func TestFunctionSampler_IsDue(t *testing.T) {
+	everyTen := &FunctionSampler{Interval: 10}
+	assert.True(t, everyTen.IsDue(20, false))
+	assert.False(t, everyTen.IsDue(21, true))
+
+	onChange := &FunctionSampler{}
+	assert.True(t, onChange.IsDue(21, true))
+	assert.False(t, onChange.IsDue(22, false))
+}
@@ -0,0 +1,132 @@
+package types
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sync"
+)
+
+// SignatureRegistry holds the function and event definitions of every known ABI, indexed by their
+// selector and topic, so that calls and events of contracts without a template can still be decoded.
+// The same selector can be defined differently by different ABIs, e.g. with other argument names or
+// indexed arguments, in which case all definitions are kept and the match is ambiguous.
+type SignatureRegistry struct {
+	mu        sync.RWMutex
+	functions map[string][]ContractABIFunction
+	events    map[string][]ContractABIEvent
+	known     map[string]bool
+}
+
+func NewSignatureRegistry() *SignatureRegistry {
+	return &SignatureRegistry{
+		functions: make(map[string][]ContractABIFunction),
+		events:    make(map[string][]ContractABIEvent),
+		known:     make(map[string]bool),
+	}
+}
+
+// AddRawABI adds all the functions and events of a JSON ABI to the registry
+func (sr *SignatureRegistry) AddRawABI(rawABI string) error {
+	if rawABI == "" {
+		return nil
+	}
+	structure, err := NewABIStructureFromJSON(rawABI)
+	if err != nil {
+		return err
+	}
+	sr.AddABI(structure.ToInternalABI())
+	return nil
+}
+
+// AddABI adds all the functions and events of an ABI to the registry
+func (sr *SignatureRegistry) AddABI(contractABI *ContractABI) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	for _, function := range contractABI.Functions {
+		key := "function " + function.String()
+		if sr.known[key] {
+			continue
+		}
+		sr.known[key] = true
+		sr.functions[function.Signature()] = append(sr.functions[function.Signature()], function)
+	}
+	for _, event := range contractABI.Events {
+		// anonymous events have no signature topic to be found by
+		key := "event " + eventDefinition(event)
+		if event.Anonymous || sr.known[key] {
+			continue
+		}
+		sr.known[key] = true
+		sr.events[event.Signature()] = append(sr.events[event.Signature()], event)
+	}
+}
+
+// Functions returns the known functions with the given 4 byte selector, as hex without 0x
+func (sr *SignatureRegistry) Functions(selector string) []ContractABIFunction {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+	return sr.functions[selector]
+}
+
+// Events returns the known events with the given signature topic that have the given number of topics
+func (sr *SignatureRegistry) Events(topic Hash, numTopics int) []ContractABIEvent {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	var matching []ContractABIEvent
+	for _, event := range sr.events[string(topic)] {
+		indexed := 0
+		for _, input := range event.Inputs {
+			if input.Indexed {
+				indexed++
+			}
+		}
+		if indexed+1 == numTopics {
+			matching = append(matching, event)
+		}
+	}
+	return matching
+}
+
+// eventDefinition is the signature of the event with its argument names and which arguments are
+// indexed, since events with the same signature topic can be decoded differently
+func eventDefinition(event ContractABIEvent) string {
+	indexed := make([]bool, len(event.Inputs))
+	for i, input := range event.Inputs {
+		indexed[i] = input.Indexed
+	}
+	return fmt.Sprintf("%s%v", event.String(), indexed)
+}
+
+// parseCandidate decodes data that may not have been produced by the candidate definition. The
+// parser does not check the bounds of the data it is given, so a mismatch is caught as an error.
+func parseCandidate(parse func([]byte) (map[string]interface{}, error), data []byte) (result map[string]interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("data does not match definition: %v", r)
+		}
+	}()
+	return parse(data)
+}
+
+// decodeCallFromRegistry finds the known function that the call data was made with, returning nil if
+// none match the data. The definitions of all the functions with the selector are given if more than one.
+func decodeCallFromRegistry(registry *SignatureRegistry, data []byte) (*ContractABIFunction, map[string]interface{}, []string) {
+	if len(data) < 4 {
+		return nil, nil, nil
+	}
+	candidates := registry.Functions(hex.EncodeToString(data[:4]))
+	var ambiguousSigs []string
+	if len(candidates) > 1 {
+		for _, candidate := range candidates {
+			ambiguousSigs = append(ambiguousSigs, candidate.String())
+		}
+	}
+	for i := range candidates {
+		if result, err := parseCandidate(candidates[i].Parse, data[4:]); err == nil {
+			return &candidates[i], result, ambiguousSigs
+		}
+	}
+	return nil, nil, nil
+}
@@ -0,0 +1,96 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	registryERC20ABI  = `[{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256","indexed":false}]}]`
+	registryERC721ABI = `[{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"tokenId","type":"uint256","indexed":true}]}]`
+	registryOtherABI  = `[{"type":"function","name":"transfer","inputs":[{"name":"recipient","type":"address"},{"name":"amount","type":"uint256"}],"outputs":[]}]`
+
+	transferTopic = "ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+)
+
+var transferCallData = NewHexData("0xa9059cbb000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000003e8")
+
+func TestSignatureRegistry_AddRawABI(t *testing.T) {
+	registry := NewSignatureRegistry()
+	assert.Nil(t, registry.AddRawABI(registryERC20ABI))
+	assert.Nil(t, registry.AddRawABI(registryERC20ABI))
+	assert.Nil(t, registry.AddRawABI(registryERC721ABI))
+	assert.Nil(t, registry.AddRawABI(""))
+	assert.NotNil(t, registry.AddRawABI("not an abi"))
+
+	assert.Len(t, registry.Functions("a9059cbb"), 1)
+	assert.Len(t, registry.Events(NewHash(transferTopic), 3), 1)
+	assert.Len(t, registry.Events(NewHash(transferTopic), 4), 1)
+	assert.Len(t, registry.Events(NewHash(transferTopic), 2), 0)
+}
+
+func TestParsedTransaction_ParseTransactionFromRegistry(t *testing.T) {
+	registry := NewSignatureRegistry()
+	assert.Nil(t, registry.AddRawABI(registryERC20ABI))
+
+	parsedTx := &ParsedTransaction{RawTransaction: &Transaction{To: NewAddress("0x0000000000000000000000000000000000000001"), Data: transferCallData}}
+	err := parsedTx.ParseTransactionFromRegistry(registry)
+
+	assert.Nil(t, err)
+	assert.True(t, parsedTx.RegistryDecoded)
+	assert.Equal(t, "transfer(address to,uint256 value)", parsedTx.Sig)
+	assert.Equal(t, big.NewInt(1000), parsedTx.ParsedData["value"])
+	assert.Empty(t, parsedTx.AmbiguousSigs)
+}
+
+func TestParsedInternalCall_ParseCallFromRegistry_Ambiguous(t *testing.T) {
+	registry := NewSignatureRegistry()
+	assert.Nil(t, registry.AddRawABI(registryERC20ABI))
+	assert.Nil(t, registry.AddRawABI(registryOtherABI))
+
+	parsedCall := &ParsedInternalCall{RawCall: &InternalCall{Input: transferCallData, Output: NewHexData("0x0000000000000000000000000000000000000000000000000000000000000001")}}
+	err := parsedCall.ParseCallFromRegistry(registry)
+
+	assert.Nil(t, err)
+	assert.True(t, parsedCall.RegistryDecoded)
+	assert.Equal(t, "transfer(address to,uint256 value)", parsedCall.Sig)
+	assert.Equal(t, true, parsedCall.ParsedOutput["output0"])
+	assert.Equal(t, []string{"transfer(address to,uint256 value)", "transfer(address recipient,uint256 amount)"}, parsedCall.AmbiguousSigs)
+}
+
+func TestParsedInternalCall_ParseCallFromRegistry_Mismatch(t *testing.T) {
+	registry := NewSignatureRegistry()
+	assert.Nil(t, registry.AddRawABI(registryERC20ABI))
+
+	// right selector, but too short for the arguments
+	parsedCall := &ParsedInternalCall{RawCall: &InternalCall{Input: NewHexData("0xa9059cbb0000")}}
+	err := parsedCall.ParseCallFromRegistry(registry)
+
+	assert.Nil(t, err)
+	assert.False(t, parsedCall.RegistryDecoded)
+	assert.Empty(t, parsedCall.Sig)
+}
+
+func TestParsedEvent_ParseEventFromRegistry(t *testing.T) {
+	registry := NewSignatureRegistry()
+	assert.Nil(t, registry.AddRawABI(registryERC20ABI))
+	assert.Nil(t, registry.AddRawABI(registryERC721ABI))
+
+	parsedEvent := &ParsedEvent{RawEvent: &Event{
+		Topics: []Hash{
+			NewHash(transferTopic),
+			NewHash("0x0000000000000000000000000000000000000000000000000000000000000001"),
+			NewHash("0x0000000000000000000000000000000000000000000000000000000000000002"),
+		},
+		Data: NewHexData("0x00000000000000000000000000000000000000000000000000000000000003e8"),
+	}}
+	err := parsedEvent.ParseEventFromRegistry(registry)
+
+	assert.Nil(t, err)
+	assert.True(t, parsedEvent.RegistryDecoded)
+	assert.Equal(t, "event Transfer(address from,address to,uint256 value)", parsedEvent.Sig)
+	assert.Equal(t, big.NewInt(1000), parsedEvent.ParsedData["value"])
+	assert.Empty(t, parsedEvent.AmbiguousSigs)
+}
@@ -115,6 +115,7 @@ type ReportingResponseTemplate struct {
 	HistoricState []*ParsedState `json:"historicState"`
 	Total         uint64         `json:"total"`
 	Options       *PageOptions   `json:"options"`
+	Next          string         `json:"next,omitempty"`
 }
 
 type ParsedState struct {
@@ -122,6 +123,24 @@ type ParsedState struct {
 	HistoricStorage []*StorageItem `json:"historicStorage"`
 }
 
+type VariableHistoryResponse struct {
+	Address Address          `json:"address"`
+	Path    string           `json:"path"`
+	Type    string           `json:"type"`
+	History []*VariableState `json:"history"`
+	// TotalStates is the number of storage states in the block range, which are what is paged over,
+	// so a page holds at most as many changes to the variable as its page size
+	TotalStates uint64       `json:"totalStates"`
+	Options     *PageOptions `json:"options"`
+	Next        string       `json:"next,omitempty"`
+}
+
+type VariableState struct {
+	BlockNumber uint64      `json:"blockNumber"`
+	Timestamp   uint64      `json:"timestamp"`
+	Value       interface{} `json:"value"`
+}
+
 type StorageResult struct {
 	Storage     map[Hash]string
 	StorageRoot Hash
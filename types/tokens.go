@@ -1,9 +1,267 @@
 package types
 
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// ERC721Token is the holder of a token of an ERC721 contract, from the block it was transferred to
+// them, until the block before it was next transferred
 type ERC721Token struct {
 	Contract  Address `json:"contract"`
 	Holder    Address `json:"holder"`
 	Token     string  `json:"token"`
 	HeldFrom  uint64  `json:"heldFrom"`
 	HeldUntil *uint64 `json:"heldUntil"`
+
+	// From, TransactionHash and Kind describe the transfer to the holder, which is the zero address
+	// once the token is burned. They are only known for tokens recorded by this version or later.
+	From            Address `json:"from,omitempty"`
+	TransactionHash Hash    `json:"transactionHash,omitempty"`
+	Kind            string  `json:"kind,omitempty"`
+
+	// Metadata is the stored metadata of the token, which is filled in when it is returned over RPC
+	Metadata *ERC721Metadata `json:"metadata,omitempty"`
+}
+
+// ERC721Metadata is the name and symbol of an ERC721 contract and the URI of one of its tokens, read when the
+// token is minted and again when the contract emits a MetadataUpdate event for it. Each of them is optional in
+// the standard, so is left empty if the contract does not provide it.
+type ERC721Metadata struct {
+	Contract Address `json:"contract"`
+	Token    string  `json:"token"`
+	Name     string  `json:"name"`
+	Symbol   string  `json:"symbol"`
+	TokenURI string  `json:"tokenURI"`
+	// Metadata is the JSON content of the token URI, if it is an on-chain data URI
+	Metadata    json.RawMessage `json:"metadata,omitempty"`
+	BlockNumber uint64          `json:"blockNumber"`
+}
+
+// ERC1155Token is the balance an account holds of a single token ID of an ERC1155 contract,
+// from the block it changed to, until the block before it next changed
+type ERC1155Token struct {
+	Contract  Address `json:"contract"`
+	Holder    Address `json:"holder"`
+	Token     string  `json:"token"`
+	Amount    string  `json:"amount"`
+	HeldFrom  uint64  `json:"heldFrom"`
+	HeldUntil *uint64 `json:"heldUntil"`
+}
+
+// ERC20Allowance is the amount a spender was allowed to transfer on behalf of an owner,
+// from the block it was set at, until the block before it next changed
+type ERC20Allowance struct {
+	Contract    Address `json:"contract"`
+	Owner       Address `json:"owner"`
+	Spender     Address `json:"spender"`
+	Amount      string  `json:"amount"`
+	BlockNumber uint64  `json:"blockNumber"`
+	ValidUntil  *uint64 `json:"validUntil"`
+	// ScaledAmount is the amount scaled by the decimals of the token, if it was asked for
+	ScaledAmount string `json:"scaledAmount,omitempty"`
+}
+
+// ERC20Balance is the balance an account holds of an ERC20 token,
+// from the block it changed to, until the block before it next changed
+type ERC20Balance struct {
+	Contract  Address `json:"contract"`
+	Holder    Address `json:"holder"`
+	Amount    string  `json:"amount"`
+	HeldFrom  uint64  `json:"heldFrom"`
+	HeldUntil *uint64 `json:"heldUntil"`
+}
+
+// ERC20HolderBalance is the balance of a holder of an ERC20 token at a block, and the block it changed to that amount at
+type ERC20HolderBalance struct {
+	Holder   Address `json:"holder"`
+	Amount   string  `json:"amount"`
+	HeldFrom uint64  `json:"heldFrom"`
+	// ScaledAmount is the amount scaled by the decimals of the token, if it was asked for
+	ScaledAmount string `json:"scaledAmount,omitempty"`
+}
+
+// ERC20Metadata is the name, symbol and decimals of an ERC20 token, read when the token is first seen.
+// Each of them is optional in the standard, so is left empty if the token does not provide it.
+type ERC20Metadata struct {
+	Contract    Address `json:"contract"`
+	Name        string  `json:"name"`
+	Symbol      string  `json:"symbol"`
+	Decimals    *uint8  `json:"decimals"`
+	BlockNumber uint64  `json:"blockNumber"`
+	// BalanceDrift is the block the balances of the token were first found to change without Transfer
+	// events, such as for a rebasing token, so they cannot be computed from the events
+	BalanceDrift *uint64 `json:"balanceDrift,omitempty"`
+}
+
+// FlagBalanceDrift marks the token as having balances that change without Transfer events, if it is not already
+func (metadata *ERC20Metadata) FlagBalanceDrift(block uint64) {
+	if metadata.BalanceDrift == nil {
+		metadata.BalanceDrift = &block
+	}
+}
+
+// ERC20TotalSupply is the total supply of an ERC20 token, from the block it was recorded at,
+// until the block before it was next recorded
+type ERC20TotalSupply struct {
+	Contract    Address `json:"contract"`
+	Amount      string  `json:"amount"`
+	BlockNumber uint64  `json:"blockNumber"`
+	ValidUntil  *uint64 `json:"validUntil"`
+}
+
+// TokenAmount is a raw token amount, along with the amount scaled by the decimals of the token if it was asked for
+type TokenAmount struct {
+	Raw    *big.Int `json:"raw"`
+	Scaled string   `json:"scaled,omitempty"`
+}
+
+func NewTokenAmount(raw *big.Int, decimals *uint8) *TokenAmount {
+	amount := &TokenAmount{Raw: raw}
+	if decimals != nil {
+		amount.Scaled = ScaleAmount(raw, *decimals)
+	}
+	return amount
+}
+
+// ScaleAmount formats a raw token amount as a decimal, e.g. 1500000000000000000 with 18 decimals is "1.5"
+func ScaleAmount(amount *big.Int, decimals uint8) string {
+	digits := amount.String()
+	if decimals == 0 {
+		return digits
+	}
+	places := int(decimals)
+	if len(digits) <= places {
+		digits = strings.Repeat("0", places-len(digits)+1) + digits
+	}
+	whole, fraction := digits[:len(digits)-places], strings.TrimRight(digits[len(digits)-places:], "0")
+	if fraction == "" {
+		return whole
+	}
+	return whole + "." + fraction
+}
+
+const (
+	TransferKindTransfer = "transfer"
+	TransferKindMint     = "mint"
+	TransferKindBurn     = "burn"
+)
+
+const (
+	TransferRoleSender   = "sender"
+	TransferRoleReceiver = "receiver"
+)
+
+// ERC20Transfer is a Transfer event of an ERC20 contract. Transfers from the zero address are
+// mints, and transfers to the zero address are burns.
+type ERC20Transfer struct {
+	Contract        Address `json:"contract"`
+	From            Address `json:"from"`
+	To              Address `json:"to"`
+	Amount          string  `json:"amount"`
+	Kind            string  `json:"kind"`
+	TransactionHash Hash    `json:"transactionHash"`
+	// Index is the index of the Transfer event in the block
+	Index       uint64 `json:"index"`
+	BlockNumber uint64 `json:"blockNumber"`
+	Timestamp   uint64 `json:"timestamp"`
+	// ScaledAmount is the amount scaled by the decimals of the token, if it was asked for
+	ScaledAmount string `json:"scaledAmount,omitempty"`
+}
+
+// NewERC20Transfer creates a transfer from its event, classifying it by the zero address
+func NewERC20Transfer(event *Event, from Address, to Address, amount *big.Int) *ERC20Transfer {
+	kind := TransferKindTransfer
+	if from.IsEmpty() {
+		kind = TransferKindMint
+	} else if to.IsEmpty() {
+		kind = TransferKindBurn
+	}
+	return &ERC20Transfer{
+		Contract:        event.Address,
+		From:            from,
+		To:              to,
+		Amount:          amount.String(),
+		Kind:            kind,
+		TransactionHash: event.TransactionHash,
+		Index:           event.Index,
+		BlockNumber:     event.BlockNumber,
+		Timestamp:       event.Timestamp,
+	}
+}
+
+// SortableAmount returns the amount in a form that can be compared by range
+func (transfer *ERC20Transfer) SortableAmount() string {
+	amount, _ := new(big.Int).SetString(transfer.Amount, 10)
+	return SortableNumber(amount)
+}
+
+// ERC20TransferFilter selects the transfers of an ERC20 contract by the accounts and amounts involved
+type ERC20TransferFilter struct {
+	// Holder selects the transfers the account sent or received
+	Holder *Address `json:"holder"`
+	// Role is sender or receiver, to only select the transfers the holder sent or received
+	Role string `json:"role"`
+	// Kind is transfer, mint or burn
+	Kind      string   `json:"kind"`
+	MinAmount *big.Int `json:"minAmount"`
+	MaxAmount *big.Int `json:"maxAmount"`
+}
+
+// Validate checks the filter is well-formed
+func (filter *ERC20TransferFilter) Validate() error {
+	if filter.Role != "" && filter.Role != TransferRoleSender && filter.Role != TransferRoleReceiver {
+		return errors.New("role must be sender or receiver")
+	}
+	if filter.Role != "" && filter.Holder == nil {
+		return errors.New("role given without a holder")
+	}
+	if filter.Kind != "" && filter.Kind != TransferKindTransfer && filter.Kind != TransferKindMint && filter.Kind != TransferKindBurn {
+		return errors.New("kind must be transfer, mint or burn")
+	}
+	if (filter.MinAmount != nil && filter.MinAmount.Sign() < 0) || (filter.MaxAmount != nil && filter.MaxAmount.Sign() < 0) {
+		return errors.New("amounts must not be negative")
+	}
+	return nil
+}
+
+// AmountRange returns the bounds of the amount in sortable form, which are empty if not given
+func (filter *ERC20TransferFilter) AmountRange() (string, string) {
+	var min, max string
+	if filter.MinAmount != nil {
+		min = SortableNumber(filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		max = SortableNumber(filter.MaxAmount)
+	}
+	return min, max
+}
+
+// Matches reports whether the transfer is selected by the filter
+func (filter *ERC20TransferFilter) Matches(transfer *ERC20Transfer) bool {
+	if filter.Holder != nil {
+		sent, received := transfer.From == *filter.Holder, transfer.To == *filter.Holder
+		switch filter.Role {
+		case TransferRoleSender:
+			if !sent {
+				return false
+			}
+		case TransferRoleReceiver:
+			if !received {
+				return false
+			}
+		default:
+			if !sent && !received {
+				return false
+			}
+		}
+	}
+	if filter.Kind != "" && filter.Kind != transfer.Kind {
+		return false
+	}
+	min, max := filter.AmountRange()
+	amount := transfer.SortableAmount()
+	return (min == "" || amount >= min) && (max == "" || amount <= max)
 }
@@ -0,0 +1,82 @@
+package types
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewERC20Transfer_ClassifiedByZeroAddress(t *testing.T) {
+	event := &Event{
+		Index:           3,
+		Address:         NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34"),
+		BlockNumber:     10,
+		TransactionHash: NewHash("0xf4f803b8d6c6b38e0b15d6cfe80fd1dcea4270ad24e93385fca36512bb9c2c59"),
+		Timestamp:       1000,
+	}
+	holder := NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17")
+	zero := NewAddress("0x0")
+
+	mint := NewERC20Transfer(event, zero, holder, big.NewInt(500))
+	burn := NewERC20Transfer(event, holder, zero, big.NewInt(500))
+	transfer := NewERC20Transfer(event, holder, holder, big.NewInt(500))
+
+	assert.Equal(t, TransferKindMint, mint.Kind)
+	assert.Equal(t, TransferKindBurn, burn.Kind)
+	assert.Equal(t, TransferKindTransfer, transfer.Kind)
+	assert.Equal(t, "500", transfer.Amount)
+	assert.EqualValues(t, 3, transfer.Index)
+	assert.EqualValues(t, 10, transfer.BlockNumber)
+	assert.EqualValues(t, 1000, transfer.Timestamp)
+}
+
+func TestERC20TransferFilter_Validate(t *testing.T) {
+	holder := NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17")
+
+	assert.Nil(t, (&ERC20TransferFilter{}).Validate())
+	assert.Nil(t, (&ERC20TransferFilter{Holder: &holder, Role: TransferRoleSender, Kind: TransferKindBurn}).Validate())
+	assert.EqualError(t, (&ERC20TransferFilter{Holder: &holder, Role: "spender"}).Validate(), "role must be sender or receiver")
+	assert.EqualError(t, (&ERC20TransferFilter{Role: TransferRoleReceiver}).Validate(), "role given without a holder")
+	assert.EqualError(t, (&ERC20TransferFilter{Kind: "airdrop"}).Validate(), "kind must be transfer, mint or burn")
+	assert.EqualError(t, (&ERC20TransferFilter{MinAmount: big.NewInt(-1)}).Validate(), "amounts must not be negative")
+}
+
+func TestERC20TransferFilter_Matches(t *testing.T) {
+	sender := NewAddress("0x1349f3e1b8d71effb47b840594ff27da7e603d17")
+	receiver := NewAddress("0x1932c48b2bf8102ba33b4a6b545c32236e342f34")
+	transfer := &ERC20Transfer{From: sender, To: receiver, Amount: "1000", Kind: TransferKindTransfer}
+
+	assert.True(t, (&ERC20TransferFilter{}).Matches(transfer))
+	assert.True(t, (&ERC20TransferFilter{Holder: &receiver}).Matches(transfer))
+	assert.True(t, (&ERC20TransferFilter{Holder: &sender, Role: TransferRoleSender}).Matches(transfer))
+	assert.False(t, (&ERC20TransferFilter{Holder: &sender, Role: TransferRoleReceiver}).Matches(transfer))
+	assert.False(t, (&ERC20TransferFilter{Kind: TransferKindMint}).Matches(transfer))
+	// amounts are compared as numbers, not strings
+	assert.True(t, (&ERC20TransferFilter{MinAmount: big.NewInt(999), MaxAmount: big.NewInt(1000)}).Matches(transfer))
+	assert.False(t, (&ERC20TransferFilter{MinAmount: big.NewInt(10000)}).Matches(transfer))
+	assert.False(t, (&ERC20TransferFilter{MaxAmount: big.NewInt(200)}).Matches(transfer))
+}
+
+func TestScaleAmount(t *testing.T) {
+	oneAndAHalf, _ := new(big.Int).SetString("1500000000000000000", 10)
+
+	assert.Equal(t, "1.5", ScaleAmount(oneAndAHalf, 18))
+	assert.Equal(t, "0.000000000000000001", ScaleAmount(big.NewInt(1), 18))
+	assert.Equal(t, "12", ScaleAmount(big.NewInt(1200), 2))
+	assert.Equal(t, "1200", ScaleAmount(big.NewInt(1200), 0))
+	assert.Equal(t, "0", ScaleAmount(big.NewInt(0), 6))
+}
+
+func TestTokenAmount_JSON(t *testing.T) {
+	decimals := uint8(2)
+
+	raw, err := json.Marshal(NewTokenAmount(big.NewInt(1250), nil))
+	assert.Nil(t, err)
+	assert.Equal(t, `{"raw":1250}`, string(raw))
+
+	scaled, err := json.Marshal(NewTokenAmount(big.NewInt(1250), &decimals))
+	assert.Nil(t, err)
+	assert.Equal(t, `{"raw":1250,"scaled":"12.5"}`, string(scaled))
+}
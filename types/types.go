@@ -2,8 +2,30 @@ package types
 
 type Template struct {
 	TemplateName  string `json:"templateName"`
+	Version       uint64 `json:"version"`
 	ABI           string `json:"abi"`
 	StorageLayout string `json:"storageLayout"`
+
+	// Details of the compiled contract, if the template was created from compiler output
+	ContractName        string `json:"contractName,omitempty"`
+	CompilerVersion     string `json:"compilerVersion,omitempty"`
+	RuntimeBytecodeHash string `json:"runtimeBytecodeHash,omitempty"`
+}
+
+// TemplateAssignment is the template a contract uses from a given block onwards,
+// until the next assignment. A version of 0 always uses the latest version of
+// the template.
+type TemplateAssignment struct {
+	TemplateName string `json:"templateName"`
+	Version      uint64 `json:"version"`
+	FromBlock    uint64 `json:"fromBlock"`
+}
+
+// ProxyImplementation is the implementation contract that a proxy delegates
+// to from a given block onwards, until the next implementation.
+type ProxyImplementation struct {
+	Implementation Address `json:"implementation"`
+	FromBlock      uint64  `json:"fromBlock"`
 }
 
 type RawHeader struct {
@@ -35,11 +57,14 @@ type RawInnerCall struct {
 	Gas     HexNumber
 	GasUsed HexNumber
 	Output  HexData
+	Error   string
 	Calls   []RawInnerCall
 }
 
 type RawOuterCall struct {
-	Calls []RawInnerCall
+	Output HexData
+	Error  string
+	Calls  []RawInnerCall
 }
 
 type Block struct {
@@ -77,6 +102,10 @@ type Transaction struct {
 	Timestamp         uint64          `json:"timestamp"`
 	Events            []*Event        `json:"events"`
 	InternalCalls     []*InternalCall `json:"internalCalls"`
+	// Error is the reason given by the node for a failed transaction, e.g. "execution reverted",
+	// and RevertData is the data the transaction reverted with, if any
+	Error      string  `json:"error,omitempty"`
+	RevertData HexData `json:"revertData,omitempty"`
 }
 
 type InternalCall struct {
@@ -88,6 +117,14 @@ type InternalCall struct {
 	Input   HexData `json:"input"`
 	Output  HexData `json:"output"`
 	Type    string  `json:"type"`
+	Error   string  `json:"error,omitempty"`
+
+	// Depth is 1 for calls made directly by the transaction, and Parent is the index of the
+	// calling frame in the list of internal calls, or -1 if made directly by the transaction.
+	// Path gives the position of the call amongst its siblings at each level of the call tree.
+	Depth  uint64   `json:"depth"`
+	Parent int      `json:"parent"`
+	Path   []uint64 `json:"path"`
 }
 
 type Event struct {
@@ -155,7 +155,7 @@ export function getERC20Balance(address, holder, startBlockNumber, endBlockNumbe
       const data = Object.entries(res)
         .map(([key, value]) => ({
           block: key,
-          balance: value,
+          balance: value.raw,
         }))
         .sort((one, two) => two.block - one.block)
       const total = calculateTotal(res, options)